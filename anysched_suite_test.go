@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestAnySched.(t *testing.T) {
+func TestAnySched(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "AnySched. Suite")
+	RunSpecs(t, "AnySched Suite")
 }
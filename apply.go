@@ -0,0 +1,262 @@
+package anysched
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// ApplyResult is what ApplySvc did to a service.
+type ApplyResult string
+
+const (
+	// SvcCreated means that the service was not deployed, so it was deployed.
+	SvcCreated ApplyResult = "created"
+
+	// SvcUpdated means that the service was deployed with different settings,
+	// so it was updated.
+	SvcUpdated ApplyResult = "updated"
+
+	// SvcUnchanged means that the service was already deployed with the same
+	// settings, so nothing was done.
+	SvcUnchanged ApplyResult = "unchanged"
+)
+
+// SvcDiff is how a deployed service differs from a SvcCfg.
+type SvcDiff struct {
+	// Changed is the settings that differ, in the order of SvcSettings. It is
+	// empty if the service is up to date.
+	Changed []string
+
+	// Unknown is the settings that the SvcCfg sets, but that the manager
+	// can't read back from the scheduler, so it can't tell whether they
+	// differ. ApplySvc updates a service with any of them.
+	Unknown []string
+}
+
+// SvcSettings is the names of the settings of a SvcCfg that DiffSvcCfgs
+// compares, in the order that it returns them in.
+var SvcSettings = []string{
+	"image", "count", "command", "args", "env", "secrets", "labels", "ports", "volumes", "resources",
+	"health-check", "readiness-check", "sidecars", "init-containers", "constraints",
+}
+
+// svcSettingValues returns the settings of svcCfg, by the names in
+// SvcSettings.
+func svcSettingValues(svcCfg SvcCfg) map[string]interface{} {
+	return map[string]interface{}{
+		"image":           svcCfg.Image,
+		"count":           svcCfg.Count,
+		"command":         svcCfg.Command,
+		"args":            svcCfg.Args,
+		"env":             svcCfg.Env,
+		"secrets":         svcCfg.Secrets,
+		"labels":          svcCfg.Labels,
+		"ports":           svcCfg.Ports,
+		"volumes":         svcCfg.Volumes,
+		"resources":       svcCfg.Resources,
+		"health-check":    svcCfg.HealthCheck,
+		"readiness-check": svcCfg.ReadinessCheck,
+		"sidecars":        svcCfg.Sidecars,
+		"init-containers": svcCfg.InitContainers,
+		"constraints":     svcCfg.Constraints,
+	}
+}
+
+// ApplySvc makes the service of svcCfg match it, whether or not it is already
+// deployed: it deploys the service if it isn't, updates it if it differs from
+// svcCfg, and otherwise does nothing and returns an Operation that is already
+// done. It returns what it did with the Operation. It returns an error for a
+// service that differs if the manager isn't a SvcUpdater.
+//
+// The differences are the ones that the manager's DiffSvc finds, if it is a
+// SvcDiffer. Otherwise ApplySvc looks for the service in Svcs and compares
+// svcCfg with the SvcCfg that the manager's Svc returns, so it needs a
+// SvcGetter, and it updates a service with any setting that Svc doesn't
+// return.
+func ApplySvc(manager Manager, svcCfg SvcCfg) (Operation, ApplyResult, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, "", err
+	}
+	svcDiff, err := diffSvc(manager, svcCfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting the differences of service %q failed: %s", svcCfg.ID, err)
+	}
+	if svcDiff == nil {
+		op, err := manager.DeploySvc(svcCfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return op, SvcCreated, nil
+	}
+	if len(svcDiff.Changed) == 0 && len(svcDiff.Unknown) == 0 {
+		return &unchangedSvc{svcCfg: svcCfg, time: time.Now()}, SvcUnchanged, nil
+	}
+	svcUpdater, ok := manager.(SvcUpdater)
+	if !ok {
+		return nil, "", fmt.Errorf("service %q differs, but the manager cannot update services", svcCfg.ID)
+	}
+	op, err := svcUpdater.UpdateSvc(svcCfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return op, SvcUpdated, nil
+}
+
+// diffSvc returns how the deployed service of svcCfg differs from it, or nil
+// if it is not deployed, as ApplySvc finds out.
+func diffSvc(manager Manager, svcCfg SvcCfg) (*SvcDiff, error) {
+	if svcDiffer, ok := manager.(SvcDiffer); ok {
+		return svcDiffer.DiffSvc(svcCfg)
+	}
+	svcGetter, ok := manager.(SvcGetter)
+	if !ok {
+		return nil, errors.New("the manager can neither diff nor get services")
+	}
+	svcs, err := manager.Svcs()
+	if err != nil {
+		return nil, err
+	}
+	deployed := false
+	for _, svc := range svcs {
+		if svc.ID == svcCfg.ID && (svcCfg.Namespace == "" || svc.Namespace == svcCfg.Namespace) {
+			deployed = true
+		}
+	}
+	if !deployed {
+		return nil, nil
+	}
+	svcDetail, err := svcGetter.Svc(svcCfg.ID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSvcCfgs(svcDetail.SvcCfg, svcCfg), nil
+}
+
+// DiffSvcCfgs returns how a deployed service, whose settings are deployed,
+// differs from desired, comparing all of the settings in SvcSettings. Managers
+// call it with SvcCfgs that they reconstruct from the scheduler's objects for
+// both, so that whatever the scheduler can't express is lost from both, and
+// set the Unknown of the SvcDiff with UnknownSvcSettings.
+//
+// A setting that is a slice or a map is the same whether it is nil or empty.
+// Nil Resources, and a Resources field that is zero, in desired mean the
+// scheduler's defaults, which the scheduler might fill in, so they are the same
+// as any resources; CPU is compared to the millicore. Likewise a port without
+// a HostPort in desired is the same as one with any HostPort, and a blank
+// Protocol is the same as "tcp".
+func DiffSvcCfgs(deployed, desired SvcCfg) *SvcDiff {
+	svcDiff := &SvcDiff{Changed: []string{}}
+	deployedValues, desiredValues := svcSettingValues(deployed), svcSettingValues(desired)
+	for _, setting := range SvcSettings {
+		var same bool
+		switch setting {
+		case "resources":
+			same = desired.Resources == nil || resourcesMatch(deployed.Resources, *desired.Resources)
+		case "ports":
+			same = portsMatch(deployed.Ports, desired.Ports)
+		default:
+			same = isEmpty(deployedValues[setting]) && isEmpty(desiredValues[setting]) ||
+				reflect.DeepEqual(deployedValues[setting], desiredValues[setting])
+		}
+		if !same {
+			svcDiff.Changed = append(svcDiff.Changed, setting)
+		}
+	}
+	return svcDiff
+}
+
+// UnknownSvcSettings returns the settings that svcCfg sets, out of the ones in
+// SvcSettings that aren't in known, for the Unknown of a SvcDiff. known is the
+// settings that a manager reads back from its scheduler.
+func UnknownSvcSettings(svcCfg SvcCfg, known ...string) []string {
+	unknown := []string{}
+	values := svcSettingValues(svcCfg)
+	for _, setting := range SvcSettings {
+		if !isEmpty(values[setting]) && !containsString(known, setting) {
+			unknown = append(unknown, setting)
+		}
+	}
+	return unknown
+}
+
+// isEmpty returns whether value is the zero value of its type, or an empty
+// slice or map.
+func isEmpty(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(value, reflect.Zero(v.Type()).Interface())
+}
+
+// portsMatch returns whether deployed has the ports of desired, in the same
+// order, apart from the host ports that aren't in desired.
+func portsMatch(deployed, desired []PortCfg) bool {
+	if len(deployed) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		if deployed[i].Name != desired[i].Name || deployed[i].ContainerPort != desired[i].ContainerPort ||
+			deployed[i].ProtocolOrDefault() != desired[i].ProtocolOrDefault() ||
+			desired[i].HostPort != 0 && deployed[i].HostPort != desired[i].HostPort {
+			return false
+		}
+	}
+	return true
+}
+
+// resourcesMatch returns whether deployed has every resource that is not zero
+// in desired.
+func resourcesMatch(deployed *Resources, desired Resources) bool {
+	if deployed == nil {
+		deployed = &Resources{}
+	}
+	cpuMatches := func(deployed, desired float64) bool {
+		return desired == 0 || math.Abs(deployed-desired) < 0.001
+	}
+	bytesMatch := func(deployed, desired int64) bool {
+		return desired == 0 || deployed == desired
+	}
+	return cpuMatches(deployed.CPU, desired.CPU) && cpuMatches(deployed.CPULimit, desired.CPULimit) &&
+		bytesMatch(deployed.Memory, desired.Memory) && bytesMatch(deployed.MemoryLimit, desired.MemoryLimit) &&
+		bytesMatch(deployed.Disk, desired.Disk)
+}
+
+// unchangedSvc is the Operation that ApplySvc returns for a service that it
+// didn't change. It is done from the start.
+type unchangedSvc struct {
+	svcCfg SvcCfg
+	time   time.Time
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (op *unchangedSvc) GetProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  op.svcCfg.ID,
+		"count": op.svcCfg.Count,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (op *unchangedSvc) Wait(ctx context.Context) (result interface{}, err error) {
+	return op, nil
+}
+
+// GetStatus is for polling the status of the deployment
+func (op *unchangedSvc) GetStatus() (status *OperationStatus, err error) {
+	return &OperationStatus{
+		ClientTime:         time.Now(),
+		LastTransitionTime: op.time,
+		LastUpdateTime:     op.time,
+		Msg:                fmt.Sprintf("Service %q is unchanged.", op.svcCfg.ID),
+		Done:               true,
+	}, nil
+}
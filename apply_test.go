@@ -0,0 +1,263 @@
+package anysched_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// applyManager is a Manager whose DiffSvc returns svcDiff, and which records
+// whether DeploySvc or UpdateSvc was called. Its other methods panic.
+type applyManager struct {
+	anysched.Manager
+	svcDiff *anysched.SvcDiff
+	diffErr error
+	called  []string
+}
+
+type applyOperation struct {
+	anysched.Operation
+}
+
+func (mgr *applyManager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	return mgr.svcDiff, mgr.diffErr
+}
+
+func (mgr *applyManager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	mgr.called = append(mgr.called, "DeploySvc")
+	return applyOperation{}, nil
+}
+
+func (mgr *applyManager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	mgr.called = append(mgr.called, "UpdateSvc")
+	return applyOperation{}, nil
+}
+
+// svcApplyManager is a Manager without DiffSvc, whose Svcs and Svc return the
+// service of svcDetail, if it isn't nil, and which records whether DeploySvc
+// or UpdateSvc was called. Its other methods panic.
+type svcApplyManager struct {
+	anysched.Manager
+	svcDetail *anysched.SvcDetail
+	called    []string
+}
+
+func (mgr *svcApplyManager) Svcs() ([]anysched.Svc, error) {
+	if mgr.svcDetail == nil {
+		return []anysched.Svc{{ID: "another"}}, nil
+	}
+	return []anysched.Svc{{ID: "another"}, mgr.svcDetail.Svc}, nil
+}
+
+func (mgr *svcApplyManager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	return mgr.svcDetail, nil
+}
+
+func (mgr *svcApplyManager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	mgr.called = append(mgr.called, "DeploySvc")
+	return applyOperation{}, nil
+}
+
+func (mgr *svcApplyManager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	mgr.called = append(mgr.called, "UpdateSvc")
+	return applyOperation{}, nil
+}
+
+var _ = Describe("apply.go", func() {
+	svcCfg := anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2}
+
+	Describe("ApplySvc", func() {
+		It("deploys a service that is not deployed", func() {
+			manager := &applyManager{}
+			op, result, err := anysched.ApplySvc(manager, svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(anysched.SvcCreated))
+			Expect(op).To(Equal(applyOperation{}))
+			Expect(manager.called).To(Equal([]string{"DeploySvc"}))
+		})
+
+		It("updates a service that differs", func() {
+			manager := &applyManager{svcDiff: &anysched.SvcDiff{Changed: []string{"image"}}}
+			op, result, err := anysched.ApplySvc(manager, svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(anysched.SvcUpdated))
+			Expect(op).To(Equal(applyOperation{}))
+			Expect(manager.called).To(Equal([]string{"UpdateSvc"}))
+		})
+
+		It("returns an Operation that is done for a service that is unchanged", func() {
+			manager := &applyManager{svcDiff: &anysched.SvcDiff{Changed: []string{}}}
+			op, result, err := anysched.ApplySvc(manager, svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(anysched.SvcUnchanged))
+			Expect(manager.called).To(BeEmpty())
+			Expect(op.GetProperties()).To(Equal(map[string]interface{}{"name": "httpbin", "count": 2}))
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "httpbin" is unchanged.`))
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if DiffSvc fails", func() {
+			manager := &applyManager{diffErr: errors.New("connection refused")}
+			op, _, err := anysched.ApplySvc(manager, svcCfg)
+			Expect(err).To(MatchError(`getting the differences of service "httpbin" failed: connection refused`))
+			Expect(op).To(BeNil())
+			Expect(manager.called).To(BeEmpty())
+		})
+
+		It("returns an error for a service that differs if the manager cannot update services", func() {
+			manager := &applyManager{svcDiff: &anysched.SvcDiff{Changed: []string{"image"}}}
+			// Only the methods of Manager and DiffSvc, so not UpdateSvc
+			managerWithoutUpdateSvc := struct {
+				anysched.Manager
+				anysched.SvcDiffer
+			}{manager, manager}
+			op, _, err := anysched.ApplySvc(managerWithoutUpdateSvc, svcCfg)
+			Expect(err).To(MatchError(`service "httpbin" differs, but the manager cannot update services`))
+			Expect(op).To(BeNil())
+			Expect(manager.called).To(BeEmpty())
+		})
+
+		It("updates a service with settings that the manager can't tell the differences of", func() {
+			manager := &applyManager{svcDiff: &anysched.SvcDiff{Changed: []string{}, Unknown: []string{"command"}}}
+			_, result, err := anysched.ApplySvc(manager, svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(anysched.SvcUpdated))
+			Expect(manager.called).To(Equal([]string{"UpdateSvc"}))
+		})
+
+		Context("with a manager without DiffSvc", func() {
+			svcDetail := &anysched.SvcDetail{Svc: anysched.Svc{ID: "httpbin"}, SvcCfg: svcCfg}
+
+			It("deploys a service that is not in Svcs", func() {
+				manager := &svcApplyManager{}
+				_, result, err := anysched.ApplySvc(manager, svcCfg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(anysched.SvcCreated))
+				Expect(manager.called).To(Equal([]string{"DeploySvc"}))
+			})
+
+			It("leaves a service whose Svc is the same unchanged", func() {
+				manager := &svcApplyManager{svcDetail: svcDetail}
+				_, result, err := anysched.ApplySvc(manager, svcCfg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(anysched.SvcUnchanged))
+				Expect(manager.called).To(BeEmpty())
+			})
+
+			It("updates a service whose Svc differs", func() {
+				manager := &svcApplyManager{svcDetail: svcDetail}
+				desired := svcCfg
+				desired.Command = []string{"gunicorn"}
+				_, result, err := anysched.ApplySvc(manager, desired)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(anysched.SvcUpdated))
+				Expect(manager.called).To(Equal([]string{"UpdateSvc"}))
+			})
+
+			It("returns an error without Svc either", func() {
+				// Only the methods of Manager, so neither DiffSvc nor Svc
+				manager := struct{ anysched.Manager }{&applyManager{}}
+				op, _, err := anysched.ApplySvc(manager, svcCfg)
+				Expect(err).To(MatchError(
+					`getting the differences of service "httpbin" failed: the manager can neither diff nor get services`))
+				Expect(op).To(BeNil())
+			})
+		})
+
+		It("returns an error for an invalid service", func() {
+			invalidSvcCfg := svcCfg
+			invalidSvcCfg.Resources = &anysched.Resources{CPU: 2, CPULimit: 1}
+			manager := &applyManager{}
+			_, _, err := anysched.ApplySvc(manager, invalidSvcCfg)
+			Expect(err).To(HaveOccurred())
+			Expect(manager.called).To(BeEmpty())
+		})
+	})
+
+	Describe("DiffSvcCfgs", func() {
+		It("returns the settings that differ", func() {
+			desired := svcCfg
+			desired.Image = "citizenstig/httpbin:v2"
+			desired.Count = 3
+			desired.Env = map[string]string{"VERSION": "2"}
+			desired.Resources = &anysched.Resources{Memory: 256 << 20}
+			Expect(anysched.DiffSvcCfgs(svcCfg, desired).Changed).To(Equal(
+				[]string{"image", "count", "env", "resources"}))
+		})
+
+		It("compares all of the settings", func() {
+			desired := svcCfg
+			desired.Command = []string{"gunicorn"}
+			desired.Args = []string{"httpbin:app"}
+			desired.Secrets = []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}}
+			desired.Labels = map[string]string{"team": "payments"}
+			desired.Ports = []anysched.PortCfg{{ContainerPort: 8000}}
+			desired.Volumes = []anysched.VolumeCfg{{Type: anysched.VolumeScratch, MountPath: "/data"}}
+			desired.HealthCheck = &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000}
+			desired.ReadinessCheck = &anysched.HealthCheck{Port: 8000}
+			desired.Sidecars = []anysched.ContainerCfg{{Name: "logs", Image: "fluent/fluent-bit"}}
+			desired.InitContainers = []anysched.ContainerCfg{{Name: "migrate", Image: "citizenstig/httpbin"}}
+			desired.Constraints = []anysched.Constraint{
+				{Attribute: "zone", Operator: anysched.ConstraintEquals, Values: []string{"a"}},
+			}
+			Expect(anysched.DiffSvcCfgs(svcCfg, desired).Changed).To(Equal([]string{
+				"command", "args", "secrets", "labels", "ports", "volumes", "health-check", "readiness-check",
+				"sidecars", "init-containers", "constraints",
+			}))
+			Expect(anysched.DiffSvcCfgs(desired, desired).Changed).To(BeEmpty())
+		})
+
+		It("treats a port without a host port or a protocol as the scheduler's defaults", func() {
+			deployed := svcCfg
+			deployed.Ports = []anysched.PortCfg{{ContainerPort: 8000, Protocol: "tcp", HostPort: 31000}}
+			desired := svcCfg
+			desired.Ports = []anysched.PortCfg{{ContainerPort: 8000}}
+			Expect(anysched.DiffSvcCfgs(deployed, desired).Changed).To(BeEmpty())
+			desired.Ports = []anysched.PortCfg{{ContainerPort: 8000, HostPort: 8000}}
+			Expect(anysched.DiffSvcCfgs(deployed, desired).Changed).To(Equal([]string{"ports"}))
+			desired.Ports = []anysched.PortCfg{{ContainerPort: 8000, Protocol: "udp"}}
+			Expect(anysched.DiffSvcCfgs(deployed, desired).Changed).To(Equal([]string{"ports"}))
+		})
+
+		It("returns no settings for the same SvcCfg", func() {
+			Expect(anysched.DiffSvcCfgs(svcCfg, svcCfg).Changed).To(BeEmpty())
+		})
+
+		It("treats nil and empty Env the same", func() {
+			desired := svcCfg
+			desired.Env = map[string]string{}
+			Expect(anysched.DiffSvcCfgs(svcCfg, desired).Changed).To(BeEmpty())
+		})
+
+		It("treats resources that are not set as the scheduler's defaults", func() {
+			deployed := svcCfg
+			deployed.Resources = &anysched.Resources{CPU: 1, Memory: 128 << 20}
+			Expect(anysched.DiffSvcCfgs(deployed, svcCfg).Changed).To(BeEmpty())
+			desired := svcCfg
+			desired.Resources = &anysched.Resources{CPU: 1.0001}
+			Expect(anysched.DiffSvcCfgs(deployed, desired).Changed).To(BeEmpty())
+			desired.Resources = &anysched.Resources{CPU: 1, MemoryLimit: 128 << 20}
+			Expect(anysched.DiffSvcCfgs(deployed, desired).Changed).To(Equal([]string{"resources"}))
+		})
+	})
+
+	Describe("UnknownSvcSettings", func() {
+		It("returns the settings that are set but not known, in order", func() {
+			desired := svcCfg
+			desired.Command = []string{"gunicorn"}
+			desired.HealthCheck = &anysched.HealthCheck{Port: 8000}
+			desired.Env = map[string]string{"VERSION": "2"}
+			Expect(anysched.UnknownSvcSettings(desired, "image", "count", "env")).To(Equal(
+				[]string{"command", "health-check"}))
+			Expect(anysched.UnknownSvcSettings(desired, anysched.SvcSettings...)).To(BeEmpty())
+		})
+	})
+})
@@ -53,3 +53,12 @@ func outputJSON(w io.Writer, data interface{}) error {
 	}
 	return nil
 }
+
+// qualifiedName returns "NAMESPACE/NAME" for something in a namespace, so that
+// lists of all namespaces are unambiguous, or just name if namespace is blank.
+func qualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
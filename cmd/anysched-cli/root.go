@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/msabramo/go-anysched"
+	_ "github.com/msabramo/go-anysched/managers/docker"
 	_ "github.com/msabramo/go-anysched/managers/dockerswarm"
 	_ "github.com/msabramo/go-anysched/managers/kubernetes"
 	_ "github.com/msabramo/go-anysched/managers/marathon"
@@ -59,6 +60,11 @@ func init() {
 	if err := viper.BindPFlag("env", rootCmd.PersistentFlags().Lookup("env")); err != nil {
 		panic(err)
 	}
+	rootCmd.PersistentFlags().String("namespace", "",
+		`namespace to work in, overriding the environment's, or "*" for all of them (Kubernetes only)`)
+	if err := viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace")); err != nil {
+		panic(err)
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -109,5 +115,9 @@ func getManagerConfig() anysched.ManagerConfig {
 	}
 	managerType := viper.GetString(envRootKey + ".type")
 	managerAddress := viper.GetString(envRootKey + ".address")
-	return anysched.ManagerConfig{Type: managerType, Address: managerAddress}
+	namespace := viper.GetString("namespace")
+	if namespace == "" {
+		namespace = viper.GetString(envRootKey + ".namespace")
+	}
+	return anysched.ManagerConfig{Type: managerType, Address: managerAddress, Namespace: namespace}
 }
@@ -0,0 +1,86 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// secretSettings are the values of the flags of "secret create" and "secret
+// update".
+var secretSettings = struct {
+	name         string
+	fromLiterals []string
+	fromFiles    []string
+}{}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Commands for managing the secrets that services refer to",
+}
+
+// getSecretsManager returns the manager for the environment, or exits if it
+// can't manage secrets.
+func getSecretsManager(command string) anysched.SecretsManager {
+	secretsManager, ok := getManager().(anysched.SecretsManager)
+	if !ok {
+		die("%s: the scheduler of this environment does not manage secrets", command)
+	}
+	return secretsManager
+}
+
+// getSecret returns a secret with the values of "--from-literal KEY=VALUE" and
+// "--from-file KEY=PATH".
+func getSecret(name string, fromLiterals, fromFiles []string) (anysched.Secret, error) {
+	secret := anysched.Secret{Name: name, Data: map[string][]byte{}}
+	for _, fromLiteral := range fromLiterals {
+		parts := strings.SplitN(fromLiteral, "=", 2)
+		if len(parts) != 2 {
+			return anysched.Secret{}, fmt.Errorf("invalid --from-literal %q: expected KEY=VALUE", fromLiteral)
+		}
+		secret.Data[parts[0]] = []byte(parts[1])
+	}
+	for _, fromFile := range fromFiles {
+		parts := strings.SplitN(fromFile, "=", 2)
+		if len(parts) != 2 {
+			return anysched.Secret{}, fmt.Errorf("invalid --from-file %q: expected KEY=PATH", fromFile)
+		}
+		data, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			return anysched.Secret{}, err
+		}
+		secret.Data[parts[0]] = data
+	}
+	return secret, nil
+}
+
+// addSecretFlags adds the flags of "secret create" and "secret update" to cmd.
+func addSecretFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&secretSettings.name, "name", "n", "", "Name of the secret")
+	cmd.Flags().StringArrayVar(&secretSettings.fromLiterals, "from-literal", nil,
+		"Value of the secret, as KEY=VALUE (can be repeated)")
+	cmd.Flags().StringArrayVar(&secretSettings.fromFiles, "from-file", nil,
+		"File with a value of the secret, as KEY=PATH (can be repeated)")
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+}
@@ -0,0 +1,41 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// secretDeleteName is the value of "secret delete --name"
+var secretDeleteName string
+
+// secretDeleteCmd represents the "secret delete" command
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a secret",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := getSecretsManager("secret delete").DeleteSecret(secretDeleteName); err != nil {
+			die("secret delete: %s", err)
+		}
+		fmt.Printf("Secret %q deleted.\n", secretDeleteName)
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretDeleteCmd)
+	secretDeleteCmd.Flags().StringVarP(&secretDeleteName, "name", "n", "", "Name of the secret to delete")
+}
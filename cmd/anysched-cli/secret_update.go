@@ -0,0 +1,42 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// secretUpdateCmd represents the "secret update" command
+var secretUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Replace the values of a secret",
+	Run: func(cmd *cobra.Command, args []string) {
+		secret, err := getSecret(secretSettings.name, secretSettings.fromLiterals, secretSettings.fromFiles)
+		if err != nil {
+			die("secret update: %s", err)
+		}
+		if err = getSecretsManager("secret update").UpdateSecret(secret); err != nil {
+			die("secret update: %s", err)
+		}
+		fmt.Printf("Secret %q updated.\n", secret.Name)
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretUpdateCmd)
+	addSecretFlags(secretUpdateCmd)
+}
@@ -0,0 +1,61 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// svcApplyCmd represents the "svc apply" command
+var svcApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Deploy a service, or update it if it differs",
+	Run: func(cmd *cobra.Command, args []string) {
+		startTime := time.Now()
+		svcCfg := getDeploySvcCfg("svc apply")
+		manager := getManager()
+		svcCfg.Count = getUpdateCount(cmd, manager, svcCfg, "svc apply")
+		op, result, err := anysched.ApplySvc(manager, svcCfg)
+		if err != nil {
+			_, err2 := fmt.Fprintf(os.Stderr, "ApplySvc error: %s\n", err)
+			if err2 != nil {
+				panic(err2)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Service %q %s.\n", svcCfg.ID, result)
+		switch result {
+		case anysched.SvcCreated:
+			followOperation(manager, svcCfg, op, "Deployment", startTime, timeoutDuration)
+		case anysched.SvcUpdated:
+			followOperation(manager, svcCfg, op, "Update", startTime, timeoutDuration)
+		}
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(svcApplyCmd)
+	addDeployFlags(svcApplyCmd, "the service")
+	svcApplyCmd.Flags().IntVarP(&deploySettings.updateCount, "count", "c", 0,
+		"Number of containers to run (default: the count of the deployed service, or 1 for a new one)")
+	svcApplyCmd.Flags().DurationVarP(&timeoutDuration, "timeout", "t", timeoutDuration,
+		"Max time to wait for apply to complete")
+}
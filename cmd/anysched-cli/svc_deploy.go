@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 	"time"
@@ -28,8 +29,23 @@ import (
 )
 
 var (
-	deploySettings  = struct{ svcCfg anysched.SvcCfg }{}
-	timeoutDuration = 15 * time.Second
+	deploySettings = struct {
+		svcCfg       anysched.SvcCfg
+		updateCount  int
+		envVars      []string
+		envFiles     []string
+		secrets      []string
+		labels       []string
+		constraints  []string
+		ports        []string
+		volumes      []string
+		volumeDriver string
+		sidecars     []string
+		initCtrs     []string
+		resources    resourceFlags
+		checks       checkFlags
+	}{}
+	timeoutDuration = 60 * time.Second
 )
 
 // svcDeployCmd represents the "svc deploy" command
@@ -37,12 +53,10 @@ var svcDeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy a service",
 	Run: func(cmd *cobra.Command, args []string) {
-		timeout := 60 * time.Second
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
 		startTime := time.Now()
+		svcCfg := getDeploySvcCfg("svc deploy")
 		manager := getManager()
-		deployment, err := manager.DeploySvc(deploySettings.svcCfg)
+		deployment, err := manager.DeploySvc(svcCfg)
 		if err != nil {
 			_, err2 := fmt.Fprintf(os.Stderr, "DeploySvc error: %s\n", err)
 			if err2 != nil {
@@ -57,29 +71,134 @@ var svcDeployCmd = &cobra.Command{
 			}
 			os.Exit(0)
 		}
+		followOperation(manager, svcCfg, deployment, "Deployment", startTime, timeoutDuration)
+	},
+}
 
-		for key, val := range deployment.GetProperties() {
-			if key == "" || val == "" {
-				continue
-			}
-			fmt.Printf("%-30s : %v\n", key, val)
+// getDeploySvcCfg returns the SvcCfg that the flags of "svc deploy" or "svc
+// update" describe, exiting with an error (prefixed with cmdName) if any of
+// them are invalid.
+func getDeploySvcCfg(cmdName string) anysched.SvcCfg {
+	svcCfg := deploySettings.svcCfg
+	var err error
+	if svcCfg.Env, err = getDeployEnv(deploySettings.envFiles, deploySettings.envVars); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Secrets, err = getDeploySecrets(deploySettings.secrets); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Labels, err = getDeployLabels(deploySettings.labels); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Ports, err = getDeployPorts(deploySettings.ports); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Constraints, err = getDeployConstraints(deploySettings.constraints); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Volumes, err = getDeployVolumes(deploySettings.volumes, deploySettings.volumeDriver); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.Sidecars, err = getDeployContainers(deploySettings.sidecars); err != nil {
+		die("%s: --sidecar: %s", cmdName, err)
+	}
+	if svcCfg.InitContainers, err = getDeployContainers(deploySettings.initCtrs); err != nil {
+		die("%s: --init-container: %s", cmdName, err)
+	}
+	if svcCfg.Resources, err = getDeployResources(deploySettings.resources); err != nil {
+		die("%s: %s", cmdName, err)
+	}
+	if svcCfg.HealthCheck, err = getDeployCheck(deploySettings.checks.healthCheck, deploySettings.checks); err != nil {
+		die("%s: --health-check: %s", cmdName, err)
+	}
+	svcCfg.ReadinessCheck, err = getDeployCheck(deploySettings.checks.readinessCheck, deploySettings.checks)
+	if err != nil {
+		die("%s: --readiness-check: %s", cmdName, err)
+	}
+	return svcCfg
+}
+
+// getUpdateCount returns the count for "svc update" or "svc apply" (cmdName):
+// the --count flag of cmd if it is given, and otherwise the count of the
+// deployed service of svcCfg, so that changing another setting doesn't scale
+// the service, or 1 if it isn't deployed.
+func getUpdateCount(cmd *cobra.Command, manager anysched.Manager, svcCfg anysched.SvcCfg, cmdName string) int {
+	if cmd.Flags().Changed("count") {
+		return deploySettings.updateCount
+	}
+	svcs, err := manager.Svcs()
+	if err != nil {
+		die("%s: getting the services failed: %s", cmdName, err)
+	}
+	deployed := false
+	for _, svc := range svcs {
+		if svc.ID == svcCfg.ID && (svcCfg.Namespace == "" || svc.Namespace == svcCfg.Namespace) {
+			deployed = true
+		}
+	}
+	if !deployed {
+		return 1
+	}
+	svcGetter, ok := manager.(anysched.SvcGetter)
+	if !ok {
+		die("%s: --count is required, since the scheduler of this environment cannot get services", cmdName)
+	}
+	svcDetail, err := svcGetter.Svc(svcCfg.ID)
+	if err != nil {
+		die("%s: getting service %q failed: %s", cmdName, svcCfg.ID, err)
+	}
+	return svcDetail.SvcCfg.Count
+}
+
+// followOperation prints the properties of op, and then its status whenever
+// it changes, until it is done, after which it prints the tasks of the
+// service, or until timeout passes. opName names op in the messages, e.g.:
+// "Deployment".
+func followOperation(manager anysched.Manager, svcCfg anysched.SvcCfg, op anysched.Operation, opName string,
+	startTime time.Time, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for key, val := range op.GetProperties() {
+		if key == "" || val == "" {
+			continue
 		}
-		fmt.Println()
+		fmt.Printf("%-30s : %v\n", key, val)
+	}
+	fmt.Println()
 
-		var lastUpdateTime time.Time
+	var lastUpdateTime time.Time
 
-		for {
-			select {
-			case <-ctx.Done():
-				_, err2 := fmt.Fprintf(os.Stderr, "Deployment polling aborted after %v: %s\n", timeout, ctx.Err())
+	for {
+		select {
+		case <-ctx.Done():
+			_, err2 := fmt.Fprintf(os.Stderr, "%s polling aborted after %v: %s\n", opName, timeout, ctx.Err())
+			if err2 != nil {
+				panic(err2)
+			}
+			return
+		case <-time.After(1 * time.Second):
+			status, err := op.GetStatus()
+			if err != nil {
+				_, err2 := fmt.Fprintf(os.Stderr, "GetStatus error: %s\n", err)
 				if err2 != nil {
 					panic(err2)
 				}
-				return
-			case <-time.After(1 * time.Second):
-				status, err := deployment.GetStatus()
+				if strings.Contains(err.Error(), "Not implemented") {
+					return
+				}
+				continue
+			}
+			if status.LastUpdateTime == lastUpdateTime {
+				continue
+			}
+			fmt.Printf("[%s] %s\n", status.LastUpdateTime.Format(time.RFC3339), status.Msg)
+			lastUpdateTime = status.LastUpdateTime
+			if status.Done {
+				elapsedTime := time.Since(startTime)
+				tasks, err := manager.SvcTasks(svcCfg)
 				if err != nil {
-					_, err2 := fmt.Fprintf(os.Stderr, "GetStatus error: %s\n", err)
+					_, err2 := fmt.Fprintf(os.Stderr, "app deploy: SvcTasks error: %s\n", err)
 					if err2 != nil {
 						panic(err2)
 					}
@@ -88,44 +207,220 @@ var svcDeployCmd = &cobra.Command{
 					}
 					continue
 				}
-				if status.LastUpdateTime == lastUpdateTime {
-					continue
-				}
-				fmt.Printf("[%s] %s\n", status.LastUpdateTime.Format(time.RFC3339), status.Msg)
-				lastUpdateTime = status.LastUpdateTime
-				if status.Done {
-					elapsedTime := time.Since(startTime)
-					tasks, err := manager.SvcTasks(deploySettings.svcCfg)
-					if err != nil {
-						_, err2 := fmt.Fprintf(os.Stderr, "app deploy: SvcTasks error: %s\n", err)
-						if err2 != nil {
-							panic(err2)
-						}
-						if strings.Contains(err.Error(), "Not implemented") {
-							return
-						}
-						continue
-					}
-					fmt.Printf("Deployment completed in %s\n\n", elapsedTime)
-					err = output(os.Stdout, tasks, viper.GetString("output_format"), outputTaskListTable)
-					if err != nil {
-						_, err2 := fmt.Fprintf(os.Stderr, "app list: task list output error: %s\n", err)
-						if err2 != nil {
-							panic(err2)
-						}
-						os.Exit(1)
+				fmt.Printf("%s completed in %s\n\n", opName, elapsedTime)
+				err = output(os.Stdout, tasks, viper.GetString("output_format"), outputTaskListTable)
+				if err != nil {
+					_, err2 := fmt.Fprintf(os.Stderr, "app list: task list output error: %s\n", err)
+					if err2 != nil {
+						panic(err2)
 					}
-					return
+					os.Exit(1)
 				}
+				return
 			}
 		}
-		/*
-			_, err = deployment.Wait(ctx)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Deployment failed: %s\n", err)
+	}
+}
+
+// getDeployLabels parses the values of "--label", which are "key=value"
+// strings. It returns nil if there aren't any.
+func getDeployLabels(labelFlags []string) (map[string]string, error) {
+	if len(labelFlags) == 0 {
+		return nil, nil
+	}
+	labels := map[string]string{}
+	for _, labelFlag := range labelFlags {
+		parts := strings.SplitN(labelFlag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", labelFlag)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// getDeployPorts parses the values of "--port".
+func getDeployPorts(ports []string) ([]anysched.PortCfg, error) {
+	var portCfgs []anysched.PortCfg
+	for _, port := range ports {
+		portCfg, err := anysched.ParsePortCfg(port)
+		if err != nil {
+			return nil, err
+		}
+		portCfgs = append(portCfgs, portCfg)
+	}
+	return portCfgs, nil
+}
+
+// getDeploySecrets parses the values of "--secret".
+func getDeploySecrets(secretFlags []string) ([]anysched.SecretRef, error) {
+	var secretRefs []anysched.SecretRef
+	for _, secretFlag := range secretFlags {
+		secretRef, err := anysched.ParseSecretRef(secretFlag)
+		if err != nil {
+			return nil, err
+		}
+		secretRefs = append(secretRefs, secretRef)
+	}
+	return secretRefs, nil
+}
+
+// getDeployConstraints parses the values of "--constraint".
+func getDeployConstraints(constraintFlags []string) ([]anysched.Constraint, error) {
+	var constraints []anysched.Constraint
+	for _, constraintFlag := range constraintFlags {
+		constraint, err := anysched.ParseConstraint(constraintFlag)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints, nil
+}
+
+// getDeployContainers parses the values of "--sidecar" or "--init-container".
+func getDeployContainers(containerFlags []string) ([]anysched.ContainerCfg, error) {
+	var containerCfgs []anysched.ContainerCfg
+	for _, containerFlag := range containerFlags {
+		containerCfg, err := anysched.ParseContainerCfg(containerFlag)
+		if err != nil {
+			return nil, err
+		}
+		containerCfgs = append(containerCfgs, containerCfg)
+	}
+	return containerCfgs, nil
+}
+
+// getDeployVolumes parses the values of "--volume", and gives the named volumes
+// the driver of "--volume-driver".
+func getDeployVolumes(volumeFlags []string, volumeDriver string) ([]anysched.VolumeCfg, error) {
+	var volumes []anysched.VolumeCfg
+	for _, volumeFlag := range volumeFlags {
+		volumeCfg, err := anysched.ParseVolumeCfg(volumeFlag)
+		if err != nil {
+			return nil, err
+		}
+		if volumeCfg.Type == anysched.VolumeNamed {
+			volumeCfg.Driver = volumeDriver
+		}
+		volumes = append(volumes, volumeCfg)
+	}
+	return volumes, nil
+}
+
+// resourceFlags are the values of the flags for the resources of a service.
+type resourceFlags struct {
+	cpu, cpuLimit, memory, memoryLimit, disk string
+}
+
+// getDeployResources parses the values of "--cpu", "--memory", etc. It returns
+// nil if none of them were given.
+func getDeployResources(flags resourceFlags) (*anysched.Resources, error) {
+	if flags == (resourceFlags{}) {
+		return nil, nil
+	}
+	var (
+		resources anysched.Resources
+		err       error
+	)
+	parseCPU := func(name, value string, cpu *float64) {
+		if err == nil && value != "" {
+			if *cpu, err = anysched.ParseCPU(value); err != nil {
+				err = fmt.Errorf("--%s: %s", name, err)
 			}
-		*/
-	},
+		}
+	}
+	parseBytes := func(name, value string, bytes *int64) {
+		if err == nil && value != "" {
+			if *bytes, err = anysched.ParseBytes(value); err != nil {
+				err = fmt.Errorf("--%s: %s", name, err)
+			}
+		}
+	}
+	parseCPU("cpu", flags.cpu, &resources.CPU)
+	parseCPU("cpu-limit", flags.cpuLimit, &resources.CPULimit)
+	parseBytes("memory", flags.memory, &resources.Memory)
+	parseBytes("memory-limit", flags.memoryLimit, &resources.MemoryLimit)
+	parseBytes("disk", flags.disk, &resources.Disk)
+	if err != nil {
+		return nil, err
+	}
+	return &resources, nil
+}
+
+// checkFlags are the values of the flags for the health and readiness checks
+// of a service. The interval, timeout, grace period and retries are for both.
+type checkFlags struct {
+	healthCheck, readinessCheck    string
+	interval, timeout, gracePeriod time.Duration
+	retries                        int
+}
+
+// getDeployCheck parses the value of "--health-check" or "--readiness-check".
+// It returns nil if s is blank.
+func getDeployCheck(s string, flags checkFlags) (*anysched.HealthCheck, error) {
+	if s == "" {
+		return nil, nil
+	}
+	healthCheck, err := anysched.ParseHealthCheck(s)
+	if err != nil {
+		return nil, err
+	}
+	healthCheck.Interval = flags.interval
+	healthCheck.Timeout = flags.timeout
+	healthCheck.GracePeriod = flags.gracePeriod
+	healthCheck.FailureThreshold = flags.retries
+	return healthCheck, nil
+}
+
+// getDeployEnv returns the environment variables from envFiles, followed by
+// envVars, which are "NAME=value" strings. A later value for the same name
+// overrides an earlier one.
+func getDeployEnv(envFiles []string, envVars []string) (map[string]string, error) {
+	allEnvVars := []string{}
+	for _, envFile := range envFiles {
+		fileEnvVars, err := readEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		allEnvVars = append(allEnvVars, fileEnvVars...)
+	}
+	allEnvVars = append(allEnvVars, envVars...)
+	env := map[string]string{}
+	for _, envVar := range allEnvVars {
+		name, value, err := parseEnvVar(envVar)
+		if err != nil {
+			return nil, err
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// readEnvFile returns the "NAME=value" lines of an env file, skipping blank
+// lines and lines that start with "#", like "docker run --env-file".
+func readEnvFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading env file failed: %s", err)
+	}
+	envVars := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		envVars = append(envVars, line)
+	}
+	return envVars, nil
+}
+
+func parseEnvVar(envVar string) (name, value string, err error) {
+	parts := strings.SplitN(envVar, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("invalid environment variable %q: expected NAME=value", envVar)
+	}
+	return parts[0], parts[1], nil
 }
 
 func init() {
@@ -139,9 +434,68 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	svcDeployCmd.Flags().StringVarP(&deploySettings.svcCfg.ID, "svc-id", "s", "", "ID for new service")
-	svcDeployCmd.Flags().StringVarP(&deploySettings.svcCfg.Image, "image", "i", "", "Docker image for new service")
+	addDeployFlags(svcDeployCmd, "new service")
 	svcDeployCmd.Flags().IntVarP(&deploySettings.svcCfg.Count, "count", "c", 1, "Number of containers to run")
 	svcDeployCmd.Flags().DurationVarP(&timeoutDuration, "timeout", "t", timeoutDuration,
 		"Max time to wait for deploy to complete")
 }
+
+// addDeployFlags adds the flags that describe a service to cmd, which is "svc
+// deploy" or "svc update". svc is how their help refers to the service, e.g.:
+// "new service".
+func addDeployFlags(cmd *cobra.Command, svc string) {
+	cmd.Flags().StringVarP(&deploySettings.svcCfg.ID, "svc-id", "s", "", "ID for "+svc)
+	cmd.Flags().StringVarP(&deploySettings.svcCfg.Image, "image", "i", "", "Docker image for "+svc)
+	cmd.Flags().StringArrayVar(&deploySettings.svcCfg.Command, "command", nil,
+		"Entrypoint for "+svc+", overriding the image's (repeat for each word)")
+	cmd.Flags().StringArrayVar(&deploySettings.svcCfg.Args, "arg", nil,
+		"Argument for the entrypoint of "+svc+", overriding the image's command (can be repeated)")
+	// "--env" and "-e" are taken by the global flag that selects the environment
+	// to target, so environment variables of the service use "--env-var"
+	cmd.Flags().StringArrayVar(&deploySettings.envVars, "env-var", nil,
+		"Environment variable for "+svc+", as NAME=value (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.envFiles, "env-file", nil,
+		"File with environment variables for "+svc+", one NAME=value per line (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.secrets, "secret", nil,
+		"Key of a secret to expose to "+svc+", as SECRET:KEY=ENV_VAR or SECRET:KEY=/FILE/PATH (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.labels, "label", nil,
+		"Label for "+svc+", as key=value, e.g.: team=payments (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.ports, "port", nil,
+		"Port for "+svc+", as [HOST_PORT:]CONTAINER_PORT[/PROTOCOL], e.g.: 8080/tcp (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.constraints, "constraint", nil,
+		"Placement constraint for "+svc+", as ATTRIBUTE==VALUE, ATTRIBUTE!=VALUE, "+
+			"ATTRIBUTE in (VALUE1,VALUE2), unique-per-host or spread:ATTRIBUTE (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.volumes, "volume", nil,
+		"Volume for "+svc+", as [HOST_PATH|NAME:]MOUNT_PATH[:ro|:rw], where no HOST_PATH or NAME "+
+			"is scratch space, e.g.: pgdata:/var/lib/postgresql/data (can be repeated)")
+	cmd.Flags().StringVar(&deploySettings.volumeDriver, "volume-driver", "",
+		"Volume driver for the named volumes of "+svc+", e.g.: rexray")
+	cmd.Flags().StringArrayVar(&deploySettings.sidecars, "sidecar", nil,
+		"Container to run next to the main one in each task of "+svc+", as NAME=IMAGE [ARG...], "+
+			"e.g.: \"log-forwarder=fluent/fluent-bit:0.13\" (can be repeated)")
+	cmd.Flags().StringArrayVar(&deploySettings.initCtrs, "init-container", nil,
+		"Container to run to completion before the others in each task of "+svc+", as NAME=IMAGE [ARG...], "+
+			"in the order given (can be repeated)")
+	cmd.Flags().StringVar(&deploySettings.resources.cpu, "cpu", "",
+		"CPU cores requested for each task of "+svc+", e.g.: 0.5 or 250m")
+	cmd.Flags().StringVar(&deploySettings.resources.cpuLimit, "cpu-limit", "",
+		"Most CPU cores that each task of "+svc+" can use")
+	cmd.Flags().StringVar(&deploySettings.resources.memory, "memory", "",
+		"Memory requested for each task of "+svc+", e.g.: 512Mi or 1Gi")
+	cmd.Flags().StringVar(&deploySettings.resources.memoryLimit, "memory-limit", "",
+		"Most memory that each task of "+svc+" can use")
+	cmd.Flags().StringVar(&deploySettings.resources.disk, "disk", "",
+		"Local disk requested for each task of "+svc+", e.g.: 10Gi")
+	cmd.Flags().StringVar(&deploySettings.checks.healthCheck, "health-check", "",
+		"Health check for "+svc+", as http:PORT/PATH, tcp:PORT or cmd:COMMAND")
+	cmd.Flags().StringVar(&deploySettings.checks.readinessCheck, "readiness-check", "",
+		"Readiness check for "+svc+", as http:PORT/PATH, tcp:PORT or cmd:COMMAND")
+	cmd.Flags().DurationVar(&deploySettings.checks.interval, "check-interval", 0,
+		"How often the health and readiness checks run (default 10s)")
+	cmd.Flags().DurationVar(&deploySettings.checks.timeout, "check-timeout", 0,
+		"How long the health and readiness checks can take (default 5s)")
+	cmd.Flags().DurationVar(&deploySettings.checks.gracePeriod, "check-grace-period", 0,
+		"How long after a task starts that failed health and readiness checks are ignored")
+	cmd.Flags().IntVar(&deploySettings.checks.retries, "check-retries", 0,
+		"How many health or readiness checks in a row have to fail for a task to be unhealthy (default 3)")
+}
@@ -0,0 +1,119 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// svcDescribeOutputFormat is the value of "svc describe --output-format"
+var svcDescribeOutputFormat string
+
+// svcDescribeCmd represents the "svc describe" command
+var svcDescribeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Show the configuration and status of a service, as the scheduler reports them",
+	Run: func(cmd *cobra.Command, args []string) {
+		svcGetter, ok := getManager().(anysched.SvcGetter)
+		if !ok {
+			die("svc describe: the scheduler of this environment cannot describe services")
+		}
+		svcDetail, err := svcGetter.Svc(svcID)
+		if err != nil {
+			die("svc describe: Svc error: %s", err)
+		}
+		if err = output(os.Stdout, svcDetail, svcDescribeOutputFormat, outputSvcDescribeTable); err != nil {
+			die("svc describe: output error: %s", err)
+		}
+	},
+}
+
+// outputSvcDescribeTable outputs the main settings and counts of a service, one
+// per line. The native object of the scheduler is only in the YAML and JSON.
+func outputSvcDescribeTable(w io.Writer, data interface{}) error {
+	svcDetail := data.(*anysched.SvcDetail)
+	svcCfg := svcDetail.SvcCfg
+	revision := "-"
+	if svcDetail.Revision != nil {
+		revision = strconv.FormatInt(*svcDetail.Revision, 10)
+	}
+	rows := [][2]string{
+		{"ID", qualifiedName(svcCfg.Namespace, svcCfg.ID)},
+		{"Image", svcCfg.Image},
+		{"Count", strconv.Itoa(svcCfg.Count)},
+		{"Running", optionalCount(svcDetail.Svc.TasksRunning)},
+		{"Healthy", optionalCount(svcDetail.Svc.TasksHealthy)},
+		{"Unhealthy", optionalCount(svcDetail.Svc.TasksUnhealthy)},
+		{"Revision", revision},
+		{"Ports", portsString(svcCfg.Ports)},
+		{"Labels", labelsString(svcCfg.Labels)},
+	}
+	for _, envVar := range svcCfg.EnvVars() {
+		rows = append(rows, [2]string{"Env", envVar.Name + "=" + envVar.Value})
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%-10s %s\n", row[0]+":", row[1]); err != nil {
+			panic(err)
+		}
+	}
+	return nil
+}
+
+// optionalCount returns a count that a scheduler might not report, or "-".
+func optionalCount(count *int) string {
+	if count == nil {
+		return "-"
+	}
+	return strconv.Itoa(*count)
+}
+
+// portsString returns ports in the form "CONTAINER_PORT/PROTOCOL->HOST_PORT",
+// without the host port if there is none.
+func portsString(portCfgs []anysched.PortCfg) string {
+	ports := make([]string, len(portCfgs))
+	for i, portCfg := range portCfgs {
+		ports[i] = fmt.Sprintf("%d/%s", portCfg.ContainerPort, portCfg.ProtocolOrDefault())
+		if portCfg.HostPort != 0 {
+			ports[i] += fmt.Sprintf("->%d", portCfg.HostPort)
+		}
+	}
+	return strings.Join(ports, ", ")
+}
+
+// labelsString returns labels in the form "KEY=VALUE,...", ordered by key.
+func labelsString(labels map[string]string) string {
+	keyValues := make([]string, 0, len(labels))
+	for key, value := range labels {
+		keyValues = append(keyValues, key+"="+value)
+	}
+	sort.Strings(keyValues)
+	return strings.Join(keyValues, ",")
+}
+
+func init() {
+	svcCmd.AddCommand(svcDescribeCmd)
+	svcDescribeCmd.Flags().StringVarP(&svcID, "svc-id", "s", "", "svc-id of service to describe")
+	svcDescribeCmd.Flags().StringVarP(&svcDescribeOutputFormat, "output-format", "f", "yaml",
+		`output format: "table", "yaml", "json"`)
+}
@@ -0,0 +1,74 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// svcHistoryOutputFormat is the value of "svc history --output-format"
+var svcHistoryOutputFormat string
+
+// svcHistoryCmd represents the "svc history" command
+var svcHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the revisions of a service that it can be rolled back to",
+	Run: func(cmd *cobra.Command, args []string) {
+		svcHistoryGetter, ok := getManager().(anysched.SvcHistoryGetter)
+		if !ok {
+			die("svc history: the scheduler of this environment does not keep the history of services")
+		}
+		revisions, err := svcHistoryGetter.SvcHistory(svcID)
+		if err != nil {
+			die("svc history: SvcHistory error: %s", err)
+		}
+		if err = output(os.Stdout, revisions, svcHistoryOutputFormat, outputSvcHistoryTable); err != nil {
+			die("svc history: output error: %s", err)
+		}
+	},
+}
+
+func outputSvcHistoryTable(w io.Writer, data interface{}) error {
+	revisions := data.([]anysched.SvcRevision)
+	for _, revision := range revisions {
+		current := ""
+		if revision.Current {
+			current = "current"
+		}
+		creationTime := ""
+		if revision.CreationTime != nil {
+			creationTime = revision.CreationTime.Format(time.RFC3339)
+		}
+		_, err := fmt.Fprintf(w, "%-10d %-40s %-25s %s\n", revision.Revision, revision.Image, creationTime, current)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	svcCmd.AddCommand(svcHistoryCmd)
+	svcHistoryCmd.Flags().StringVarP(&svcID, "svc-id", "s", "", "svc-id of service to list the revisions of")
+	svcHistoryCmd.Flags().StringVarP(&svcHistoryOutputFormat, "output-format", "f", "table",
+		`output format: "table", "yaml", "json"`)
+}
@@ -25,13 +25,20 @@ import (
 	"github.com/msabramo/go-anysched"
 )
 
+// svcListSelector is the value of "svc list --selector"
+var svcListSelector string
+
 // svcListCmd represents the "svc list" command
 var svcListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List running services",
 	Run: func(cmd *cobra.Command, args []string) {
+		selector, err := anysched.ParseSelector(svcListSelector)
+		if err != nil {
+			die("svc list: --selector: %s", err)
+		}
 		manager := getManager()
-		svcs, err := manager.Svcs()
+		svcs, err := anysched.SvcsWithSelector(manager, selector)
 		if err != nil {
 			_, err2 := fmt.Fprintf(os.Stderr, "svc list: Svcs error: %s\n", err)
 			if err2 != nil {
@@ -54,7 +61,7 @@ var svcListCmd = &cobra.Command{
 func outputSvcListTable(w io.Writer, data interface{}) error {
 	svcs := data.([]anysched.Svc)
 	for _, svc := range svcs {
-		if _, err := fmt.Fprintf(w, "%-40s\n", svc.ID); err != nil {
+		if _, err := fmt.Fprintf(w, "%-40s\n", qualifiedName(svc.Namespace, svc.ID)); err != nil {
 			panic(err)
 		}
 	}
@@ -65,6 +72,8 @@ func init() {
 	svcCmd.AddCommand(svcListCmd)
 
 	svcListCmd.Flags().StringP("output-format", "f", "yaml", `output format: "table", "yaml", "json"`)
+	svcListCmd.Flags().StringVarP(&svcListSelector, "selector", "l", "",
+		`Only list services whose labels match a selector, e.g.: "team=payments,tier in (web,api)"`)
 	if err := viper.BindPFlag("output_format", taskListCmd.Flags().Lookup("output-format")); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,63 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var (
+	rollbackRevision int64 // revision that we are going to roll the service back to
+)
+
+// svcRollbackCmd represents the "svc rollback" command
+var svcRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: `Roll a service back to one of the revisions that "svc history" lists`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("to") {
+			die("svc rollback: --to is required")
+		}
+		startTime := time.Now()
+		manager := getManager()
+		svcRollbacker, ok := manager.(anysched.SvcRollbacker)
+		if !ok {
+			die("svc rollback: the scheduler of this environment cannot roll back services")
+		}
+		rollback, err := svcRollbacker.RollbackSvc(svcID, rollbackRevision)
+		if err != nil {
+			_, err2 := fmt.Fprintf(os.Stderr, "RollbackSvc error: %s\n", err)
+			if err2 != nil {
+				panic(err2)
+			}
+			os.Exit(1)
+		}
+		followOperation(manager, anysched.SvcCfg{ID: svcID}, rollback, "Rollback", startTime, timeoutDuration)
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(svcRollbackCmd)
+	svcRollbackCmd.Flags().StringVarP(&svcID, "svc-id", "s", "", "svc-id of service to roll back")
+	svcRollbackCmd.Flags().Int64VarP(&rollbackRevision, "to", "r", 0, "Revision to roll the service back to")
+	svcRollbackCmd.Flags().DurationVarP(&timeoutDuration, "timeout", "t", timeoutDuration,
+		"Max time to wait for rollback to complete")
+}
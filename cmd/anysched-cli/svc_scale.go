@@ -0,0 +1,67 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var (
+	scaleCount int // number of tasks that we are going to scale the service to
+)
+
+// svcScaleCmd represents the "svc scale" command
+var svcScaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Change the number of tasks of a service",
+	Run: func(cmd *cobra.Command, args []string) {
+		if svcID == "" {
+			die("svc scale: --svc-id is required")
+		}
+		if !cmd.Flags().Changed("count") {
+			die("svc scale: --count is required")
+		}
+		startTime := time.Now()
+		manager := getManager()
+		svcScaler, ok := manager.(anysched.SvcScaler)
+		if !ok {
+			die("svc scale: the scheduler of this environment cannot scale services")
+		}
+		scaling, err := svcScaler.ScaleSvc(svcID, scaleCount)
+		if err != nil {
+			_, err2 := fmt.Fprintf(os.Stderr, "ScaleSvc error: %s\n", err)
+			if err2 != nil {
+				panic(err2)
+			}
+			os.Exit(1)
+		}
+		followOperation(manager, anysched.SvcCfg{ID: svcID, Count: scaleCount}, scaling, "Scaling", startTime,
+			timeoutDuration)
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(svcScaleCmd)
+	svcScaleCmd.Flags().StringVarP(&svcID, "svc-id", "s", "", "svc-id of service to scale")
+	svcScaleCmd.Flags().IntVarP(&scaleCount, "count", "c", 0, "Number of tasks to run")
+	svcScaleCmd.Flags().DurationVarP(&timeoutDuration, "timeout", "t", timeoutDuration,
+		"Max time to wait for scaling to complete")
+}
@@ -0,0 +1,59 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// svcUpdateCmd represents the "svc update" command
+var svcUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a deployed service",
+	Run: func(cmd *cobra.Command, args []string) {
+		startTime := time.Now()
+		svcCfg := getDeploySvcCfg("svc update")
+		manager := getManager()
+		svcUpdater, ok := manager.(anysched.SvcUpdater)
+		if !ok {
+			die("svc update: the scheduler of this environment cannot update services")
+		}
+		svcCfg.Count = getUpdateCount(cmd, manager, svcCfg, "svc update")
+		update, err := svcUpdater.UpdateSvc(svcCfg)
+		if err != nil {
+			_, err2 := fmt.Fprintf(os.Stderr, "UpdateSvc error: %s\n", err)
+			if err2 != nil {
+				panic(err2)
+			}
+			os.Exit(1)
+		}
+		followOperation(manager, svcCfg, update, "Update", startTime, timeoutDuration)
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(svcUpdateCmd)
+	addDeployFlags(svcUpdateCmd, "the service")
+	svcUpdateCmd.Flags().IntVarP(&deploySettings.updateCount, "count", "c", 0,
+		"Number of containers to run (default: the count of the deployed service)")
+	svcUpdateCmd.Flags().DurationVarP(&timeoutDuration, "timeout", "t", timeoutDuration,
+		"Max time to wait for update to complete")
+}
@@ -52,7 +52,8 @@ var taskListCmd = &cobra.Command{
 func outputTaskListTable(w io.Writer, data interface{}) error {
 	tasks := data.([]anysched.Task)
 	for _, task := range tasks {
-		_, err := fmt.Fprintf(w, "%-40s %-16s %-16s %s\n", task.Name, task.HostIP, task.TaskIP, task.ReadyTime)
+		_, err := fmt.Fprintf(w, "%-40s %-16s %-16s %s\n", qualifiedName(task.Namespace, task.Name), task.HostIP,
+			task.TaskIP, task.ReadyTime)
 		if err != nil {
 			panic(err)
 		}
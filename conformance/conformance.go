@@ -0,0 +1,411 @@
+// Package conformance is a Ginkgo suite that checks that an anysched.Manager
+// behaves the way that users of anysched expect, whatever scheduler it talks
+// to.
+//
+// A manager's tests run the suite against a stand-in for its scheduler, e.g.:
+//
+//	var _ = conformance.DescribeManager("fake", func() anysched.Manager {
+//		manager, _ := NewManager("")
+//		return manager
+//	})
+//
+// The contract that the suite checks is:
+//
+//   - DeploySvc returns a non-nil Operation, whose Wait returns once all of the
+//     service's tasks are running. After that, the service is in Svcs and its
+//     tasks are in SvcTasks and Tasks.
+//   - DeploySvc returns an error for a service ID that is already deployed, and
+//     for a SvcCfg that fails SvcCfg.Validate.
+//   - Svcs returns the labels of a service, and SvcsWithSelector returns only
+//     the services whose labels match a selector.
+//   - For managers that implement SvcGetter, Svc returns the image, count and
+//     labels of a service in its SvcCfg, which DiffSvcCfgs finds no
+//     differences in from the deployed SvcCfg. Svc returns an error for a
+//     service ID that is not deployed.
+//   - For managers that implement SvcUpdater, UpdateSvc returns an Operation,
+//     whose Wait returns once all of the service's tasks run the new SvcCfg.
+//     After that, the service has the new count of tasks. UpdateSvc returns an
+//     error for a service ID that is not deployed.
+//   - For managers that implement SvcScaler, ScaleSvc returns an Operation,
+//     whose Wait returns once the service has the new count of tasks, whether
+//     it grew or shrank. ScaleSvc returns an error for a service ID that is not
+//     deployed.
+//   - For managers that also implement SvcHistoryGetter and SvcRollbacker,
+//     SvcHistory returns the revisions of a service, oldest first, and only
+//     the last one is current. RollbackSvc to the revision before it returns
+//     an Operation, whose Wait returns once the service runs that revision
+//     again, as a new current revision.
+//   - For managers that implement SvcDiffer, DiffSvc returns nil for a service
+//     ID that is not deployed, and otherwise the settings that differ, with no
+//     Unknown settings for the image, count and labels. ApplySvc deploys a
+//     service, leaves it unchanged when it is applied again, and updates it
+//     when its count changes, with DiffSvc or with Svc.
+//   - DestroySvc returns an error for a service ID that is not deployed.
+//     Otherwise it returns a non-nil Operation, whose Wait returns once the
+//     service has been destroyed. After that, the service is not in Svcs.
+//   - An Operation's GetProperties returns a non-nil map, and its GetStatus
+//     returns a status with a Msg. Once GetStatus reports Done, Wait returns
+//     without an error.
+package conformance
+
+import (
+	"context"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// WaitTimeout is how long the suite waits (in real time) for an Operation.
+var WaitTimeout = 5 * time.Minute
+
+// SvcCfg is the service that the suite deploys. Tests of managers that don't
+// run container images can change its Image before the suite runs.
+var SvcCfg = anysched.SvcCfg{
+	ID:    "conformance-httpbin",
+	Image: "citizenstig/httpbin",
+	Count: 2,
+	Labels: map[string]string{
+		"app":  "httpbin",
+		"team": "conformance",
+	},
+}
+
+// DescribeManager adds the conformance suite to the specs of the current Ginkgo
+// suite, calling newManager to get a Manager for each spec. It returns a bool
+// so that it can be called at the top level of a _test.go file.
+func DescribeManager(name string, newManager func() anysched.Manager) bool {
+	return ginkgo.Describe("conformance: "+name, func() {
+		var manager anysched.Manager
+
+		ginkgo.BeforeEach(func() {
+			manager = newManager()
+			gomega.Expect(manager).ToNot(gomega.BeNil())
+		})
+
+		ginkgo.It("deploys, lists and destroys a service", func() {
+			deploy(manager)
+
+			svcs, err := manager.Svcs()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			svc := findSvc(svcs, SvcCfg.ID)
+			gomega.Expect(svc).ToNot(gomega.BeNil(), "Svcs did not return the deployed service")
+			if svc.TasksRunning != nil {
+				gomega.Expect(*svc.TasksRunning).To(gomega.Equal(SvcCfg.Count))
+			}
+
+			svcTasks, err := manager.SvcTasks(SvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(svcTasks).To(gomega.HaveLen(SvcCfg.Count))
+			for _, task := range svcTasks {
+				gomega.Expect(task.Name).ToNot(gomega.BeEmpty())
+				gomega.Expect(task.AppID).To(gomega.Equal(SvcCfg.ID))
+			}
+
+			tasks, err := manager.Tasks()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			for _, svcTask := range svcTasks {
+				gomega.Expect(taskNames(tasks)).To(gomega.ContainElement(svcTask.Name))
+			}
+
+			destroy(manager, SvcCfg.ID)
+			svcs, err = manager.Svcs()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(findSvc(svcs, SvcCfg.ID)).To(gomega.BeNil(), "Svcs returned the destroyed service")
+		})
+
+		ginkgo.It("gets a service", func() {
+			svcGetter := getSvcGetter(manager)
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+
+			svcDetail, err := svcGetter.Svc(SvcCfg.ID)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(svcDetail).ToNot(gomega.BeNil())
+			gomega.Expect(svcDetail.Svc.ID).To(gomega.Equal(SvcCfg.ID))
+			gomega.Expect(svcDetail.SvcCfg.ID).To(gomega.Equal(SvcCfg.ID))
+			gomega.Expect(svcDetail.SvcCfg.Image).To(gomega.Equal(SvcCfg.Image))
+			gomega.Expect(svcDetail.SvcCfg.Count).To(gomega.Equal(SvcCfg.Count))
+			gomega.Expect(svcDetail.SvcCfg.Labels).To(gomega.Equal(SvcCfg.Labels))
+			gomega.Expect(anysched.DiffSvcCfgs(svcDetail.SvcCfg, SvcCfg).Changed).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("returns an error when getting a service that does not exist", func() {
+			svcDetail, err := getSvcGetter(manager).Svc("conformance-does-not-exist")
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(svcDetail).To(gomega.BeNil())
+		})
+
+		ginkgo.It("lists services by label", func() {
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+
+			svcs, err := manager.Svcs()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			svc := findSvc(svcs, SvcCfg.ID)
+			gomega.Expect(svc).ToNot(gomega.BeNil(), "Svcs did not return the deployed service")
+			gomega.Expect(svc.Labels).To(gomega.Equal(SvcCfg.Labels))
+
+			for selector, selected := range map[string]bool{
+				"team=conformance":              true,
+				"team in (conformance,another)": true,
+				"app,!deprecated":               true,
+				"team!=conformance":             false,
+				"team=conformance,app=another":  false,
+			} {
+				parsedSelector, err := anysched.ParseSelector(selector)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				svcs, err := anysched.SvcsWithSelector(manager, parsedSelector)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				if selected {
+					gomega.Expect(findSvc(svcs, SvcCfg.ID)).ToNot(gomega.BeNil(), "not selected by %q", selector)
+				} else {
+					gomega.Expect(findSvc(svcs, SvcCfg.ID)).To(gomega.BeNil(), "selected by %q", selector)
+				}
+			}
+		})
+
+		ginkgo.It("returns an error when deploying a service that already exists", func() {
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+			op, err := manager.DeploySvc(SvcCfg)
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(op).To(gomega.BeNil())
+		})
+
+		ginkgo.It("returns an error when deploying an invalid service", func() {
+			invalidSvcCfg := SvcCfg
+			invalidSvcCfg.Resources = &anysched.Resources{CPU: 2, CPULimit: 1}
+			op, err := manager.DeploySvc(invalidSvcCfg)
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(op).To(gomega.BeNil())
+			svcs, err := manager.Svcs()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(findSvc(svcs, SvcCfg.ID)).To(gomega.BeNil(), "DeploySvc deployed an invalid service")
+		})
+
+		ginkgo.It("updates a service", func() {
+			svcUpdater := getSvcUpdater(manager)
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+
+			updatedSvcCfg := SvcCfg
+			updatedSvcCfg.Count = SvcCfg.Count + 1
+			updatedSvcCfg.Env = map[string]string{"CONFORMANCE_VERSION": "2"}
+			op, err := svcUpdater.UpdateSvc(updatedSvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(op).ToNot(gomega.BeNil(), "UpdateSvc returned a nil Operation")
+			wait(op)
+
+			svcs, err := manager.Svcs()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			svc := findSvc(svcs, SvcCfg.ID)
+			gomega.Expect(svc).ToNot(gomega.BeNil(), "Svcs did not return the updated service")
+			if svc.TasksRunning != nil {
+				gomega.Expect(*svc.TasksRunning).To(gomega.Equal(updatedSvcCfg.Count))
+			}
+			svcTasks, err := manager.SvcTasks(updatedSvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(svcTasks).To(gomega.HaveLen(updatedSvcCfg.Count))
+		})
+
+		ginkgo.It("scales a service", func() {
+			svcScaler := getSvcScaler(manager)
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+
+			for _, count := range []int{SvcCfg.Count + 1, 1} {
+				op, err := svcScaler.ScaleSvc(SvcCfg.ID, count)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(op).ToNot(gomega.BeNil(), "ScaleSvc returned a nil Operation")
+				wait(op)
+
+				svcTasks, err := manager.SvcTasks(SvcCfg)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(svcTasks).To(gomega.HaveLen(count))
+			}
+		})
+
+		ginkgo.It("returns an error when scaling a service that does not exist", func() {
+			op, err := getSvcScaler(manager).ScaleSvc("conformance-does-not-exist", 3)
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(op).To(gomega.BeNil())
+		})
+
+		ginkgo.It("rolls a service back to a previous revision", func() {
+			svcUpdater := getSvcUpdater(manager)
+			svcHistoryGetter, ok := manager.(anysched.SvcHistoryGetter)
+			svcRollbacker, ok2 := manager.(anysched.SvcRollbacker)
+			if !ok || !ok2 {
+				ginkgo.Skip("the manager does not keep the history of services")
+			}
+			deploy(manager)
+			defer destroy(manager, SvcCfg.ID)
+			updatedSvcCfg := SvcCfg
+			updatedSvcCfg.Env = map[string]string{"CONFORMANCE_VERSION": "2"}
+			op, err := svcUpdater.UpdateSvc(updatedSvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			wait(op)
+
+			revisions, err := svcHistoryGetter.SvcHistory(SvcCfg.ID)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(len(revisions)).To(gomega.BeNumerically(">=", 2))
+			previous, current := revisions[len(revisions)-2], revisions[len(revisions)-1]
+			gomega.Expect(previous.Current).To(gomega.BeFalse())
+			gomega.Expect(current.Current).To(gomega.BeTrue())
+			gomega.Expect(current.Revision).To(gomega.BeNumerically(">", previous.Revision))
+
+			op, err = svcRollbacker.RollbackSvc(SvcCfg.ID, previous.Revision)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(op).ToNot(gomega.BeNil(), "RollbackSvc returned a nil Operation")
+			wait(op)
+			revisions, err = svcHistoryGetter.SvcHistory(SvcCfg.ID)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(revisions[len(revisions)-1].Current).To(gomega.BeTrue())
+			gomega.Expect(revisions[len(revisions)-1].Image).To(gomega.Equal(previous.Image))
+		})
+
+		ginkgo.It("applies a service", func() {
+			// ApplySvc updates the service with UpdateSvc, and finds out how it
+			// differs with DiffSvc, or with Svc if there is no DiffSvc
+			getSvcUpdater(manager)
+			svcDiffer, ok := manager.(anysched.SvcDiffer)
+			if ok {
+				svcDiff, err := svcDiffer.DiffSvc(SvcCfg)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(svcDiff).To(gomega.BeNil())
+			} else {
+				getSvcGetter(manager)
+			}
+			op, result, err := anysched.ApplySvc(manager, SvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(result).To(gomega.Equal(anysched.SvcCreated))
+			wait(op)
+			defer destroy(manager, SvcCfg.ID)
+
+			op, result, err = anysched.ApplySvc(manager, SvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(result).To(gomega.Equal(anysched.SvcUnchanged))
+			wait(op)
+
+			scaledSvcCfg := SvcCfg
+			scaledSvcCfg.Count = SvcCfg.Count + 1
+			if svcDiffer != nil {
+				svcDiff, err := svcDiffer.DiffSvc(scaledSvcCfg)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(svcDiff.Changed).To(gomega.Equal([]string{"count"}))
+				gomega.Expect(svcDiff.Unknown).To(gomega.BeEmpty())
+			}
+			op, result, err = anysched.ApplySvc(manager, scaledSvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(result).To(gomega.Equal(anysched.SvcUpdated))
+			wait(op)
+		})
+
+		ginkgo.It("returns an error when updating a service that does not exist", func() {
+			missingSvcCfg := SvcCfg
+			missingSvcCfg.ID = "conformance-does-not-exist"
+			op, err := getSvcUpdater(manager).UpdateSvc(missingSvcCfg)
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(op).To(gomega.BeNil())
+		})
+
+		ginkgo.It("returns an error when destroying a service that does not exist", func() {
+			op, err := manager.DestroySvc("conformance-does-not-exist")
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(op).To(gomega.BeNil())
+		})
+
+		ginkgo.It("returns Operations that report their status", func() {
+			op, err := manager.DeploySvc(SvcCfg)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(op).ToNot(gomega.BeNil())
+			defer destroy(manager, SvcCfg.ID)
+			gomega.Expect(op.GetProperties()).ToNot(gomega.BeNil())
+
+			status, err := op.GetStatus()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(status).ToNot(gomega.BeNil())
+			gomega.Expect(status.Msg).ToNot(gomega.BeEmpty())
+
+			wait(op)
+			status, err = op.GetStatus()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(status.Done).To(gomega.BeTrue())
+			gomega.Expect(status.Msg).ToNot(gomega.BeEmpty())
+
+			// Waiting for an Operation that is done returns right away
+			wait(op)
+		})
+	})
+}
+
+func deploy(manager anysched.Manager) {
+	op, err := manager.DeploySvc(SvcCfg)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(op).ToNot(gomega.BeNil(), "DeploySvc returned a nil Operation")
+	wait(op)
+}
+
+func destroy(manager anysched.Manager, svcID string) {
+	op, err := manager.DestroySvc(svcID)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(op).ToNot(gomega.BeNil(), "DestroySvc returned a nil Operation")
+	wait(op)
+}
+
+// getSvcGetter returns manager as a SvcGetter, or skips the current spec if it
+// isn't one.
+func getSvcGetter(manager anysched.Manager) anysched.SvcGetter {
+	svcGetter, ok := manager.(anysched.SvcGetter)
+	if !ok {
+		ginkgo.Skip("the manager cannot get a service")
+	}
+	return svcGetter
+}
+
+// getSvcUpdater returns manager as a SvcUpdater, or skips the current spec if
+// it isn't one.
+func getSvcUpdater(manager anysched.Manager) anysched.SvcUpdater {
+	svcUpdater, ok := manager.(anysched.SvcUpdater)
+	if !ok {
+		ginkgo.Skip("the manager cannot update services")
+	}
+	return svcUpdater
+}
+
+// getSvcScaler returns manager as a SvcScaler, or skips the current spec if it
+// isn't one.
+func getSvcScaler(manager anysched.Manager) anysched.SvcScaler {
+	svcScaler, ok := manager.(anysched.SvcScaler)
+	if !ok {
+		ginkgo.Skip("the manager cannot scale services")
+	}
+	return svcScaler
+}
+
+func wait(op anysched.Operation) {
+	ctx, cancel := context.WithTimeout(context.Background(), WaitTimeout)
+	defer cancel()
+	_, err := op.Wait(ctx)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+}
+
+func findSvc(svcs []anysched.Svc, svcID string) *anysched.Svc {
+	for i := range svcs {
+		if svcs[i].ID == svcID {
+			return &svcs[i]
+		}
+	}
+	return nil
+}
+
+func taskNames(tasks []anysched.Task) []string {
+	names := make([]string, len(tasks))
+	for i, task := range tasks {
+		names[i] = task.Name
+	}
+	return names
+}
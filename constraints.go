@@ -0,0 +1,131 @@
+package anysched
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operators of a Constraint
+const (
+	ConstraintEquals        = "=="
+	ConstraintNotEquals     = "!="
+	ConstraintIn            = "in"
+	ConstraintUniquePerHost = "unique-per-host"
+	ConstraintSpread        = "spread"
+)
+
+// ConstraintAttributeHostname is the attribute of a node that is its host name.
+// Managers translate it to the scheduler's own name for it, e.g.:
+// "kubernetes.io/hostname" for Kubernetes or "node.hostname" for Swarm.
+const ConstraintAttributeHostname = "hostname"
+
+// Constraint restricts which nodes the tasks of a service are placed on, or
+// how they are spread across them.
+//
+// Attribute is the name of an attribute of the nodes, as the scheduler knows
+// it: a Kubernetes node label, a Mesos agent attribute, a Nomad node meta key
+// or a Swarm node label, or ConstraintAttributeHostname.
+//
+// ConstraintEquals and ConstraintNotEquals have one value and ConstraintIn has
+// one or more. ConstraintUniquePerHost places at most one task on each host,
+// so it has no Attribute, and ConstraintSpread spreads tasks evenly across the
+// values of Attribute, e.g. zones. Neither of them has values.
+type Constraint struct {
+	Attribute string
+	Operator  string
+	Values    []string
+}
+
+// constraintRegexp matches constraints, e.g.: "disk==ssd" or "zone in (a, b)"
+var constraintRegexp = regexp.MustCompile(`^\s*([^\s=!]+)\s*(==|=|!=|\s+in\s+)\s*(.*?)\s*$`)
+
+// ParseConstraint parses a constraint: "ATTRIBUTE==VALUE" (or
+// "ATTRIBUTE=VALUE"), "ATTRIBUTE!=VALUE", "ATTRIBUTE in (VALUE1,VALUE2)",
+// "unique-per-host" or "spread:ATTRIBUTE", e.g.: "disk==ssd".
+func ParseConstraint(s string) (Constraint, error) {
+	var constraint Constraint
+	switch {
+	case strings.TrimSpace(s) == ConstraintUniquePerHost:
+		constraint = Constraint{Operator: ConstraintUniquePerHost}
+	case strings.HasPrefix(s, ConstraintSpread+":"):
+		constraint = Constraint{Attribute: strings.TrimSpace(s[len(ConstraintSpread)+1:]), Operator: ConstraintSpread}
+	default:
+		match := constraintRegexp.FindStringSubmatch(s)
+		if match == nil {
+			return Constraint{}, fmt.Errorf("invalid constraint %q", s)
+		}
+		constraint = Constraint{Attribute: match[1], Operator: strings.TrimSpace(match[2]), Values: []string{match[3]}}
+		switch constraint.Operator {
+		case "=":
+			constraint.Operator = ConstraintEquals
+		case ConstraintIn:
+			values := strings.TrimSuffix(strings.TrimPrefix(match[3], "("), ")")
+			constraint.Values = strings.Split(values, ",")
+			for i, value := range constraint.Values {
+				constraint.Values[i] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if err := constraint.Validate(); err != nil {
+		return Constraint{}, fmt.Errorf("invalid constraint %q: %s", s, err)
+	}
+	return constraint, nil
+}
+
+// constraintArity is what each operator of a Constraint takes, and how the
+// error for a constraint that doesn't have it describes it.
+var constraintArity = map[string]struct {
+	attribute              bool
+	minValues, maxValues   int
+	attributeAndValuesDesc string
+}{
+	ConstraintEquals:        {true, 1, 1, "an attribute and one value"},
+	ConstraintNotEquals:     {true, 1, 1, "an attribute and one value"},
+	ConstraintIn:            {true, 1, -1, "an attribute and at least one value"},
+	ConstraintUniquePerHost: {false, 0, 0, "no attribute and no values"},
+	ConstraintSpread:        {true, 0, 0, "an attribute and no values"},
+}
+
+// Validate returns an error if the constraint has an unknown operator, or an
+// attribute or values that its operator doesn't take.
+func (constraint Constraint) Validate() error {
+	arity, ok := constraintArity[constraint.Operator]
+	if !ok {
+		return fmt.Errorf("unknown constraint operator %q", constraint.Operator)
+	}
+	numValues := len(constraint.Values)
+	if arity.attribute != (constraint.Attribute != "") ||
+		numValues < arity.minValues || (arity.maxValues >= 0 && numValues > arity.maxValues) {
+		return fmt.Errorf("%q constraint must have %s", constraint.Operator, arity.attributeAndValuesDesc)
+	}
+	for _, value := range constraint.Values {
+		if value == "" {
+			return fmt.Errorf("%q constraint cannot have a blank value", constraint.Operator)
+		}
+	}
+	return nil
+}
+
+// validateConstraints returns an error if a constraint is invalid.
+func validateConstraints(constraints []Constraint) error {
+	for _, constraint := range constraints {
+		if err := constraint.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns the constraint in the syntax that ParseConstraint parses.
+func (constraint Constraint) String() string {
+	switch constraint.Operator {
+	case ConstraintIn:
+		return fmt.Sprintf("%s in (%s)", constraint.Attribute, strings.Join(constraint.Values, ","))
+	case ConstraintUniquePerHost:
+		return ConstraintUniquePerHost
+	case ConstraintSpread:
+		return ConstraintSpread + ":" + constraint.Attribute
+	}
+	return constraint.Attribute + constraint.Operator + strings.Join(constraint.Values, ",")
+}
@@ -0,0 +1,74 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("constraints.go", func() {
+	Describe("ParseConstraint", func() {
+		It("works with equals and not equals", func() {
+			constraint, err := anysched.ParseConstraint("disk==ssd")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{Attribute: "disk", Operator: "==", Values: []string{"ssd"}}))
+			constraint, err = anysched.ParseConstraint("disk = ssd")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{Attribute: "disk", Operator: "==", Values: []string{"ssd"}}))
+			constraint, err = anysched.ParseConstraint("hostname!=node-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{Attribute: "hostname", Operator: "!=", Values: []string{"node-1"}}))
+			Expect(constraint.String()).To(Equal("hostname!=node-1"))
+		})
+
+		It("works with in", func() {
+			constraint, err := anysched.ParseConstraint("zone in (us-east-1a, us-east-1b)")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{
+				Attribute: "zone",
+				Operator:  anysched.ConstraintIn,
+				Values:    []string{"us-east-1a", "us-east-1b"},
+			}))
+			Expect(constraint.String()).To(Equal("zone in (us-east-1a,us-east-1b)"))
+		})
+
+		It("works with unique-per-host and spread", func() {
+			constraint, err := anysched.ParseConstraint("unique-per-host")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{Operator: anysched.ConstraintUniquePerHost}))
+			constraint, err = anysched.ParseConstraint("spread:zone")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(constraint).To(Equal(anysched.Constraint{Attribute: "zone", Operator: anysched.ConstraintSpread}))
+			Expect(constraint.String()).To(Equal("spread:zone"))
+		})
+
+		It("returns an error for an invalid constraint", func() {
+			_, err := anysched.ParseConstraint("ssd")
+			Expect(err).To(MatchError(`invalid constraint "ssd"`))
+			_, err = anysched.ParseConstraint("spread:")
+			Expect(err).To(MatchError(`invalid constraint "spread:": "spread" constraint must have an attribute and no values`))
+			_, err = anysched.ParseConstraint("disk==")
+			Expect(err).To(MatchError(`invalid constraint "disk==": "==" constraint cannot have a blank value`))
+		})
+	})
+
+	Describe("Constraint.Validate", func() {
+		It("returns an error for an unknown operator", func() {
+			err := anysched.Constraint{Attribute: "disk", Operator: "like", Values: []string{"ssd"}}.Validate()
+			Expect(err).To(MatchError(`unknown constraint operator "like"`))
+		})
+
+		It("returns an error for an operator with the wrong number of values", func() {
+			err := anysched.Constraint{Attribute: "disk", Operator: "==", Values: []string{"ssd", "nvme"}}.Validate()
+			Expect(err).To(MatchError(`"==" constraint must have an attribute and one value`))
+			err = anysched.Constraint{Attribute: "zone", Operator: anysched.ConstraintIn}.Validate()
+			Expect(err).To(MatchError(`"in" constraint must have an attribute and at least one value`))
+		})
+
+		It("returns an error for unique-per-host with an attribute", func() {
+			err := anysched.Constraint{Attribute: "zone", Operator: anysched.ConstraintUniquePerHost}.Validate()
+			Expect(err).To(MatchError(`"unique-per-host" constraint must have no attribute and no values`))
+		})
+	})
+})
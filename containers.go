@@ -0,0 +1,112 @@
+package anysched
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ContainerCfg declares a container of a service other than its main one,
+// which is declared by the Image, Command, Args, Env, Ports and Resources of
+// the SvcCfg, so that a service with one container is declared just like
+// before. See SvcCfg.Sidecars and SvcCfg.InitContainers.
+type ContainerCfg struct {
+	// Name identifies the container within a task. It follows the rules of
+	// DNS labels, like the names of Kubernetes containers, and it cannot be
+	// the ID of the service, which is the name of the main container.
+	Name string
+
+	Image   string
+	Command []string
+	Args    []string
+	Env     map[string]string
+	Ports   []PortCfg
+
+	// Resources is the resources of the container. nil means the scheduler's
+	// defaults.
+	Resources *Resources
+}
+
+// ParseContainerCfg parses a container in the form "NAME=IMAGE [ARG...]",
+// where the arguments, which are separated by spaces, are passed to the
+// image's entrypoint, e.g.: "migrate=example/payments:1.2 migrate --yes".
+func ParseContainerCfg(s string) (ContainerCfg, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return ContainerCfg{}, fmt.Errorf("invalid container %q: expected NAME=IMAGE [ARG...]", s)
+	}
+	containerCfg := ContainerCfg{Name: parts[0]}
+	if words := strings.Fields(parts[1]); len(words) > 0 {
+		containerCfg.Image = words[0]
+		if len(words) > 1 {
+			containerCfg.Args = words[1:]
+		}
+	}
+	if err := containerCfg.Validate(); err != nil {
+		return ContainerCfg{}, fmt.Errorf("invalid container %q: %s", s, err)
+	}
+	return containerCfg, nil
+}
+
+// EnvVars returns the environment variables in Env, ordered by name.
+func (containerCfg ContainerCfg) EnvVars() []EnvVar {
+	return envVars(containerCfg.Env)
+}
+
+// dnsLabelRegexp matches DNS labels, which is what the names of containers and
+// namespaces are, e.g.: "log-forwarder"
+var dnsLabelRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate returns an error if the container has an invalid name, no image,
+// or an invalid port or resources.
+func (containerCfg ContainerCfg) Validate() error {
+	if len(containerCfg.Name) > maxLabelLength || !dnsLabelRegexp.MatchString(containerCfg.Name) {
+		return fmt.Errorf("invalid container name %q: must be at most %d lowercase letters, digits or \"-\"",
+			containerCfg.Name, maxLabelLength)
+	}
+	if containerCfg.Image == "" {
+		return fmt.Errorf("container %q has no image", containerCfg.Name)
+	}
+	if err := validatePorts(containerCfg.Ports); err != nil {
+		return fmt.Errorf("container %q: %s", containerCfg.Name, err)
+	}
+	if err := containerCfg.Resources.Validate(); err != nil {
+		return fmt.Errorf("container %q: invalid resources: %s", containerCfg.Name, err)
+	}
+	return nil
+}
+
+// validateContainers returns an error if a sidecar or init container is
+// invalid, if two containers of a task have the same name, or if two of the
+// containers that run at the same time listen on the same port, since the
+// containers of a task share its network. Init containers run before the
+// others, so they can't have ports.
+func validateContainers(svcCfg SvcCfg) error {
+	names := map[string]bool{svcCfg.ID: true}
+	for _, containerCfg := range append(append([]ContainerCfg{}, svcCfg.Sidecars...), svcCfg.InitContainers...) {
+		if err := containerCfg.Validate(); err != nil {
+			return err
+		}
+		if names[containerCfg.Name] {
+			return fmt.Errorf("more than one container is named %q", containerCfg.Name)
+		}
+		names[containerCfg.Name] = true
+	}
+	for _, containerCfg := range svcCfg.InitContainers {
+		if len(containerCfg.Ports) > 0 {
+			return fmt.Errorf("init container %q cannot have ports", containerCfg.Name)
+		}
+	}
+	listeners := map[PortCfg]string{}
+	for _, containerCfg := range append([]ContainerCfg{{Name: svcCfg.ID, Ports: svcCfg.Ports}}, svcCfg.Sidecars...) {
+		for _, portCfg := range containerCfg.Ports {
+			port := PortCfg{ContainerPort: portCfg.ContainerPort, Protocol: portCfg.ProtocolOrDefault()}
+			if listener, ok := listeners[port]; ok && listener != containerCfg.Name {
+				return fmt.Errorf("containers %q and %q both listen on port %d/%s",
+					listener, containerCfg.Name, port.ContainerPort, port.Protocol)
+			}
+			listeners[port] = containerCfg.Name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("containers.go", func() {
+	Describe("ParseContainerCfg", func() {
+		It("works with an image", func() {
+			containerCfg, err := anysched.ParseContainerCfg("log-forwarder=fluent/fluent-bit:0.13")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerCfg).To(Equal(anysched.ContainerCfg{Name: "log-forwarder", Image: "fluent/fluent-bit:0.13"}))
+		})
+
+		It("works with arguments", func() {
+			containerCfg, err := anysched.ParseContainerCfg("migrate=example/payments:1.2 migrate --yes")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerCfg).To(Equal(anysched.ContainerCfg{
+				Name:  "migrate",
+				Image: "example/payments:1.2",
+				Args:  []string{"migrate", "--yes"},
+			}))
+		})
+
+		It("returns an error without a name", func() {
+			_, err := anysched.ParseContainerCfg("fluent/fluent-bit:0.13")
+			Expect(err).To(MatchError(`invalid container "fluent/fluent-bit:0.13": expected NAME=IMAGE [ARG...]`))
+		})
+
+		It("returns an error without an image", func() {
+			_, err := anysched.ParseContainerCfg("migrate=")
+			Expect(err).To(MatchError(`invalid container "migrate=": container "migrate" has no image`))
+		})
+	})
+
+	Describe("ContainerCfg.Validate", func() {
+		It("returns an error for an invalid name", func() {
+			err := anysched.ContainerCfg{Name: "Log_Forwarder", Image: "fluent/fluent-bit"}.Validate()
+			Expect(err).To(MatchError(
+				`invalid container name "Log_Forwarder": must be at most 63 lowercase letters, digits or "-"`))
+		})
+
+		It("returns an error for an invalid port", func() {
+			err := anysched.ContainerCfg{
+				Name:  "log-forwarder",
+				Image: "fluent/fluent-bit",
+				Ports: []anysched.PortCfg{{ContainerPort: 70000}},
+			}.Validate()
+			Expect(err).To(MatchError(`container "log-forwarder": container port 70000 is not between 1 and 65535`))
+		})
+	})
+})
@@ -11,3 +11,8 @@ func appManagerTypeAlreadyRegisteredError(appManagerType string) error {
 	return fmt.Errorf("already registered app manager type: %q",
 		appManagerType)
 }
+
+func appManagerTypeWithoutNamespacesError(appManagerType string) error {
+	return fmt.Errorf("app manager type %q does not have namespaces",
+		appManagerType)
+}
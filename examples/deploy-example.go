@@ -18,7 +18,11 @@ func doStuff() error {
 	// }
 	// managerConfig := anysched.ManagerConfig{
 	// 	Type:    "dockerswarm",
-	// 	Address: "http://127.0.0.1:2377",
+	// 	Address: "unix:///var/run/docker.sock",
+	// }
+	// managerConfig := anysched.ManagerConfig{
+	// 	Type:    "docker",
+	// 	Address: "unix:///var/run/docker.sock",
 	// }
 	// managerConfig := anysched.ManagerConfig{
 	// 	Type:    "nomad",
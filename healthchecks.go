@@ -0,0 +1,137 @@
+package anysched
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of health checks
+const (
+	HealthCheckHTTP    = "http"
+	HealthCheckTCP     = "tcp"
+	HealthCheckCommand = "command"
+)
+
+// Defaults for the zero values of the fields of a HealthCheck
+const (
+	DefaultHealthCheckInterval         = 10 * time.Second
+	DefaultHealthCheckTimeout          = 5 * time.Second
+	DefaultHealthCheckFailureThreshold = 3
+)
+
+// HealthCheck checks whether a task is healthy, in one of three ways: with an
+// HTTP GET of HTTPPath on Port, which succeeds with a 2xx or 3xx status; by
+// connecting to Port with TCP, if HTTPPath is blank; or by running Command in
+// the task, which succeeds if it exits with 0.
+type HealthCheck struct {
+	HTTPPath string
+	Port     int
+	Command  []string
+
+	// Interval is how often the check runs, and Timeout is how long it can
+	// take before it fails.
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// GracePeriod is how long after a task starts that failed checks are
+	// ignored.
+	GracePeriod time.Duration
+
+	// FailureThreshold is how many checks in a row have to fail for the task
+	// to be unhealthy.
+	FailureThreshold int
+}
+
+// Kind returns HealthCheckHTTP, HealthCheckTCP or HealthCheckCommand.
+func (healthCheck *HealthCheck) Kind() string {
+	switch {
+	case len(healthCheck.Command) > 0:
+		return HealthCheckCommand
+	case healthCheck.HTTPPath != "":
+		return HealthCheckHTTP
+	default:
+		return HealthCheckTCP
+	}
+}
+
+// IntervalOrDefault returns Interval, or DefaultHealthCheckInterval if it is 0.
+func (healthCheck *HealthCheck) IntervalOrDefault() time.Duration {
+	if healthCheck.Interval == 0 {
+		return DefaultHealthCheckInterval
+	}
+	return healthCheck.Interval
+}
+
+// TimeoutOrDefault returns Timeout, or DefaultHealthCheckTimeout if it is 0.
+func (healthCheck *HealthCheck) TimeoutOrDefault() time.Duration {
+	if healthCheck.Timeout == 0 {
+		return DefaultHealthCheckTimeout
+	}
+	return healthCheck.Timeout
+}
+
+// FailureThresholdOrDefault returns FailureThreshold, or
+// DefaultHealthCheckFailureThreshold if it is 0.
+func (healthCheck *HealthCheck) FailureThresholdOrDefault() int {
+	if healthCheck.FailureThreshold == 0 {
+		return DefaultHealthCheckFailureThreshold
+	}
+	return healthCheck.FailureThreshold
+}
+
+// Validate returns an error if the check has both a Command and a port, has
+// neither, or has negative durations or FailureThreshold.
+func (healthCheck *HealthCheck) Validate() error {
+	if healthCheck == nil {
+		return nil
+	}
+	if len(healthCheck.Command) > 0 && (healthCheck.HTTPPath != "" || healthCheck.Port != 0) {
+		return fmt.Errorf("a health check cannot have both a command and an HTTP path or port")
+	}
+	if len(healthCheck.Command) == 0 && (healthCheck.Port < 1 || healthCheck.Port > 65535) {
+		return fmt.Errorf("port %d of %s health check is not between 1 and 65535", healthCheck.Port, healthCheck.Kind())
+	}
+	if healthCheck.Interval < 0 || healthCheck.Timeout < 0 || healthCheck.GracePeriod < 0 {
+		return fmt.Errorf("durations of a health check cannot be negative")
+	}
+	if healthCheck.FailureThreshold < 0 {
+		return fmt.Errorf("failure threshold of a health check cannot be negative")
+	}
+	return nil
+}
+
+// ParseHealthCheck parses a health check in one of the forms "http:PORT/PATH",
+// "tcp:PORT" or "cmd:COMMAND ARGS...", e.g.: "http:8000/status", "tcp:5432"
+// or "cmd:pg_isready -U postgres". The command is split on spaces.
+func ParseHealthCheck(s string) (*HealthCheck, error) {
+	kind, rest := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, rest = s[:i], s[i+1:]
+	}
+	healthCheck := &HealthCheck{}
+	var err error
+	switch kind {
+	case "http":
+		port := rest
+		if j := strings.Index(rest, "/"); j >= 0 {
+			port, healthCheck.HTTPPath = rest[:j], rest[j:]
+		} else {
+			healthCheck.HTTPPath = "/"
+		}
+		healthCheck.Port, err = parsePortNumber(port)
+	case "tcp":
+		healthCheck.Port, err = parsePortNumber(rest)
+	case "cmd":
+		healthCheck.Command = strings.Fields(rest)
+		if len(healthCheck.Command) == 0 {
+			err = fmt.Errorf("command is blank")
+		}
+	default:
+		err = fmt.Errorf("must start with \"http:\", \"tcp:\" or \"cmd:\"")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check %q: %s", s, err)
+	}
+	return healthCheck, nil
+}
@@ -0,0 +1,78 @@
+package anysched_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("healthchecks.go", func() {
+	Describe("HealthCheck", func() {
+		Describe("Kind", func() {
+			It("works", func() {
+				Expect((&anysched.HealthCheck{HTTPPath: "/status", Port: 8000}).Kind()).To(Equal(anysched.HealthCheckHTTP))
+				Expect((&anysched.HealthCheck{Port: 5432}).Kind()).To(Equal(anysched.HealthCheckTCP))
+				Expect((&anysched.HealthCheck{Command: []string{"true"}}).Kind()).To(Equal(anysched.HealthCheckCommand))
+			})
+		})
+
+		Describe("defaults", func() {
+			It("works", func() {
+				healthCheck := &anysched.HealthCheck{Port: 5432}
+				Expect(healthCheck.IntervalOrDefault()).To(Equal(10 * time.Second))
+				Expect(healthCheck.TimeoutOrDefault()).To(Equal(5 * time.Second))
+				Expect(healthCheck.FailureThresholdOrDefault()).To(Equal(3))
+				healthCheck = &anysched.HealthCheck{Port: 5432, Interval: time.Minute, Timeout: time.Second, FailureThreshold: 1}
+				Expect(healthCheck.IntervalOrDefault()).To(Equal(time.Minute))
+				Expect(healthCheck.TimeoutOrDefault()).To(Equal(time.Second))
+				Expect(healthCheck.FailureThresholdOrDefault()).To(Equal(1))
+			})
+		})
+
+		Describe("Validate", func() {
+			It("returns an error for a check with a command and a port", func() {
+				err := (&anysched.HealthCheck{Command: []string{"true"}, Port: 8000}).Validate()
+				Expect(err).To(MatchError("a health check cannot have both a command and an HTTP path or port"))
+			})
+
+			It("returns an error for a check without a command or a port", func() {
+				err := (&anysched.HealthCheck{HTTPPath: "/status"}).Validate()
+				Expect(err).To(MatchError("port 0 of http health check is not between 1 and 65535"))
+			})
+
+			It("returns an error for negative durations", func() {
+				err := (&anysched.HealthCheck{Port: 8000, Interval: -time.Second}).Validate()
+				Expect(err).To(MatchError("durations of a health check cannot be negative"))
+			})
+		})
+	})
+
+	Describe("ParseHealthCheck", func() {
+		It("works with HTTP checks", func() {
+			Expect(anysched.ParseHealthCheck("http:8000/status")).To(Equal(
+				&anysched.HealthCheck{HTTPPath: "/status", Port: 8000}))
+			Expect(anysched.ParseHealthCheck("http:8000")).To(Equal(&anysched.HealthCheck{HTTPPath: "/", Port: 8000}))
+		})
+
+		It("works with TCP checks", func() {
+			Expect(anysched.ParseHealthCheck("tcp:5432")).To(Equal(&anysched.HealthCheck{Port: 5432}))
+		})
+
+		It("works with commands", func() {
+			Expect(anysched.ParseHealthCheck("cmd:pg_isready -U postgres")).To(Equal(
+				&anysched.HealthCheck{Command: []string{"pg_isready", "-U", "postgres"}}))
+		})
+
+		It("returns an error for an invalid check", func() {
+			_, err := anysched.ParseHealthCheck("/status")
+			Expect(err).To(MatchError(`invalid health check "/status": must start with "http:", "tcp:" or "cmd:"`))
+			_, err = anysched.ParseHealthCheck("http:web/status")
+			Expect(err).To(MatchError(`invalid health check "http:web/status": "web" is not a number`))
+			_, err = anysched.ParseHealthCheck("cmd: ")
+			Expect(err).To(MatchError(`invalid health check "cmd: ": command is blank`))
+		})
+	})
+})
@@ -21,18 +21,100 @@ type SvcDeployer interface {
 	DeploySvc(SvcCfg) (Operation, error)
 }
 
+// SvcUpdater is an interface with a method for updating a deployed service. It
+// isn't part of Manager, so that managers written before it existed still
+// are Managers, but all of the managers in this repo implement it.
+type SvcUpdater interface {
+	// UpdateSvc takes the new SvcCfg of a deployed service and replaces its
+	// tasks with ones that run it, a few at a time, returning an Operation
+	// that is done once all of them do.
+	UpdateSvc(SvcCfg) (Operation, error)
+}
+
+// SvcScaler is an interface with a method for changing the number of tasks of
+// a deployed service without redeploying it. It isn't part of Manager, like
+// SvcUpdater.
+type SvcScaler interface {
+	// ScaleSvc changes the number of tasks of a service to count, returning
+	// an Operation that is done once count tasks are running.
+	ScaleSvc(svcID string, count int) (Operation, error)
+}
+
+// SvcDiffer is an interface with a method for finding out how a deployed
+// service differs from a SvcCfg. It isn't part of Manager: ApplySvc uses it if
+// a manager implements it, and compares the SvcCfg that SvcGetter returns
+// otherwise.
+type SvcDiffer interface {
+	// DiffSvc returns how the deployed service with the ID of svcCfg differs
+	// from it, or nil if there is no such service. The settings of svcCfg that
+	// the manager can't read back from the scheduler are the Unknown of the
+	// SvcDiff.
+	DiffSvc(svcCfg SvcCfg) (*SvcDiff, error)
+}
+
 // SvcDestroyer is an interface with a method for destroying a service.
 type SvcDestroyer interface {
-	// DestroySvc destroys a service.
+	// DestroySvc destroys a service, returning a non-nil Operation that is
+	// done once the service and its tasks are gone.
 	DestroySvc(svcID string) (Operation, error)
 }
 
+// SvcGetter is an interface with a method for getting one deployed service. It
+// isn't part of Manager, like SvcUpdater.
+type SvcGetter interface {
+	// Svc returns what the scheduler reports about a service, or an error if
+	// there is no such service.
+	Svc(svcID string) (*SvcDetail, error)
+}
+
 // SvcsGetter is an interface with a method for getting all running services.
 type SvcsGetter interface {
 	// Svcs returns info about the running services.
 	Svcs() ([]Svc, error)
 }
 
+// SvcsWithSelectorGetter is an interface with a method for getting the running
+// services whose labels match a selector. It isn't part of Manager: managers
+// implement it if their scheduler can filter services by label itself, and
+// the SvcsWithSelector function filters the services of the ones that don't.
+type SvcsWithSelectorGetter interface {
+	// SvcsWithSelector returns info about the running services whose labels
+	// match selector.
+	SvcsWithSelector(selector Selector) ([]Svc, error)
+}
+
+// SecretsManager is an interface with methods for managing the secrets that
+// services refer to with SecretRefs. It isn't part of Manager: managers
+// implement it if their scheduler stores secrets itself.
+type SecretsManager interface {
+	// CreateSecret creates a secret, or returns an error if it exists.
+	CreateSecret(secret Secret) error
+
+	// UpdateSecret replaces the values of an existing secret.
+	UpdateSecret(secret Secret) error
+
+	// DeleteSecret deletes a secret.
+	DeleteSecret(name string) error
+}
+
+// SvcHistoryGetter is an interface with a method for getting the revisions of a
+// service. It isn't part of Manager: managers implement it if their scheduler
+// keeps the previous configurations of a service.
+type SvcHistoryGetter interface {
+	// SvcHistory returns the revisions of a service, oldest first.
+	SvcHistory(svcID string) ([]SvcRevision, error)
+}
+
+// SvcRollbacker is an interface with a method for rolling a service back to
+// one of the revisions that SvcHistory returns. It isn't part of Manager, like
+// SvcHistoryGetter.
+type SvcRollbacker interface {
+	// RollbackSvc updates a service to the configuration of one of its
+	// revisions, which makes a new revision, returning an Operation. Whether
+	// the count of the service is rolled back too depends on the scheduler.
+	RollbackSvc(svcID string, revision int64) (Operation, error)
+}
+
 // SvcTasksGetter is an interface with a method for getting all running tasks
 // for a particular service.
 type SvcTasksGetter interface {
@@ -2,9 +2,13 @@ package anysched
 
 type newManagerFuncType func(managerAddress string) (Manager, error)
 
+// newManagerWithConfigFuncType is the type of the functions that create
+// managers from all of a ManagerConfig, not just its Address.
+type newManagerWithConfigFuncType func(managerConfig ManagerConfig) (Manager, error)
+
 // gManagerTypeRegistry is a map of manager type names to functions that create
 // new managers
-var gManagerTypeRegistry = make(map[string]newManagerFuncType)
+var gManagerTypeRegistry = make(map[string]newManagerWithConfigFuncType)
 
 // gManagerTypes is a slice with valid manager type names.
 var gManagerTypes = []string{}
@@ -12,11 +16,25 @@ var gManagerTypes = []string{}
 // ClearManagerTypeRegistry clears the manager type registry, which is probably
 // only useful for tests.
 func ClearManagerTypeRegistry() {
-	gManagerTypeRegistry = make(map[string]newManagerFuncType)
+	gManagerTypeRegistry = make(map[string]newManagerWithConfigFuncType)
 }
 
 // RegisterManagerType registers the name given by managerType with a NewManager function.
+// NewManager returns an error for a ManagerConfig with a Namespace, since the
+// function only takes the Address.
 func RegisterManagerType(managerType string, f newManagerFuncType) {
+	RegisterManagerTypeWithConfig(managerType, func(managerConfig ManagerConfig) (Manager, error) {
+		if managerConfig.Namespace != "" {
+			return nil, appManagerTypeWithoutNamespacesError(managerType)
+		}
+		return f(managerConfig.Address)
+	})
+}
+
+// RegisterManagerTypeWithConfig registers the name given by managerType with a
+// function that takes the whole ManagerConfig, for managers that use more of
+// it than the Address, e.g. the Namespace.
+func RegisterManagerTypeWithConfig(managerType string, f newManagerWithConfigFuncType) {
 	if _, alreadyExists := gManagerTypeRegistry[managerType]; alreadyExists {
 		panic(appManagerTypeAlreadyRegisteredError(managerType))
 	}
@@ -31,5 +49,5 @@ func NewManager(managerConfig ManagerConfig) (manager Manager, err error) {
 	if !ok {
 		return nil, appManagerTypeUnknownError(managerConfig.Type)
 	}
-	return newManagerFunc(managerConfig.Address)
+	return newManagerFunc(managerConfig)
 }
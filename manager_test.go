@@ -45,6 +45,14 @@ var _ = Describe("manager.go", func() {
 				Expect(manager).ToNot(BeNil())
 			})
 
+			It("returns an error for a Namespace, since that function only takes the address", func() {
+				managerConfig := anysched.ManagerConfig{Type: "foo", Address: "http://1.2.3.4:5678", Namespace: "payments"}
+				manager, err := anysched.NewManager(managerConfig)
+				Expect(myNewManagerFuncCalled).To(BeFalse())
+				Expect(err).To(MatchError(`app manager type "foo" does not have namespaces`))
+				Expect(manager).To(BeNil())
+			})
+
 			It("returns an error if an unknown type is passed in", func() {
 				managerConfig := anysched.ManagerConfig{Type: "unknown_type", Address: "http://1.2.3.4:5678"}
 				manager, err := anysched.NewManager(managerConfig)
@@ -55,4 +63,27 @@ var _ = Describe("manager.go", func() {
 			})
 		})
 	})
+
+	Context("a manager constructor function that takes a ManagerConfig is registered under a type", func() {
+		var receivedManagerConfig anysched.ManagerConfig
+
+		BeforeEach(func() {
+			receivedManagerConfig = anysched.ManagerConfig{}
+			anysched.ClearManagerTypeRegistry()
+			anysched.RegisterManagerTypeWithConfig("foo", func(managerConfig anysched.ManagerConfig) (anysched.Manager, error) {
+				receivedManagerConfig = managerConfig
+				return marathon.NewManager(managerConfig.Address)
+			})
+		})
+
+		Describe("NewManager", func() {
+			It("passes that function the whole ManagerConfig", func() {
+				managerConfig := anysched.ManagerConfig{Type: "foo", Address: "http://1.2.3.4:5678", Namespace: "payments"}
+				manager, err := anysched.NewManager(managerConfig)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manager).ToNot(BeNil())
+				Expect(receivedManagerConfig).To(Equal(managerConfig))
+			})
+		})
+	})
 })
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+var _ = conformance.DescribeManager("docker", func() anysched.Manager {
+	pollInterval = 10 * time.Millisecond
+	return NewManagerWithTestServer(newEngineStandIn())
+})
+
+// engineStandIn is a stand-in for the Docker Engine API that keeps the
+// containers that it is asked to create, and runs them right away, for the
+// conformance suite. It only has the citizenstig/httpbin image.
+type engineStandIn struct {
+	mutex      sync.Mutex
+	containers map[string]*types.ContainerJSON
+	lastID     int
+}
+
+func newEngineStandIn() *httptest.Server {
+	standIn := &engineStandIn{containers: map[string]*types.ContainerJSON{}}
+	return httptest.NewServer(standIn)
+}
+
+func (standIn *engineStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	path := apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+	switch {
+	case path == "/info":
+		writeJSON(w, types.Info{Name: "conformance-host"})
+	case path == "/images/citizenstig/httpbin/json":
+		writeJSON(w, types.ImageInspect{ID: "sha256:httpbin", Config: &container.Config{}})
+	case path == "/containers/json":
+		standIn.listContainers(w, r)
+	case path == "/containers/create":
+		standIn.createContainer(w, r)
+	case strings.HasPrefix(path, "/containers/"):
+		standIn.serveContainer(w, r, strings.Split(strings.TrimPrefix(path, "/containers/"), "/"))
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (standIn *engineStandIn) listContainers(w http.ResponseWriter, r *http.Request) {
+	containerFilters, err := filters.FromParam(r.URL.Query().Get("filters"))
+	Expect(err).ToNot(HaveOccurred())
+	containers := []types.Container{}
+	for _, containerJSON := range standIn.containers {
+		if !containerFilters.MatchKVList("label", containerJSON.Config.Labels) {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339Nano, containerJSON.Created)
+		containers = append(containers, types.Container{
+			ID:      containerJSON.ID,
+			Names:   []string{containerJSON.Name},
+			Image:   containerJSON.Config.Image,
+			ImageID: containerJSON.Image,
+			Created: created.Unix(),
+			Labels:  containerJSON.Config.Labels,
+			State:   containerJSON.State.Status,
+			Status:  "Up Less than a second",
+		})
+	}
+	writeJSON(w, containers)
+}
+
+func (standIn *engineStandIn) createContainer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		*container.Config
+		HostConfig       *container.HostConfig
+		NetworkingConfig *network.NetworkingConfig
+	}
+	Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+	name := "/" + r.URL.Query().Get("name")
+	for _, containerJSON := range standIn.containers {
+		if containerJSON.Name == name {
+			w.WriteHeader(409)
+			return
+		}
+	}
+	standIn.lastID++
+	containerJSON := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         fmt.Sprintf("%064d", standIn.lastID),
+			Name:       name,
+			Created:    time.Now().Format(time.RFC3339Nano),
+			Image:      "sha256:httpbin",
+			State:      &types.ContainerState{Status: "created"},
+			HostConfig: body.HostConfig,
+		},
+		Config: body.Config,
+	}
+	standIn.containers[containerJSON.ID] = containerJSON
+	writeJSON(w, container.ContainerCreateCreatedBody{ID: containerJSON.ID})
+}
+
+func (standIn *engineStandIn) serveContainer(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	containerJSON, ok := standIn.containers[pathParts[0]]
+	switch {
+	case !ok:
+		w.WriteHeader(404)
+	case r.Method == "DELETE" && len(pathParts) == 1:
+		delete(standIn.containers, containerJSON.ID)
+		w.WriteHeader(204)
+	case r.Method == "POST" && len(pathParts) == 2 && pathParts[1] == "start":
+		containerJSON.State = &types.ContainerState{Status: "running", Running: true,
+			StartedAt: time.Now().Format(time.RFC3339Nano)}
+		w.WriteHeader(204)
+	case r.Method == "GET" && len(pathParts) == 2 && pathParts[1] == "json":
+		writeJSON(w, containerJSON)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	bytes, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	writeJSONResponseBytes(w, bytes)
+}
@@ -0,0 +1,196 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api/types"
+	dockerclient "github.com/docker/docker/client"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var (
+	// pollInterval is how often Wait checks the status of a deployment.
+	pollInterval = 2 * time.Second
+
+	getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
+		if svcCfg.DeployTimeoutDuration == nil {
+			return 60 * time.Second
+		}
+		return *svcCfg.DeployTimeoutDuration
+	}
+)
+
+// deployment implements the anysched.Operation interface for the containers of
+// a service. It is done once all of the containers are running and none of
+// them are still waiting for, or failing, their health checks.
+type deployment struct {
+	manager         *manager
+	svcCfg          anysched.SvcCfg
+	timeoutDuration time.Duration
+	// update is true for the deployment of an update to a service
+	update bool
+	// rollout replaces the containers of the service for an update, if any
+	rollout *rollout
+}
+
+func (dep *deployment) String() string {
+	return fmt.Sprintf("<docker.deployment name=%q count=%d />", dep.svcCfg.ID, dep.svcCfg.Count)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dep *deployment) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = dep.svcCfg.ID
+	propertiesMap["count"] = dep.svcCfg.Count
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deployment. It also advances the
+// rollout of an update, so an update only finishes while it is polled.
+func (dep *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
+	containers, err := dep.manager.containers(svcFilters(dep.svcCfg.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.deployment.GetStatus: manager.containers failed")
+	}
+	if dep.rollout != nil {
+		advanced, err := dep.rollout.advance(dep.manager, dep.svcCfg, containers)
+		if err != nil {
+			return nil, errors.Wrap(err, "docker.deployment.GetStatus: rollout.advance failed")
+		}
+		if advanced {
+			if containers, err = dep.manager.containers(svcFilters(dep.svcCfg.ID)); err != nil {
+				return nil, errors.Wrap(err, "docker.deployment.GetStatus: manager.containers failed")
+			}
+		}
+	}
+	lastUpdateTime, err := dep.manager.lastUpdateTime(containers)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.deployment.GetStatus: manager.lastUpdateTime failed")
+	}
+	status, err = getStatusOfContainers(dep.svcCfg, containers, lastUpdateTime, dep.update)
+	if err != nil || dep.rollout == nil || dep.rollout.isDone() {
+		return status, err
+	}
+	updated, waiting := dep.rollout.progress(dep.svcCfg, containers)
+	msg := fmt.Sprintf("%d of %d containers are running the new version, %d waiting to be replaced...",
+		updated, dep.svcCfg.Count, waiting)
+	return notDoneStatus(dep.svcCfg.ID, msg, lastUpdateTime, dep.update), nil
+}
+
+// lastUpdateTime returns when the state of the last of containers changed,
+// which is when it started or last finished a health check. A container that
+// is removed in the meantime is left out.
+func (mgr *manager) lastUpdateTime(containers []types.Container) (time.Time, error) {
+	var lastUpdateTime time.Time
+	for _, c := range containers {
+		containerJSON, err := mgr.client.ContainerInspect(ctx, c.ID)
+		if dockerclient.IsErrContainerNotFound(err) {
+			continue
+		} else if err != nil {
+			return time.Time{}, errors.Wrapf(err, "mgr.client.ContainerInspect(%q) failed", c.ID)
+		}
+		if updateTime := containerUpdateTime(containerJSON); updateTime.After(lastUpdateTime) {
+			lastUpdateTime = updateTime
+		}
+	}
+	return lastUpdateTime, nil
+}
+
+// containerUpdateTime returns when a container started or last finished a
+// health check, whichever is later.
+func containerUpdateTime(containerJSON types.ContainerJSON) time.Time {
+	var updateTime time.Time
+	if startTime := containerStartTime(containerJSON); startTime != nil {
+		updateTime = *startTime
+	}
+	if containerJSON.ContainerJSONBase == nil || containerJSON.State == nil || containerJSON.State.Health == nil {
+		return updateTime
+	}
+	for _, result := range containerJSON.State.Health.Log {
+		if result != nil && result.End.After(updateTime) {
+			updateTime = result.End
+		}
+	}
+	return updateTime
+}
+
+// getStatusOfContainers returns the status of a deployment of svcCfg from its
+// containers, which last changed at lastUpdateTime. A deployment that is done
+// without any containers, of a Count of 0, is given the current time instead.
+func getStatusOfContainers(svcCfg anysched.SvcCfg, containers []types.Container, lastUpdateTime time.Time,
+	update bool) (*anysched.OperationStatus, error) {
+	running, starting := 0, 0
+	for _, c := range containers {
+		switch {
+		case c.State == "exited" || c.State == "dead":
+			return nil, fmt.Errorf("container %s of service %q stopped: %s", containerName(c), svcCfg.ID, c.Status)
+		case containerIsUnhealthy(c):
+			return nil, fmt.Errorf("container %s of service %q is unhealthy: %s", containerName(c), svcCfg.ID, c.Status)
+		case c.State == "running" && containerHealthIsStarting(c):
+			starting++
+		case c.State == "running":
+			running++
+		}
+	}
+	if running < svcCfg.Count {
+		msg := fmt.Sprintf("%d of %d containers are running, %d waiting for health checks...",
+			running, svcCfg.Count, starting)
+		return notDoneStatus(svcCfg.ID, msg, lastUpdateTime, update), nil
+	}
+	if lastUpdateTime.IsZero() {
+		lastUpdateTime = time.Now()
+	}
+	verb := "deployed"
+	if update {
+		verb = "updated"
+	}
+	msg := fmt.Sprintf("Service %q successfully %s. %d of %d containers are running.",
+		svcCfg.ID, verb, running, svcCfg.Count)
+	return status(msg, true, lastUpdateTime), nil
+}
+
+func notDoneStatus(svcID string, msg string, lastUpdateTime time.Time, update bool) *anysched.OperationStatus {
+	verb := "start"
+	if update {
+		verb = "update"
+	}
+	msg = fmt.Sprintf("Waiting for service %q to %s: %s", svcID, verb, msg)
+	return status(msg, false, lastUpdateTime)
+}
+
+func status(msg string, done bool, lastUpdateTime time.Time) *anysched.OperationStatus {
+	return &anysched.OperationStatus{
+		ClientTime:         time.Now(),
+		LastTransitionTime: lastUpdateTime,
+		LastUpdateTime:     lastUpdateTime,
+		Msg:                msg,
+		Done:               done,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (dep *deployment) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, dep.timeoutDuration)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "docker.deployment.Wait: Timed out after %s", dep.timeoutDuration)
+		case <-time.After(pollInterval):
+			status, err := dep.GetStatus()
+			if err != nil {
+				return nil, errors.Wrap(err, "docker.deployment.Wait: GetStatus failed")
+			}
+			if status.Done {
+				return dep, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,772 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/managers/internal/dockerhost"
+)
+
+// Labels that the manager puts on the containers that it runs, so that services
+// and tasks can be reconstructed from the containers on the Docker host.
+// svcLabelsLabel lists the keys of the service's labels, since containers also
+// have the labels of their image.
+const (
+	svcIDLabel     = "anysched.svc-id"
+	taskIndexLabel = "anysched.task-index"
+	svcLabelsLabel = "anysched.svc-labels"
+)
+
+var ctx = context.TODO()
+
+type manager struct {
+	client *dockerclient.Client
+	url    string
+}
+
+func init() {
+	anysched.RegisterManagerType("docker", NewManager)
+}
+
+// NewManager returns a Manager for a single Docker Engine, which runs each
+// service as SvcCfg.Count containers on that host.
+//
+// url is the address of the Docker Engine API, e.g.:
+// "unix:///var/run/docker.sock" or "tcp://127.0.0.1:2375". If url is blank,
+// the client is configured from the same environment variables as the docker
+// CLI (DOCKER_HOST, etc.).
+func NewManager(url string) (anysched.Manager, error) {
+	client, err := dockerhost.NewClient(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.NewManager: creating Docker client failed")
+	}
+	return &manager{client: client, url: url}, nil
+}
+
+// Svcs returns info about all running services.
+func (mgr *manager) Svcs() ([]anysched.Svc, error) {
+	svcs, err := mgr.svcs(filters.NewArgs())
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.Svcs: mgr.svcs failed")
+	}
+	return svcs, nil
+}
+
+// SvcsWithSelector returns info about the running services whose labels match
+// selector. Docker can only filter containers by label equality and existence,
+// so it does that for those requirements, and the rest are matched here.
+func (mgr *manager) SvcsWithSelector(selector anysched.Selector) ([]anysched.Svc, error) {
+	containerFilters := filters.NewArgs()
+	for _, requirement := range selector {
+		switch requirement.Operator {
+		case anysched.SelectorOpEquals:
+			containerFilters.Add("label", requirement.Key+"="+requirement.Values[0])
+		case anysched.SelectorOpExists:
+			containerFilters.Add("label", requirement.Key)
+		}
+	}
+	svcs, err := mgr.svcs(containerFilters)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.SvcsWithSelector: mgr.svcs failed")
+	}
+	return anysched.FilterSvcs(svcs, selector), nil
+}
+
+// svcs returns info about the services with containers that match
+// containerFilters.
+func (mgr *manager) svcs(containerFilters filters.Args) ([]anysched.Svc, error) {
+	containers, err := mgr.containers(containerFilters)
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.containers failed")
+	}
+	containersBySvcID := map[string][]types.Container{}
+	for _, c := range containers {
+		svcID := c.Labels[svcIDLabel]
+		containersBySvcID[svcID] = append(containersBySvcID[svcID], c)
+	}
+	svcIDs := make([]string, 0, len(containersBySvcID))
+	for svcID := range containersBySvcID {
+		svcIDs = append(svcIDs, svcID)
+	}
+	sort.Strings(svcIDs)
+	svcs := make([]anysched.Svc, len(svcIDs))
+	for i, svcID := range svcIDs {
+		svcs[i] = svcFromContainers(svcID, containersBySvcID[svcID])
+	}
+	return svcs, nil
+}
+
+func svcFromContainers(svcID string, containers []types.Container) anysched.Svc {
+	tasksRunning, tasksHealthy, tasksUnhealthy := 0, 0, 0
+	var creationTime *time.Time
+	for _, c := range containers {
+		if c.State == "running" {
+			tasksRunning++
+			switch {
+			case containerIsUnhealthy(c):
+				tasksUnhealthy++
+			case !containerHealthIsStarting(c):
+				tasksHealthy++
+			}
+		}
+		created := time.Unix(c.Created, 0)
+		if creationTime == nil || created.Before(*creationTime) {
+			creationTime = &created
+		}
+	}
+	return anysched.Svc{
+		ID:             svcID,
+		TasksRunning:   &tasksRunning,
+		TasksHealthy:   &tasksHealthy,
+		TasksUnhealthy: &tasksUnhealthy,
+		CreationTime:   creationTime,
+		Labels:         svcLabels(containers[0]),
+	}
+}
+
+// containerLabels returns the labels of the container of the task of a service
+// with index. The labels of the service can't override the manager's own.
+func containerLabels(svcCfg anysched.SvcCfg, index int) map[string]string {
+	labels := map[string]string{}
+	keys := []string{}
+	for key, value := range svcCfg.Labels {
+		labels[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels[svcIDLabel] = svcCfg.ID
+	labels[taskIndexLabel] = strconv.Itoa(index)
+	if len(keys) > 0 {
+		labels[svcLabelsLabel] = strings.Join(keys, ",")
+	}
+	return labels
+}
+
+// svcLabels returns the labels of the service that a container belongs to, or
+// nil if it doesn't have any.
+func svcLabels(c types.Container) map[string]string {
+	if c.Labels[svcLabelsLabel] == "" {
+		return nil
+	}
+	labels := map[string]string{}
+	for _, key := range strings.Split(c.Labels[svcLabelsLabel], ",") {
+		labels[key] = c.Labels[key]
+	}
+	return labels
+}
+
+// containerIsUnhealthy and containerHealthIsStarting look at the container's
+// human-readable status, e.g.: "Up 5 minutes (unhealthy)", because that is the
+// only place that the container list has the result of health checks.
+func containerIsUnhealthy(c types.Container) bool {
+	return strings.Contains(c.Status, "(unhealthy)")
+}
+
+func containerHealthIsStarting(c types.Container) bool {
+	return strings.Contains(c.Status, "(health: starting)")
+}
+
+// SvcTasks returns info about the running tasks for a service.
+func (mgr *manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
+	tasks, err := mgr.tasks(svcFilters(svcCfg.ID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "docker.manager.SvcTasks: mgr.tasks failed for svcCfg.ID = %q", svcCfg.ID)
+	}
+	return tasks, nil
+}
+
+// Tasks returns info about all running tasks.
+func (mgr *manager) Tasks() ([]anysched.Task, error) {
+	tasks, err := mgr.tasks(filters.NewArgs())
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.Tasks: mgr.tasks failed")
+	}
+	return tasks, nil
+}
+
+func svcFilters(svcID string) filters.Args {
+	containerFilters := filters.NewArgs()
+	containerFilters.Add("label", svcIDLabel+"="+svcID)
+	return containerFilters
+}
+
+// containers returns all of the containers (including stopped ones) that this
+// manager started and that match containerFilters, ordered by service ID and
+// task index.
+func (mgr *manager) containers(containerFilters filters.Args) ([]types.Container, error) {
+	containerFilters.Add("label", svcIDLabel)
+	containers, err := mgr.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: containerFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.client.ContainerList failed")
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		if containers[i].Labels[svcIDLabel] != containers[j].Labels[svcIDLabel] {
+			return containers[i].Labels[svcIDLabel] < containers[j].Labels[svcIDLabel]
+		}
+		return taskIndex(containers[i]) < taskIndex(containers[j])
+	})
+	return containers, nil
+}
+
+func taskIndex(c types.Container) int {
+	index, err := strconv.Atoi(c.Labels[taskIndexLabel])
+	if err != nil {
+		return -1
+	}
+	return index
+}
+
+func (mgr *manager) tasks(containerFilters filters.Args) ([]anysched.Task, error) {
+	containers, err := mgr.containers(containerFilters)
+	if err != nil {
+		return nil, err
+	}
+	info, err := mgr.client.Info(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.client.Info failed")
+	}
+	tasks := []anysched.Task{}
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		containerJSON, err := mgr.client.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "mgr.client.ContainerInspect(%q) failed", c.ID)
+		}
+		tasks = append(tasks, taskFromContainer(c, containerJSON, info.Name))
+	}
+	return tasks, nil
+}
+
+func taskFromContainer(c types.Container, containerJSON types.ContainerJSON, hostName string) anysched.Task {
+	ipAddresses := containerIPAddresses(c)
+	stageTime := time.Unix(c.Created, 0)
+	task := anysched.Task{
+		Name:        containerName(c),
+		AppID:       c.Labels[svcIDLabel],
+		HostName:    hostName,
+		IPAddresses: ipAddresses,
+		Ports:       publishedPorts(c),
+		StageTime:   &stageTime,
+		StartTime:   containerStartTime(containerJSON),
+		State:       c.State,
+		Version:     c.ImageID,
+	}
+	if len(ipAddresses) > 0 {
+		task.TaskIP = ipAddresses[0]
+	}
+	return task
+}
+
+func containerName(c types.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// containerIPAddresses returns the IP addresses of a container on all of its
+// networks, ordered by network name.
+func containerIPAddresses(c types.Container) (ipAddresses []string) {
+	if c.NetworkSettings == nil {
+		return nil
+	}
+	networkNames := make([]string, 0, len(c.NetworkSettings.Networks))
+	for networkName := range c.NetworkSettings.Networks {
+		networkNames = append(networkNames, networkName)
+	}
+	sort.Strings(networkNames)
+	for _, networkName := range networkNames {
+		endpointSettings := c.NetworkSettings.Networks[networkName]
+		if endpointSettings != nil && endpointSettings.IPAddress != "" {
+			ipAddresses = append(ipAddresses, endpointSettings.IPAddress)
+		}
+	}
+	return ipAddresses
+}
+
+func publishedPorts(c types.Container) (ports []int) {
+	for _, port := range c.Ports {
+		if port.PublicPort != 0 {
+			ports = append(ports, int(port.PublicPort))
+		}
+	}
+	return ports
+}
+
+func containerStartTime(containerJSON types.ContainerJSON) *time.Time {
+	if containerJSON.ContainerJSONBase == nil || containerJSON.State == nil {
+		return nil
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, containerJSON.State.StartedAt)
+	if err != nil || startTime.IsZero() {
+		return nil
+	}
+	return &startTime
+}
+
+// DeploySvc takes a SvcCfg and deploys it, returning an Operation. If the
+// container of a task can't be run, the containers of the others are removed.
+func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DeploySvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DeploySvc: validateSvcCfg failed")
+	}
+	healthConfig, err := dockerhost.HealthConfig(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DeploySvc: dockerhost.HealthConfig failed")
+	}
+	existingContainers, err := mgr.containers(svcFilters(svcCfg.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DeploySvc: mgr.containers failed")
+	}
+	if len(existingContainers) > 0 {
+		return nil, fmt.Errorf("docker.manager.DeploySvc: service %q already exists", svcCfg.ID)
+	}
+	if err = mgr.ensureImage(svcCfg.Image); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DeploySvc: mgr.ensureImage failed")
+	}
+	for i := 0; i < svcCfg.Count; i++ {
+		if err = mgr.runContainer(svcCfg, healthConfig, i); err != nil {
+			// Leave nothing behind, or retrying would fail as the service exists
+			if removeErr := mgr.removeContainersOf(svcCfg.ID); removeErr != nil {
+				return nil, errors.Wrapf(err, "docker.manager.DeploySvc: mgr.runContainer failed for task %d "+
+					"(and mgr.removeContainersOf failed: %s)", i, removeErr)
+			}
+			return nil, errors.Wrapf(err, "docker.manager.DeploySvc: mgr.runContainer failed for task %d", i)
+		}
+	}
+	dep := &deployment{
+		manager:         mgr,
+		svcCfg:          svcCfg,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and replaces its
+// containers with ones that run it, one at a time, as a rollout does. It
+// replaces the container of the first task, and the returned Operation
+// replaces each of the others as its GetStatus (or Wait) finds that the one
+// before it is ready.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: validateSvcCfg failed")
+	}
+	healthConfig, err := dockerhost.HealthConfig(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: dockerhost.HealthConfig failed")
+	}
+	oldContainers, err := mgr.containers(svcFilters(svcCfg.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: mgr.containers failed")
+	}
+	if len(oldContainers) == 0 {
+		return nil, fmt.Errorf("docker.manager.UpdateSvc: service %q does not exist", svcCfg.ID)
+	}
+	if err = mgr.ensureImage(svcCfg.Image); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: mgr.ensureImage failed")
+	}
+	rollout := newRollout(svcCfg, healthConfig, oldContainers)
+	if _, err = rollout.advance(mgr, svcCfg, oldContainers); err != nil {
+		return nil, errors.Wrap(err, "docker.manager.UpdateSvc: rollout.advance failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		svcCfg:          svcCfg,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+		update:          true,
+		rollout:         rollout,
+	}
+	return dep, nil
+}
+
+// ScaleSvc changes the number of containers of a service to count, returning an
+// Operation. The containers that it adds are copies of the container of the
+// service's first task, and the ones that it removes are those of the last
+// tasks. A service is its containers, so it can't be scaled to 0.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("docker.manager.ScaleSvc: service %q cannot be scaled to %d containers", svcID, count)
+	}
+	containers, err := mgr.containers(svcFilters(svcID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.ScaleSvc: mgr.containers failed")
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("docker.manager.ScaleSvc: service %q does not exist", svcID)
+	}
+	containerJSON, err := mgr.client.ContainerInspect(ctx, containers[0].ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "docker.manager.ScaleSvc: mgr.client.ContainerInspect(%q) failed",
+			containers[0].ID)
+	}
+	indexes, err := mgr.removeContainersFrom(containers, count)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.ScaleSvc: mgr.removeContainersFrom failed")
+	}
+	for i := 0; i < count; i++ {
+		if indexes[i] {
+			continue
+		}
+		if err = mgr.runContainerLike(svcID, containerJSON, i); err != nil {
+			return nil, errors.Wrapf(err, "docker.manager.ScaleSvc: mgr.runContainerLike failed for task %d", i)
+		}
+	}
+	svcCfg := anysched.SvcCfg{ID: svcID, Count: count}
+	dep := &deployment{
+		manager:         mgr,
+		svcCfg:          svcCfg,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// DiffSvc returns how the containers of the service with the ID of svcCfg
+// differ from the ones that DeploySvc would run for svcCfg, or nil if there are
+// no such containers. The settings are those of the container of the first
+// task.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	containers, err := mgr.containers(svcFilters(svcCfg.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DiffSvc: mgr.containers failed")
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	deployed, _, err := mgr.svcCfgFromContainers(svcCfg.ID, containers, svcCfg.Env)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DiffSvc")
+	}
+	_, portBindings := getPorts(svcCfg)
+	desired := anysched.SvcCfg{
+		ID:        svcCfg.ID,
+		Image:     svcCfg.Image,
+		Count:     svcCfg.Count,
+		Env:       svcCfg.Env,
+		Ports:     portCfgsFromPortBindings(portBindings),
+		Labels:    svcCfg.Labels,
+		Resources: resourcesFromContainer(getResources(svcCfg.Resources)),
+	}
+	svcDiff := anysched.DiffSvcCfgs(deployed, desired)
+	svcDiff.Unknown = anysched.UnknownSvcSettings(svcCfg, svcCfgFromContainersSettings...)
+	return svcDiff, nil
+}
+
+// svcCfgFromContainersSettings is the settings that svcCfgFromContainers reads
+// back from the containers of a service.
+var svcCfgFromContainersSettings = []string{"image", "count", "env", "labels", "ports", "resources"}
+
+// Svc returns a service with the SvcCfg of its containers, as in DiffSvc.
+// Docker has no revisions, and Native is the types.ContainerJSON of the
+// container of the first task.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	containers, err := mgr.containers(svcFilters(svcID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.Svc: mgr.containers failed")
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("docker.manager.Svc: service %q does not exist", svcID)
+	}
+	svcCfg, containerJSON, err := mgr.svcCfgFromContainers(svcID, containers, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.Svc")
+	}
+	return &anysched.SvcDetail{
+		Svc:    svcFromContainers(svcID, containers),
+		SvcCfg: svcCfg,
+		Native: containerJSON,
+	}, nil
+}
+
+// svcCfgFromContainers returns a SvcCfg with the number of containers of a
+// service, and the image, environment variables, ports, labels and resources
+// of the container of its first task, along with that container. svcEnv is as
+// in containerEnv.
+func (mgr *manager) svcCfgFromContainers(svcID string, containers []types.Container,
+	svcEnv map[string]string) (anysched.SvcCfg, *types.ContainerJSON, error) {
+	containerJSON, err := mgr.client.ContainerInspect(ctx, containers[0].ID)
+	if err != nil {
+		return anysched.SvcCfg{}, nil, errors.Wrapf(err, "mgr.client.ContainerInspect(%q) failed", containers[0].ID)
+	}
+	image, _, err := mgr.client.ImageInspectWithRaw(ctx, containerJSON.Config.Image)
+	if err != nil {
+		return anysched.SvcCfg{}, nil, errors.Wrapf(err, "mgr.client.ImageInspectWithRaw(%q) failed",
+			containerJSON.Config.Image)
+	}
+	svcCfg := anysched.SvcCfg{
+		ID:        svcID,
+		Image:     containerJSON.Config.Image,
+		Count:     len(containers),
+		Env:       containerEnv(containerJSON.Config.Env, image.Config, svcEnv),
+		Ports:     portCfgsFromPortBindings(containerJSON.HostConfig.PortBindings),
+		Labels:    svcLabels(containers[0]),
+		Resources: resourcesFromContainer(containerJSON.HostConfig.Resources),
+	}
+	return svcCfg, &containerJSON, nil
+}
+
+// portCfgsFromPortBindings returns the ports of a container, ordered by
+// container port and protocol. It is the reverse of getPorts.
+func portCfgsFromPortBindings(portBindings nat.PortMap) []anysched.PortCfg {
+	var portCfgs []anysched.PortCfg
+	for port, bindings := range portBindings {
+		portCfg := anysched.PortCfg{ContainerPort: port.Int(), Protocol: port.Proto()}
+		if len(bindings) > 0 && bindings[0].HostPort != "" {
+			portCfg.HostPort, _ = strconv.Atoi(bindings[0].HostPort)
+		}
+		portCfgs = append(portCfgs, portCfg)
+	}
+	sort.Slice(portCfgs, func(i, j int) bool {
+		if portCfgs[i].ContainerPort != portCfgs[j].ContainerPort {
+			return portCfgs[i].ContainerPort < portCfgs[j].ContainerPort
+		}
+		return portCfgs[i].Protocol < portCfgs[j].Protocol
+	})
+	return portCfgs
+}
+
+// containerEnv returns the environment variables of a container that do not
+// come from its image, by name. A container has the environment variables of
+// its image too, unless it overrides them, so one with the same value as in
+// the image only counts as the container's if svcEnv has it.
+func containerEnv(envList []string, imageConfig *container.Config, svcEnv map[string]string) map[string]string {
+	env := dockerhost.Env(envList)
+	if imageConfig == nil {
+		return env
+	}
+	for name, value := range dockerhost.Env(imageConfig.Env) {
+		if svcValue, ok := svcEnv[name]; env[name] == value && !(ok && svcValue == value) {
+			delete(env, name)
+		}
+	}
+	return env
+}
+
+// resourcesFromContainer returns the resources of a container. It is the
+// reverse of getResources, except that a CPU limit can't be told apart from a
+// CPU request that is used as the limit.
+func resourcesFromContainer(resources container.Resources) *anysched.Resources {
+	return &anysched.Resources{
+		CPULimit:    float64(resources.NanoCPUs) / 1e9,
+		Memory:      resources.MemoryReservation,
+		MemoryLimit: resources.Memory,
+	}
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that Docker cannot
+// express. All of the containers run on the same host, so only one of them can
+// publish a host port, and they can't be placed by constraints. Secrets are
+// stored by Swarm, not by the Docker Engine, so they can't be exposed either,
+// and each task is one container, so there are no sidecars or init containers.
+// Docker has no namespaces.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	if svcCfg.Namespace != "" {
+		return fmt.Errorf("service %q: Docker has no namespaces", svcCfg.ID)
+	}
+	if len(svcCfg.Sidecars) > 0 || len(svcCfg.InitContainers) > 0 {
+		return fmt.Errorf("service %q: Docker runs each task as one container, so it cannot run sidecars "+
+			"or init containers", svcCfg.ID)
+	}
+	if len(svcCfg.Constraints) > 0 {
+		return fmt.Errorf("service %q: Docker runs every task on one host, so it cannot place them by constraints",
+			svcCfg.ID)
+	}
+	if len(svcCfg.Secrets) > 0 {
+		return fmt.Errorf("service %q: Docker has no secrets outside of Swarm, so it cannot expose them", svcCfg.ID)
+	}
+	if svcCfg.Count > 1 {
+		for _, portCfg := range svcCfg.Ports {
+			if portCfg.HostPort != 0 {
+				return fmt.Errorf("service %q cannot publish host port %d for more than one container",
+					svcCfg.ID, portCfg.HostPort)
+			}
+		}
+	}
+	return nil
+}
+
+// ensureImage pulls image, unless it is already present on the Docker host.
+func (mgr *manager) ensureImage(image string) error {
+	_, _, err := mgr.client.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !dockerclient.IsErrImageNotFound(err) {
+		return errors.Wrapf(err, "mgr.client.ImageInspectWithRaw(%q) failed", image)
+	}
+	progress, err := mgr.client.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "mgr.client.ImagePull(%q) failed", image)
+	}
+	defer progress.Close()
+	// The pull is not finished until the progress stream has been read to the end
+	if _, err = io.Copy(ioutil.Discard, progress); err != nil {
+		return errors.Wrapf(err, "reading progress of mgr.client.ImagePull(%q) failed", image)
+	}
+	return nil
+}
+
+func (mgr *manager) runContainer(svcCfg anysched.SvcCfg, healthConfig *container.HealthConfig, index int) error {
+	exposedPorts, portBindings := getPorts(svcCfg)
+	containerConfig := &container.Config{
+		Image:        svcCfg.Image,
+		Entrypoint:   svcCfg.Command,
+		Cmd:          svcCfg.Args,
+		Env:          svcCfg.EnvList(),
+		ExposedPorts: exposedPorts,
+		Healthcheck:  healthConfig,
+		Labels:       containerLabels(svcCfg, index),
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings:  portBindings,
+		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+		Resources:     getResources(svcCfg.Resources),
+		Mounts:        dockerhost.Mounts(svcCfg),
+	}
+	name := fmt.Sprintf("%s.%d", svcCfg.ID, index)
+	created, err := mgr.client.ContainerCreate(ctx, containerConfig, hostConfig, &network.NetworkingConfig{}, name)
+	if err != nil {
+		return errors.Wrapf(err, "mgr.client.ContainerCreate(%q) failed", name)
+	}
+	if err = mgr.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrapf(err, "mgr.client.ContainerStart(%q) failed", created.ID)
+	}
+	return nil
+}
+
+// removeContainersOf removes all of the containers of a service, so that
+// DeploySvc can be retried after it fails part of the way through.
+func (mgr *manager) removeContainersOf(svcID string) error {
+	containers, err := mgr.containers(svcFilters(svcID))
+	if err != nil {
+		return errors.Wrap(err, "mgr.containers failed")
+	}
+	_, err = mgr.removeContainersFrom(containers, 0)
+	return err
+}
+
+// removeContainersFrom removes the containers of the tasks of a service whose
+// index is index or more, and returns the indexes of the others.
+func (mgr *manager) removeContainersFrom(containers []types.Container, index int) (map[int]bool, error) {
+	indexes := map[int]bool{}
+	for _, c := range containers {
+		if taskIndex(c) < index {
+			indexes[taskIndex(c)] = true
+			continue
+		}
+		err := mgr.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "mgr.client.ContainerRemove(%q) failed", c.ID)
+		}
+	}
+	return indexes, nil
+}
+
+// runContainerLike runs a container for the task of a service with index, with
+// the same configuration as containerJSON, the container of another task.
+func (mgr *manager) runContainerLike(svcID string, containerJSON types.ContainerJSON, index int) error {
+	containerConfig := *containerJSON.Config
+	containerConfig.Hostname = ""
+	containerConfig.Labels = map[string]string{}
+	for key, value := range containerJSON.Config.Labels {
+		containerConfig.Labels[key] = value
+	}
+	containerConfig.Labels[taskIndexLabel] = strconv.Itoa(index)
+	name := fmt.Sprintf("%s.%d", svcID, index)
+	created, err := mgr.client.ContainerCreate(ctx, &containerConfig, containerJSON.HostConfig,
+		&network.NetworkingConfig{}, name)
+	if err != nil {
+		return errors.Wrapf(err, "mgr.client.ContainerCreate(%q) failed", name)
+	}
+	if err = mgr.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrapf(err, "mgr.client.ContainerStart(%q) failed", created.ID)
+	}
+	return nil
+}
+
+// getResources returns the resources of a container. Docker only limits CPU, so
+// CPU is the limit if there is no CPULimit. The memory request is a soft limit
+// that Docker enforces when the host is low on memory. Docker cannot reserve
+// disk, so Disk is ignored.
+func getResources(resources *anysched.Resources) container.Resources {
+	if resources == nil {
+		return container.Resources{}
+	}
+	cpuLimit := resources.CPULimit
+	if cpuLimit == 0 {
+		cpuLimit = resources.CPU
+	}
+	return container.Resources{
+		NanoCPUs:          nanoCPUs(cpuLimit),
+		Memory:            resources.MemoryLimit,
+		MemoryReservation: resources.Memory,
+	}
+}
+
+// nanoCPUs converts CPU cores to the billionths of a CPU that Docker takes.
+func nanoCPUs(cpu float64) int64 {
+	return int64(math.Ceil(cpu * 1e9))
+}
+
+// getPorts returns the ports that a container exposes, and the ports on the
+// host that they are published on. Docker picks a port on the host for any
+// port without a HostPort.
+func getPorts(svcCfg anysched.SvcCfg) (nat.PortSet, nat.PortMap) {
+	if len(svcCfg.Ports) == 0 {
+		return nil, nil
+	}
+	exposedPorts, portBindings := nat.PortSet{}, nat.PortMap{}
+	for _, portCfg := range svcCfg.Ports {
+		port := nat.Port(fmt.Sprintf("%d/%s", portCfg.ContainerPort, portCfg.ProtocolOrDefault()))
+		exposedPorts[port] = struct{}{}
+		portBinding := nat.PortBinding{}
+		if portCfg.HostPort != 0 {
+			portBinding.HostPort = strconv.Itoa(portCfg.HostPort)
+		}
+		portBindings[port] = append(portBindings[port], portBinding)
+	}
+	return exposedPorts, portBindings
+}
+
+// DestroySvc destroys a service, force-removing its containers, and returns an
+// Operation that is done once none of them are left.
+func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
+	containers, err := mgr.containers(svcFilters(svcID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.manager.DestroySvc: mgr.containers failed")
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("docker.manager.DestroySvc: service %q does not exist", svcID)
+	}
+	for _, c := range containers {
+		err = mgr.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "docker.manager.DestroySvc: mgr.client.ContainerRemove(%q) failed", c.ID)
+		}
+	}
+	return &removal{manager: mgr, svcID: svcID, timeoutDuration: 60 * time.Second}, nil
+}
@@ -0,0 +1,1068 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// apiVersionPrefixRegexp matches the API version that the Docker client puts
+// at the start of request paths, e.g.: "/v1.29"
+var apiVersionPrefixRegexp = regexp.MustCompile(`^/v[0-9.]+`)
+
+// NewTestServerJSONRoutes returns a stand-in for the Docker Engine API that
+// responds to each URL path (without the API version prefix) in routes with
+// the contents of the corresponding JSON file, with HTTP 204 if the file is
+// blank, and with HTTP 404 for any other path. Each request is passed to
+// onRequest, if it is not nil.
+func NewTestServerJSONRoutes(routes map[string]string, onRequest func(r *http.Request)) *httptest.Server {
+	routeSequences := make(map[string][]string, len(routes))
+	for path, jsonResponseFilePath := range routes {
+		routeSequences[path] = []string{jsonResponseFilePath}
+	}
+	return NewTestServerJSONRouteSequences(routeSequences, onRequest)
+}
+
+// NewTestServerJSONRouteSequences is like NewTestServerJSONRoutes, but each URL
+// path responds with the next JSON file in its sequence every time that it is
+// requested, repeating the last one once the sequence is exhausted.
+func NewTestServerJSONRouteSequences(
+	routeSequences map[string][]string, onRequest func(r *http.Request),
+) *httptest.Server {
+	var mutex sync.Mutex
+	counts := map[string]int{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if onRequest != nil {
+			onRequest(r)
+		}
+		path := apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+		jsonResponseFilePaths, ok := routeSequences[path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		if jsonResponseFilePath := jsonResponseFilePaths[counts[path]]; jsonResponseFilePath == "" {
+			w.WriteHeader(204)
+		} else {
+			writeJSONResponseFromFile(w, jsonResponseFilePath)
+		}
+		if counts[path] < len(jsonResponseFilePaths)-1 {
+			counts[path]++
+		}
+	}))
+}
+
+func writeJSONResponseFromFile(w http.ResponseWriter, jsonResponseFilePath string) {
+	bytes, err := ioutil.ReadFile(jsonResponseFilePath)
+	if err != nil {
+		panic(err)
+	}
+	writeJSONResponseBytes(w, bytes)
+}
+
+func writeJSONResponseBytes(w http.ResponseWriter, bytes []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytes)))
+	w.Write(bytes)
+}
+
+func NewManagerWithTestServer(ts *httptest.Server) anysched.Manager {
+	client, err := dockerclient.NewClient("tcp://"+ts.Listener.Addr().String(), "1.29", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return &manager{client: client, url: ts.URL}
+}
+
+var (
+	httpbinContainer0ID = "4f0c7a2e9b1d3c5e7f9a1b3d5c7e9f1a3b5d7c9e1f3a5b7d9c1e3f5a7b9d1c3e"
+	httpbinContainer1ID = "8b2d4f6a8c0e2b4d6f8a0c2e4b6d8f0a2c4e6b8d0f2a4c6e8b0d2f4a6c8e0b2d"
+
+	dockerRoutes = map[string]string{
+		"/containers/json": "testdata/containers_list.json",
+		"/containers/" + httpbinContainer0ID + "/json": "testdata/container_inspect_httpbin_0.json",
+		"/containers/" + httpbinContainer1ID + "/json": "testdata/container_inspect_httpbin_1.json",
+		"/info": "testdata/info.json",
+	}
+
+	// deployRoutes are the routes that DeploySvc needs for an image that is
+	// already present, apart from "/containers/json"
+	deployRoutes = map[string][]string{
+		"/images/citizenstig/httpbin/json":              {"testdata/image_inspect.json"},
+		"/containers/create":                            {"testdata/container_create.json"},
+		"/containers/" + httpbinContainer0ID + "/start": {""},
+		"/containers/" + httpbinContainer0ID + "/json":  {"testdata/container_inspect_httpbin_0.json"},
+		"/containers/" + httpbinContainer1ID + "/json":  {"testdata/container_inspect_httpbin_1.json"},
+		"/info": {"testdata/info.json"},
+	}
+)
+
+func deployRoutesWithContainerLists(containerListFilePaths ...string) map[string][]string {
+	routeSequences := map[string][]string{"/containers/json": containerListFilePaths}
+	for path, jsonResponseFilePaths := range deployRoutes {
+		routeSequences[path] = jsonResponseFilePaths
+	}
+	return routeSequences
+}
+
+func deployHttpbin(ts *httptest.Server) *deployment {
+	manager := NewManagerWithTestServer(ts)
+	timeout := 5 * time.Second
+	svcCfg := anysched.SvcCfg{
+		ID:                    "httpbin",
+		Image:                 "citizenstig/httpbin",
+		Count:                 2,
+		DeployTimeoutDuration: &timeout,
+	}
+	op, err := manager.DeploySvc(svcCfg)
+	Expect(err).ToNot(HaveOccurred())
+	return op.(*deployment)
+}
+
+func requestPath(r *http.Request) string {
+	return r.Method + " " + apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+}
+
+var _ = Describe("docker/manager.go", func() {
+	Describe("NewManager", func() {
+		It("works", func() {
+			manager, err := NewManager("unix:///var/run/docker.sock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).ToNot(BeNil())
+		})
+
+		It("works if address is blank", func() {
+			manager, err := NewManager("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).ToNot(BeNil())
+		})
+
+		It("connects to the address that it is given", func() {
+			ts := NewTestServerJSONRoutes(dockerRoutes, nil)
+			defer ts.Close()
+			manager, err := NewManager(ts.URL)
+			Expect(err).ToNot(HaveOccurred())
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(2))
+		})
+
+		It("fails with an unsupported URL scheme", func() {
+			manager, err := NewManager("ftp://1.2.3.4:2375")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`unsupported URL scheme "ftp"`))
+			Expect(manager).To(BeNil())
+		})
+	})
+
+	Describe("Svcs", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("works", func() {
+			var containerFilters string
+			ts = NewTestServerJSONRoutes(dockerRoutes, func(r *http.Request) {
+				containerFilters = r.URL.Query().Get("filters")
+			})
+			manager = NewManagerWithTestServer(ts)
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerFilters).To(ContainSubstring(`"label":{"anysched.svc-id":true}`))
+			Expect(svcs).To(HaveLen(2))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+			Expect(*svcs[0].TasksRunning).To(Equal(2))
+			Expect(*svcs[0].TasksHealthy).To(Equal(1))
+			Expect(*svcs[0].TasksUnhealthy).To(Equal(1))
+			Expect((*svcs[0].CreationTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:01Z"))
+			Expect(svcs[0].Labels).To(Equal(map[string]string{"team": "payments"}))
+			Expect(svcs[1].ID).To(Equal("redis"))
+			Expect(svcs[1].Labels).To(BeNil())
+			Expect(*svcs[1].TasksRunning).To(Equal(0))
+			Expect(*svcs[1].TasksHealthy).To(Equal(0))
+			Expect(*svcs[1].TasksUnhealthy).To(Equal(0))
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			svcs, err := manager.Svcs()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ContainerList failed"))
+			Expect(svcs).To(BeNil())
+		})
+
+		It("filters services by label equality and existence, and matches the rest itself", func() {
+			var containerFilters string
+			ts = NewTestServerJSONRoutes(dockerRoutes, func(r *http.Request) {
+				containerFilters = r.URL.Query().Get("filters")
+			})
+			manager = NewManagerWithTestServer(ts)
+			selector, err := anysched.ParseSelector("team=payments,maintainer")
+			Expect(err).ToNot(HaveOccurred())
+			svcs, err := manager.(anysched.SvcsWithSelectorGetter).SvcsWithSelector(selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerFilters).To(ContainSubstring(`"team=payments":true`))
+			Expect(containerFilters).To(ContainSubstring(`"maintainer":true`))
+			// maintainer is a label of the image, not of the service
+			Expect(svcs).To(BeEmpty())
+
+			selector, err = anysched.ParseSelector("team in (payments,search)")
+			Expect(err).ToNot(HaveOccurred())
+			svcs, err = manager.(anysched.SvcsWithSelectorGetter).SvcsWithSelector(selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+		})
+	})
+
+	Describe("Tasks", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("works", func() {
+			ts = NewTestServerJSONRoutes(dockerRoutes, nil)
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.Tasks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Name).To(Equal("httpbin.0"))
+			Expect(tasks[0].AppID).To(Equal("httpbin"))
+			Expect(tasks[0].HostName).To(Equal("docker-host-1"))
+			Expect(tasks[0].TaskIP).To(Equal("172.17.0.2"))
+			Expect(tasks[0].IPAddresses).To(Equal([]string{"172.17.0.2"}))
+			Expect(tasks[0].Ports).To(Equal([]int{32768}))
+			Expect(tasks[0].State).To(Equal("running"))
+			Expect((*tasks[0].StageTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:01Z"))
+			Expect((*tasks[0].StartTime).Format(time.RFC3339Nano)).To(Equal("2018-07-25T18:49:02.5Z"))
+			Expect(tasks[1].Name).To(Equal("httpbin.1"))
+			Expect(tasks[1].TaskIP).To(Equal("172.17.0.3"))
+			Expect(tasks[1].Ports).To(Equal([]int{32769}))
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.Tasks()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ContainerList failed"))
+			Expect(tasks).To(BeNil())
+		})
+	})
+
+	Describe("SvcTasks", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("filters containers by service", func() {
+			var containerFilters string
+			ts = NewTestServerJSONRoutes(dockerRoutes, func(r *http.Request) {
+				if requestPath(r) == "GET /containers/json" {
+					containerFilters = r.URL.Query().Get("filters")
+				}
+			})
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerFilters).To(ContainSubstring(`"anysched.svc-id=httpbin":true`))
+			Expect(tasks).To(HaveLen(2))
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`mgr.tasks failed for svcCfg.ID = "httpbin"`))
+			Expect(tasks).To(BeNil())
+		})
+	})
+
+	Describe("DeploySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("creates and starts a container for each task", func() {
+			var (
+				requests       []string
+				createRequests []string
+			)
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"),
+				func(r *http.Request) {
+					requests = append(requests, requestPath(r))
+					if requestPath(r) == "POST /containers/create" {
+						body, _ := ioutil.ReadAll(r.Body)
+						createRequests = append(createRequests, r.URL.Query().Get("name")+" "+string(body))
+					}
+				})
+			dep := deployHttpbin(ts)
+			Expect(requests).To(Equal([]string{
+				"GET /containers/json",
+				"GET /images/citizenstig/httpbin/json",
+				"POST /containers/create",
+				"POST /containers/" + httpbinContainer0ID + "/start",
+				"POST /containers/create",
+				"POST /containers/" + httpbinContainer0ID + "/start",
+			}))
+			Expect(createRequests[0]).To(HavePrefix("httpbin.0 "))
+			Expect(createRequests[0]).To(ContainSubstring(`"anysched.task-index":"0"`))
+			Expect(createRequests[0]).To(ContainSubstring(`"unless-stopped"`))
+			Expect(createRequests[1]).To(HavePrefix("httpbin.1 "))
+			Expect(createRequests[1]).To(ContainSubstring(`"anysched.svc-id":"httpbin"`))
+			Expect(dep.GetProperties()).To(Equal(map[string]interface{}{"name": "httpbin", "count": 2}))
+			Expect(dep.String()).To(Equal(`<docker.deployment name="httpbin" count=2 />`))
+		})
+
+		It("sets the environment variables, command and arguments of the containers", func() {
+			var containerCreateBody string
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"),
+				func(r *http.Request) {
+					if requestPath(r) == "POST /containers/create" {
+						body, _ := ioutil.ReadAll(r.Body)
+						containerCreateBody = string(body)
+					}
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   1,
+				Env:     map[string]string{"PORT": "8000", "DEBUG": "1"},
+				Command: []string{"gunicorn"},
+				Args:    []string{"httpbin:app"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerCreateBody).To(ContainSubstring(`"Env":["DEBUG=1","PORT=8000"]`))
+			Expect(containerCreateBody).To(ContainSubstring(`"Cmd":["httpbin:app"]`))
+			Expect(containerCreateBody).To(ContainSubstring(`"Entrypoint":["gunicorn"]`))
+		})
+
+		It("puts the labels of the service on the containers", func() {
+			Expect(containerLabels(anysched.SvcCfg{
+				ID:     "httpbin",
+				Labels: map[string]string{"team": "payments", "tier": "web"},
+			}, 1)).To(Equal(map[string]string{
+				"anysched.svc-id":     "httpbin",
+				"anysched.task-index": "1",
+				"anysched.svc-labels": "team,tier",
+				"team":                "payments",
+				"tier":                "web",
+			}))
+			Expect(containerLabels(anysched.SvcCfg{ID: "httpbin"}, 0)).To(Equal(map[string]string{
+				"anysched.svc-id":     "httpbin",
+				"anysched.task-index": "0",
+			}))
+		})
+
+		It("exposes and publishes the ports of the containers", func() {
+			exposedPorts, portBindings := getPorts(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(exposedPorts).To(Equal(nat.PortSet{"8000/tcp": struct{}{}, "53/udp": struct{}{}}))
+			Expect(portBindings).To(Equal(nat.PortMap{
+				"8000/tcp": []nat.PortBinding{{HostPort: "80"}},
+				"53/udp":   []nat.PortBinding{{}},
+			}))
+		})
+
+		It("returns an error if more than one container would publish the same host port", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Ports: []anysched.PortCfg{{ContainerPort: 8000, HostPort: 80}},
+			})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin" cannot publish host port 80 for more than one container`))
+			Expect(op).To(BeNil())
+		})
+
+		It("mounts the volumes in the containers", func() {
+			var containerCreateBody string
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"),
+				func(r *http.Request) {
+					if requestPath(r) == "POST /containers/create" {
+						body, _ := ioutil.ReadAll(r.Body)
+						containerCreateBody = string(body)
+					}
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 1,
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeHostPath, Source: "/var/log", MountPath: "/logs", ReadOnly: true},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerCreateBody).To(ContainSubstring(
+				`"Mounts":[{"Type":"bind","Source":"/var/log","Target":"/logs","ReadOnly":true}]`))
+		})
+
+		It("returns an error for constraints", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:          "httpbin",
+				Image:       "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{{Operator: anysched.ConstraintUniquePerHost}},
+			})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Docker runs every task on one host, so it cannot place them by constraints`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for a namespace", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Namespace: "payments", Image: "citizenstig/httpbin"})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: validateSvcCfg failed: service "httpbin": ` +
+				`Docker has no namespaces`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for sidecars", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:       "httpbin",
+				Image:    "citizenstig/httpbin",
+				Sidecars: []anysched.ContainerCfg{{Name: "log-forwarder", Image: "fluent/fluent-bit:0.13"}},
+			})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: validateSvcCfg failed: service "httpbin": ` +
+				`Docker runs each task as one container, so it cannot run sidecars or init containers`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for secrets", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Docker has no secrets outside of Swarm, so it cannot expose them`))
+			Expect(op).To(BeNil())
+		})
+
+		It("limits the resources of the containers", func() {
+			resources := getResources(&anysched.Resources{CPU: 0.5, Memory: 256 << 20, MemoryLimit: 512 << 20})
+			Expect(resources.NanoCPUs).To(Equal(int64(500000000)))
+			Expect(resources.MemoryReservation).To(Equal(int64(256 << 20)))
+			Expect(resources.Memory).To(Equal(int64(512 << 20)))
+			Expect(getResources(nil)).To(Equal(container.Resources{}))
+		})
+
+		It("sets the health check of the containers", func() {
+			var containerCreateBody string
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"),
+				func(r *http.Request) {
+					if requestPath(r) == "POST /containers/create" {
+						body, _ := ioutil.ReadAll(r.Body)
+						containerCreateBody = string(body)
+					}
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Count:          1,
+				HealthCheck:    &anysched.HealthCheck{Command: []string{"pgrep", "gunicorn"}, Interval: time.Second},
+				ReadinessCheck: &anysched.HealthCheck{Command: []string{"pgrep", "gunicorn"}, Interval: time.Second},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containerCreateBody).To(ContainSubstring(`"Healthcheck":{"Test":["CMD","pgrep","gunicorn"]`))
+		})
+
+		It("returns an error if the readiness check is different from the health check", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Count:          1,
+				HealthCheck:    &anysched.HealthCheck{Command: []string{"pgrep", "gunicorn"}},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000},
+			})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: dockerhost.HealthConfig failed: service "httpbin": ` +
+				`Docker cannot have a readiness check that is different from the health check`))
+			Expect(op).To(BeNil())
+		})
+
+		It("pulls the image if it is not present", func() {
+			var requests []string
+			routeSequences := deployRoutesWithContainerLists("testdata/containers_list_empty.json")
+			delete(routeSequences, "/images/citizenstig/httpbin/json")
+			routeSequences["/images/create"] = []string{"testdata/image_pull.json"}
+			ts = NewTestServerJSONRouteSequences(routeSequences, func(r *http.Request) {
+				requests = append(requests, requestPath(r))
+			})
+			deployHttpbin(ts)
+			Expect(requests).To(ContainElement("POST /images/create"))
+		})
+
+		It("returns an error if the service already exists", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_httpbin.json"), nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(MatchError(`docker.manager.DeploySvc: service "httpbin" already exists`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if a container cannot be created", func() {
+			routeSequences := deployRoutesWithContainerLists("testdata/containers_list_empty.json")
+			delete(routeSequences, "/containers/create")
+			ts = NewTestServerJSONRouteSequences(routeSequences, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`mgr.client.ContainerCreate("httpbin.0") failed`))
+			Expect(op).To(BeNil())
+		})
+
+		It("removes the containers that it started if a later one cannot be started", func() {
+			var requests []string
+			routeSequences := deployRoutesWithContainerLists(
+				"testdata/containers_list_empty.json",
+				"testdata/containers_list_httpbin.json",
+			)
+			// The second container gets another ID, which cannot be started
+			routeSequences["/containers/create"] = []string{
+				"testdata/container_create.json",
+				"testdata/container_create_1.json",
+			}
+			routeSequences["/containers/"+httpbinContainer0ID] = []string{""}
+			routeSequences["/containers/"+httpbinContainer1ID] = []string{""}
+			ts = NewTestServerJSONRouteSequences(routeSequences, func(r *http.Request) {
+				requests = append(requests, requestPath(r))
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("docker.manager.DeploySvc: mgr.runContainer failed for task 1"))
+			Expect(op).To(BeNil())
+			Expect(requests[len(requests)-2:]).To(ConsistOf(
+				"DELETE /containers/"+httpbinContainer0ID,
+				"DELETE /containers/"+httpbinContainer1ID,
+			))
+		})
+	})
+
+	Context("a deployment that succeeds", func() {
+		var (
+			ts  *httptest.Server
+			dep *deployment
+		)
+
+		BeforeEach(func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists(
+				"testdata/containers_list_empty.json",
+				"testdata/containers_list_httpbin_starting.json",
+				"testdata/containers_list_httpbin.json",
+			), nil)
+			dep = deployHttpbin(ts)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		Describe("GetStatus", func() {
+			It("works", func() {
+				status, err := dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeFalse())
+				Expect(status.Msg).To(Equal(
+					`Waiting for service "httpbin" to start: 1 of 2 containers are running, 1 waiting for health checks...`))
+				Expect(status.LastUpdateTime.UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:04Z"))
+
+				status, err = dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeTrue())
+				Expect(status.Msg).To(Equal(`Service "httpbin" successfully deployed. 2 of 2 containers are running.`))
+			})
+		})
+
+		Describe("Wait", func() {
+			It("works", func() {
+				result, err := dep.Wait(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(dep))
+			})
+		})
+	})
+
+	Describe("containerUpdateTime", func() {
+		It("returns when the container last finished a health check", func() {
+			startedAt := time.Date(2018, 7, 25, 18, 49, 2, 0, time.UTC)
+			healthCheckEnd := startedAt.Add(30 * time.Second)
+			containerJSON := types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+				State: &types.ContainerState{
+					StartedAt: startedAt.Format(time.RFC3339Nano),
+					Health: &types.Health{Log: []*types.HealthcheckResult{
+						{Start: startedAt.Add(29 * time.Second), End: healthCheckEnd},
+					}},
+				},
+			}}
+			Expect(containerUpdateTime(containerJSON)).To(Equal(healthCheckEnd))
+			containerJSON.State.Health = nil
+			Expect(containerUpdateTime(containerJSON)).To(Equal(startedAt))
+		})
+	})
+
+	Describe("getStatusOfContainers", func() {
+		It("gives a deployment of no containers the current time once it is done", func() {
+			status, err := getStatusOfContainers(anysched.SvcCfg{ID: "httpbin"}, nil, time.Time{}, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.LastUpdateTime).To(BeTemporally("~", time.Now(), time.Second))
+		})
+	})
+
+	Context("a deployment that fails", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns an error if a container is unhealthy", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists(
+				"testdata/containers_list_empty.json",
+				"testdata/containers_list.json",
+			), nil)
+			dep := deployHttpbin(ts)
+			status, err := dep.GetStatus()
+			Expect(err).To(MatchError(
+				`container httpbin.1 of service "httpbin" is unhealthy: Up 2 minutes (unhealthy)`))
+			Expect(status).To(BeNil())
+			_, err = dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is unhealthy"))
+		})
+
+		It("times out if not enough containers start", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists(
+				"testdata/containers_list_empty.json",
+				"testdata/containers_list_httpbin_starting.json",
+			), nil)
+			dep := deployHttpbin(ts)
+			dep.timeoutDuration = 100 * time.Millisecond
+			_, err := dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out after 100ms"))
+		})
+
+		It("honors the context", func() {
+			ts = NewTestServerJSONRouteSequences(deployRoutesWithContainerLists("testdata/containers_list_empty.json"), nil)
+			dep := deployHttpbin(ts)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := dep.Wait(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("context canceled"))
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		updateRoutes := func(containerListFilePaths ...string) map[string][]string {
+			routeSequences := deployRoutesWithContainerLists(containerListFilePaths...)
+			routeSequences["/containers/"+httpbinContainer0ID] = []string{""}
+			routeSequences["/containers/"+httpbinContainer1ID] = []string{""}
+			return routeSequences
+		}
+
+		It("replaces the container of each task once the one before it is ready", func() {
+			var (
+				requests       []string
+				createRequests []string
+			)
+			ts = NewTestServerJSONRouteSequences(updateRoutes(
+				"testdata/containers_list_httpbin.json",
+				"testdata/containers_list_httpbin_0_starting.json",
+				"testdata/containers_list_httpbin.json",
+				"testdata/containers_list_httpbin_starting.json",
+				"testdata/containers_list_httpbin.json",
+			), func(r *http.Request) {
+				if r.Method != "GET" {
+					requests = append(requests, requestPath(r))
+				}
+				if requestPath(r) == "POST /containers/create" {
+					body, _ := ioutil.ReadAll(r.Body)
+					createRequests = append(createRequests, r.URL.Query().Get("name")+" "+string(body))
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Env:   map[string]string{"VERSION": "2"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{
+				"DELETE /containers/" + httpbinContainer0ID,
+				"POST /containers/create",
+				"POST /containers/" + httpbinContainer0ID + "/start",
+			}))
+			Expect(createRequests).To(HaveLen(1))
+			Expect(createRequests[0]).To(HavePrefix("httpbin.0 "))
+			Expect(createRequests[0]).To(ContainSubstring(`"VERSION=2"`))
+
+			// The new container of the first task is waiting for its health checks
+			requests = nil
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to update: ` +
+				`0 of 2 containers are running the new version, 1 waiting to be replaced...`))
+			Expect(requests).To(BeEmpty())
+
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(
+				`Waiting for service "httpbin" to update: 1 of 2 containers are running, 1 waiting for health checks...`))
+			Expect(requests).To(Equal([]string{
+				"DELETE /containers/" + httpbinContainer1ID,
+				"POST /containers/create",
+				"POST /containers/" + httpbinContainer0ID + "/start",
+			}))
+			Expect(createRequests[1]).To(HavePrefix("httpbin.1 "))
+
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "httpbin" successfully updated. 2 of 2 containers are running.`))
+		})
+
+		It("removes the containers of tasks that are no longer needed", func() {
+			var requests []string
+			ts = NewTestServerJSONRouteSequences(updateRoutes(
+				"testdata/containers_list_httpbin.json",
+				"testdata/containers_list_httpbin.json",
+			), func(r *http.Request) {
+				requests = append(requests, requestPath(r))
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).ToNot(ContainElement("DELETE /containers/" + httpbinContainer1ID))
+
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(ContainElement("DELETE /containers/" + httpbinContainer1ID))
+			Expect(status.Done).To(BeTrue())
+		})
+
+		It("stops replacing containers once a new one is unhealthy", func() {
+			creates := 0
+			ts = NewTestServerJSONRouteSequences(updateRoutes(
+				"testdata/containers_list_httpbin.json",
+				"testdata/containers_list_httpbin.json",
+				"testdata/containers_list_httpbin_unhealthy.json",
+			), func(r *http.Request) {
+				if requestPath(r) == "POST /containers/create" {
+					creates++
+				}
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creates).To(Equal(1))
+
+			// The new container of the second task is unhealthy, so the third is never started
+			for i := 0; i < 2; i++ {
+				status, err := op.GetStatus()
+				Expect(err).To(MatchError(ContainSubstring(`container httpbin.1 of service "httpbin" is unhealthy`)))
+				Expect(status).To(BeNil())
+				Expect(creates).To(Equal(2))
+			}
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/containers/json": "testdata/containers_list_empty.json"}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(MatchError(`docker.manager.UpdateSvc: service "httpbin" does not exist`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		scaleRoutes := func() map[string][]string {
+			routeSequences := deployRoutesWithContainerLists("testdata/containers_list_httpbin.json")
+			routeSequences["/containers/"+httpbinContainer1ID] = []string{""}
+			return routeSequences
+		}
+
+		It("runs copies of the container of the first task", func() {
+			var createRequest string
+			ts = NewTestServerJSONRouteSequences(scaleRoutes(), func(r *http.Request) {
+				requests = append(requests, requestPath(r))
+				if requestPath(r) == "POST /containers/create" {
+					body, _ := ioutil.ReadAll(r.Body)
+					createRequest = r.URL.Query().Get("name") + " " + string(body)
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{
+				"GET /containers/json",
+				"GET /containers/" + httpbinContainer0ID + "/json",
+				"POST /containers/create",
+				"POST /containers/" + httpbinContainer0ID + "/start",
+			}))
+			Expect(createRequest).To(HavePrefix("httpbin.2 "))
+			Expect(createRequest).To(ContainSubstring(`"anysched.task-index":"2"`))
+
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(HavePrefix(`Waiting for service "httpbin" to start: 2 of 3 containers are running`))
+		})
+
+		It("removes the containers of the last tasks", func() {
+			ts = NewTestServerJSONRouteSequences(scaleRoutes(), func(r *http.Request) {
+				requests = append(requests, requestPath(r))
+			})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{
+				"GET /containers/json",
+				"GET /containers/" + httpbinContainer0ID + "/json",
+				"DELETE /containers/" + httpbinContainer1ID,
+			}))
+		})
+
+		It("returns an error for a count of 0", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 0)
+			Expect(err).To(MatchError(`docker.manager.ScaleSvc: service "httpbin" cannot be scaled to 0 containers`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/containers/json": "testdata/containers_list_empty.json"}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).To(MatchError(`docker.manager.ScaleSvc: service "httpbin" does not exist`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("DiffSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the settings that differ from the containers", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/containers/json": "testdata/containers_list_httpbin.json",
+				"/containers/" + httpbinContainer0ID + "/json": "testdata/container_inspect_httpbin_0.json",
+				"/images/citizenstig/httpbin/json":             "testdata/image_inspect.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Ports: []anysched.PortCfg{{ContainerPort: 8000}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(BeEmpty())
+			svcDiff, err = manager.DiffSvc(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Ports:     []anysched.PortCfg{{ContainerPort: 8000, HostPort: 8000}},
+				Resources: &anysched.Resources{CPU: 0.5},
+				Volumes:   []anysched.VolumeCfg{{Type: anysched.VolumeScratch, MountPath: "/tmp"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(Equal([]string{"count", "ports", "resources"}))
+			Expect(svcDiff.Unknown).To(Equal([]string{"volumes"}))
+		})
+
+		It("returns nil if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/containers/json": "testdata/containers_list_empty.json"}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff).To(BeNil())
+		})
+	})
+
+	Describe("Svc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the configuration of the container of the first task", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/containers/json": "testdata/containers_list_httpbin.json",
+				"/containers/" + httpbinContainer0ID + "/json": "testdata/container_inspect_httpbin_0.json",
+				"/images/citizenstig/httpbin/json":             "testdata/image_inspect.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(2))
+			Expect(svcDetail.SvcCfg.ID).To(Equal("httpbin"))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("citizenstig/httpbin"))
+			Expect(svcDetail.SvcCfg.Count).To(Equal(2))
+			Expect(svcDetail.SvcCfg.Ports).To(Equal([]anysched.PortCfg{{ContainerPort: 8000, Protocol: "tcp"}}))
+			Expect(svcDetail.Revision).To(BeNil())
+			Expect(svcDetail.Native).To(BeAssignableToTypeOf(&types.ContainerJSON{}))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/containers/json": "testdata/containers_list_empty.json"}, nil)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).To(MatchError(`docker.manager.Svc: service "httpbin" does not exist`))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("portCfgsFromPortBindings", func() {
+		It("reads back what getPorts returns", func() {
+			_, portBindings := getPorts(anysched.SvcCfg{
+				Ports: []anysched.PortCfg{{ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(portCfgsFromPortBindings(portBindings)).To(Equal([]anysched.PortCfg{
+				{ContainerPort: 53, Protocol: "udp"},
+				{ContainerPort: 8000, HostPort: 80, Protocol: "tcp"},
+			}))
+		})
+	})
+
+	Describe("containerEnv", func() {
+		It("leaves out the environment variables of the image, unless the service sets them", func() {
+			imageConfig := &container.Config{Env: []string{"PATH=/usr/bin", "LANG=C.UTF-8", "PORT=80"}}
+			env := containerEnv([]string{"PATH=/usr/bin", "LANG=C.UTF-8", "PORT=8000", "DEBUG=1"}, imageConfig,
+				map[string]string{"LANG": "C.UTF-8"})
+			Expect(env).To(Equal(map[string]string{"LANG": "C.UTF-8", "PORT": "8000", "DEBUG": "1"}))
+		})
+	})
+
+	Describe("DestroySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("removes the containers of the service", func() {
+			var removed []string
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/containers/json":                   "testdata/containers_list_httpbin.json",
+				"/containers/" + httpbinContainer0ID: "",
+				"/containers/" + httpbinContainer1ID: "",
+			}, func(r *http.Request) {
+				if r.Method == "DELETE" {
+					removed = append(removed, requestPath(r)+"?"+r.URL.RawQuery)
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(removed).To(Equal([]string{
+				"DELETE /containers/" + httpbinContainer0ID + "?force=1",
+				"DELETE /containers/" + httpbinContainer1ID + "?force=1",
+			}))
+			// The test server keeps listing the containers
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to be removed: 2 containers are left...`))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/containers/json": "testdata/containers_list_empty.json"}, nil)
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DestroySvc("httpbin")
+			Expect(err).To(MatchError(`docker.manager.DestroySvc: service "httpbin" does not exist`))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// removal implements the anysched.Operation interface for the removal of the
+// containers of a service. It is done once none of them are left.
+type removal struct {
+	manager         *manager
+	svcID           string
+	timeoutDuration time.Duration
+}
+
+func (rem *removal) String() string {
+	return fmt.Sprintf("<docker.removal name=%q />", rem.svcID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (rem *removal) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = rem.svcID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the removal
+func (rem *removal) GetStatus() (*anysched.OperationStatus, error) {
+	containers, err := rem.manager.containers(svcFilters(rem.svcID))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker.removal.GetStatus: manager.containers failed")
+	}
+	if len(containers) > 0 {
+		msg := fmt.Sprintf("Waiting for service %q to be removed: %d containers are left...",
+			rem.svcID, len(containers))
+		return status(msg, false, time.Time{}), nil
+	}
+	return status(fmt.Sprintf("Service %q successfully removed.", rem.svcID), true, time.Time{}), nil
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (rem *removal) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, rem.timeoutDuration)
+	defer cancel()
+
+	for {
+		status, err := rem.GetStatus()
+		if err != nil {
+			return nil, errors.Wrap(err, "docker.removal.Wait: GetStatus failed")
+		}
+		if status.Done {
+			return rem, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "docker.removal.Wait: Timed out after %s", rem.timeoutDuration)
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// rollout replaces the containers of a service with ones that run a new
+// SvcCfg, one task index at a time, for the deployment of an update. The
+// container of an index is only replaced once the new container of the index
+// before it is running and has passed its health checks, so a new version that
+// doesn't come up stops the rollout with the old containers of the remaining
+// indexes still running. Containers have fixed names, so each old container is
+// removed before its replacement is started.
+type rollout struct {
+	mutex         sync.Mutex
+	healthConfig  *container.HealthConfig
+	oldContainers map[int]types.Container // by task index
+	// next is the next index to replace, and end is one more than the last
+	// index that has a container, or that is below the count of the service
+	next, end int
+	// err is the error that stopped the rollout, if any
+	err error
+}
+
+func newRollout(svcCfg anysched.SvcCfg, healthConfig *container.HealthConfig, oldContainers []types.Container) *rollout {
+	r := &rollout{
+		healthConfig:  healthConfig,
+		oldContainers: map[int]types.Container{},
+		end:           svcCfg.Count,
+	}
+	for _, c := range oldContainers {
+		index := taskIndex(c)
+		r.oldContainers[index] = c
+		if index >= r.end {
+			r.end = index + 1
+		}
+	}
+	return r
+}
+
+// advance replaces the container of the next index, if the container of the
+// index before it is ready in containers, the current containers of the
+// service. Indexes that are no longer below the count of the service have no
+// replacement to wait for, so their containers are all removed at once. It
+// returns whether it changed any containers.
+func (r *rollout) advance(mgr *manager, svcCfg anysched.SvcCfg, containers []types.Container) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return false, r.err
+	}
+	if r.next >= r.end {
+		return false, nil
+	}
+	if r.next > 0 && r.next-1 < svcCfg.Count && !containerIsReady(containerWithIndex(containers, r.next-1)) {
+		return false, nil
+	}
+	for r.next < r.end {
+		index := r.next
+		r.next++
+		if c, ok := r.oldContainers[index]; ok {
+			err := mgr.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true})
+			if err != nil {
+				r.err = errors.Wrapf(err, "mgr.client.ContainerRemove(%q) failed", c.ID)
+				return true, r.err
+			}
+		}
+		if index < svcCfg.Count {
+			if err := mgr.runContainer(svcCfg, r.healthConfig, index); err != nil {
+				r.err = errors.Wrapf(err, "mgr.runContainer failed for task %d", index)
+				return true, r.err
+			}
+			break
+		}
+	}
+	return true, nil
+}
+
+// isDone returns whether every index has been replaced.
+func (r *rollout) isDone() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.next >= r.end
+}
+
+// progress returns how many of containers, the current containers of svcCfg,
+// are new ones that are ready, and how many old containers are still waiting
+// to be replaced.
+func (r *rollout) progress(svcCfg anysched.SvcCfg, containers []types.Container) (updated, waiting int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, c := range containers {
+		if index := taskIndex(c); index < r.next && index < svcCfg.Count && containerIsReady(&c) {
+			updated++
+		}
+	}
+	for index := range r.oldContainers {
+		if index >= r.next {
+			waiting++
+		}
+	}
+	return updated, waiting
+}
+
+// containerWithIndex returns the container of the task with index, or nil if
+// there is none.
+func containerWithIndex(containers []types.Container, index int) *types.Container {
+	for i := range containers {
+		if taskIndex(containers[i]) == index {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// containerIsReady returns whether c is running and not waiting for, or
+// failing, its health checks.
+func containerIsReady(c *types.Container) bool {
+	return c != nil && c.State == "running" && !containerHealthIsStarting(*c) && !containerIsUnhealthy(*c)
+}
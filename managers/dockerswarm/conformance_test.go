@@ -0,0 +1,236 @@
+package dockerswarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+var _ = conformance.DescribeManager("dockerswarm", func() anysched.Manager {
+	pollInterval = 10 * time.Millisecond
+	return NewManagerWithTestServer(newSwarmStandIn())
+})
+
+// swarmStandIn is a stand-in for the Docker Engine API of a swarm manager with
+// one node, for the conformance suite. It keeps the services that it is asked
+// to create, and runs their tasks right away. An update that changes the task
+// template of a service replaces all of its tasks and completes at once.
+type swarmStandIn struct {
+	mutex    sync.Mutex
+	services map[string]*swarm.Service
+	tasks    []*swarm.Task
+	lastID   int
+}
+
+func newSwarmStandIn() *httptest.Server {
+	return httptest.NewServer(&swarmStandIn{services: map[string]*swarm.Service{}})
+}
+
+func (standIn *swarmStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	path := apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+	switch {
+	case path == "/nodes":
+		writeJSON(w, []swarm.Node{{
+			ID:          "conformance-node",
+			Description: swarm.NodeDescription{Hostname: "conformance-host"},
+			Status:      swarm.NodeStatus{Addr: "10.0.0.1"},
+		}})
+	case path == "/services":
+		standIn.listServices(w, r)
+	case path == "/services/create":
+		standIn.createService(w, r)
+	case path == "/tasks":
+		standIn.listTasks(w, r)
+	case strings.HasPrefix(path, "/services/"):
+		standIn.serveService(w, r, strings.Split(strings.TrimPrefix(path, "/services/"), "/"))
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (standIn *swarmStandIn) listServices(w http.ResponseWriter, r *http.Request) {
+	serviceFilters := parseFilters(r)
+	services := []swarm.Service{}
+	for _, service := range standIn.services {
+		if serviceFilters.MatchKVList("label", service.Spec.Labels) {
+			services = append(services, *service)
+		}
+	}
+	writeJSON(w, services)
+}
+
+func (standIn *swarmStandIn) listTasks(w http.ResponseWriter, r *http.Request) {
+	taskFilters := parseFilters(r)
+	tasks := []swarm.Task{}
+	for _, task := range standIn.tasks {
+		if taskFilters.Include("desired-state") && !taskFilters.ExactMatch("desired-state", string(task.DesiredState)) {
+			continue
+		}
+		if taskFilters.Include("service") {
+			service := standIn.services[task.ServiceID]
+			if !taskFilters.ExactMatch("service", task.ServiceID) &&
+				(service == nil || !taskFilters.ExactMatch("service", service.Spec.Name)) {
+				continue
+			}
+		}
+		tasks = append(tasks, *task)
+	}
+	writeJSON(w, tasks)
+}
+
+func (standIn *swarmStandIn) createService(w http.ResponseWriter, r *http.Request) {
+	var spec swarm.ServiceSpec
+	Expect(json.NewDecoder(r.Body).Decode(&spec)).To(Succeed())
+	if standIn.service(spec.Name) != nil {
+		writeError(w, 409, fmt.Sprintf("service %s already exists", spec.Name))
+		return
+	}
+	now := time.Now()
+	service := &swarm.Service{ID: standIn.newID(), Spec: spec}
+	service.Version.Index = 1
+	service.CreatedAt, service.UpdatedAt = now, now
+	standIn.services[service.ID] = service
+	standIn.scheduleTasks(service)
+	writeJSON(w, types.ServiceCreateResponse{ID: service.ID})
+}
+
+func (standIn *swarmStandIn) serveService(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	service := standIn.service(pathParts[0])
+	switch {
+	case service == nil:
+		writeError(w, 404, fmt.Sprintf("service %s not found", pathParts[0]))
+	case r.Method == "GET" && len(pathParts) == 1:
+		writeJSON(w, service)
+	case r.Method == "DELETE" && len(pathParts) == 1:
+		delete(standIn.services, service.ID)
+		standIn.shutDownTasks(service.ID)
+		w.WriteHeader(200)
+	case r.Method == "POST" && len(pathParts) == 2 && pathParts[1] == "update":
+		standIn.updateService(w, r, service)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (standIn *swarmStandIn) updateService(w http.ResponseWriter, r *http.Request, service *swarm.Service) {
+	if r.URL.Query().Get("version") != strconv.FormatUint(service.Version.Index, 10) {
+		writeError(w, 500, "update out of sequence")
+		return
+	}
+	var spec swarm.ServiceSpec
+	Expect(json.NewDecoder(r.Body).Decode(&spec)).To(Succeed())
+	now := time.Now()
+	previousSpec := service.Spec
+	service.PreviousSpec, service.Spec = &previousSpec, spec
+	service.Version.Index++
+	service.UpdatedAt = now
+	if !reflect.DeepEqual(previousSpec.TaskTemplate, spec.TaskTemplate) {
+		standIn.shutDownTasks(service.ID)
+		service.UpdateStatus = &swarm.UpdateStatus{
+			State:       swarm.UpdateStateCompleted,
+			StartedAt:   &now,
+			CompletedAt: &now,
+			Message:     "update completed",
+		}
+	}
+	standIn.scheduleTasks(service)
+	writeJSON(w, types.ServiceUpdateResponse{})
+}
+
+// scheduleTasks starts a running task for each slot of a service that doesn't
+// have one, and shuts down the tasks of slots above its replicas.
+func (standIn *swarmStandIn) scheduleTasks(service *swarm.Service) {
+	replicas := int(*service.Spec.Mode.Replicated.Replicas)
+	slots := map[int]bool{}
+	for _, task := range standIn.tasks {
+		if task.ServiceID != service.ID || task.DesiredState != swarm.TaskStateRunning {
+			continue
+		}
+		if task.Slot > replicas {
+			shutDownTask(task)
+		} else {
+			slots[task.Slot] = true
+		}
+	}
+	for slot := 1; slot <= replicas; slot++ {
+		if slots[slot] {
+			continue
+		}
+		task := &swarm.Task{
+			ID:           standIn.newID(),
+			Spec:         service.Spec.TaskTemplate,
+			ServiceID:    service.ID,
+			Slot:         slot,
+			NodeID:       "conformance-node",
+			Status:       swarm.TaskStatus{Timestamp: time.Now(), State: swarm.TaskStateRunning},
+			DesiredState: swarm.TaskStateRunning,
+		}
+		task.Version.Index = service.Version.Index
+		task.CreatedAt, task.UpdatedAt = task.Status.Timestamp, task.Status.Timestamp
+		standIn.tasks = append(standIn.tasks, task)
+	}
+}
+
+func (standIn *swarmStandIn) shutDownTasks(serviceID string) {
+	for _, task := range standIn.tasks {
+		if task.ServiceID == serviceID {
+			shutDownTask(task)
+		}
+	}
+}
+
+func shutDownTask(task *swarm.Task) {
+	task.DesiredState = swarm.TaskStateShutdown
+	task.Status = swarm.TaskStatus{Timestamp: time.Now(), State: swarm.TaskStateShutdown}
+}
+
+// service returns the service with the ID or name idOrName, or nil if there
+// isn't one.
+func (standIn *swarmStandIn) service(idOrName string) *swarm.Service {
+	for _, service := range standIn.services {
+		if service.ID == idOrName || service.Spec.Name == idOrName {
+			return service
+		}
+	}
+	return nil
+}
+
+func (standIn *swarmStandIn) newID() string {
+	standIn.lastID++
+	return fmt.Sprintf("%025d", standIn.lastID)
+}
+
+func parseFilters(r *http.Request) filters.Args {
+	args, err := filters.FromParam(r.URL.Query().Get("filters"))
+	Expect(err).ToNot(HaveOccurred())
+	return args
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	Expect(json.NewEncoder(w).Encode(types.ErrorResponse{Message: message})).To(Succeed())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	bytes, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	writeJSONResponseBytes(w, bytes)
+}
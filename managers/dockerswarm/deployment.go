@@ -0,0 +1,198 @@
+package dockerswarm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// Swarm service update states that mean that an update did not converge
+const (
+	updateStatePaused            swarm.UpdateState = "paused"
+	updateStateRollbackStarted   swarm.UpdateState = "rollback_started"
+	updateStateRollbackPaused    swarm.UpdateState = "rollback_paused"
+	updateStateRollbackCompleted swarm.UpdateState = "rollback_completed"
+)
+
+var (
+	// pollInterval is how often Wait checks the status of a deployment.
+	pollInterval = 2 * time.Second
+
+	getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
+		if svcCfg.DeployTimeoutDuration == nil {
+			return 60 * time.Second
+		}
+		return *svcCfg.DeployTimeoutDuration
+	}
+)
+
+// deployment implements the anysched.Operation interface for a swarm service.
+// It is done once the desired number of tasks of the service are running and
+// any update of the service has completed.
+type deployment struct {
+	manager         *manager
+	serviceID       string
+	svcCfg          anysched.SvcCfg
+	timeoutDuration time.Duration
+
+	// For an update, oldUpdateStatus is the update status of the service
+	// before it, so that the update isn't done before swarm has started it.
+	update          bool
+	oldUpdateStatus *swarm.UpdateStatus
+}
+
+func (dep *deployment) String() string {
+	return fmt.Sprintf("<dockerswarm.deployment name=%q serviceID=%q />", dep.svcCfg.ID, dep.serviceID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dep *deployment) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = dep.svcCfg.ID
+	propertiesMap["serviceID"] = dep.serviceID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deployment
+func (dep *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
+	swarmService, _, err := dep.manager.client.ServiceInspectWithRaw(ctx, dep.serviceID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"dockerswarm.deployment.GetStatus: client.ServiceInspectWithRaw(%q) failed", dep.serviceID)
+	}
+	taskFilters := runningTasksFilters()
+	taskFilters.Add("service", dep.serviceID)
+	swarmTasks, err := dep.manager.client.TaskList(ctx, types.TaskListOptions{Filters: taskFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.deployment.GetStatus: client.TaskList failed")
+	}
+	if dep.update && !updateStarted(swarmService, dep.oldUpdateStatus) {
+		msg := "Waiting for the update to start..."
+		return notDoneStatus(swarmService, msg, mostRecentUpdateTime(swarmService, swarmTasks)), nil
+	}
+	return getStatusOfSwarmService(swarmService, swarmTasks)
+}
+
+func getStatusOfSwarmService(swarmService swarm.Service, swarmTasks []swarm.Task) (*anysched.OperationStatus, error) {
+	if updateDidNotConverge(swarmService) {
+		return nil, updateDidNotConvergeError(swarmService)
+	}
+	running := 0
+	for _, swarmTask := range swarmTasks {
+		if swarmTask.Status.State == swarm.TaskStateRunning {
+			running++
+		}
+	}
+	desired := desiredTasks(swarmService, swarmTasks)
+	lastUpdateTime := mostRecentUpdateTime(swarmService, swarmTasks)
+	if running < desired {
+		msg := fmt.Sprintf("%d of %d tasks are running...", running, desired)
+		return notDoneStatus(swarmService, msg, lastUpdateTime), nil
+	}
+	if updateInProgress(swarmService) {
+		msg := fmt.Sprintf("Update in progress: %s", swarmService.UpdateStatus.Message)
+		return notDoneStatus(swarmService, msg, lastUpdateTime), nil
+	}
+	msg := fmt.Sprintf("Service %q successfully converged. %d of %d tasks are running.",
+		swarmService.Spec.Name, running, desired)
+	return status(msg, true, lastUpdateTime), nil
+}
+
+// desiredTasks returns the number of replicas of a replicated service, or the
+// number of tasks that swarm wants to run for a global service.
+func desiredTasks(swarmService swarm.Service, swarmTasks []swarm.Task) int {
+	replicated := swarmService.Spec.Mode.Replicated
+	if replicated != nil && replicated.Replicas != nil {
+		return int(*replicated.Replicas)
+	}
+	return len(swarmTasks)
+}
+
+// updateStarted returns true if swarm has started to roll out an update of
+// swarmService, whose update status was oldUpdateStatus before the update, or
+// if there is nothing to roll out, because the update didn't change the task
+// template (e.g.: it only changed the number of replicas).
+func updateStarted(swarmService swarm.Service, oldUpdateStatus *swarm.UpdateStatus) bool {
+	previousSpec := swarmService.PreviousSpec
+	if previousSpec != nil && reflect.DeepEqual(previousSpec.TaskTemplate, swarmService.Spec.TaskTemplate) {
+		return true
+	}
+	return !reflect.DeepEqual(swarmService.UpdateStatus, oldUpdateStatus)
+}
+
+func updateInProgress(swarmService swarm.Service) bool {
+	return swarmService.UpdateStatus != nil && swarmService.UpdateStatus.State == swarm.UpdateStateUpdating
+}
+
+func updateDidNotConverge(swarmService swarm.Service) bool {
+	if swarmService.UpdateStatus == nil {
+		return false
+	}
+	switch swarmService.UpdateStatus.State {
+	case updateStatePaused, updateStateRollbackStarted, updateStateRollbackPaused, updateStateRollbackCompleted:
+		return true
+	}
+	return false
+}
+
+func updateDidNotConvergeError(swarmService swarm.Service) error {
+	return fmt.Errorf("update of service %q did not converge (%s): %s",
+		swarmService.Spec.Name, swarmService.UpdateStatus.State, swarmService.UpdateStatus.Message)
+}
+
+// mostRecentUpdateTime returns the most recent time that the service or any of
+// its tasks changed.
+func mostRecentUpdateTime(swarmService swarm.Service, swarmTasks []swarm.Task) time.Time {
+	lastUpdateTime := swarmService.UpdatedAt
+	for _, swarmTask := range swarmTasks {
+		if swarmTask.Status.Timestamp.After(lastUpdateTime) {
+			lastUpdateTime = swarmTask.Status.Timestamp
+		}
+	}
+	return lastUpdateTime
+}
+
+func notDoneStatus(swarmService swarm.Service, msg string, lastUpdateTime time.Time) *anysched.OperationStatus {
+	msg = fmt.Sprintf("Waiting for service %q to converge: %s", swarmService.Spec.Name, msg)
+	return status(msg, false, lastUpdateTime)
+}
+
+func status(msg string, done bool, lastUpdateTime time.Time) *anysched.OperationStatus {
+	return &anysched.OperationStatus{
+		ClientTime:         time.Now(),
+		LastTransitionTime: lastUpdateTime,
+		LastUpdateTime:     lastUpdateTime,
+		Msg:                msg,
+		Done:               done,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (dep *deployment) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, dep.timeoutDuration)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "dockerswarm.deployment.Wait: Timed out after %s", dep.timeoutDuration)
+		case <-time.After(pollInterval):
+			status, err := dep.GetStatus()
+			if err != nil {
+				return nil, errors.Wrap(err, "dockerswarm.deployment.Wait: GetStatus failed")
+			}
+			if status.Done {
+				return dep, nil
+			}
+		}
+	}
+}
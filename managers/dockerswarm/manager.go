@@ -3,18 +3,34 @@ package dockerswarm
 import (
 	"context"
 	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/swarm"
 	dockerclient "github.com/docker/docker/client"
 
 	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/managers/internal/dockerhost"
 )
 
 var ctx = context.TODO()
 
+// secretLabel is the label of the Swarm secrets that CreateSecret creates, with
+// the name of the anysched.Secret that they hold a value of.
+const secretLabel = "anysched.secret"
+
+// secretsDir is where Swarm mounts secrets in containers. The Swarm API that
+// this manager uses can't mount them anywhere else.
+const secretsDir = "/run/secrets"
+
 type manager struct {
 	client *dockerclient.Client
 	url    string
@@ -25,35 +41,417 @@ func init() {
 }
 
 // NewManager returns a Manager for Docker Swarm.
+//
+// url is the address of the Docker Engine API of a swarm manager node, e.g.:
+// "unix:///var/run/docker.sock", "tcp://127.0.0.1:2375" or
+// "https://127.0.0.1:2376". The certificate of an "https://" address is verified
+// unless the address ends with "?tlsverify=false". If url is blank, the client
+// is configured from the DOCKER_HOST, DOCKER_API_VERSION, DOCKER_CERT_PATH and
+// DOCKER_TLS_VERIFY environment variables, just like the docker CLI.
 func NewManager(url string) (anysched.Manager, error) {
-	client, err := dockerclient.NewEnvClient()
+	client, err := dockerhost.NewClient(url)
 	if err != nil {
-		return nil, errors.Wrap(err, "dockerswarm.NewManager: dockerclient.NewEnvClient failed")
+		return nil, errors.Wrap(err, "dockerswarm.NewManager: creating Docker client failed")
 	}
 	return &manager{client: client, url: url}, nil
 }
 
 // Svcs returns info about all running services.
 func (mgr *manager) Svcs() ([]anysched.Svc, error) {
-	return nil, errors.New("dockerswarm.manager.Svcs: Not implemented")
+	svcs, err := mgr.svcs(filters.NewArgs())
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.Svcs: mgr.svcs failed")
+	}
+	return svcs, nil
+}
+
+// SvcsWithSelector returns info about the running services whose labels match
+// selector. Swarm can only filter services by label equality and existence,
+// so it does that for those requirements, and the rest are matched here.
+func (mgr *manager) SvcsWithSelector(selector anysched.Selector) ([]anysched.Svc, error) {
+	serviceFilters := filters.NewArgs()
+	for _, requirement := range selector {
+		switch requirement.Operator {
+		case anysched.SelectorOpEquals:
+			serviceFilters.Add("label", requirement.Key+"="+requirement.Values[0])
+		case anysched.SelectorOpExists:
+			serviceFilters.Add("label", requirement.Key)
+		}
+	}
+	svcs, err := mgr.svcs(serviceFilters)
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.SvcsWithSelector: mgr.svcs failed")
+	}
+	return anysched.FilterSvcs(svcs, selector), nil
+}
+
+// svcs returns info about the running services that match serviceFilters.
+func (mgr *manager) svcs(serviceFilters filters.Args) ([]anysched.Svc, error) {
+	swarmServices, err := mgr.client.ServiceList(ctx, types.ServiceListOptions{Filters: serviceFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.svcs: mgr.client.ServiceList failed")
+	}
+	swarmTasks, err := mgr.client.TaskList(ctx, types.TaskListOptions{Filters: runningTasksFilters()})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.svcs: mgr.client.TaskList failed")
+	}
+	tasksRunning := tasksRunningByServiceID(swarmTasks)
+	svcs := make([]anysched.Svc, len(swarmServices))
+	for i, swarmService := range swarmServices {
+		svcs[i] = svcFromSwarmService(swarmService, tasksRunning[swarmService.ID])
+	}
+	return svcs, nil
+}
+
+// tasksRunningByServiceID returns the number of running tasks in swarmTasks for
+// each service ID.
+func tasksRunningByServiceID(swarmTasks []swarm.Task) map[string]int {
+	tasksRunningByServiceID := map[string]int{}
+	for _, swarmTask := range swarmTasks {
+		if swarmTask.Status.State == swarm.TaskStateRunning {
+			tasksRunningByServiceID[swarmTask.ServiceID]++
+		}
+	}
+	return tasksRunningByServiceID
+}
+
+func svcFromSwarmService(swarmService swarm.Service, tasksRunning int) anysched.Svc {
+	creationTime := swarmService.CreatedAt
+	return anysched.Svc{
+		ID:           swarmService.Spec.Name,
+		TasksRunning: &tasksRunning,
+		CreationTime: &creationTime,
+		Labels:       swarmService.Spec.Labels,
+	}
+}
+
+// Svc returns a service with the SvcCfg of the spec of its swarm service, and
+// the ports that Swarm published for it. Revision is 2 if Swarm kept the
+// previous spec, as in SvcHistory, and Native is the *swarm.Service.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	service, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.Svc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	taskFilters := runningTasksFilters()
+	taskFilters.Add("service", service.ID)
+	swarmTasks, err := mgr.client.TaskList(ctx, types.TaskListOptions{Filters: taskFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.Svc: mgr.client.TaskList failed")
+	}
+	svcCfg := svcCfgFromServiceSpec(service.Spec)
+	setPublishedPorts(svcCfg.Ports, service.Endpoint.Ports)
+	revision := int64(1)
+	if service.PreviousSpec != nil {
+		revision = 2
+	}
+	return &anysched.SvcDetail{
+		Svc:      svcFromSwarmService(service, tasksRunningByServiceID(swarmTasks)[service.ID]),
+		SvcCfg:   svcCfg,
+		Revision: &revision,
+		Native:   &service,
+	}, nil
+}
+
+// setPublishedPorts sets the HostPort of each of portCfgs that doesn't have one
+// to the port that Swarm published it on.
+func setPublishedPorts(portCfgs []anysched.PortCfg, endpointPorts []swarm.PortConfig) {
+	for i := range portCfgs {
+		for _, endpointPort := range endpointPorts {
+			if portCfgs[i].HostPort == 0 && int(endpointPort.TargetPort) == portCfgs[i].ContainerPort &&
+				string(endpointPort.Protocol) == portCfgs[i].ProtocolOrDefault() {
+				portCfgs[i].HostPort = int(endpointPort.PublishedPort)
+			}
+		}
+	}
 }
 
 // SvcTasks returns info about the running tasks for a service.
 func (mgr *manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
-	return nil, errors.New("dockerswarm.manager.SvcTasks: Not implemented")
+	taskFilters := runningTasksFilters()
+	taskFilters.Add("service", svcCfg.ID)
+	tasks, err := mgr.tasks(taskFilters)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dockerswarm.manager.SvcTasks: mgr.tasks failed for svcCfg.ID = %q", svcCfg.ID)
+	}
+	return tasks, nil
 }
 
 // Tasks returns info about all running tasks.
 func (mgr *manager) Tasks() ([]anysched.Task, error) {
-	return nil, errors.New("dockerswarm.manager.Tasks: Not implemented")
+	tasks, err := mgr.tasks(runningTasksFilters())
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.Tasks: mgr.tasks failed")
+	}
+	return tasks, nil
+}
+
+// runningTasksFilters returns filters that match the tasks that swarm wants
+// to be running, which excludes the tasks of previous versions of a service.
+func runningTasksFilters() filters.Args {
+	taskFilters := filters.NewArgs()
+	taskFilters.Add("desired-state", string(swarm.TaskStateRunning))
+	return taskFilters
+}
+
+// tasks returns the tasks that match taskFilters, along with the names of
+// their services and the hostnames and addresses of their nodes.
+func (mgr *manager) tasks(taskFilters filters.Args) ([]anysched.Task, error) {
+	swarmTasks, err := mgr.client.TaskList(ctx, types.TaskListOptions{Filters: taskFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.client.TaskList failed")
+	}
+	swarmServices, err := mgr.client.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.client.ServiceList failed")
+	}
+	swarmNodes, err := mgr.client.NodeList(ctx, types.NodeListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "mgr.client.NodeList failed")
+	}
+	swarmServicesByID := make(map[string]swarm.Service, len(swarmServices))
+	for _, swarmService := range swarmServices {
+		swarmServicesByID[swarmService.ID] = swarmService
+	}
+	swarmNodesByID := make(map[string]swarm.Node, len(swarmNodes))
+	for _, swarmNode := range swarmNodes {
+		swarmNodesByID[swarmNode.ID] = swarmNode
+	}
+	tasks := make([]anysched.Task, len(swarmTasks))
+	for i, swarmTask := range swarmTasks {
+		tasks[i] = taskFromSwarmTask(swarmTask,
+			swarmServicesByID[swarmTask.ServiceID], swarmNodesByID[swarmTask.NodeID])
+	}
+	return tasks, nil
+}
+
+func taskFromSwarmTask(swarmTask swarm.Task, swarmService swarm.Service, swarmNode swarm.Node) anysched.Task {
+	ipAddresses := taskIPAddresses(swarmTask)
+	stageTime := swarmTask.CreatedAt
+	task := anysched.Task{
+		Name:        swarmTaskName(swarmTask, swarmService),
+		AppID:       swarmService.Spec.Name,
+		HostName:    swarmNode.Description.Hostname,
+		HostIP:      swarmNode.Status.Addr,
+		IPAddresses: ipAddresses,
+		Ports:       publishedPorts(swarmService),
+		StageTime:   &stageTime,
+		State:       string(swarmTask.Status.State),
+		Version:     strconv.FormatUint(swarmTask.Version.Index, 10),
+	}
+	if len(ipAddresses) > 0 {
+		task.TaskIP = ipAddresses[0]
+	}
+	if swarmTask.Status.State == swarm.TaskStateRunning {
+		startTime := swarmTask.Status.Timestamp
+		task.StartTime = &startTime
+	}
+	return task
+}
+
+// swarmTaskName returns a task name the way that the docker CLI displays it,
+// e.g.: "httpbin.1.ra1p5jvxyl0ct6pgr7ri8m8ox"
+func swarmTaskName(swarmTask swarm.Task, swarmService swarm.Service) string {
+	if swarmTask.Slot != 0 {
+		return fmt.Sprintf("%s.%d.%s", swarmService.Spec.Name, swarmTask.Slot, swarmTask.ID)
+	}
+	// Tasks of global services have no slot, so use the node ID instead
+	return fmt.Sprintf("%s.%s.%s", swarmService.Spec.Name, swarmTask.NodeID, swarmTask.ID)
+}
+
+// taskIPAddresses returns the container IP addresses of a task on all of the
+// networks that it is attached to, without the network prefix lengths.
+func taskIPAddresses(swarmTask swarm.Task) (ipAddresses []string) {
+	for _, networkAttachment := range swarmTask.NetworksAttachments {
+		for _, address := range networkAttachment.Addresses {
+			ipAddresses = append(ipAddresses, strings.SplitN(address, "/", 2)[0])
+		}
+	}
+	return ipAddresses
+}
+
+func publishedPorts(swarmService swarm.Service) (ports []int) {
+	for _, portConfig := range swarmService.Endpoint.Ports {
+		if portConfig.PublishedPort != 0 {
+			ports = append(ports, int(portConfig.PublishedPort))
+		}
+	}
+	return ports
 }
 
 // DeploySvc takes a SvcCfg and deploys it, returning an Operation.
 func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DeploySvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DeploySvc: validateSvcCfg failed")
+	}
+	service, err := mgr.serviceSpec(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DeploySvc")
+	}
+	options := types.ServiceCreateOptions{}
+	serviceCreateResponse, err := mgr.client.ServiceCreate(ctx, service, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DeploySvc: mgr.client.ServiceCreate failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		serviceID:       serviceCreateResponse.ID,
+		svcCfg:          svcCfg,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and updates its swarm
+// service, which makes swarm replace its tasks following the service's update
+// config, returning an Operation.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.UpdateSvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.UpdateSvc: validateSvcCfg failed")
+	}
+	service, err := mgr.serviceSpec(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.UpdateSvc")
+	}
+	oldService, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcCfg.ID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.UpdateSvc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	if err = mgr.updateService(oldService, service); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.UpdateSvc: mgr.updateService failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		serviceID:       oldService.ID,
+		svcCfg:          svcCfg,
+		update:          true,
+		oldUpdateStatus: oldService.UpdateStatus,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// ScaleSvc changes the number of replicas of a service's swarm service to
+// count, leaving the rest of its spec alone, returning an Operation. Swarm
+// doesn't roll out a change that only changes the replicas; it starts or stops
+// tasks right away.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("dockerswarm.manager.ScaleSvc: service %q cannot be scaled to %d tasks", svcID, count)
+	}
+	service, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.ScaleSvc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	if service.Spec.Mode.Replicated == nil {
+		return nil, fmt.Errorf("dockerswarm.manager.ScaleSvc: service %q is not replicated", svcID)
+	}
+	replicas := uint64(count)
+	service.Spec.Mode.Replicated.Replicas = &replicas
+	if err = mgr.updateService(service, service.Spec); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.ScaleSvc: mgr.updateService failed")
+	}
+	svcCfg := anysched.SvcCfg{ID: svcID, Count: count}
+	dep := &deployment{
+		manager:         mgr,
+		serviceID:       service.ID,
+		svcCfg:          svcCfg,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// updateService updates the spec of service, the swarm service as it was read
+// before, to spec. Swarm rejects an update of an out-of-date version, so an
+// update that races with someone else's fails instead of overwriting it.
+func (mgr *manager) updateService(service swarm.Service, spec swarm.ServiceSpec) error {
+	_, err := mgr.client.ServiceUpdate(ctx, service.ID, service.Version, spec, types.ServiceUpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "mgr.client.ServiceUpdate(%q) failed", service.ID)
+	}
+	return nil
+}
+
+// SvcHistory returns the revisions of a service, oldest first. Swarm only keeps
+// the spec of a service before its last update, so there are at most two:
+// revision 1 is the previous spec, if there is one, and the last one is the
+// current spec.
+func (mgr *manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	service, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.SvcHistory: mgr.client.ServiceInspectWithRaw failed")
+	}
+	revisions := []anysched.SvcRevision{}
+	if service.PreviousSpec != nil {
+		revisions = append(revisions, anysched.SvcRevision{
+			Revision: 1,
+			Image:    svcCfgFromServiceSpec(*service.PreviousSpec).Image,
+		})
+	}
+	updatedAt := service.UpdatedAt
+	revisions = append(revisions, anysched.SvcRevision{
+		Revision:     int64(len(revisions) + 1),
+		Image:        svcCfgFromServiceSpec(service.Spec).Image,
+		CreationTime: &updatedAt,
+		Current:      true,
+	})
+	return revisions, nil
+}
+
+// RollbackSvc updates a service to its previous spec, which is revision 1,
+// returning an Operation. Swarm keeps the spec that it replaces as the previous
+// one, so rolling back twice undoes the rollback.
+func (mgr *manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	service, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.RollbackSvc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	currentRevision := int64(1)
+	if service.PreviousSpec != nil {
+		currentRevision = 2
+	}
+	switch {
+	case revision == currentRevision:
+		return nil, fmt.Errorf("dockerswarm.manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	case revision != 1:
+		return nil, fmt.Errorf("dockerswarm.manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	}
+	if err = mgr.updateService(service, *service.PreviousSpec); err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.RollbackSvc: mgr.updateService failed")
+	}
+	svcCfg := svcCfgFromServiceSpec(*service.PreviousSpec)
+	dep := &deployment{
+		manager:         mgr,
+		serviceID:       service.ID,
+		svcCfg:          svcCfg,
+		update:          true,
+		oldUpdateStatus: service.UpdateStatus,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// serviceSpec returns the spec of the swarm service of a service.
+func (mgr *manager) serviceSpec(svcCfg anysched.SvcCfg) (swarm.ServiceSpec, error) {
+	healthConfig, err := dockerhost.HealthConfig(svcCfg)
+	if err != nil {
+		return swarm.ServiceSpec{}, errors.Wrap(err, "dockerhost.HealthConfig failed")
+	}
+	secretReferences, err := mgr.secretReferences(svcCfg.Secrets)
+	if err != nil {
+		return swarm.ServiceSpec{}, errors.Wrap(err, "mgr.secretReferences failed")
+	}
 	count := uint64(svcCfg.Count)
 	service := swarm.ServiceSpec{
 		Annotations: swarm.Annotations{
-			Name: svcCfg.ID,
+			Name:   svcCfg.ID,
+			Labels: svcCfg.Labels,
 		},
 		Mode: swarm.ServiceMode{
 			Replicated: &swarm.ReplicatedService{
@@ -62,24 +460,313 @@ func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error
 		},
 		TaskTemplate: swarm.TaskSpec{
 			ContainerSpec: swarm.ContainerSpec{
-				Image: svcCfg.Image,
+				Image:       svcCfg.Image,
+				Command:     svcCfg.Command,
+				Args:        svcCfg.Args,
+				Env:         svcCfg.EnvList(),
+				Healthcheck: healthConfig,
+				Mounts:      dockerhost.Mounts(svcCfg),
+				Secrets:     secretReferences,
 			},
+			Resources: getResourceRequirements(svcCfg.Resources),
+			Placement: getPlacement(svcCfg.Constraints),
 		},
+		EndpointSpec: getEndpointSpec(svcCfg),
 	}
-	options := types.ServiceCreateOptions{}
-	serviceCreateResponse, err := mgr.client.ServiceCreate(ctx, service, options)
-	fmt.Printf("*** serviceCreateResponse = %+v; err = %+v\n", serviceCreateResponse, err)
+	return service, nil
+}
+
+// DiffSvc returns how the service with the ID of svcCfg differs from the one
+// that DeploySvc would create for svcCfg, or nil if there is no such service.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	service, err := mgr.serviceSpec(svcCfg)
 	if err != nil {
-		return nil, errors.Wrap(err, "dockerswarm.manager.DeploySvc: mgr.client.ServiceCreate failed")
+		return nil, errors.Wrap(err, "dockerswarm.manager.DiffSvc")
+	}
+	deployedService, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcCfg.ID, types.ServiceInspectOptions{})
+	if dockerclient.IsErrServiceNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DiffSvc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	svcDiff := anysched.DiffSvcCfgs(svcCfgFromServiceSpec(deployedService.Spec), svcCfgFromServiceSpec(service))
+	svcDiff.Unknown = anysched.UnknownSvcSettings(svcCfg, svcCfgFromServiceSpecSettings...)
+	return svcDiff, nil
+}
+
+// svcCfgFromServiceSpecSettings is the settings that svcCfgFromServiceSpec
+// reads back from a service spec.
+var svcCfgFromServiceSpecSettings = []string{"image", "count", "env", "labels", "ports", "resources"}
+
+// svcCfgFromServiceSpec returns a SvcCfg with the image, count, environment
+// variables, ports, labels and resources of a Swarm service. Secrets are files,
+// so they are not in the environment variables.
+func svcCfgFromServiceSpec(service swarm.ServiceSpec) anysched.SvcCfg {
+	svcCfg := anysched.SvcCfg{
+		ID:     service.Name,
+		Image:  service.TaskTemplate.ContainerSpec.Image,
+		Env:    dockerhost.Env(service.TaskTemplate.ContainerSpec.Env),
+		Ports:  portCfgsFromEndpointSpec(service.EndpointSpec),
+		Labels: service.Labels,
+	}
+	if service.Mode.Replicated != nil && service.Mode.Replicated.Replicas != nil {
+		svcCfg.Count = int(*service.Mode.Replicated.Replicas)
+	}
+	svcCfg.Resources = &anysched.Resources{}
+	if resources := service.TaskTemplate.Resources; resources != nil && resources.Reservations != nil {
+		svcCfg.Resources.CPU = float64(resources.Reservations.NanoCPUs) / 1e9
+		svcCfg.Resources.Memory = resources.Reservations.MemoryBytes
+	}
+	if resources := service.TaskTemplate.Resources; resources != nil && resources.Limits != nil {
+		svcCfg.Resources.CPULimit = float64(resources.Limits.NanoCPUs) / 1e9
+		svcCfg.Resources.MemoryLimit = resources.Limits.MemoryBytes
 	}
-	return nil, nil
+	return svcCfg
 }
 
-// DestroySvc destroys a service.
+// portCfgsFromEndpointSpec returns the ports of an EndpointSpec. It is the
+// reverse of getEndpointSpec.
+func portCfgsFromEndpointSpec(endpointSpec *swarm.EndpointSpec) []anysched.PortCfg {
+	if endpointSpec == nil {
+		return nil
+	}
+	var portCfgs []anysched.PortCfg
+	for _, portConfig := range endpointSpec.Ports {
+		portCfgs = append(portCfgs, anysched.PortCfg{
+			Name:          portConfig.Name,
+			ContainerPort: int(portConfig.TargetPort),
+			HostPort:      int(portConfig.PublishedPort),
+			Protocol:      string(portConfig.Protocol),
+		})
+	}
+	return portCfgs
+}
+
+// secretReferences returns references to the Swarm secrets that hold the
+// values of the secrets of a service, which Swarm mounts in secretsDir, or an
+// error if one of them doesn't exist.
+func (mgr *manager) secretReferences(secretRefs []anysched.SecretRef) ([]*swarm.SecretReference, error) {
+	if len(secretRefs) == 0 {
+		return nil, nil
+	}
+	secretFilters := filters.NewArgs()
+	for _, secretRef := range secretRefs {
+		secretFilters.Add("name", swarmSecretName(secretRef.Secret, secretRef.Key))
+	}
+	swarmSecrets, err := mgr.client.SecretList(ctx, types.SecretListOptions{Filters: secretFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.secretReferences: mgr.client.SecretList failed")
+	}
+	swarmSecretIDs := map[string]string{}
+	for _, swarmSecret := range swarmSecrets {
+		swarmSecretIDs[swarmSecret.Spec.Name] = swarmSecret.ID
+	}
+	secretReferences := make([]*swarm.SecretReference, len(secretRefs))
+	for i, secretRef := range secretRefs {
+		name := swarmSecretName(secretRef.Secret, secretRef.Key)
+		id, ok := swarmSecretIDs[name]
+		if !ok {
+			return nil, fmt.Errorf("dockerswarm.manager.secretReferences: no Swarm secret %q for secret %q",
+				name, secretRef.String())
+		}
+		secretReferences[i] = &swarm.SecretReference{
+			File: &swarm.SecretReferenceFileTarget{
+				Name: path.Base(secretRef.MountPath),
+				UID:  "0",
+				GID:  "0",
+				Mode: 0444,
+			},
+			SecretID:   id,
+			SecretName: name,
+		}
+	}
+	return secretReferences, nil
+}
+
+// CreateSecret creates a Swarm secret for each value of a secret, since Swarm
+// secrets only have one value. They are named "NAME.KEY".
+func (mgr *manager) CreateSecret(secret anysched.Secret) error {
+	if err := secret.Validate(); err != nil {
+		return errors.Wrap(err, "dockerswarm.manager.CreateSecret: secret.Validate failed")
+	}
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, err := mgr.client.SecretCreate(ctx, swarm.SecretSpec{
+			Annotations: swarm.Annotations{
+				Name:   swarmSecretName(secret.Name, key),
+				Labels: map[string]string{secretLabel: secret.Name},
+			},
+			Data: secret.Data[key],
+		})
+		if err != nil {
+			return errors.Wrapf(err, "dockerswarm.manager.CreateSecret: mgr.client.SecretCreate(%q) failed",
+				swarmSecretName(secret.Name, key))
+		}
+	}
+	return nil
+}
+
+// UpdateSecret returns an error, since the values of Swarm secrets can't be
+// changed. Services have to be deployed with a secret with a new name instead.
+func (mgr *manager) UpdateSecret(secret anysched.Secret) error {
+	return fmt.Errorf("dockerswarm.manager.UpdateSecret: Swarm secrets cannot be updated, "+
+		"so create a secret with a name other than %q", secret.Name)
+}
+
+// DeleteSecret deletes the Swarm secrets that CreateSecret created for a
+// secret.
+func (mgr *manager) DeleteSecret(name string) error {
+	secretFilters := filters.NewArgs()
+	secretFilters.Add("label", secretLabel+"="+name)
+	swarmSecrets, err := mgr.client.SecretList(ctx, types.SecretListOptions{Filters: secretFilters})
+	if err != nil {
+		return errors.Wrap(err, "dockerswarm.manager.DeleteSecret: mgr.client.SecretList failed")
+	}
+	if len(swarmSecrets) == 0 {
+		return fmt.Errorf("dockerswarm.manager.DeleteSecret: secret %q not found", name)
+	}
+	for _, swarmSecret := range swarmSecrets {
+		if err := mgr.client.SecretRemove(ctx, swarmSecret.ID); err != nil {
+			return errors.Wrapf(err, "dockerswarm.manager.DeleteSecret: mgr.client.SecretRemove(%q) failed",
+				swarmSecret.Spec.Name)
+		}
+	}
+	return nil
+}
+
+// swarmSecretName returns the name of the Swarm secret with the value of the
+// key of a secret.
+func swarmSecretName(secret, key string) string {
+	return secret + "." + key
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that Swarm cannot
+// express. Swarm's placement constraints can't match one of several values,
+// the Swarm API that this manager uses can't limit the tasks on a node, and
+// Swarm only exposes secrets as files in secretsDir. Swarm tasks have one
+// container, so there are no sidecars or init containers either, and Swarm
+// has no namespaces.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	if svcCfg.Namespace != "" {
+		return fmt.Errorf("service %q: Swarm has no namespaces", svcCfg.ID)
+	}
+	if len(svcCfg.Sidecars) > 0 || len(svcCfg.InitContainers) > 0 {
+		return fmt.Errorf("service %q: Swarm tasks have one container, so they cannot have sidecars or init containers",
+			svcCfg.ID)
+	}
+	for _, constraint := range svcCfg.Constraints {
+		switch {
+		case constraint.Operator == anysched.ConstraintIn && len(constraint.Values) > 1:
+			return fmt.Errorf("service %q: Swarm cannot constrain %q to one of several values",
+				svcCfg.ID, constraint.Attribute)
+		case constraint.Operator == anysched.ConstraintUniquePerHost:
+			return fmt.Errorf("service %q: Swarm cannot place at most one task on each host", svcCfg.ID)
+		}
+	}
+	for _, secretRef := range svcCfg.Secrets {
+		if path.Dir(secretRef.MountPath) != secretsDir {
+			return fmt.Errorf("service %q: Swarm can only expose secret %q as a file in %s",
+				svcCfg.ID, secretRef.String(), secretsDir)
+		}
+	}
+	return nil
+}
+
+// getPlacement returns the placement constraints and preferences of the tasks
+// of a service, or nil if there aren't any. An in constraint with one value is
+// an equals constraint, and validateSvcCfg makes sure that there aren't any
+// with more, or any unique-per-host constraints.
+func getPlacement(constraints []anysched.Constraint) *swarm.Placement {
+	if len(constraints) == 0 {
+		return nil
+	}
+	placement := &swarm.Placement{}
+	for _, constraint := range constraints {
+		attribute := swarmNodeAttribute(constraint.Attribute)
+		switch constraint.Operator {
+		case anysched.ConstraintEquals, anysched.ConstraintIn:
+			placement.Constraints = append(placement.Constraints, attribute+"=="+constraint.Values[0])
+		case anysched.ConstraintNotEquals:
+			placement.Constraints = append(placement.Constraints, attribute+"!="+constraint.Values[0])
+		case anysched.ConstraintSpread:
+			placement.Preferences = append(placement.Preferences, swarm.PlacementPreference{
+				Spread: &swarm.SpreadOver{SpreadDescriptor: attribute},
+			})
+		}
+	}
+	return placement
+}
+
+// swarmNodeAttribute returns the Swarm node attribute for an attribute of a
+// constraint: the node's host name for anysched.ConstraintAttributeHostname,
+// or else a node label.
+func swarmNodeAttribute(attribute string) string {
+	if attribute == anysched.ConstraintAttributeHostname {
+		return "node.hostname"
+	}
+	return "node.labels." + attribute
+}
+
+// getResourceRequirements returns the reservations and limits of the tasks of a
+// service, or nil if there aren't any. Swarm cannot reserve disk, so Disk is
+// ignored.
+func getResourceRequirements(resources *anysched.Resources) *swarm.ResourceRequirements {
+	if resources == nil {
+		return nil
+	}
+	return &swarm.ResourceRequirements{
+		Reservations: &swarm.Resources{
+			NanoCPUs:    nanoCPUs(resources.CPU),
+			MemoryBytes: resources.Memory,
+		},
+		Limits: &swarm.Resources{
+			NanoCPUs:    nanoCPUs(resources.CPULimit),
+			MemoryBytes: resources.MemoryLimit,
+		},
+	}
+}
+
+// nanoCPUs converts CPU cores to the billionths of a CPU that Docker takes.
+func nanoCPUs(cpu float64) int64 {
+	return int64(math.Ceil(cpu * 1e9))
+}
+
+// getEndpointSpec returns an EndpointSpec that publishes the ports of a service
+// on the routing mesh, or nil if it doesn't have any ports. Swarm picks a
+// published port for any port without a HostPort.
+func getEndpointSpec(svcCfg anysched.SvcCfg) *swarm.EndpointSpec {
+	if len(svcCfg.Ports) == 0 {
+		return nil
+	}
+	endpointSpec := &swarm.EndpointSpec{Mode: swarm.ResolutionModeVIP}
+	for _, portCfg := range svcCfg.Ports {
+		endpointSpec.Ports = append(endpointSpec.Ports, swarm.PortConfig{
+			Name:          portCfg.Name,
+			Protocol:      swarm.PortConfigProtocol(portCfg.ProtocolOrDefault()),
+			TargetPort:    uint32(portCfg.ContainerPort),
+			PublishedPort: uint32(portCfg.HostPort),
+			PublishMode:   swarm.PortConfigPublishModeIngress,
+		})
+	}
+	return endpointSpec
+}
+
+// DestroySvc destroys a service, returning an Operation that is done once
+// none of its tasks are left running.
 func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
-	err := mgr.client.ServiceRemove(ctx, svcID)
+	// The removal follows the tasks of the service by the ID of the service,
+	// since swarm forgets its name.
+	service, _, err := mgr.client.ServiceInspectWithRaw(ctx, svcID, types.ServiceInspectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.manager.DestroySvc: mgr.client.ServiceInspectWithRaw failed")
+	}
+	err = mgr.client.ServiceRemove(ctx, service.ID)
 	if err != nil {
 		return nil, errors.Wrap(err, "dockerswarm.manager.DestroySvc: mgr.client.ServiceRemove failed")
 	}
-	return nil, nil
+	return &removal{manager: mgr, svcID: svcID, serviceID: service.ID, timeoutDuration: 60 * time.Second}, nil
 }
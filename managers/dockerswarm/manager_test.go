@@ -1,10 +1,142 @@
 package dockerswarm
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/swarm"
+	dockerclient "github.com/docker/docker/client"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
 )
 
+// apiVersionPrefixRegexp matches the API version that the Docker client puts
+// at the start of request paths, e.g.: "/v1.29"
+var apiVersionPrefixRegexp = regexp.MustCompile(`^/v[0-9.]+`)
+
+// NewTestServerJSONRoutes returns a stand-in for the Docker Engine API that
+// responds to each URL path (without the API version prefix) in routes with
+// the contents of the corresponding JSON file, and with HTTP 404 for any other
+// path. Each request is passed to onRequest, if it is not nil.
+func NewTestServerJSONRoutes(routes map[string]string, onRequest func(r *http.Request)) *httptest.Server {
+	routeSequences := make(map[string][]string, len(routes))
+	for path, jsonResponseFilePath := range routes {
+		routeSequences[path] = []string{jsonResponseFilePath}
+	}
+	return NewTestServerJSONRouteSequences(routeSequences, onRequest)
+}
+
+// NewTestServerJSONRouteSequences is like NewTestServerJSONRoutes, but each URL
+// path responds with the next JSON file in its sequence every time that it is
+// requested, repeating the last one once the sequence is exhausted.
+func NewTestServerJSONRouteSequences(
+	routeSequences map[string][]string, onRequest func(r *http.Request),
+) *httptest.Server {
+	var mutex sync.Mutex
+	counts := map[string]int{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if onRequest != nil {
+			onRequest(r)
+		}
+		path := apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+		jsonResponseFilePaths, ok := routeSequences[path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		writeJSONResponseFromFile(w, jsonResponseFilePaths[counts[path]])
+		if counts[path] < len(jsonResponseFilePaths)-1 {
+			counts[path]++
+		}
+	}))
+}
+
+func writeJSONResponseFromFile(w http.ResponseWriter, jsonResponseFilePath string) {
+	bytes, err := ioutil.ReadFile(jsonResponseFilePath)
+	if err != nil {
+		panic(err)
+	}
+	writeJSONResponseBytes(w, bytes)
+}
+
+func writeJSONResponseBytes(w http.ResponseWriter, bytes []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytes)))
+	w.Write(bytes)
+}
+
+func NewManagerWithTestServer(ts *httptest.Server) anysched.Manager {
+	client, err := dockerclient.NewClient("tcp://"+ts.Listener.Addr().String(), "1.29", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return &manager{client: client, url: ts.URL}
+}
+
+var (
+	swarmRoutes = map[string]string{
+		"/services": "testdata/services_list.json",
+		"/tasks":    "testdata/tasks_list.json",
+		"/nodes":    "testdata/nodes_list.json",
+	}
+	httpbinRoutes = map[string]string{
+		"/services": "testdata/services_list.json",
+		"/tasks":    "testdata/tasks_list_httpbin.json",
+		"/nodes":    "testdata/nodes_list.json",
+	}
+
+	httpbinServicePath = "/services/9mnpnzenvg8p8tdbtq4wvbkcz"
+)
+
+func deployHttpbin(ts *httptest.Server) *deployment {
+	manager := NewManagerWithTestServer(ts)
+	timeout := 5 * time.Second
+	svcCfg := anysched.SvcCfg{
+		ID:                    "httpbin",
+		Image:                 "citizenstig/httpbin",
+		Count:                 2,
+		DeployTimeoutDuration: &timeout,
+	}
+	op, err := manager.DeploySvc(svcCfg)
+	Expect(err).ToNot(HaveOccurred())
+	return op.(*deployment)
+}
+
+func expectHttpbinTasks(tasks []anysched.Task) {
+	Expect(tasks[0].Name).To(Equal("httpbin.1.ra1p5jvxyl0ct6pgr7ri8m8ox"))
+	Expect(tasks[0].AppID).To(Equal("httpbin"))
+	Expect(tasks[0].HostName).To(Equal("swarm-manager-1"))
+	Expect(tasks[0].HostIP).To(Equal("192.168.65.2"))
+	Expect(tasks[0].TaskIP).To(Equal("10.255.0.6"))
+	Expect(tasks[0].IPAddresses).To(Equal([]string{"10.255.0.6"}))
+	Expect(tasks[0].Ports).To(Equal([]int{30000}))
+	Expect(tasks[0].State).To(Equal("running"))
+	Expect(tasks[0].Version).To(Equal("31"))
+	Expect((*tasks[0].StageTime).Format(time.RFC3339Nano)).To(Equal("2018-07-25T18:49:01.2Z"))
+	Expect((*tasks[0].StartTime).Format(time.RFC3339)).To(Equal("2018-07-25T18:49:05Z"))
+
+	Expect(tasks[1].Name).To(Equal("httpbin.2.tq7wtk1jd3n2hn9fdu6cw5o2k"))
+	Expect(tasks[1].HostName).To(Equal("swarm-worker-1"))
+	Expect(tasks[1].HostIP).To(Equal("192.168.65.3"))
+	Expect(tasks[1].TaskIP).To(Equal("10.255.0.7"))
+	Expect(tasks[1].State).To(Equal("preparing"))
+	Expect(tasks[1].StartTime).To(BeNil())
+}
+
 var _ = Describe("dockerswarm/manager.go", func() {
 	Describe("NewManager", func() {
 		It("works", func() {
@@ -12,5 +144,977 @@ var _ = Describe("dockerswarm/manager.go", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manager).ToNot(BeNil())
 		})
+
+		It("works with tcp:// and unix:// addresses", func() {
+			manager, err := NewManager("tcp://1.2.3.4:2375")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).ToNot(BeNil())
+
+			manager, err = NewManager("unix:///var/run/docker.sock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).ToNot(BeNil())
+		})
+
+		It("works if address is blank", func() {
+			manager, err := NewManager("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).ToNot(BeNil())
+		})
+
+		It("connects to the address that it is given", func() {
+			ts := NewTestServerJSONRoutes(swarmRoutes, nil)
+			defer ts.Close()
+			manager, err := NewManager(ts.URL)
+			Expect(err).ToNot(HaveOccurred())
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(2))
+		})
+
+		It("fails with an unsupported URL scheme", func() {
+			manager, err := NewManager("ftp://1.2.3.4:2375")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`unsupported URL scheme "ftp"`))
+			Expect(manager).To(BeNil())
+		})
+
+		It("fails with an https:// address if there are no TLS certificates", func() {
+			oldCertPath := os.Getenv("DOCKER_CERT_PATH")
+			os.Setenv("DOCKER_CERT_PATH", "/dev/does-not-exist")
+			defer func() { os.Setenv("DOCKER_CERT_PATH", oldCertPath) }()
+			manager, err := NewManager("https://1.2.3.4:2376")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("tlsconfig.Client failed"))
+			Expect(manager).To(BeNil())
+		})
+
+		It("verifies the certificate of an https:// address", func() {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeJSONResponseBytes(w, []byte("[]"))
+			}))
+			defer ts.Close()
+			oldCertPath := os.Getenv("DOCKER_CERT_PATH")
+			os.Setenv("DOCKER_CERT_PATH", "testdata/certs")
+			defer func() { os.Setenv("DOCKER_CERT_PATH", oldCertPath) }()
+			manager, err := NewManager(ts.URL)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.Svcs()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("certificate"))
+
+			manager, err = NewManager(ts.URL + "?tlsverify=false")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails with an invalid tlsverify", func() {
+			manager, err := NewManager("https://1.2.3.4:2376?tlsverify=maybe")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`invalid tlsverify "maybe"`))
+			Expect(manager).To(BeNil())
+		})
+	})
+
+	Describe("Svcs", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("works", func() {
+			ts = NewTestServerJSONRoutes(swarmRoutes, nil)
+			manager = NewManagerWithTestServer(ts)
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(2))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+			Expect(*svcs[0].TasksRunning).To(Equal(1))
+			Expect((*svcs[0].CreationTime).Format(time.RFC3339Nano)).To(Equal("2018-07-25T18:49:01.123456789Z"))
+			Expect(svcs[0].Labels).To(Equal(map[string]string{"team": "payments"}))
+			Expect(svcs[1].ID).To(Equal("node-exporter"))
+			Expect(*svcs[1].TasksRunning).To(Equal(1))
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			svcs, err := manager.Svcs()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ServiceList failed"))
+			Expect(svcs).To(BeNil())
+		})
+	})
+
+	Describe("SvcsWithSelector", func() {
+		It("filters services by label equality and existence, and matches the rest itself", func() {
+			var serviceFilters string
+			ts := NewTestServerJSONRoutes(swarmRoutes, func(r *http.Request) {
+				if apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "") == "/services" {
+					serviceFilters = r.URL.Query().Get("filters")
+				}
+			})
+			defer ts.Close()
+			manager := NewManagerWithTestServer(ts)
+			selector, err := anysched.ParseSelector("team=payments,tier notin (db)")
+			Expect(err).ToNot(HaveOccurred())
+
+			svcs, err := manager.(anysched.SvcsWithSelectorGetter).SvcsWithSelector(selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceFilters).To(Equal(`{"label":{"team=payments":true}}`))
+			// The test server ignores the filters, so node-exporter is
+			// filtered out by SvcsWithSelector
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+		})
+	})
+
+	Describe("Svc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the spec of the service with its running tasks", func() {
+			var taskFilters string
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect.json",
+				"/tasks":            "testdata/tasks_list_httpbin.json",
+			}, func(r *http.Request) {
+				if apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "") == "/tasks" {
+					taskFilters = r.URL.Query().Get("filters")
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taskFilters).To(ContainSubstring(`"service":{"9mnpnzenvg8p8tdbtq4wvbkcz":true}`))
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(1))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("citizenstig/httpbin:latest"))
+			Expect(svcDetail.SvcCfg.Count).To(Equal(2))
+			Expect(svcDetail.SvcCfg.Ports).To(Equal([]anysched.PortCfg{
+				{ContainerPort: 8000, HostPort: 30000, Protocol: "tcp"},
+			}))
+			Expect(*svcDetail.Revision).To(Equal(int64(1)))
+			Expect(svcDetail.Native).To(BeAssignableToTypeOf(&swarm.Service{}))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("dockerswarm.manager.Svc: mgr.client.ServiceInspectWithRaw failed"))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("Tasks", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("works", func() {
+			ts = NewTestServerJSONRoutes(swarmRoutes, nil)
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.Tasks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(3))
+			expectHttpbinTasks(tasks)
+			Expect(tasks[2].Name).To(Equal("node-exporter.hd4mn8pfqqbqgxdm2x2pj26tm.x8u6gb3ivp6ft4wg4w6jq3trq"))
+			Expect(tasks[2].AppID).To(Equal("node-exporter"))
+			Expect(tasks[2].TaskIP).To(Equal(""))
+			Expect(tasks[2].Ports).To(BeNil())
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.Tasks()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.TaskList failed"))
+			Expect(tasks).To(BeNil())
+		})
+	})
+
+	Describe("SvcTasks", func() {
+		var (
+			manager     anysched.Manager
+			ts          *httptest.Server
+			taskFilters string
+		)
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("filters tasks by service", func() {
+			ts = NewTestServerJSONRoutes(httpbinRoutes, func(r *http.Request) {
+				if apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, "") == "/tasks" {
+					taskFilters = r.URL.Query().Get("filters")
+				}
+			})
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taskFilters).To(ContainSubstring(`"service":{"httpbin":true}`))
+			Expect(taskFilters).To(ContainSubstring(`"desired-state":{"running":true}`))
+			Expect(tasks).To(HaveLen(2))
+			expectHttpbinTasks(tasks)
+		})
+
+		It("returns an error if the Docker Engine API fails", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager = NewManagerWithTestServer(ts)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`mgr.tasks failed for svcCfg.ID = "httpbin"`))
+			Expect(tasks).To(BeNil())
+		})
+	})
+
+	Describe("DeploySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns a deployment for the new service", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"}, nil)
+			dep := deployHttpbin(ts)
+			Expect(dep.GetProperties()).To(Equal(map[string]interface{}{
+				"name":      "httpbin",
+				"serviceID": "9mnpnzenvg8p8tdbtq4wvbkcz",
+			}))
+			Expect(dep.String()).To(Equal(`<dockerswarm.deployment name="httpbin" serviceID="9mnpnzenvg8p8tdbtq4wvbkcz" />`))
+		})
+
+		It("sets the environment variables of the container", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"},
+				func(r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Env:   map[string]string{"PORT": "8000", "DEBUG": "1"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Env":["DEBUG=1","PORT=8000"]`))
+		})
+
+		It("translates the constraints to placement constraints and preferences", func() {
+			Expect(getPlacement([]anysched.Constraint{
+				{Attribute: "disk", Operator: anysched.ConstraintEquals, Values: []string{"ssd"}},
+				{Attribute: "hostname", Operator: anysched.ConstraintNotEquals, Values: []string{"node-1"}},
+				{Attribute: "zone", Operator: anysched.ConstraintIn, Values: []string{"a"}},
+				{Attribute: "rack", Operator: anysched.ConstraintSpread},
+			})).To(Equal(&swarm.Placement{
+				Constraints: []string{"node.labels.disk==ssd", "node.hostname!=node-1", "node.labels.zone==a"},
+				Preferences: []swarm.PlacementPreference{
+					{Spread: &swarm.SpreadOver{SpreadDescriptor: "node.labels.rack"}},
+				},
+			}))
+			Expect(getPlacement(nil)).To(BeNil())
+		})
+
+		It("returns an error for constraints that Swarm cannot express", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{
+					{Attribute: "zone", Operator: anysched.ConstraintIn, Values: []string{"a", "b"}},
+				},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Swarm cannot constrain "zone" to one of several values`))
+			_, err = manager.DeploySvc(anysched.SvcCfg{
+				ID:          "httpbin",
+				Image:       "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{{Operator: anysched.ConstraintUniquePerHost}},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Swarm cannot place at most one task on each host`))
+		})
+
+		It("mounts the volumes in the containers", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"},
+				func(r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data", Driver: "rexray"},
+					{Type: anysched.VolumeScratch, MountPath: "/tmp"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Mounts":[` +
+				`{"Type":"volume","Source":"httpbin-data","Target":"/data",` +
+				`"VolumeOptions":{"DriverConfig":{"Name":"rexray"}}},` +
+				`{"Type":"tmpfs","Target":"/tmp"}]`))
+		})
+
+		It("mounts the secrets in the containers", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/secrets":         "testdata/secrets_list.json",
+				"/services/create": "testdata/service_create.json",
+			}, func(r *http.Request) {
+				if r.Method == "POST" {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   2,
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", MountPath: "/run/secrets/db_password"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Secrets":[` +
+				`{"File":{"Name":"db_password","UID":"0","GID":"0","Mode":292},` +
+				`"SecretID":"ktnbjxoalbkvbvedmg1urrz8h","SecretName":"db.password"}]`))
+		})
+
+		It("returns an error for a secret that does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/secrets": "testdata/secrets_list.json"}, nil)
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   2,
+				Secrets: []anysched.SecretRef{{Secret: "tls", Key: "key", MountPath: "/run/secrets/tls_key"}},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: mgr.secretReferences failed: ` +
+				`dockerswarm.manager.secretReferences: no Swarm secret "tls.key" for secret "tls:key"`))
+		})
+
+		It("returns an error for a namespace", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Namespace: "payments", Image: "citizenstig/httpbin"})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: validateSvcCfg failed: service "httpbin": ` +
+				`Swarm has no namespaces`))
+		})
+
+		It("returns an error for init containers", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Count:          2,
+				InitContainers: []anysched.ContainerCfg{{Name: "migrate", Image: "example/payments:1.2"}},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: validateSvcCfg failed: service "httpbin": ` +
+				`Swarm tasks have one container, so they cannot have sidecars or init containers`))
+		})
+
+		It("returns an error for a secret exposed as an environment variable", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   2,
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Swarm can only expose secret "db:password" as a file in /run/secrets`))
+		})
+
+		It("sets the labels of the service", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"},
+				func(r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin",
+				Count:  2,
+				Labels: map[string]string{"team": "payments"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Name":"httpbin","Labels":{"team":"payments"}`))
+		})
+
+		It("sets the command and arguments of the container", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"},
+				func(r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   2,
+				Command: []string{"gunicorn"},
+				Args:    []string{"httpbin:app"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Command":["gunicorn"],"Args":["httpbin:app"]`))
+		})
+
+		It("publishes the ports of the service on the routing mesh", func() {
+			endpointSpec := getEndpointSpec(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(endpointSpec).To(Equal(&swarm.EndpointSpec{
+				Mode: swarm.ResolutionModeVIP,
+				Ports: []swarm.PortConfig{
+					{
+						Name:          "http",
+						Protocol:      swarm.PortConfigProtocolTCP,
+						TargetPort:    8000,
+						PublishedPort: 80,
+						PublishMode:   swarm.PortConfigPublishModeIngress,
+					},
+					{
+						Protocol:    swarm.PortConfigProtocolUDP,
+						TargetPort:  53,
+						PublishMode: swarm.PortConfigPublishModeIngress,
+					},
+				},
+			}))
+			Expect(getEndpointSpec(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"})).To(BeNil())
+		})
+
+		It("sets the health check of the containers", func() {
+			var serviceCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"/services/create": "testdata/service_create.json"},
+				func(r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					serviceCreateBody = string(body)
+				})
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:          "httpbin",
+				Image:       "citizenstig/httpbin",
+				Count:       2,
+				HealthCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000, GracePeriod: time.Minute},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceCreateBody).To(ContainSubstring(`"Healthcheck":{"Test":["CMD","curl","--fail","--silent",` +
+				`"--output","/dev/null","http://localhost:8000/status/200"]`))
+			Expect(serviceCreateBody).To(ContainSubstring(`"StartPeriod":60000000000`))
+			Expect(serviceCreateBody).To(ContainSubstring(`"Retries":3`))
+		})
+
+		It("returns an error for a health check that Docker cannot run", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				ReadinessCheck: &anysched.HealthCheck{Port: 8000},
+			})
+			Expect(err).To(MatchError(`dockerswarm.manager.DeploySvc: dockerhost.HealthConfig failed: ` +
+				`service "httpbin": Docker cannot run tcp health checks`))
+			Expect(op).To(BeNil())
+		})
+
+		It("reserves and limits the resources of the tasks", func() {
+			resourceRequirements := getResourceRequirements(&anysched.Resources{
+				CPU:         0.25,
+				CPULimit:    1,
+				Memory:      256 << 20,
+				MemoryLimit: 512 << 20,
+			})
+			Expect(resourceRequirements).To(Equal(&swarm.ResourceRequirements{
+				Reservations: &swarm.Resources{NanoCPUs: 250000000, MemoryBytes: 256 << 20},
+				Limits:       &swarm.Resources{NanoCPUs: 1000000000, MemoryBytes: 512 << 20},
+			}))
+			Expect(getResourceRequirements(nil)).To(BeNil())
+		})
+
+		It("returns an error if the service cannot be created", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ServiceCreate failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("DiffSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the settings that differ from the service", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect_updated.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin:v2",
+				Count: 2,
+				Ports: []anysched.PortCfg{{ContainerPort: 8000}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(BeEmpty())
+			svcDiff, err = manager.DiffSvc(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin:v3",
+				Count:     2,
+				Env:       map[string]string{"VERSION": "3"},
+				Resources: &anysched.Resources{CPULimit: 1},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(Equal([]string{"image", "env", "ports", "resources"}))
+		})
+
+		It("returns nil if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff).To(BeNil())
+		})
+	})
+
+	Describe("svcCfgFromServiceSpec", func() {
+		It("reads back what serviceSpec builds", func() {
+			svcCfg := anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Env:       map[string]string{"GREETING": "a=b"},
+				Ports:     []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80, Protocol: "tcp"}},
+				Labels:    map[string]string{"team": "payments"},
+				Resources: &anysched.Resources{CPU: 0.25, CPULimit: 1, Memory: 256 << 20, MemoryLimit: 512 << 20},
+			}
+			service, err := (&manager{}).serviceSpec(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcCfgFromServiceSpec(service)).To(Equal(svcCfg))
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the service at its current version and waits for swarm to roll it out", func() {
+			pollInterval = 10 * time.Millisecond
+			var updateVersion string
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/services/httpbin":            {"testdata/service_inspect.json"},
+				httpbinServicePath + "/update": {"testdata/service_update.json"},
+				httpbinServicePath: {
+					"testdata/service_inspect.json",
+					"testdata/service_inspect_updating.json",
+					"testdata/service_inspect_updated.json",
+				},
+				"/tasks": {"testdata/tasks_list_httpbin_running.json"},
+			}, func(r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/update") {
+					updateVersion = r.URL.Query().Get("version")
+				}
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updateVersion).To(Equal("19"))
+
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to converge: Waiting for the update to start...`))
+
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to converge: Update in progress: update in progress`))
+
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ServiceInspectWithRaw failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the replicas of the service at its current version", func() {
+			var (
+				updateVersion string
+				serviceSpec   swarm.ServiceSpec
+			)
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin":            "testdata/service_inspect.json",
+				httpbinServicePath + "/update": "testdata/service_update.json",
+			}, func(r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/update") {
+					updateVersion = r.URL.Query().Get("version")
+					json.NewDecoder(r.Body).Decode(&serviceSpec)
+				}
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()["serviceID"]).To(Equal("9mnpnzenvg8p8tdbtq4wvbkcz"))
+			Expect(updateVersion).To(Equal("19"))
+			Expect(*serviceSpec.Mode.Replicated.Replicas).To(Equal(uint64(5)))
+			Expect(serviceSpec.TaskTemplate.ContainerSpec.Image).To(Equal("citizenstig/httpbin:latest"))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 5)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mgr.client.ServiceInspectWithRaw failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("SvcHistory", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the previous spec and the current one", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect_updated.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			updatedAt := time.Date(2018, 7, 25, 18, 52, 10, 123456789, time.UTC)
+			Expect(revisions).To(Equal([]anysched.SvcRevision{
+				{Revision: 1, Image: "citizenstig/httpbin:latest"},
+				{Revision: 2, Image: "citizenstig/httpbin:v2", CreationTime: &updatedAt, Current: true},
+			}))
+		})
+
+		It("returns only the current spec of a service that was never updated", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(1))
+			Expect(revisions[0].Revision).To(Equal(int64(1)))
+			Expect(revisions[0].Current).To(BeTrue())
+		})
+	})
+
+	Describe("RollbackSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the service to its previous spec at its current version", func() {
+			var (
+				updateVersion string
+				serviceSpec   swarm.ServiceSpec
+			)
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin":            "testdata/service_inspect_updated.json",
+				httpbinServicePath + "/update": "testdata/service_update.json",
+			}, func(r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/update") {
+					updateVersion = r.URL.Query().Get("version")
+					json.NewDecoder(r.Body).Decode(&serviceSpec)
+				}
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()["serviceID"]).To(Equal("9mnpnzenvg8p8tdbtq4wvbkcz"))
+			Expect(updateVersion).To(Equal("23"))
+			Expect(serviceSpec.TaskTemplate.ContainerSpec.Image).To(Equal("citizenstig/httpbin:latest"))
+		})
+
+		It("returns an error for the current spec", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect_updated.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 2)
+			Expect(err).To(MatchError(`dockerswarm.manager.RollbackSvc: service "httpbin" is already at revision 2`))
+		})
+
+		It("returns an error for a service that was never updated", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/httpbin": "testdata/service_inspect.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 2)
+			Expect(err).To(MatchError(`dockerswarm.manager.RollbackSvc: service "httpbin" has no revision 2`))
+		})
+	})
+
+	Describe("DestroySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("removes the service and follows its tasks until they stop", func() {
+			pollInterval = 10 * time.Millisecond
+			var requests []string
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/services/httpbin": {"testdata/service_inspect.json"},
+				httpbinServicePath:  {"testdata/service_inspect.json"},
+				"/tasks":            {"testdata/tasks_list_httpbin.json", "testdata/tasks_list_empty.json"},
+			}, func(r *http.Request) {
+				requests = append(requests, r.Method+" "+apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, ""))
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{"GET /services/httpbin", "DELETE " + httpbinServicePath}))
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(
+				`Waiting for service "httpbin" to be removed: 2 tasks are pending termination...`))
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ServiceInspectWithRaw failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Context("a deployment that converges", func() {
+		var (
+			ts  *httptest.Server
+			dep *deployment
+		)
+
+		BeforeEach(func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/services/create": {"testdata/service_create.json"},
+				httpbinServicePath: {"testdata/service_inspect.json"},
+				"/tasks": {
+					"testdata/tasks_list_httpbin.json",
+					"testdata/tasks_list_httpbin_running.json",
+				},
+			}, nil)
+			dep = deployHttpbin(ts)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		Describe("GetStatus", func() {
+			It("works", func() {
+				status, err := dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeFalse())
+				Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to converge: 1 of 2 tasks are running...`))
+				Expect(status.LastUpdateTime.Format(time.RFC3339)).To(Equal("2018-07-25T18:49:05Z"))
+
+				status, err = dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeTrue())
+				Expect(status.Msg).To(Equal(`Service "httpbin" successfully converged. 2 of 2 tasks are running.`))
+				Expect(status.LastUpdateTime.Format(time.RFC3339)).To(Equal("2018-07-25T18:49:08Z"))
+			})
+		})
+
+		Describe("Wait", func() {
+			It("works", func() {
+				result, err := dep.Wait(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(dep))
+			})
+		})
+	})
+
+	Context("a deployment that does not converge", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns an error if the update is paused", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/create": "testdata/service_create.json",
+				httpbinServicePath: "testdata/service_inspect_update_paused.json",
+				"/tasks":           "testdata/tasks_list_httpbin.json",
+			}, nil)
+			dep := deployHttpbin(ts)
+			status, err := dep.GetStatus()
+			Expect(err).To(MatchError(`update of service "httpbin" did not converge (paused): ` +
+				`update paused due to failure or early termination of task tq7wtk1jd3n2hn9fdu6cw5o2k`))
+			Expect(status).To(BeNil())
+			_, err = dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("did not converge (paused)"))
+		})
+
+		It("times out if not enough tasks start", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/create": "testdata/service_create.json",
+				httpbinServicePath: "testdata/service_inspect.json",
+				"/tasks":           "testdata/tasks_list_httpbin.json",
+			}, nil)
+			dep := deployHttpbin(ts)
+			dep.timeoutDuration = 100 * time.Millisecond
+			_, err := dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out after 100ms"))
+		})
+
+		It("honors the context", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/services/create": "testdata/service_create.json",
+			}, nil)
+			dep := deployHttpbin(ts)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := dep.Wait(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("context canceled"))
+		})
+	})
+
+	Describe("SecretsManager", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		recordRequest := func(r *http.Request) {
+			requests = append(requests, r.Method+" "+apiVersionPrefixRegexp.ReplaceAllString(r.URL.Path, ""))
+		}
+
+		It("creates a Swarm secret for each value", func() {
+			var names []string
+			ts = NewTestServerJSONRoutes(map[string]string{"/secrets/create": "testdata/secret_create.json"},
+				func(r *http.Request) {
+					var secretSpec swarm.SecretSpec
+					json.NewDecoder(r.Body).Decode(&secretSpec)
+					names = append(names, secretSpec.Name)
+					Expect(secretSpec.Labels).To(Equal(map[string]string{"anysched.secret": "db"}))
+				})
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.CreateSecret(anysched.Secret{
+				Name: "db",
+				Data: map[string][]byte{"username": []byte("httpbin"), "password": []byte("hunter2")},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"db.password", "db.username"}))
+		})
+
+		It("returns an error for updates", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.UpdateSecret(anysched.Secret{Name: "db"})
+			Expect(err).To(MatchError(`dockerswarm.manager.UpdateSecret: Swarm secrets cannot be updated, ` +
+				`so create a secret with a name other than "db"`))
+		})
+
+		It("deletes the Swarm secrets of a secret", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/secrets":                           "testdata/secrets_list.json",
+				"/secrets/ktnbjxoalbkvbvedmg1urrz8h": "testdata/secret_create.json",
+				"/secrets/b6fqj1i2e1kd1rb8iz0oq0aeo": "testdata/secret_create.json",
+			}, recordRequest)
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			Expect(secretsManager.DeleteSecret("db")).To(Succeed())
+			Expect(requests).To(Equal([]string{
+				"GET /secrets",
+				"DELETE /secrets/ktnbjxoalbkvbvedmg1urrz8h",
+				"DELETE /secrets/b6fqj1i2e1kd1rb8iz0oq0aeo",
+			}))
+		})
+
+		It("returns an error for a secret that does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/secrets": "testdata/secrets_list_empty.json"}, recordRequest)
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.DeleteSecret("db")
+			Expect(err).To(MatchError(`dockerswarm.manager.DeleteSecret: secret "db" not found`))
+			Expect(requests).To(Equal([]string{"GET /secrets"}))
+		})
 	})
 })
@@ -0,0 +1,88 @@
+package dockerswarm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// removal implements the anysched.Operation interface for the removal of a
+// swarm service. Swarm shuts the tasks of a service down after it is removed,
+// and the removal is done once none of them are left running.
+type removal struct {
+	manager         *manager
+	svcID           string
+	serviceID       string
+	timeoutDuration time.Duration
+}
+
+func (rem *removal) String() string {
+	return fmt.Sprintf("<dockerswarm.removal name=%q serviceID=%q />", rem.svcID, rem.serviceID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (rem *removal) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = rem.svcID
+	propertiesMap["serviceID"] = rem.serviceID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the removal
+func (rem *removal) GetStatus() (*anysched.OperationStatus, error) {
+	// Swarm can't filter tasks by a service that no longer exists.
+	swarmTasks, err := rem.manager.client.TaskList(ctx, types.TaskListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerswarm.removal.GetStatus: client.TaskList failed")
+	}
+	stillRunning := 0
+	for _, swarmTask := range swarmTasks {
+		if swarmTask.ServiceID == rem.serviceID && !taskIsTerminal(swarmTask) {
+			stillRunning++
+		}
+	}
+	if stillRunning > 0 {
+		msg := fmt.Sprintf("Waiting for service %q to be removed: %d tasks are pending termination...",
+			rem.svcID, stillRunning)
+		return status(msg, false, time.Now()), nil
+	}
+	return status(fmt.Sprintf("Service %q successfully removed.", rem.svcID), true, time.Now()), nil
+}
+
+// taskIsTerminal returns true if swarmTask has stopped for good.
+func taskIsTerminal(swarmTask swarm.Task) bool {
+	switch swarmTask.Status.State {
+	case swarm.TaskStateComplete, swarm.TaskStateShutdown, swarm.TaskStateFailed, swarm.TaskStateRejected:
+		return true
+	}
+	return false
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (rem *removal) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, rem.timeoutDuration)
+	defer cancel()
+
+	for {
+		status, err := rem.GetStatus()
+		if err != nil {
+			return nil, errors.Wrap(err, "dockerswarm.removal.Wait: GetStatus failed")
+		}
+		if status.Done {
+			return rem, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "dockerswarm.removal.Wait: Timed out after %s", rem.timeoutDuration)
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
+	if svcCfg.DeployTimeoutDuration == nil {
+		return 60 * time.Second
+	}
+	return *svcCfg.DeployTimeoutDuration
+}
+
+// deployment implements the anysched.Operation interface for a fake service.
+// It is done once all of the tasks of the service are running its version,
+// and it fails if that has not happened by its deadline, which is SvcCfg's
+// DeployTimeoutDuration (in virtual time) after the service was deployed,
+// updated or scaled.
+type deployment struct {
+	manager   *Manager
+	svcCfg    anysched.SvcCfg
+	version   int
+	startTime time.Time
+	deadline  time.Time
+	// scaling is true for the deployment of the tasks that ScaleSvc adds
+	scaling bool
+}
+
+func (dep *deployment) String() string {
+	return fmt.Sprintf("<fake.deployment name=%q count=%d />", dep.svcCfg.ID, dep.svcCfg.Count)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dep *deployment) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = dep.svcCfg.ID
+	propertiesMap["count"] = dep.svcCfg.Count
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deployment
+func (dep *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
+	mgr := dep.manager
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[dep.svcCfg.ID]
+	if !ok {
+		return nil, fmt.Errorf("fake.deployment.GetStatus: service %q no longer exists", dep.svcCfg.ID)
+	}
+	if svc.version != dep.version {
+		return nil, fmt.Errorf("fake.deployment.GetStatus: service %q was updated to version %d",
+			dep.svcCfg.ID, svc.version)
+	}
+	running, old, crashLooping := 0, 0, 0
+	lastUpdateTime := dep.startTime
+	for _, task := range mgr.svcTasks(svc) {
+		switch {
+		case task.Version != strconv.Itoa(dep.version):
+			old++
+		case task.State == TaskStateRunning:
+			running++
+			if task.StartTime.After(lastUpdateTime) {
+				lastUpdateTime = *task.StartTime
+			}
+		case task.State == TaskStateCrashLooping:
+			crashLooping++
+		}
+	}
+	if dep.version > 1 && !dep.scaling {
+		return dep.updateStatus(running, old, crashLooping, lastUpdateTime)
+	}
+	return dep.startStatus(svc, running, old, crashLooping, lastUpdateTime)
+}
+
+// startStatus returns the status of the deployment of a new service, or of the
+// scaling of a service, given how many of its tasks run its version, still run
+// an old one, and crash-loop. The caller must hold dep.manager.mutex.
+func (dep *deployment) startStatus(svc *svc, running, old, crashLooping int, lastUpdateTime time.Time) (
+	*anysched.OperationStatus, error) {
+	opName, verb, pastVerb := "deployment", "start", "deployed"
+	if dep.scaling {
+		opName, verb, pastVerb = "scaling", "scale", "scaled"
+	}
+	if running == svc.cfg.Count && old == 0 {
+		msg := fmt.Sprintf("Service %q successfully %s. %d of %d tasks are running.",
+			svc.cfg.ID, pastVerb, running, svc.cfg.Count)
+		return dep.status(msg, true, lastUpdateTime), nil
+	}
+	msg := fmt.Sprintf("%d of %d tasks are running, %d crash-looping...", running, svc.cfg.Count, crashLooping)
+	if dep.manager.now.After(dep.deadline) {
+		return nil, fmt.Errorf("%s of service %q exceeded its progress deadline of %s: %s",
+			opName, svc.cfg.ID, dep.deadline.Sub(dep.startTime), msg)
+	}
+	msg = fmt.Sprintf("Waiting for service %q to %s: %s", svc.cfg.ID, verb, msg)
+	return dep.status(msg, false, lastUpdateTime), nil
+}
+
+// updateStatus returns the status of an update, given how many tasks run the
+// new version, still run an old one, and crash-loop. The caller must hold
+// dep.manager.mutex.
+func (dep *deployment) updateStatus(running, old, crashLooping int, lastUpdateTime time.Time) (
+	*anysched.OperationStatus, error) {
+	if running == dep.svcCfg.Count && old == 0 {
+		msg := fmt.Sprintf("Service %q successfully updated. %d of %d tasks are running version %d.",
+			dep.svcCfg.ID, running, dep.svcCfg.Count, dep.version)
+		return dep.status(msg, true, lastUpdateTime), nil
+	}
+	msg := fmt.Sprintf("%d of %d tasks are updated and running, %d old ones are still running, %d crash-looping...",
+		running, dep.svcCfg.Count, old, crashLooping)
+	if dep.manager.now.After(dep.deadline) {
+		return nil, fmt.Errorf("update of service %q exceeded its progress deadline of %s: %s",
+			dep.svcCfg.ID, dep.deadline.Sub(dep.startTime), msg)
+	}
+	msg = fmt.Sprintf("Waiting for service %q to update: %s", dep.svcCfg.ID, msg)
+	return dep.status(msg, false, lastUpdateTime), nil
+}
+
+// status returns an OperationStatus as of the current virtual time. The caller
+// must hold dep.manager.mutex.
+func (dep *deployment) status(msg string, done bool, lastUpdateTime time.Time) *anysched.OperationStatus {
+	return &anysched.OperationStatus{
+		ClientTime:         dep.manager.now,
+		LastTransitionTime: lastUpdateTime,
+		LastUpdateTime:     lastUpdateTime,
+		Msg:                msg,
+		Done:               done,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil. Instead of
+// sleeping between polls, it advances the manager's virtual time by its poll
+// interval, so it returns right away in real time.
+func (dep *deployment) Wait(ctx context.Context) (result interface{}, err error) {
+	for {
+		status, err := dep.GetStatus()
+		if err != nil {
+			return nil, errors.Wrap(err, "fake.deployment.Wait: GetStatus failed")
+		}
+		if status.Done {
+			return dep, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "fake.deployment.Wait")
+		default:
+		}
+		dep.manager.mutex.Lock()
+		dep.manager.now = dep.manager.now.Add(dep.manager.pollInterval)
+		dep.manager.mutex.Unlock()
+	}
+}
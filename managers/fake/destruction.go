@@ -0,0 +1,50 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// destruction implements the anysched.Operation interface for the destruction
+// of a fake service. The tasks of a fake service stop as soon as it is
+// destroyed, so it is always done.
+type destruction struct {
+	manager *Manager
+	svcID   string
+	// time is the virtual time at which the service was destroyed
+	time time.Time
+}
+
+func (dest *destruction) String() string {
+	return fmt.Sprintf("<fake.destruction name=%q />", dest.svcID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dest *destruction) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = dest.svcID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the destruction
+func (dest *destruction) GetStatus() (*anysched.OperationStatus, error) {
+	dest.manager.mutex.Lock()
+	defer dest.manager.mutex.Unlock()
+	return &anysched.OperationStatus{
+		ClientTime:         dest.manager.now,
+		LastTransitionTime: dest.time,
+		LastUpdateTime:     dest.time,
+		Msg:                fmt.Sprintf("Service %q successfully destroyed.", dest.svcID),
+		Done:               true,
+	}, nil
+}
+
+// Wait waits for an operation to finish and return error or nil. A
+// destruction is always done, so it returns right away.
+func (dest *destruction) Wait(ctx context.Context) (result interface{}, err error) {
+	return dest, nil
+}
@@ -0,0 +1,484 @@
+// Package fake provides an in-memory anysched.Manager, registered as "fake",
+// for unit testing code that uses anysched without a real scheduler.
+//
+// Time in the fake is virtual. It starts at Epoch and only moves forward when
+// Advance is called or when an Operation's Wait polls, so rollouts take the
+// same course every time that a test runs. Tests can get at the methods that
+// control the fake with a type assertion:
+//
+//	manager, _ := anysched.NewManager(anysched.ManagerConfig{Type: "fake"})
+//	fakeManager := manager.(*fake.Manager)
+//	fakeManager.CrashLoop("my-svc-id")
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// States of the tasks of a fake service
+const (
+	TaskStatePending      = "pending"
+	TaskStateRunning      = "running"
+	TaskStateCrashLooping = "crash-looping"
+)
+
+// Epoch is the virtual time at which a new Manager starts.
+var Epoch = time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Defaults for the virtual durations of a new Manager
+const (
+	DefaultTaskStartDuration = 1 * time.Second
+	DefaultPollInterval      = 1 * time.Second
+)
+
+type taskFailureMode int
+
+const (
+	noFailure taskFailureMode = iota
+	stalled
+	crashLooping
+)
+
+// Manager is an in-memory anysched.Manager. The zero value is not usable; use
+// NewManager.
+type Manager struct {
+	mutex             sync.Mutex
+	now               time.Time
+	taskStartDuration time.Duration
+	pollInterval      time.Duration
+	deployErr         error
+	svcs              map[string]*svc
+	failureModes      map[string]taskFailureMode
+}
+
+type svc struct {
+	cfg          anysched.SvcCfg
+	creationTime time.Time
+	version      int
+
+	// deployTime is when the current version was deployed or updated to, and
+	// prev is the version before it, whose tasks are replaced one by one.
+	deployTime time.Time
+	prev       *svc
+
+	// scaleStages are the batches of tasks that ScaleSvc has added since
+	// then, which start one by one after they are added.
+	scaleStages []scaleStage
+
+	// revisions are the versions of the service so far, oldest first, so
+	// that version v is revisions[v-1].
+	revisions []revision
+}
+
+// revision is a version of a service, which SvcHistory returns and RollbackSvc
+// rolls back to.
+type revision struct {
+	cfg  anysched.SvcCfg
+	time time.Time
+}
+
+// scaleStage is a batch of tasks that ScaleSvc added to a service, starting
+// at task first.
+type scaleStage struct {
+	first int
+	time  time.Time
+}
+
+func init() {
+	anysched.RegisterManagerType("fake", NewManager)
+}
+
+// NewManager returns a new, empty fake Manager. url is ignored; every call
+// returns a Manager with its own services and its own virtual clock.
+func NewManager(url string) (anysched.Manager, error) {
+	return &Manager{
+		now:               Epoch,
+		taskStartDuration: DefaultTaskStartDuration,
+		pollInterval:      DefaultPollInterval,
+		svcs:              map[string]*svc{},
+		failureModes:      map[string]taskFailureMode{},
+	}, nil
+}
+
+// Now returns the current virtual time of the manager.
+func (mgr *Manager) Now() time.Time {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	return mgr.now
+}
+
+// Advance moves the virtual time of the manager forward by d.
+func (mgr *Manager) Advance(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.now = mgr.now.Add(d)
+}
+
+// SetTaskStartDuration sets how much virtual time each task of a service takes
+// to start. The tasks of a service start one after another, so a service with
+// a Count of 3 is fully running 3*d after it is deployed.
+func (mgr *Manager) SetTaskStartDuration(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.taskStartDuration = d
+}
+
+// SetPollInterval sets how much virtual time passes between the polls of an
+// Operation's Wait.
+func (mgr *Manager) SetPollInterval(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.pollInterval = d
+}
+
+// SetDeployError makes DeploySvc, UpdateSvc, ScaleSvc and RollbackSvc fail
+// with err, until it is called again with a nil err.
+func (mgr *Manager) SetDeployError(err error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.deployErr = err
+}
+
+// CrashLoop makes the tasks of a service crash over and over, so that they
+// never become running. It can be called before or after the service is
+// deployed.
+func (mgr *Manager) CrashLoop(svcID string) {
+	mgr.setFailureMode(svcID, crashLooping)
+}
+
+// Stall makes the tasks of a service stay pending forever, e.g.: as if there
+// were no room for them in the cluster. It can be called before or after the
+// service is deployed.
+func (mgr *Manager) Stall(svcID string) {
+	mgr.setFailureMode(svcID, stalled)
+}
+
+// Heal undoes CrashLoop or Stall for a service.
+func (mgr *Manager) Heal(svcID string) {
+	mgr.setFailureMode(svcID, noFailure)
+}
+
+func (mgr *Manager) setFailureMode(svcID string, failureMode taskFailureMode) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.failureModes[svcID] = failureMode
+}
+
+// Svcs returns info about all running services.
+func (mgr *Manager) Svcs() ([]anysched.Svc, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svcs := []anysched.Svc{}
+	for _, svcID := range mgr.sortedSvcIDs() {
+		svcs = append(svcs, mgr.svcInfo(mgr.svcs[svcID]))
+	}
+	return svcs, nil
+}
+
+// Svc returns a service with the SvcCfg that it was deployed, updated or
+// scaled with. Its revision is its version, and there is no native object.
+func (mgr *Manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("fake.Manager.Svc: service %q does not exist", svcID)
+	}
+	revision := int64(svc.version)
+	return &anysched.SvcDetail{Svc: mgr.svcInfo(svc), SvcCfg: svc.cfg.Copy(), Revision: &revision}, nil
+}
+
+// svcInfo returns the info about svc that Svcs returns. The caller must hold
+// mgr.mutex.
+func (mgr *Manager) svcInfo(svc *svc) anysched.Svc {
+	tasksRunning, tasksCrashLooping := 0, 0
+	for _, task := range mgr.svcTasks(svc) {
+		switch task.State {
+		case TaskStateRunning:
+			tasksRunning++
+		case TaskStateCrashLooping:
+			tasksCrashLooping++
+		}
+	}
+	tasksHealthy := tasksRunning
+	creationTime := svc.creationTime
+	return anysched.Svc{
+		ID:             svc.cfg.ID,
+		TasksRunning:   &tasksRunning,
+		TasksHealthy:   &tasksHealthy,
+		TasksUnhealthy: &tasksCrashLooping,
+		CreationTime:   &creationTime,
+		Labels:         svc.cfg.Labels,
+	}
+}
+
+// SvcTasks returns info about the running tasks for a service.
+func (mgr *Manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcCfg.ID]
+	if !ok {
+		return nil, fmt.Errorf("fake.Manager.SvcTasks: service %q does not exist", svcCfg.ID)
+	}
+	return mgr.svcTasks(svc), nil
+}
+
+// Tasks returns info about all running tasks.
+func (mgr *Manager) Tasks() ([]anysched.Task, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	tasks := []anysched.Task{}
+	for _, svcID := range mgr.sortedSvcIDs() {
+		tasks = append(tasks, mgr.svcTasks(mgr.svcs[svcID])...)
+	}
+	return tasks, nil
+}
+
+func (mgr *Manager) sortedSvcIDs() []string {
+	svcIDs := make([]string, 0, len(mgr.svcs))
+	for svcID := range mgr.svcs {
+		svcIDs = append(svcIDs, svcID)
+	}
+	sort.Strings(svcIDs)
+	return svcIDs
+}
+
+// svcTasks returns the tasks of svc as of the current virtual time. During an
+// update, task i of the previous version keeps running until task i of the new
+// version has started, and the tasks that the new version has no room for are
+// stopped right away. The caller must hold mgr.mutex.
+func (mgr *Manager) svcTasks(svc *svc) []anysched.Task {
+	tasks := make([]anysched.Task, svc.cfg.Count)
+	for i := range tasks {
+		tasks[i] = mgr.svcTask(svc, i, mgr.failureModes[svc.cfg.ID])
+		if tasks[i].State != TaskStateRunning && svc.prev != nil && i < svc.prev.cfg.Count {
+			tasks[i] = mgr.svcTask(svc.prev, i, noFailure)
+		}
+	}
+	return tasks
+}
+
+// svcTask returns task i of svc as of the current virtual time. The caller
+// must hold mgr.mutex.
+func (mgr *Manager) svcTask(svc *svc, i int, failureMode taskFailureMode) anysched.Task {
+	stageTime, position := taskStage(svc, i)
+	task := anysched.Task{
+		Name:      fmt.Sprintf("%s.%d", svc.cfg.ID, i),
+		AppID:     svc.cfg.ID,
+		HostName:  "localhost",
+		HostIP:    "127.0.0.1",
+		StageTime: &stageTime,
+		State:     TaskStatePending,
+		Version:   strconv.Itoa(svc.version),
+	}
+	switch failureMode {
+	case crashLooping:
+		task.State = TaskStateCrashLooping
+	case noFailure:
+		startTime := stageTime.Add(time.Duration(position+1) * mgr.taskStartDuration)
+		if !startTime.After(mgr.now) {
+			task.State = TaskStateRunning
+			task.StartTime = &startTime
+		}
+	}
+	return task
+}
+
+// taskStage returns the virtual time at which task i of svc was staged, and
+// how many tasks were staged at the same time before it, which start first.
+func taskStage(svc *svc, i int) (stageTime time.Time, position int) {
+	stageTime, position = svc.deployTime, i
+	for _, stage := range svc.scaleStages {
+		if i >= stage.first {
+			stageTime, position = stage.time, i-stage.first
+		}
+	}
+	return stageTime, position
+}
+
+// DeploySvc takes a SvcCfg and deploys it, returning an Operation.
+func (mgr *Manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("fake.Manager.DeploySvc: svcCfg.Validate failed: %s", err)
+	}
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.deployErr != nil {
+		return nil, mgr.deployErr
+	}
+	if _, ok := mgr.svcs[svcCfg.ID]; ok {
+		return nil, fmt.Errorf("fake.Manager.DeploySvc: service %q already exists", svcCfg.ID)
+	}
+	svcCfg = svcCfg.Copy()
+	mgr.svcs[svcCfg.ID] = &svc{
+		cfg:          svcCfg,
+		creationTime: mgr.now,
+		version:      1,
+		deployTime:   mgr.now,
+		revisions:    []revision{{cfg: svcCfg, time: mgr.now}},
+	}
+	dep := &deployment{
+		manager:   mgr,
+		svcCfg:    svcCfg,
+		version:   1,
+		startTime: mgr.now,
+		deadline:  mgr.now.Add(getDeployTimeoutDuration(svcCfg)),
+	}
+	return dep, nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and rolls its tasks over
+// to it, one by one, returning an Operation.
+func (mgr *Manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("fake.Manager.UpdateSvc: svcCfg.Validate failed: %s", err)
+	}
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.deployErr != nil {
+		return nil, mgr.deployErr
+	}
+	if _, ok := mgr.svcs[svcCfg.ID]; !ok {
+		return nil, fmt.Errorf("fake.Manager.UpdateSvc: service %q does not exist", svcCfg.ID)
+	}
+	return mgr.updateSvc(svcCfg), nil
+}
+
+// updateSvc replaces the deployed service of svcCfg with a new version of it.
+// The caller must hold mgr.mutex.
+func (mgr *Manager) updateSvc(svcCfg anysched.SvcCfg) *deployment {
+	svcCfg = svcCfg.Copy()
+	oldSvc := mgr.svcs[svcCfg.ID]
+	prev := *oldSvc
+	prev.prev = nil
+	revisions := make([]revision, len(oldSvc.revisions), len(oldSvc.revisions)+1)
+	copy(revisions, oldSvc.revisions)
+	mgr.svcs[svcCfg.ID] = &svc{
+		cfg:          svcCfg,
+		creationTime: oldSvc.creationTime,
+		version:      oldSvc.version + 1,
+		deployTime:   mgr.now,
+		prev:         &prev,
+		revisions:    append(revisions, revision{cfg: svcCfg, time: mgr.now}),
+	}
+	return &deployment{
+		manager:   mgr,
+		svcCfg:    svcCfg,
+		version:   oldSvc.version + 1,
+		startTime: mgr.now,
+		deadline:  mgr.now.Add(getDeployTimeoutDuration(svcCfg)),
+	}
+}
+
+// ScaleSvc changes the number of tasks of a service to count, returning an
+// Operation. The tasks that it adds start one by one, like those of a new
+// service, and the ones that it removes stop right away.
+func (mgr *Manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("fake.Manager.ScaleSvc: service %q cannot be scaled to %d tasks", svcID, count)
+	}
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.deployErr != nil {
+		return nil, mgr.deployErr
+	}
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("fake.Manager.ScaleSvc: service %q does not exist", svcID)
+	}
+	scaleStages := []scaleStage{}
+	for _, stage := range svc.scaleStages {
+		if stage.first < count {
+			scaleStages = append(scaleStages, stage)
+		}
+	}
+	if count > svc.cfg.Count {
+		scaleStages = append(scaleStages, scaleStage{first: svc.cfg.Count, time: mgr.now})
+	}
+	svc.scaleStages = scaleStages
+	svc.cfg.Count = count
+	dep := &deployment{
+		manager:   mgr,
+		svcCfg:    svc.cfg,
+		version:   svc.version,
+		scaling:   true,
+		startTime: mgr.now,
+		deadline:  mgr.now.Add(getDeployTimeoutDuration(svc.cfg)),
+	}
+	return dep, nil
+}
+
+// SvcHistory returns the revisions of a service, oldest first. Every version of
+// the service is a revision, whose number is the version.
+func (mgr *Manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("fake.Manager.SvcHistory: service %q does not exist", svcID)
+	}
+	revisions := make([]anysched.SvcRevision, len(svc.revisions))
+	for i, rev := range svc.revisions {
+		creationTime := rev.time
+		revisions[i] = anysched.SvcRevision{
+			Revision:     int64(i + 1),
+			Image:        rev.cfg.Image,
+			CreationTime: &creationTime,
+			Current:      i+1 == svc.version,
+		}
+	}
+	return revisions, nil
+}
+
+// RollbackSvc updates a service to the SvcCfg of one of its revisions, with
+// the count that it has now, returning an Operation.
+func (mgr *Manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.deployErr != nil {
+		return nil, mgr.deployErr
+	}
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("fake.Manager.RollbackSvc: service %q does not exist", svcID)
+	}
+	if revision < 1 || revision > int64(len(svc.revisions)) {
+		return nil, fmt.Errorf("fake.Manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	}
+	if revision == int64(svc.version) {
+		return nil, fmt.Errorf("fake.Manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	}
+	svcCfg := svc.revisions[revision-1].cfg
+	svcCfg.Count = svc.cfg.Count
+	return mgr.updateSvc(svcCfg), nil
+}
+
+// DiffSvc returns how the deployed service with the ID of svcCfg differs from
+// it, or nil if there is no such service.
+func (mgr *Manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcCfg.ID]
+	if !ok {
+		return nil, nil
+	}
+	return anysched.DiffSvcCfgs(svc.cfg, svcCfg), nil
+}
+
+// DestroySvc destroys a service, returning an Operation that is done right
+// away, since the tasks of fake services stop as soon as they are destroyed.
+func (mgr *Manager) DestroySvc(svcID string) (anysched.Operation, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.svcs[svcID]; !ok {
+		return nil, fmt.Errorf("fake.Manager.DestroySvc: service %q does not exist", svcID)
+	}
+	delete(mgr.svcs, svcID)
+	return &destruction{manager: mgr, svcID: svcID, time: mgr.now}, nil
+}
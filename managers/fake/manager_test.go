@@ -0,0 +1,500 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+func newFakeManager() *Manager {
+	manager, err := NewManager("")
+	Expect(err).ToNot(HaveOccurred())
+	return manager.(*Manager)
+}
+
+func deployHttpbin(manager *Manager) *deployment {
+	timeout := 10 * time.Second
+	op, err := manager.DeploySvc(anysched.SvcCfg{
+		ID:                    "httpbin",
+		Image:                 "citizenstig/httpbin",
+		Count:                 3,
+		DeployTimeoutDuration: &timeout,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return op.(*deployment)
+}
+
+var _ = conformance.DescribeManager("fake", func() anysched.Manager {
+	return newFakeManager()
+})
+
+var _ = Describe("fake/manager.go", func() {
+	var manager *Manager
+
+	BeforeEach(func() {
+		manager = newFakeManager()
+	})
+
+	Describe("NewManager", func() {
+		It("is registered as the fake manager type", func() {
+			manager, err := anysched.NewManager(anysched.ManagerConfig{Type: "fake"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager).To(BeAssignableToTypeOf(&Manager{}))
+		})
+
+		It("starts at Epoch", func() {
+			Expect(manager.Now()).To(Equal(Epoch))
+			manager.Advance(time.Minute)
+			Expect(manager.Now()).To(Equal(Epoch.Add(time.Minute)))
+		})
+	})
+
+	Describe("Svcs", func() {
+		It("is empty at first", func() {
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(BeEmpty())
+		})
+
+		It("counts the tasks that have started", func() {
+			deployHttpbin(manager)
+			manager.Advance(2 * time.Second)
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+			Expect(*svcs[0].TasksRunning).To(Equal(2))
+			Expect(*svcs[0].TasksHealthy).To(Equal(2))
+			Expect(*svcs[0].TasksUnhealthy).To(Equal(0))
+			Expect(*svcs[0].CreationTime).To(Equal(Epoch))
+		})
+
+		It("counts crash-looping tasks as unhealthy", func() {
+			deployHttpbin(manager)
+			manager.CrashLoop("httpbin")
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*svcs[0].TasksRunning).To(Equal(0))
+			Expect(*svcs[0].TasksUnhealthy).To(Equal(3))
+		})
+	})
+
+	Describe("Svc", func() {
+		It("returns the service with its SvcCfg and version", func() {
+			deployHttpbin(manager)
+			manager.Advance(2 * time.Second)
+			_, err := manager.ScaleSvc("httpbin", 2)
+			Expect(err).ToNot(HaveOccurred())
+			svcDetail, err := manager.Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(2))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("citizenstig/httpbin"))
+			Expect(svcDetail.SvcCfg.Count).To(Equal(2))
+			Expect(*svcDetail.Revision).To(Equal(int64(1)))
+			Expect(svcDetail.Native).To(BeNil())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			svcDetail, err := manager.Svc("httpbin")
+			Expect(err).To(MatchError(`fake.Manager.Svc: service "httpbin" does not exist`))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("Tasks and SvcTasks", func() {
+		It("works", func() {
+			deployHttpbin(manager)
+			manager.Advance(time.Second)
+			tasks, err := manager.Tasks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(3))
+			Expect(tasks[0].Name).To(Equal("httpbin.0"))
+			Expect(tasks[0].AppID).To(Equal("httpbin"))
+			Expect(tasks[0].State).To(Equal(TaskStateRunning))
+			Expect(*tasks[0].StageTime).To(Equal(Epoch))
+			Expect(*tasks[0].StartTime).To(Equal(Epoch.Add(time.Second)))
+			Expect(tasks[0].Version).To(Equal("1"))
+			Expect(tasks[1].Name).To(Equal("httpbin.1"))
+			Expect(tasks[1].State).To(Equal(TaskStatePending))
+			Expect(tasks[1].StartTime).To(BeNil())
+
+			svcTasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcTasks).To(Equal(tasks))
+		})
+
+		It("returns an error for a service that does not exist", func() {
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).To(MatchError(`fake.Manager.SvcTasks: service "httpbin" does not exist`))
+			Expect(tasks).To(BeNil())
+		})
+	})
+
+	Describe("DeploySvc", func() {
+		It("returns a deployment", func() {
+			dep := deployHttpbin(manager)
+			Expect(dep.GetProperties()).To(Equal(map[string]interface{}{"name": "httpbin", "count": 3}))
+			Expect(dep.String()).To(Equal(`<fake.deployment name="httpbin" count=3 />`))
+		})
+
+		It("returns the injected error", func() {
+			manager.SetDeployError(errors.New("quota exceeded"))
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).To(MatchError("quota exceeded"))
+			Expect(op).To(BeNil())
+
+			manager.SetDeployError(nil)
+			_, err = manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the injected error for updates, scales and rollbacks too", func() {
+			deployHttpbin(manager)
+			manager.SetDeployError(errors.New("quota exceeded"))
+			_, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).To(MatchError("quota exceeded"))
+			_, err = manager.ScaleSvc("httpbin", 1)
+			Expect(err).To(MatchError("quota exceeded"))
+			_, err = manager.RollbackSvc("httpbin", 1)
+			Expect(err).To(MatchError("quota exceeded"))
+		})
+
+		It("keeps a copy of the SvcCfg", func() {
+			svcCfg := anysched.SvcCfg{ID: "httpbin", Count: 1, Env: map[string]string{"PORT": "8000"}}
+			_, err := manager.DeploySvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			svcCfg.Env["PORT"] = "9000"
+			svcDetail, err := manager.Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.SvcCfg.Env).To(Equal(map[string]string{"PORT": "8000"}))
+		})
+
+		It("returns an error if the service already exists", func() {
+			deployHttpbin(manager)
+			_, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).To(MatchError(`fake.Manager.DeploySvc: service "httpbin" already exists`))
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		It("replaces the tasks one by one", func() {
+			_, err := deployHttpbin(manager).Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to update: ` +
+				`0 of 2 tasks are updated and running, 2 old ones are still running, 0 crash-looping...`))
+
+			manager.Advance(1 * time.Second)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Version).To(Equal("2"))
+			Expect(tasks[1].Version).To(Equal("1"))
+			Expect(tasks[1].State).To(Equal(TaskStateRunning))
+
+			manager.Advance(1 * time.Second)
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "httpbin" successfully updated. 2 of 2 tasks are running version 2.`))
+		})
+
+		It("keeps the old tasks running while the new ones crash-loop", func() {
+			_, err := deployHttpbin(manager).Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			manager.CrashLoop("httpbin")
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).To(MatchError(ContainSubstring(`update of service "httpbin" exceeded its progress deadline`)))
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*svcs[0].TasksRunning).To(Equal(3))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).To(MatchError(`fake.Manager.UpdateSvc: service "httpbin" does not exist`))
+			Expect(op).To(BeNil())
+		})
+
+		It("fails the deployment that it supersedes", func() {
+			dep := deployHttpbin(manager)
+			_, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = dep.GetStatus()
+			Expect(err).To(MatchError(`fake.deployment.GetStatus: service "httpbin" was updated to version 2`))
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		It("starts the tasks that it adds one by one", func() {
+			_, err := deployHttpbin(manager).Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			op, err := manager.ScaleSvc("httpbin", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()).To(Equal(map[string]interface{}{"name": "httpbin", "count": 5}))
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for service "httpbin" to scale: 3 of 5 tasks are running, 0 crash-looping...`))
+
+			manager.Advance(1 * time.Second)
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(5))
+			Expect(tasks[3].State).To(Equal(TaskStateRunning))
+			Expect(tasks[4].State).To(Equal(TaskStatePending))
+			Expect(tasks[0].Version).To(Equal("1"))
+
+			manager.Advance(1 * time.Second)
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "httpbin" successfully scaled. 5 of 5 tasks are running.`))
+		})
+
+		It("stops the tasks that it removes right away", func() {
+			_, err := deployHttpbin(manager).Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			op, err := manager.ScaleSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(1))
+
+			op, err = manager.ScaleSvc("httpbin", 2)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manager.Now()).To(Equal(Epoch.Add(4 * time.Second)))
+		})
+
+		It("fails if the tasks that it adds crash-loop past the progress deadline", func() {
+			deployHttpbin(manager)
+			manager.CrashLoop("httpbin")
+			op, err := manager.ScaleSvc("httpbin", 4)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).To(MatchError(ContainSubstring(`scaling of service "httpbin" exceeded its progress deadline`)))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			op, err := manager.ScaleSvc("httpbin", 2)
+			Expect(err).To(MatchError(`fake.Manager.ScaleSvc: service "httpbin" does not exist`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for a negative count", func() {
+			deployHttpbin(manager)
+			op, err := manager.ScaleSvc("httpbin", -1)
+			Expect(err).To(MatchError(`fake.Manager.ScaleSvc: service "httpbin" cannot be scaled to -1 tasks`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("SvcHistory", func() {
+		It("returns a revision for every version", func() {
+			deployHttpbin(manager)
+			manager.Advance(1 * time.Second)
+			_, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			epochPlus1s := Epoch.Add(1 * time.Second)
+			Expect(revisions).To(Equal([]anysched.SvcRevision{
+				{Revision: 1, Image: "citizenstig/httpbin", CreationTime: &Epoch},
+				{Revision: 2, Image: "citizenstig/httpbin:v2", CreationTime: &epochPlus1s, Current: true},
+			}))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			_, err := manager.SvcHistory("httpbin")
+			Expect(err).To(MatchError(`fake.Manager.SvcHistory: service "httpbin" does not exist`))
+		})
+	})
+
+	Describe("RollbackSvc", func() {
+		It("updates the service to a revision with its current count", func() {
+			deployHttpbin(manager)
+			_, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.ScaleSvc("httpbin", 2)
+			Expect(err).ToNot(HaveOccurred())
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Version).To(Equal("3"))
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(3))
+			Expect(revisions[2].Image).To(Equal("citizenstig/httpbin"))
+			Expect(revisions[2].Current).To(BeTrue())
+		})
+
+		It("returns an error for a revision that does not exist", func() {
+			deployHttpbin(manager)
+			_, err := manager.RollbackSvc("httpbin", 2)
+			Expect(err).To(MatchError(`fake.Manager.RollbackSvc: service "httpbin" has no revision 2`))
+		})
+
+		It("returns an error for the current revision", func() {
+			deployHttpbin(manager)
+			_, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).To(MatchError(`fake.Manager.RollbackSvc: service "httpbin" is already at revision 1`))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).To(MatchError(`fake.Manager.RollbackSvc: service "httpbin" does not exist`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("DiffSvc", func() {
+		It("returns the settings that differ", func() {
+			deployHttpbin(manager)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin:v2",
+				Count:     3,
+				Env:       map[string]string{"VERSION": "2"},
+				Resources: &anysched.Resources{CPU: 0.5},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(Equal([]string{"image", "env", "resources"}))
+		})
+
+		It("returns nil if the service does not exist", func() {
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{ID: "httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff).To(BeNil())
+		})
+	})
+
+	Describe("DestroySvc", func() {
+		It("works", func() {
+			deployHttpbin(manager)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.(*destruction).String()).To(Equal(`<fake.destruction name="httpbin" />`))
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "httpbin" successfully destroyed.`))
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(BeEmpty())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			_, err := manager.DestroySvc("httpbin")
+			Expect(err).To(MatchError(`fake.Manager.DestroySvc: service "httpbin" does not exist`))
+		})
+	})
+
+	Describe("deployment", func() {
+		Describe("GetStatus", func() {
+			It("follows the rollout in virtual time", func() {
+				dep := deployHttpbin(manager)
+				status, err := dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeFalse())
+				Expect(status.Msg).To(Equal(
+					`Waiting for service "httpbin" to start: 0 of 3 tasks are running, 0 crash-looping...`))
+				Expect(status.ClientTime).To(Equal(Epoch))
+
+				manager.Advance(3 * time.Second)
+				status, err = dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeTrue())
+				Expect(status.Msg).To(Equal(`Service "httpbin" successfully deployed. 3 of 3 tasks are running.`))
+				Expect(status.LastUpdateTime).To(Equal(Epoch.Add(3 * time.Second)))
+			})
+
+			It("honors the task start duration", func() {
+				manager.SetTaskStartDuration(5 * time.Second)
+				dep := deployHttpbin(manager)
+				manager.Advance(9 * time.Second)
+				status, err := dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Msg).To(ContainSubstring("1 of 3 tasks are running"))
+			})
+
+			It("fails once the progress deadline is exceeded", func() {
+				manager.Stall("httpbin")
+				dep := deployHttpbin(manager)
+				manager.Advance(11 * time.Second)
+				status, err := dep.GetStatus()
+				Expect(err).To(MatchError(`deployment of service "httpbin" exceeded its progress deadline of 10s: ` +
+					`0 of 3 tasks are running, 0 crash-looping...`))
+				Expect(status).To(BeNil())
+			})
+
+			It("returns an error if the service was destroyed", func() {
+				dep := deployHttpbin(manager)
+				_, err := manager.DestroySvc("httpbin")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = dep.GetStatus()
+				Expect(err).To(MatchError(`fake.deployment.GetStatus: service "httpbin" no longer exists`))
+			})
+		})
+
+		Describe("Wait", func() {
+			It("works", func() {
+				dep := deployHttpbin(manager)
+				result, err := dep.Wait(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(dep))
+				Expect(manager.Now()).To(Equal(Epoch.Add(3 * time.Second)))
+			})
+
+			It("recovers from a crash loop that is healed in time", func() {
+				manager.CrashLoop("httpbin")
+				dep := deployHttpbin(manager)
+				manager.Advance(5 * time.Second)
+				manager.Heal("httpbin")
+				_, err := dep.Wait(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("fails if tasks crash-loop past the progress deadline", func() {
+				manager.CrashLoop("httpbin")
+				manager.SetPollInterval(3 * time.Second)
+				dep := deployHttpbin(manager)
+				_, err := dep.Wait(context.Background())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("exceeded its progress deadline of 10s"))
+				Expect(err.Error()).To(ContainSubstring("3 crash-looping"))
+				Expect(manager.Now()).To(Equal(Epoch.Add(12 * time.Second)))
+			})
+
+			It("honors the context", func() {
+				dep := deployHttpbin(manager)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := dep.Wait(ctx)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("context canceled"))
+			})
+		})
+	})
+})
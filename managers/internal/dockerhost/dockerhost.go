@@ -0,0 +1,108 @@
+// Package dockerhost creates Docker Engine API clients from the addresses that
+// users put in a ManagerConfig, and translates the parts of a SvcCfg that
+// Docker containers and Swarm services have in common. It is shared by the
+// managers that talk to the Docker Engine API.
+package dockerhost
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/docker/docker/api"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+// NewClient returns a Docker client for a "tcp://", "unix://", "http://" or
+// "https://" URL. TLS is used for "https://" URLs, and for "tcp://" URLs if
+// DOCKER_TLS_VERIFY is set, with the certificates in DOCKER_CERT_PATH. The
+// certificate of the server is verified unless the URL asks not to with
+// "tlsverify=false", e.g.: "https://127.0.0.1:2376?tlsverify=false".
+//
+// If rawURL is blank, the client is configured from the DOCKER_HOST,
+// DOCKER_API_VERSION, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY environment
+// variables, just like the docker CLI.
+func NewClient(rawURL string) (*dockerclient.Client, error) {
+	if rawURL == "" {
+		client, err := dockerclient.NewEnvClient()
+		if err != nil {
+			return nil, errors.Wrap(err, "dockerhost.NewClient: dockerclient.NewEnvClient failed")
+		}
+		return client, nil
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dockerhost.NewClient: url.Parse(%q) failed", rawURL)
+	}
+	tlsVerify := true
+	if value := parsedURL.Query().Get("tlsverify"); value != "" {
+		if tlsVerify, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("dockerhost.NewClient: invalid tlsverify %q in %q", value, rawURL)
+		}
+	}
+	var (
+		host   string
+		useTLS bool
+	)
+	switch parsedURL.Scheme {
+	case "unix":
+		host = "unix://" + parsedURL.Path
+	case "tcp":
+		host, useTLS = "tcp://"+parsedURL.Host, os.Getenv("DOCKER_TLS_VERIFY") != ""
+	case "http":
+		host = "tcp://" + parsedURL.Host
+	case "https":
+		host, useTLS = "tcp://"+parsedURL.Host, true
+	default:
+		return nil, fmt.Errorf("dockerhost.NewClient: unsupported URL scheme %q in %q", parsedURL.Scheme, rawURL)
+	}
+	var httpClient *http.Client
+	if useTLS {
+		if httpClient, err = tlsHTTPClient(parsedURL.Host, tlsVerify); err != nil {
+			return nil, err
+		}
+	}
+	client, err := dockerclient.NewClient(host, apiVersion(), httpClient, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dockerhost.NewClient: dockerclient.NewClient(%q) failed", host)
+	}
+	return client, nil
+}
+
+// tlsHTTPClient returns an HTTP client that connects to addr with TLS, using
+// the client certificate and CA in DOCKER_CERT_PATH (or ~/.docker), and
+// verifying the certificate of the server if verify is true.
+func tlsHTTPClient(addr string, verify bool) (*http.Client, error) {
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	tlsConfig, err := tlsconfig.Client(tlsconfig.Options{
+		CAFile:             filepath.Join(certPath, "ca.pem"),
+		CertFile:           filepath.Join(certPath, "cert.pem"),
+		KeyFile:            filepath.Join(certPath, "key.pem"),
+		InsecureSkipVerify: !verify,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "dockerhost.tlsHTTPClient: tlsconfig.Client failed")
+	}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	if err = sockets.ConfigureTransport(transport, "tcp", addr); err != nil {
+		return nil, errors.Wrap(err, "dockerhost.tlsHTTPClient: sockets.ConfigureTransport failed")
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+func apiVersion() string {
+	if version := os.Getenv("DOCKER_API_VERSION"); version != "" {
+		return version
+	}
+	return api.DefaultVersion
+}
@@ -0,0 +1,21 @@
+package dockerhost
+
+import "strings"
+
+// Env returns the environment variables of a container or a Swarm service,
+// which are "NAME=value" strings, by name, or nil if there aren't any. It is
+// the reverse of SvcCfg.EnvList.
+func Env(envList []string) map[string]string {
+	var env map[string]string
+	for _, envVar := range envList {
+		parts := strings.SplitN(envVar, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if env == nil {
+			env = map[string]string{}
+		}
+		env[parts[0]] = parts[1]
+	}
+	return env
+}
@@ -0,0 +1,46 @@
+package dockerhost
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// HealthConfig returns the HEALTHCHECK of the containers of a service, or nil
+// to keep the image's. Docker has a single health check, which Swarm also
+// waits for when it deploys, so it is the HealthCheck or the ReadinessCheck of
+// svcCfg, and it is an error to have two different ones. Docker can only run
+// commands, so HTTP checks run curl in the container, and TCP checks aren't
+// supported.
+func HealthConfig(svcCfg anysched.SvcCfg) (*container.HealthConfig, error) {
+	healthCheck := svcCfg.HealthCheck
+	if healthCheck == nil {
+		healthCheck = svcCfg.ReadinessCheck
+	} else if svcCfg.ReadinessCheck != nil && !reflect.DeepEqual(svcCfg.ReadinessCheck, healthCheck) {
+		return nil, fmt.Errorf("service %q: Docker cannot have a readiness check that is different "+
+			"from the health check", svcCfg.ID)
+	}
+	if healthCheck == nil {
+		return nil, nil
+	}
+	healthConfig := &container.HealthConfig{
+		Interval:    healthCheck.IntervalOrDefault(),
+		Timeout:     healthCheck.TimeoutOrDefault(),
+		StartPeriod: healthCheck.GracePeriod,
+		Retries:     healthCheck.FailureThresholdOrDefault(),
+	}
+	switch healthCheck.Kind() {
+	case anysched.HealthCheckCommand:
+		healthConfig.Test = append([]string{"CMD"}, healthCheck.Command...)
+	case anysched.HealthCheckHTTP:
+		url := "http://localhost:" + strconv.Itoa(healthCheck.Port) + healthCheck.HTTPPath
+		healthConfig.Test = []string{"CMD", "curl", "--fail", "--silent", "--output", "/dev/null", url}
+	default:
+		return nil, fmt.Errorf("service %q: Docker cannot run %s health checks", svcCfg.ID, healthCheck.Kind())
+	}
+	return healthConfig, nil
+}
@@ -0,0 +1,36 @@
+package dockerhost
+
+import (
+	"github.com/docker/docker/api/types/mount"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// Mounts returns the mounts of the containers of a service, or nil if it has no
+// volumes: bind mounts for host paths, Docker volumes for named volumes, and
+// tmpfs mounts for scratch space.
+func Mounts(svcCfg anysched.SvcCfg) []mount.Mount {
+	if len(svcCfg.Volumes) == 0 {
+		return nil
+	}
+	mounts := make([]mount.Mount, len(svcCfg.Volumes))
+	for i, volumeCfg := range svcCfg.Volumes {
+		mounts[i] = mount.Mount{
+			Source:   volumeCfg.Source,
+			Target:   volumeCfg.MountPath,
+			ReadOnly: volumeCfg.ReadOnly,
+		}
+		switch volumeCfg.Type {
+		case anysched.VolumeHostPath:
+			mounts[i].Type = mount.TypeBind
+		case anysched.VolumeNamed:
+			mounts[i].Type = mount.TypeVolume
+			if volumeCfg.Driver != "" {
+				mounts[i].VolumeOptions = &mount.VolumeOptions{DriverConfig: &mount.Driver{Name: volumeCfg.Driver}}
+			}
+		case anysched.VolumeScratch:
+			mounts[i].Type = mount.TypeTmpfs
+		}
+	}
+	return mounts
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	// Registers the auth provider plugins that kubeconfig users can have, e.g.
+	// for GKE, AKS or OpenID Connect. exec plugins don't need registering.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+// inClusterAddress is the address of a manager that runs in a pod and talks to
+// the API server of its cluster as the pod's service account.
+const inClusterAddress = "in-cluster"
+
+// serviceAccountNamespaceFile is where Kubernetes puts the namespace of a pod
+// in its containers, next to the token of its service account.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// addressOptions are the options that the query string of an address can have,
+// and whether they configure the client from the kubeconfig, which the
+// in-cluster config doesn't take.
+var addressOptions = map[string]bool{
+	"namespace":             false,
+	"createNamespace":       false,
+	"kubeconfig":            true,
+	"context":               true,
+	"cluster":               true,
+	"user":                  true,
+	"token":                 true,
+	"tokenFile":             true,
+	"clientCertificate":     true,
+	"clientKey":             true,
+	"certificateAuthority":  true,
+	"insecureSkipTLSVerify": true,
+}
+
+// parseAddress splits the address of a manager into the URL of the API server
+// and the options in its query string.
+//
+// The URL is "" or "kubeconfig" for the server of the kubeconfig, or
+// "in-cluster" for the in-cluster config of a pod. These options pick the
+// namespace and whether DeploySvc creates it:
+//
+//	namespace        the namespace to work in
+//	createNamespace  "true" to create the namespace of a service if it doesn't exist
+//
+// and these ones change what is taken from the kubeconfig:
+//
+//	kubeconfig             the path of the kubeconfig, instead of $KUBECONFIG or ~/.kube/config
+//	context                the context to use, instead of the current one
+//	cluster, user          the cluster and user to use, instead of the context's
+//	token, tokenFile       a bearer token, or a file with one
+//	clientCertificate      a file with a client certificate, and
+//	clientKey              a file with its key
+//	certificateAuthority   a file with the certificate of the API server's CA
+//	insecureSkipTLSVerify  "true" to skip verifying the API server's certificate
+//
+// e.g.: "kubeconfig?context=prod&namespace=payments" or
+// "https://10.0.0.1:6443?tokenFile=/etc/deploy-bot/token&certificateAuthority=/etc/deploy-bot/ca.crt".
+//
+// Users of the kubeconfig can also authenticate with exec plugins or auth
+// provider plugins, e.g. for GKE.
+func parseAddress(address string) (string, url.Values, error) {
+	parts := strings.SplitN(address, "?", 2)
+	if len(parts) == 1 {
+		return address, url.Values{}, nil
+	}
+	options, err := url.ParseQuery(parts[1])
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "kubernetes.parseAddress: url.ParseQuery failed for %q", address)
+	}
+	for option := range options {
+		if _, ok := addressOptions[option]; !ok {
+			return "", nil, fmt.Errorf("kubernetes.parseAddress: unknown option %q in %q", option, address)
+		}
+	}
+	return parts[0], options, nil
+}
+
+// getRestConfig returns the config of the clients for the URL and options of an
+// address, and the namespace that the config picks.
+func getRestConfig(serverURL string, options url.Values) (*rest.Config, string, error) {
+	if serverURL == inClusterAddress {
+		return getInClusterConfig(options)
+	}
+	clientConfig, err := getClientConfig(serverURL, options)
+	if err != nil {
+		return nil, "", err
+	}
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "kubernetes.getRestConfig: clientConfig.ClientConfig failed")
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "kubernetes.getRestConfig: clientConfig.Namespace failed")
+	}
+	return restConfig, namespace, nil
+}
+
+// getInClusterConfig returns the in-cluster config, which authenticates as the
+// service account of the pod that the manager runs in, and the namespace of
+// the pod.
+func getInClusterConfig(options url.Values) (*rest.Config, string, error) {
+	for option := range options {
+		if addressOptions[option] {
+			return nil, "", fmt.Errorf("kubernetes.getInClusterConfig: option %q only applies to the kubeconfig",
+				option)
+		}
+	}
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "kubernetes.getInClusterConfig: rest.InClusterConfig failed")
+	}
+	namespace, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "kubernetes.getInClusterConfig: ioutil.ReadFile failed")
+	}
+	return restConfig, strings.TrimSpace(string(namespace)), nil
+}
+
+// getClientConfig returns the client config for the kubeconfig, with what the
+// options and the URL of an API server, unless it is "" or "kubeconfig",
+// override.
+func getClientConfig(serverURL string, options url.Values) (clientcmd.ClientConfig, error) {
+	if serverURL == "kubeconfig" {
+		serverURL = ""
+	}
+	token := options.Get("token")
+	if tokenFile := options.Get("tokenFile"); tokenFile != "" {
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes.getClientConfig: ioutil.ReadFile failed")
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	kubeconfig := options.Get("kubeconfig")
+	if kubeconfig == "" {
+		kubeconfig = getKubeconfig()
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: options.Get("context"),
+		Context:        clientcmdapi.Context{Cluster: options.Get("cluster"), AuthInfo: options.Get("user")},
+		ClusterInfo: clientcmdapi.Cluster{
+			Server:                serverURL,
+			CertificateAuthority:  options.Get("certificateAuthority"),
+			InsecureSkipTLSVerify: options.Get("insecureSkipTLSVerify") == "true",
+		},
+		AuthInfo: clientcmdapi.AuthInfo{
+			Token:             token,
+			ClientCertificate: options.Get("clientCertificate"),
+			ClientKey:         options.Get("clientKey"),
+		},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig}, overrides), nil
+}
+
+func getKubeconfig() string {
+	if os.Getenv("KUBECONFIG") != "" {
+		return os.Getenv("KUBECONFIG")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+}
@@ -0,0 +1,389 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+var _ = conformance.DescribeManager("kubernetes", func() anysched.Manager {
+	pollInterval = 10 * time.Millisecond
+	return NewManagerWithTestServer(newAPIServerStandIn())
+})
+
+// apiServerStandIn is a stand-in for the API server of a Kubernetes cluster,
+// for the conformance suite. It keeps the Deployments in the "default"
+// namespace that it is asked to create, and does the work of the deployment
+// controller right away whenever one of them changes: it gives each pod
+// template a ReplicaSet, scales the ReplicaSet of the current template up and
+// the others down to 0, and runs their pods, which are ready at once. It
+// deletes the ReplicaSets and pods of a Deployment along with it. There are no
+// Services, because the suite's service has no ports.
+type apiServerStandIn struct {
+	mutex           sync.Mutex
+	deployments     map[string]*appsv1.Deployment
+	replicaSets     map[string]*appsv1.ReplicaSet
+	pods            map[string]*apiv1.Pod
+	resourceVersion int
+}
+
+func newAPIServerStandIn() *httptest.Server {
+	return httptest.NewServer(&apiServerStandIn{
+		deployments: map[string]*appsv1.Deployment{},
+		replicaSets: map[string]*appsv1.ReplicaSet{},
+		pods:        map[string]*apiv1.Pod{},
+	})
+}
+
+func (standIn *apiServerStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	path := r.URL.Path
+	for _, prefix := range []string{"/apis/apps/v1/namespaces/default/", "/api/v1/namespaces/default/"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	pathParts := strings.Split(path, "/")
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	Expect(err).ToNot(HaveOccurred())
+	switch {
+	case path == "deployments" && r.Method == "GET":
+		standIn.listDeployments(w, selector)
+	case path == "deployments" && r.Method == "POST":
+		standIn.createDeployment(w, r)
+	case path == "replicasets" && r.Method == "GET":
+		standIn.listReplicaSets(w, selector)
+	case path == "pods" && r.Method == "GET":
+		standIn.listPods(w, selector)
+	case pathParts[0] == "deployments" && standIn.deployments[pathParts[1]] != nil:
+		standIn.serveDeployment(w, r, standIn.deployments[pathParts[1]], pathParts[2:])
+	default:
+		writeStatus(w, http.StatusNotFound, metav1.StatusReasonNotFound, fmt.Sprintf("%s not found", path))
+	}
+}
+
+func (standIn *apiServerStandIn) listDeployments(w http.ResponseWriter, selector labels.Selector) {
+	k8sDeploymentList := &appsv1.DeploymentList{TypeMeta: metav1.TypeMeta{Kind: "DeploymentList", APIVersion: "apps/v1"}}
+	for _, name := range sortedKeys(standIn.deployments) {
+		if k8sDeployment := standIn.deployments[name]; selector.Matches(labels.Set(k8sDeployment.Labels)) {
+			k8sDeploymentList.Items = append(k8sDeploymentList.Items, *k8sDeployment)
+		}
+	}
+	writeJSON(w, http.StatusOK, k8sDeploymentList)
+}
+
+func (standIn *apiServerStandIn) listReplicaSets(w http.ResponseWriter, selector labels.Selector) {
+	k8sReplicaSetList := &appsv1.ReplicaSetList{TypeMeta: metav1.TypeMeta{Kind: "ReplicaSetList", APIVersion: "apps/v1"}}
+	for _, name := range sortedKeys(standIn.replicaSets) {
+		if k8sReplicaSet := standIn.replicaSets[name]; selector.Matches(labels.Set(k8sReplicaSet.Labels)) {
+			k8sReplicaSetList.Items = append(k8sReplicaSetList.Items, *k8sReplicaSet)
+		}
+	}
+	writeJSON(w, http.StatusOK, k8sReplicaSetList)
+}
+
+func (standIn *apiServerStandIn) listPods(w http.ResponseWriter, selector labels.Selector) {
+	k8sPodList := &apiv1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+	for _, name := range sortedKeys(standIn.pods) {
+		if k8sPod := standIn.pods[name]; selector.Matches(labels.Set(k8sPod.Labels)) {
+			k8sPodList.Items = append(k8sPodList.Items, *k8sPod)
+		}
+	}
+	writeJSON(w, http.StatusOK, k8sPodList)
+}
+
+func (standIn *apiServerStandIn) createDeployment(w http.ResponseWriter, r *http.Request) {
+	k8sDeployment := &appsv1.Deployment{}
+	Expect(json.NewDecoder(r.Body).Decode(k8sDeployment)).To(Succeed())
+	if standIn.deployments[k8sDeployment.Name] != nil {
+		writeStatus(w, http.StatusConflict, metav1.StatusReasonAlreadyExists,
+			fmt.Sprintf("deployments.apps %q already exists", k8sDeployment.Name))
+		return
+	}
+	k8sDeployment.TypeMeta = metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"}
+	k8sDeployment.Namespace = "default"
+	k8sDeployment.UID = types.UID(fmt.Sprintf("deployment-%s", k8sDeployment.Name))
+	k8sDeployment.CreationTimestamp = metav1.Now()
+	k8sDeployment.Generation = 1
+	standIn.deployments[k8sDeployment.Name] = k8sDeployment
+	standIn.reconcile(k8sDeployment)
+	writeJSON(w, http.StatusCreated, k8sDeployment)
+}
+
+func (standIn *apiServerStandIn) serveDeployment(w http.ResponseWriter, r *http.Request,
+	k8sDeployment *appsv1.Deployment, pathParts []string) {
+	switch {
+	case len(pathParts) == 0 && r.Method == "GET":
+		writeJSON(w, http.StatusOK, k8sDeployment)
+	case len(pathParts) == 0 && r.Method == "PUT":
+		updatedK8sDeployment := &appsv1.Deployment{}
+		Expect(json.NewDecoder(r.Body).Decode(updatedK8sDeployment)).To(Succeed())
+		if !standIn.checkResourceVersion(w, k8sDeployment, updatedK8sDeployment.ResourceVersion) {
+			return
+		}
+		k8sDeployment.Labels = updatedK8sDeployment.Labels
+		k8sDeployment.Spec = updatedK8sDeployment.Spec
+		k8sDeployment.Generation++
+		standIn.reconcile(k8sDeployment)
+		writeJSON(w, http.StatusOK, k8sDeployment)
+	case len(pathParts) == 0 && r.Method == "DELETE":
+		standIn.deleteDeployment(k8sDeployment)
+		writeStatus(w, http.StatusOK, "", "")
+	case len(pathParts) == 1 && pathParts[0] == "scale":
+		standIn.serveScale(w, r, k8sDeployment)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed, r.Method)
+	}
+}
+
+func (standIn *apiServerStandIn) serveScale(w http.ResponseWriter, r *http.Request, k8sDeployment *appsv1.Deployment) {
+	if r.Method == "PUT" {
+		scale := &autoscalingv1.Scale{}
+		Expect(json.NewDecoder(r.Body).Decode(scale)).To(Succeed())
+		if !standIn.checkResourceVersion(w, k8sDeployment, scale.ResourceVersion) {
+			return
+		}
+		k8sDeployment.Spec.Replicas = &scale.Spec.Replicas
+		k8sDeployment.Generation++
+		standIn.reconcile(k8sDeployment)
+	}
+	writeJSON(w, http.StatusOK, &autoscalingv1.Scale{
+		TypeMeta: metav1.TypeMeta{Kind: "Scale", APIVersion: "autoscaling/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            k8sDeployment.Name,
+			Namespace:       k8sDeployment.Namespace,
+			ResourceVersion: k8sDeployment.ResourceVersion,
+		},
+		Spec:   autoscalingv1.ScaleSpec{Replicas: *k8sDeployment.Spec.Replicas},
+		Status: autoscalingv1.ScaleStatus{Replicas: k8sDeployment.Status.Replicas},
+	})
+}
+
+// checkResourceVersion writes a conflict, and returns false, unless the
+// resource version of an update is the one of the Deployment.
+func (standIn *apiServerStandIn) checkResourceVersion(w http.ResponseWriter, k8sDeployment *appsv1.Deployment,
+	resourceVersion string) bool {
+	if resourceVersion != "" && resourceVersion != k8sDeployment.ResourceVersion {
+		writeStatus(w, http.StatusConflict, metav1.StatusReasonConflict,
+			fmt.Sprintf("the object has been modified; resource version %s is not %s",
+				resourceVersion, k8sDeployment.ResourceVersion))
+		return false
+	}
+	return true
+}
+
+func (standIn *apiServerStandIn) deleteDeployment(k8sDeployment *appsv1.Deployment) {
+	delete(standIn.deployments, k8sDeployment.Name)
+	for _, k8sReplicaSet := range standIn.replicaSetsOf(k8sDeployment) {
+		standIn.scaleReplicaSet(k8sReplicaSet, 0)
+		delete(standIn.replicaSets, k8sReplicaSet.Name)
+	}
+}
+
+// reconcile does what the deployment controller would do for a Deployment
+// that changed, and what the ReplicaSet controller and kubelets would do for
+// its ReplicaSets.
+func (standIn *apiServerStandIn) reconcile(k8sDeployment *appsv1.Deployment) {
+	k8sReplicaSets := standIn.replicaSetsOf(k8sDeployment)
+	revision := int64(0)
+	for _, k8sReplicaSet := range k8sReplicaSets {
+		if k8sRevision(k8sReplicaSet.ObjectMeta) > revision {
+			revision = k8sRevision(k8sReplicaSet.ObjectMeta)
+		}
+	}
+	hash := podTemplateHash(k8sDeployment.Spec.Template)
+	newK8sReplicaSet := standIn.replicaSets[k8sDeployment.Name+"-"+hash]
+	if newK8sReplicaSet == nil {
+		newK8sReplicaSet = newReplicaSet(k8sDeployment, hash)
+		standIn.replicaSets[newK8sReplicaSet.Name] = newK8sReplicaSet
+		k8sReplicaSets = append(k8sReplicaSets, newK8sReplicaSet)
+	}
+	// The ReplicaSet of a template that comes back gets the next revision
+	if k8sRevision(newK8sReplicaSet.ObjectMeta) != revision || revision == 0 {
+		revision++
+	}
+	newK8sReplicaSet.Annotations = map[string]string{k8sRevisionAnnotation: strconv.FormatInt(revision, 10)}
+	k8sDeployment.Annotations = map[string]string{k8sRevisionAnnotation: strconv.FormatInt(revision, 10)}
+	for _, k8sReplicaSet := range k8sReplicaSets {
+		replicas := int32(0)
+		if k8sReplicaSet == newK8sReplicaSet {
+			replicas = *k8sDeployment.Spec.Replicas
+		}
+		standIn.scaleReplicaSet(k8sReplicaSet, replicas)
+	}
+	standIn.resourceVersion++
+	k8sDeployment.ResourceVersion = strconv.Itoa(standIn.resourceVersion)
+	replicas := *k8sDeployment.Spec.Replicas
+	now := metav1.Now()
+	k8sDeployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: k8sDeployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+		Conditions: []appsv1.DeploymentCondition{{
+			Type:               appsv1.DeploymentProgressing,
+			Status:             apiv1.ConditionTrue,
+			LastUpdateTime:     now,
+			LastTransitionTime: now,
+			Reason:             "NewReplicaSetAvailable",
+		}},
+	}
+}
+
+func newReplicaSet(k8sDeployment *appsv1.Deployment, hash string) *appsv1.ReplicaSet {
+	template := *k8sDeployment.Spec.Template.DeepCopy()
+	template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = hash
+	selector := k8sDeployment.Spec.Selector.DeepCopy()
+	selector.MatchLabels[appsv1.DefaultDeploymentUniqueLabelKey] = hash
+	controller := true
+	return &appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{Kind: "ReplicaSet", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              k8sDeployment.Name + "-" + hash,
+			Namespace:         k8sDeployment.Namespace,
+			UID:               types.UID("replicaset-" + k8sDeployment.Name + "-" + hash),
+			CreationTimestamp: metav1.Now(),
+			Labels:            template.Labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       k8sDeployment.Name,
+				UID:        k8sDeployment.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Selector: selector, Template: template},
+	}
+}
+
+// scaleReplicaSet starts or deletes pods of a ReplicaSet until it has
+// replicas of them.
+func (standIn *apiServerStandIn) scaleReplicaSet(k8sReplicaSet *appsv1.ReplicaSet, replicas int32) {
+	k8sReplicaSet.Spec.Replicas = &replicas
+	k8sReplicaSet.Status.Replicas = replicas
+	for i := int32(0); i < replicas; i++ {
+		name := fmt.Sprintf("%s-%d", k8sReplicaSet.Name, i)
+		if standIn.pods[name] == nil {
+			standIn.pods[name] = newPod(k8sReplicaSet, name)
+		}
+	}
+	for name, k8sPod := range standIn.pods {
+		owner := metav1.GetControllerOf(k8sPod)
+		if owner.UID == k8sReplicaSet.UID && name >= fmt.Sprintf("%s-%d", k8sReplicaSet.Name, replicas) {
+			delete(standIn.pods, name)
+		}
+	}
+}
+
+func newPod(k8sReplicaSet *appsv1.ReplicaSet, name string) *apiv1.Pod {
+	controller := true
+	now := metav1.Now()
+	return &apiv1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         k8sReplicaSet.Namespace,
+			CreationTimestamp: now,
+			Labels:            k8sReplicaSet.Spec.Template.Labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "ReplicaSet",
+				Name:       k8sReplicaSet.Name,
+				UID:        k8sReplicaSet.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: k8sReplicaSet.Spec.Template.Spec,
+		Status: apiv1.PodStatus{
+			Phase:      apiv1.PodRunning,
+			HostIP:     "10.0.0.1",
+			PodIP:      "172.17.0.2",
+			Conditions: []apiv1.PodCondition{{Type: apiv1.PodReady, Status: apiv1.ConditionTrue, LastTransitionTime: now}},
+		},
+	}
+}
+
+func (standIn *apiServerStandIn) replicaSetsOf(k8sDeployment *appsv1.Deployment) []*appsv1.ReplicaSet {
+	var k8sReplicaSets []*appsv1.ReplicaSet
+	for _, name := range sortedKeys(standIn.replicaSets) {
+		k8sReplicaSet := standIn.replicaSets[name]
+		if owner := metav1.GetControllerOf(k8sReplicaSet); owner != nil && owner.UID == k8sDeployment.UID {
+			k8sReplicaSets = append(k8sReplicaSets, k8sReplicaSet)
+		}
+	}
+	return k8sReplicaSets
+}
+
+// podTemplateHash returns a hash of a pod template, which is the same for the
+// template of a ReplicaSet with the hash label removed, as in RollbackSvc.
+func podTemplateHash(template apiv1.PodTemplateSpec) string {
+	template = *template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	bytes, err := json.Marshal(template)
+	Expect(err).ToNot(HaveOccurred())
+	hash := fnv.New32a()
+	hash.Write(bytes)
+	return fmt.Sprintf("%x", hash.Sum32())
+}
+
+// sortedKeys returns the keys of a map of names to objects, in order.
+func sortedKeys(objects interface{}) []string {
+	var keys []string
+	switch objects := objects.(type) {
+	case map[string]*appsv1.Deployment:
+		for key := range objects {
+			keys = append(keys, key)
+		}
+	case map[string]*appsv1.ReplicaSet:
+		for key := range objects {
+			keys = append(keys, key)
+		}
+	case map[string]*apiv1.Pod:
+		for key := range objects {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason metav1.StatusReason, message string) {
+	status := &metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusSuccess,
+		Code:     int32(code),
+		Reason:   reason,
+		Message:  message,
+	}
+	if code >= 300 {
+		status.Status = metav1.StatusFailure
+	}
+	writeJSON(w, code, status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	bytes, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(bytes)
+}
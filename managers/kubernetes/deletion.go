@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// deletion implements the anysched.Operation interface for the deletion of the
+// Deployment of a service. DestroySvc deletes it in the foreground, so the
+// Deployment is only gone once its ReplicaSets and pods are, and the deletion
+// is done then.
+type deletion struct {
+	manager         *manager
+	name            string
+	timeoutDuration time.Duration
+}
+
+func (del deletion) String() string {
+	return fmt.Sprintf("<kubernetes.deletion name=%q />", del.name)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (del deletion) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = del.name
+	propertiesMap["namespace"] = del.manager.namespace
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deletion
+func (del deletion) GetStatus() (*anysched.OperationStatus, error) {
+	k8sDeployment, err := del.manager.deploymentsClient.Get(del.name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return &anysched.OperationStatus{
+			ClientTime: time.Now(),
+			Msg:        fmt.Sprintf("Deployment %q successfully deleted.", del.name),
+			Done:       true,
+		}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.deletion.GetStatus: deploymentsClient.Get failed")
+	}
+	msg := fmt.Sprintf("Waiting for deployment %q to be deleted: %d replicas are pending termination...",
+		del.name, k8sDeployment.Status.Replicas)
+	return status(k8sDeployment, msg, false), nil
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (del deletion) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, del.timeoutDuration)
+	defer cancel()
+
+	for {
+		status, err := del.GetStatus()
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes.deletion.Wait")
+		}
+		if status.Done {
+			return del, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "kubernetes.deletion.Wait: Timed out after %s", del.timeoutDuration)
+		case <-time.After(pollInterval):
+		}
+	}
+}
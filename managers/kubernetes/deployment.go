@@ -19,6 +19,9 @@ const (
 )
 
 var (
+	// pollInterval is how often Wait checks the status of an operation.
+	pollInterval = 2 * time.Second
+
 	getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
 		if svcCfg.DeployTimeoutDuration == nil {
 			return 60 * time.Second
@@ -221,7 +224,7 @@ func (dep deployment) Wait(ctx context.Context) (result interface{}, err error)
 		select {
 		case <-ctx.Done():
 			return nil, errors.Wrapf(ctx.Err(), "kubernetes.deployment.Wait: Timed out after %s", timeout)
-		case <-time.After(2 * time.Second):
+		case <-time.After(pollInterval):
 			k8sDeployment, err := dep.manager.deploymentsClient.Get(dep.GetName(), metav1.GetOptions{})
 			if err != nil {
 				return nil, errors.Wrap(err, "kubernetes.deployment.Wait: deploymentsClient.Get failed")
@@ -1,114 +1,197 @@
 package kubernetes
 
 import (
-	"os"
-	"path/filepath"
+	"fmt"
+	"math"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	tappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	tcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/msabramo/go-anysched"
 	"github.com/msabramo/go-anysched/utils"
 )
 
+// k8sHostnameLabel is the node label for anysched.ConstraintAttributeHostname
+const k8sHostnameLabel = "kubernetes.io/hostname"
+
+// k8sRevisionAnnotation is the annotation with which the deployment controller
+// numbers the ReplicaSets of a Deployment, and the Deployment itself with the
+// number of its current ReplicaSet.
+const k8sRevisionAnnotation = "deployment.kubernetes.io/revision"
+
 type manager struct {
-	clientset         *kubernetes.Clientset
+	clientset *kubernetes.Clientset
+
+	// namespace is the namespace that the manager works in, or
+	// metav1.NamespaceAll if it lists the services and tasks of all of them.
+	namespace string
+
+	// createNamespace is whether DeploySvc creates the namespace of a service
+	// if it doesn't exist.
+	createNamespace bool
+
 	deploymentsClient tappsv1.DeploymentInterface
+	replicaSetsClient tappsv1.ReplicaSetInterface
 	podsClient        tcorev1.PodInterface
+	servicesClient    tcorev1.ServiceInterface
+	secretsClient     tcorev1.SecretInterface
 	namespacesClient  tcorev1.NamespaceInterface
 }
 
 func init() {
-	anysched.RegisterManagerType("kubernetes", NewManager)
+	anysched.RegisterManagerTypeWithConfig("kubernetes", NewManagerWithConfig)
 }
 
 // NewManager returns a Manager for Kubernetes.
 func NewManager(url string) (anysched.Manager, error) {
-	var (
-		restConfig *rest.Config
-		err        error
-	)
-	if url == "" || url == "kubeconfig" {
-		restConfig, err = configFromKubeconfig()
-	} else {
-		restConfig, err = configFromURL(url)
+	return NewManagerWithConfig(anysched.ManagerConfig{Address: url})
+}
+
+// NewManagerWithConfig returns a Manager for Kubernetes. See parseAddress for
+// the addresses that it takes.
+//
+// The manager works in managerConfig.Namespace, or if it's blank, the
+// namespace of the address, or of the config, which is the namespace of the
+// kubeconfig context, or of the pod for the in-cluster config, or "default".
+func NewManagerWithConfig(managerConfig anysched.ManagerConfig) (anysched.Manager, error) {
+	serverURL, options, err := parseAddress(managerConfig.Address)
+	if err != nil {
+		return nil, err
 	}
+	restConfig, configNamespace, err := getRestConfig(serverURL, options)
 	if err != nil {
 		return nil, err
 	}
+	namespace := managerConfig.Namespace
+	if namespace == "" {
+		namespace = options.Get("namespace")
+	}
+	if namespace == "" {
+		namespace = configNamespace
+	}
 	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.NewManager: kubernetes.NewForConfig failed")
+		return nil, errors.Wrap(err, "kubernetes.NewManagerWithConfig: kubernetes.NewForConfig failed")
 	}
+	return newManager(clientset, namespace, options.Get("createNamespace") == "true"), nil
+}
 
-	mgr := &manager{
+// newManager returns a manager that works in namespace, or in all of them if
+// it is anysched.AllNamespaces.
+func newManager(clientset *kubernetes.Clientset, namespace string, createNamespace bool) *manager {
+	if namespace == anysched.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	return &manager{
 		clientset:         clientset,
-		deploymentsClient: clientset.AppsV1().Deployments(apiv1.NamespaceDefault),
+		namespace:         namespace,
+		createNamespace:   createNamespace,
+		deploymentsClient: clientset.AppsV1().Deployments(namespace),
+		replicaSetsClient: clientset.AppsV1().ReplicaSets(namespace),
 		namespacesClient:  clientset.CoreV1().Namespaces(),
-		podsClient:        clientset.CoreV1().Pods(apiv1.NamespaceDefault),
+		podsClient:        clientset.CoreV1().Pods(namespace),
+		servicesClient:    clientset.CoreV1().Services(namespace),
+		secretsClient:     clientset.CoreV1().Secrets(namespace),
 	}
-	return mgr, nil
 }
 
-func configFromKubeconfig() (*rest.Config, error) {
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", getKubeconfig())
+// Svcs returns info about all running services
+func (mgr *manager) Svcs() ([]anysched.Svc, error) {
+	k8sDeploymentList, err := mgr.deploymentsClient.List(metav1.ListOptions{})
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.configFromKubeconfig: clientcmd.BuildConfigFromFlags failed")
+		return nil, errors.Wrap(err, "kubernetes.manager.Svcs: deploymentsClient.List failed")
 	}
-	return config, nil
+	return svcsFromK8sDeploymentList(k8sDeploymentList), nil
 }
 
-func configFromURL(url string) (*rest.Config, error) {
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags(url, getKubeconfig())
+// SvcsWithSelector returns info about the running services whose labels match
+// selector. Kubernetes does the filtering, since the labels of a service are
+// the labels of its deployment.
+func (mgr *manager) SvcsWithSelector(selector anysched.Selector) ([]anysched.Svc, error) {
+	k8sDeploymentList, err := mgr.deploymentsClient.List(metav1.ListOptions{LabelSelector: selector.String()})
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.configFromURL: clientcmd.BuildConfigFromFlags failed")
+		return nil, errors.Wrap(err, "kubernetes.manager.SvcsWithSelector: deploymentsClient.List failed")
 	}
-	return config, nil
+	return svcsFromK8sDeploymentList(k8sDeploymentList), nil
 }
 
-func getKubeconfig() string {
-	if os.Getenv("KUBECONFIG") != "" {
-		return os.Getenv("KUBECONFIG")
+func svcsFromK8sDeploymentList(k8sDeploymentList *appsv1.DeploymentList) []anysched.Svc {
+	svcs := make([]anysched.Svc, len(k8sDeploymentList.Items))
+	for i := range k8sDeploymentList.Items {
+		svcs[i] = svcFromK8sDeployment(&k8sDeploymentList.Items[i])
 	}
-	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	return svcs
 }
 
-// Svcs returns info about all running services
-func (mgr *manager) Svcs() ([]anysched.Svc, error) {
-	k8sDeploymentList, err := mgr.deploymentsClient.List(metav1.ListOptions{})
+func svcFromK8sDeployment(k8sDeployment *appsv1.Deployment) anysched.Svc {
+	tasksRunning := int(k8sDeployment.Status.Replicas)
+	tasksHealthy := int(k8sDeployment.Status.AvailableReplicas)
+	tasksUnhealthy := int(k8sDeployment.Status.UnavailableReplicas)
+	creationTimestamp := k8sDeployment.GetCreationTimestamp().Time
+	return anysched.Svc{
+		ID:             k8sDeployment.GetName(),
+		Namespace:      k8sDeployment.GetNamespace(),
+		TasksRunning:   &tasksRunning,
+		TasksHealthy:   &tasksHealthy,
+		TasksUnhealthy: &tasksUnhealthy,
+		CreationTime:   &creationTimestamp,
+		Labels:         k8sDeployment.GetLabels(),
+	}
+}
+
+// Svc returns a service with the SvcCfg of its Deployment, the host ports of
+// its companion Service, and the revision of its Deployment. Native is the
+// *appsv1.Deployment.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	if err := mgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.Svc: checkNamespace failed")
+	}
+	k8sDeployment, err := mgr.deploymentsClient.Get(svcID, metav1.GetOptions{})
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.manager.Svcs: deploymentsClient.List failed")
+		return nil, errors.Wrap(err, "kubernetes.manager.Svc: deploymentsClient.Get failed")
 	}
-	svcs := make([]anysched.Svc, len(k8sDeploymentList.Items))
-	for i := range k8sDeploymentList.Items {
-		k8sDeployment := k8sDeploymentList.Items[i]
-		tasksRunning := int(k8sDeployment.Status.Replicas)
-		tasksHealthy := int(k8sDeployment.Status.AvailableReplicas)
-		tasksUnhealthy := int(k8sDeployment.Status.UnavailableReplicas)
-		creationTimestamp := k8sDeployment.GetCreationTimestamp().Time
-		svcs[i] = anysched.Svc{
-			ID:             k8sDeployment.GetName(),
-			TasksRunning:   &tasksRunning,
-			TasksHealthy:   &tasksHealthy,
-			TasksUnhealthy: &tasksUnhealthy,
-			CreationTime:   &creationTimestamp,
+	svcCfg := svcCfgFromK8sDeployment(k8sDeployment)
+	k8sService, err := mgr.servicesClient.Get(svcID, metav1.GetOptions{})
+	if err == nil {
+		setHostPortsFromK8sService(svcCfg.Ports, k8sService)
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "kubernetes.manager.Svc: servicesClient.Get failed")
+	}
+	svcDetail := &anysched.SvcDetail{Svc: svcFromK8sDeployment(k8sDeployment), SvcCfg: svcCfg, Native: k8sDeployment}
+	if revision := k8sRevision(k8sDeployment.ObjectMeta); revision > 0 {
+		svcDetail.Revision = &revision
+	}
+	return svcDetail, nil
+}
+
+// setHostPortsFromK8sService sets the HostPort of each of portCfgs to the port
+// of the Service that targets it.
+func setHostPortsFromK8sService(portCfgs []anysched.PortCfg, k8sService *apiv1.Service) {
+	for i := range portCfgs {
+		for _, k8sServicePort := range k8sService.Spec.Ports {
+			if k8sServicePort.TargetPort.IntValue() == portCfgs[i].ContainerPort &&
+				strings.EqualFold(string(k8sServicePort.Protocol), portCfgs[i].ProtocolOrDefault()) {
+				portCfgs[i].HostPort = int(k8sServicePort.Port)
+			}
 		}
 	}
-	return svcs, nil
 }
 
 // Tasks returns info about all running tasks
@@ -124,9 +207,14 @@ func (mgr *manager) Tasks() ([]anysched.Task, error) {
 	return tasks, nil
 }
 
-// SvcTasks returns info about the running tasks for a service
+// SvcTasks returns info about the running tasks for a service, in the namespace
+// of svcCfg if it has one.
 func (mgr *manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
-	k8sPodList, err := mgr.podsClient.List(metav1.ListOptions{LabelSelector: "appID=" + svcCfg.ID})
+	podsClient := mgr.podsClient
+	if svcCfg.Namespace != "" {
+		podsClient = mgr.inNamespace(svcCfg.Namespace).podsClient
+	}
+	k8sPodList, err := podsClient.List(metav1.ListOptions{LabelSelector: "appID=" + svcCfg.ID})
 	if err != nil {
 		return nil, errors.Wrapf(err, "kubernetes.manager.SvcTasks: podsClient.List failed for svcCfg.ID = %q", svcCfg.ID)
 	}
@@ -145,6 +233,8 @@ func taskFromK8SPod(k8sPod apiv1.Pod) *anysched.Task {
 	}
 	return &anysched.Task{
 		Name:      k8sPod.GetName(),
+		AppID:     k8sPod.GetLabels()["appID"],
+		Namespace: k8sPod.GetNamespace(),
 		HostIP:    k8sPod.Status.HostIP,
 		TaskIP:    k8sPod.Status.PodIP,
 		ReadyTime: &cond.LastTransitionTime.Time,
@@ -159,27 +249,471 @@ func sortTasksByReadyTime(tasks []anysched.Task) {
 	})
 }
 
-// DeploySvc takes a SvcCfg and deploys it, returning an Operation.
+// DeploySvc takes a SvcCfg and deploys it in the namespace of svcCfg, or the
+// manager's if it has none, returning an Operation. If the service has ports,
+// it also creates a Service with the same name that exposes them.
 func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DeploySvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DeploySvc: validateSvcCfg failed")
+	}
+	svcMgr, err := mgr.svcManager(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DeploySvc: svcManager failed")
+	}
+	return svcMgr.createSvc(svcCfg)
+}
+
+// createSvc creates the Deployment of a service, and the Service that exposes
+// its ports if it has any.
+func (mgr *manager) createSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
 	k8sDeploymentRequest, err := getK8sDeploymentRequest(svcCfg)
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.manager.DeploySvc: getK8sDeploymentRequest failed")
+		return nil, errors.Wrap(err, "kubernetes.manager.createSvc: getK8sDeploymentRequest failed")
 	}
 	k8sDeployment, err := mgr.deploymentsClient.Create(k8sDeploymentRequest)
 	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.manager.DeploySvc: deploymentsClient.Create failed")
+		return nil, errors.Wrap(err, "kubernetes.manager.createSvc: deploymentsClient.Create failed")
+	}
+	if len(svcCfg.Ports) > 0 {
+		k8sServiceRequest, err := getK8sServiceRequest(svcCfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes.manager.createSvc: getK8sServiceRequest failed")
+		}
+		_, err = mgr.servicesClient.Create(k8sServiceRequest)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes.manager.createSvc: servicesClient.Create failed")
+		}
+	}
+
+	return deployment{manager: mgr, Deployment: k8sDeployment, svcCfg: svcCfg}, nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and updates its
+// Deployment, which replaces its pods following the Deployment's strategy
+// (a rolling update by default), returning an Operation. It creates, updates
+// or deletes the Service that exposes its ports to match.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.UpdateSvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.UpdateSvc: validateSvcCfg failed")
+	}
+	svcMgr, err := mgr.svcManager(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.UpdateSvc: svcManager failed")
+	}
+	return svcMgr.updateSvc(svcCfg)
+}
+
+// updateSvc updates the Deployment of a service, and its Service.
+func (mgr *manager) updateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	k8sDeploymentRequest, err := getK8sDeploymentRequest(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.updateSvc: getK8sDeploymentRequest failed")
+	}
+	oldK8sDeployment, err := mgr.deploymentsClient.Get(svcCfg.ID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.updateSvc: deploymentsClient.Get failed")
+	}
+	// The resource version makes the update fail if someone else changed the
+	// Deployment in the meantime, rather than undo their change.
+	k8sDeploymentRequest.ResourceVersion = oldK8sDeployment.ResourceVersion
+	k8sDeployment, err := mgr.deploymentsClient.Update(k8sDeploymentRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.updateSvc: deploymentsClient.Update failed")
+	}
+	if err := mgr.updateK8sService(svcCfg); err != nil {
+		return nil, err
+	}
+	return deployment{manager: mgr, Deployment: k8sDeployment, svcCfg: svcCfg}, nil
+}
+
+// updateK8sService makes the Service of a service match its ports: it creates
+// or updates the Service if the service has ports, and deletes it otherwise.
+func (mgr *manager) updateK8sService(svcCfg anysched.SvcCfg) error {
+	oldK8sService, err := mgr.servicesClient.Get(svcCfg.ID, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		oldK8sService = nil
+	} else if err != nil {
+		return errors.Wrap(err, "kubernetes.manager.updateK8sService: servicesClient.Get failed")
+	}
+	if len(svcCfg.Ports) == 0 {
+		if oldK8sService == nil {
+			return nil
+		}
+		if err := mgr.servicesClient.Delete(svcCfg.ID, &metav1.DeleteOptions{}); err != nil {
+			return errors.Wrap(err, "kubernetes.manager.updateK8sService: servicesClient.Delete failed")
+		}
+		return nil
+	}
+	k8sServiceRequest, err := getK8sServiceRequest(svcCfg)
+	if err != nil {
+		return errors.Wrap(err, "kubernetes.manager.updateK8sService: getK8sServiceRequest failed")
 	}
+	if oldK8sService == nil {
+		if _, err := mgr.servicesClient.Create(k8sServiceRequest); err != nil {
+			return errors.Wrap(err, "kubernetes.manager.updateK8sService: servicesClient.Create failed")
+		}
+		return nil
+	}
+	// The cluster IP of a Service cannot change.
+	k8sServiceRequest.ResourceVersion = oldK8sService.ResourceVersion
+	k8sServiceRequest.Spec.ClusterIP = oldK8sService.Spec.ClusterIP
+	if _, err := mgr.servicesClient.Update(k8sServiceRequest); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.updateK8sService: servicesClient.Update failed")
+	}
+	return nil
+}
 
+// ScaleSvc changes the number of replicas of a service's Deployment to count
+// through its scale subresource, which leaves the rest of the Deployment alone,
+// returning an Operation.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if err := mgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.ScaleSvc: checkNamespace failed")
+	}
+	// The typed client of apps/v1 has no methods for the scale subresource.
+	restClient := mgr.clientset.AppsV1().RESTClient()
+	scale := &autoscalingv1.Scale{}
+	err := restClient.Get().Namespace(mgr.namespace).Resource("deployments").Name(svcID).
+		SubResource("scale").Do().Into(scale)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.ScaleSvc: getting the scale of the deployment failed")
+	}
+	// The scale keeps the resource version of the Deployment, as in updateSvc
+	scale.Spec.Replicas = int32(count)
+	err = restClient.Put().Namespace(mgr.namespace).Resource("deployments").Name(svcID).
+		SubResource("scale").Body(scale).Do().Into(scale)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.ScaleSvc: updating the scale of the deployment failed")
+	}
+	k8sDeployment, err := mgr.deploymentsClient.Get(svcID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.ScaleSvc: deploymentsClient.Get failed")
+	}
+	svcCfg := anysched.SvcCfg{ID: svcID, Count: count}
 	return deployment{manager: mgr, Deployment: k8sDeployment, svcCfg: svcCfg}, nil
 }
 
-// DestroySvc destroys a service.
+// SvcHistory returns the revisions of a service, oldest first, which are the
+// ReplicaSets of its Deployment that the deployment controller keeps, up to the
+// Deployment's revisionHistoryLimit.
+func (mgr *manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	if err := mgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.SvcHistory: checkNamespace failed")
+	}
+	k8sDeployment, err := mgr.deploymentsClient.Get(svcID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.SvcHistory: deploymentsClient.Get failed")
+	}
+	k8sReplicaSets, err := mgr.k8sReplicaSetsOf(k8sDeployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.SvcHistory")
+	}
+	currentRevision := k8sRevision(k8sDeployment.ObjectMeta)
+	revisions := make([]anysched.SvcRevision, len(k8sReplicaSets))
+	for i, k8sReplicaSet := range k8sReplicaSets {
+		creationTimestamp := k8sReplicaSet.GetCreationTimestamp().Time
+		revisions[i] = anysched.SvcRevision{
+			Revision:     k8sRevision(k8sReplicaSet.ObjectMeta),
+			Version:      k8sReplicaSet.Name,
+			CreationTime: &creationTimestamp,
+			Current:      k8sRevision(k8sReplicaSet.ObjectMeta) == currentRevision,
+		}
+		if containers := k8sReplicaSet.Spec.Template.Spec.Containers; len(containers) > 0 {
+			revisions[i].Image = containers[0].Image
+		}
+	}
+	return revisions, nil
+}
+
+// RollbackSvc updates the Deployment of a service to the pod template of the
+// ReplicaSet of one of its revisions, like "kubectl rollout undo", returning
+// an Operation. The count of the service, and its companion Service, are left
+// as they are. The deployment controller gives the ReplicaSet the next
+// revision number.
+func (mgr *manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	if err := mgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.RollbackSvc: checkNamespace failed")
+	}
+	k8sDeployment, err := mgr.deploymentsClient.Get(svcID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.RollbackSvc: deploymentsClient.Get failed")
+	}
+	if k8sRevision(k8sDeployment.ObjectMeta) == revision {
+		return nil, fmt.Errorf("kubernetes.manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	}
+	k8sReplicaSets, err := mgr.k8sReplicaSetsOf(k8sDeployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.RollbackSvc")
+	}
+	var k8sReplicaSet *appsv1.ReplicaSet
+	for i := range k8sReplicaSets {
+		if k8sRevision(k8sReplicaSets[i].ObjectMeta) == revision {
+			k8sReplicaSet = &k8sReplicaSets[i]
+		}
+	}
+	if k8sReplicaSet == nil {
+		return nil, fmt.Errorf("kubernetes.manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	}
+	// The deployment controller labels the pods of each ReplicaSet with the
+	// hash of its template, which isn't part of the Deployment's template.
+	k8sDeployment.Spec.Template = *k8sReplicaSet.Spec.Template.DeepCopy()
+	delete(k8sDeployment.Spec.Template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	k8sDeployment, err = mgr.deploymentsClient.Update(k8sDeployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.RollbackSvc: deploymentsClient.Update failed")
+	}
+	return deployment{manager: mgr, Deployment: k8sDeployment, svcCfg: svcCfgFromK8sDeployment(k8sDeployment)}, nil
+}
+
+// k8sReplicaSetsOf returns the ReplicaSets that a Deployment controls, sorted by
+// revision.
+func (mgr *manager) k8sReplicaSetsOf(k8sDeployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(k8sDeployment.Spec.Selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "metav1.LabelSelectorAsSelector failed")
+	}
+	k8sReplicaSetList, err := mgr.replicaSetsClient.List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "replicaSetsClient.List failed")
+	}
+	k8sReplicaSets := []appsv1.ReplicaSet{}
+	for _, k8sReplicaSet := range k8sReplicaSetList.Items {
+		if owner := metav1.GetControllerOf(&k8sReplicaSet); owner != nil && owner.UID == k8sDeployment.UID {
+			k8sReplicaSets = append(k8sReplicaSets, k8sReplicaSet)
+		}
+	}
+	sort.Slice(k8sReplicaSets, func(i, j int) bool {
+		return k8sRevision(k8sReplicaSets[i].ObjectMeta) < k8sRevision(k8sReplicaSets[j].ObjectMeta)
+	})
+	return k8sReplicaSets, nil
+}
+
+// k8sRevision returns the revision number that the deployment controller
+// annotated a Deployment or ReplicaSet with, or 0 if it has none.
+func k8sRevision(objectMeta metav1.ObjectMeta) int64 {
+	revision, err := strconv.ParseInt(objectMeta.Annotations[k8sRevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// DiffSvc returns how the Deployment of the service with the ID of svcCfg
+// differs from the one that DeploySvc would create for svcCfg, or nil if there
+// is no such Deployment.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	svcMgr := mgr
+	if svcCfg.Namespace != "" {
+		svcMgr = mgr.inNamespace(svcCfg.Namespace)
+	}
+	if err := svcMgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DiffSvc: checkNamespace failed")
+	}
+	k8sDeploymentRequest, err := getK8sDeploymentRequest(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DiffSvc: getK8sDeploymentRequest failed")
+	}
+	k8sDeployment, err := svcMgr.deploymentsClient.Get(svcCfg.ID, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DiffSvc: deploymentsClient.Get failed")
+	}
+	svcDiff := anysched.DiffSvcCfgs(svcCfgFromK8sDeployment(k8sDeployment),
+		svcCfgFromK8sDeployment(k8sDeploymentRequest))
+	svcDiff.Unknown = anysched.UnknownSvcSettings(svcCfg, svcCfgFromK8sDeploymentSettings...)
+	return svcDiff, nil
+}
+
+// svcCfgFromK8sDeploymentSettings is the settings that svcCfgFromK8sDeployment
+// reads back from a Deployment.
+var svcCfgFromK8sDeploymentSettings = []string{"image", "count", "env", "labels", "ports", "resources"}
+
+// svcCfgFromK8sDeployment returns a SvcCfg with the namespace, labels and
+// count of a Deployment, and the image, environment variables, ports and
+// resources of its main container. Environment variables from secrets are left
+// out, and the host ports are in the companion Service.
+func svcCfgFromK8sDeployment(k8sDeployment *appsv1.Deployment) anysched.SvcCfg {
+	svcCfg := anysched.SvcCfg{
+		ID:        k8sDeployment.Name,
+		Namespace: k8sDeployment.Namespace,
+		Labels:    k8sDeployment.Labels,
+	}
+	if k8sDeployment.Spec.Replicas != nil {
+		svcCfg.Count = int(*k8sDeployment.Spec.Replicas)
+	}
+	containers := k8sDeployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return svcCfg
+	}
+	svcCfg.Image = containers[0].Image
+	for _, k8sContainerPort := range containers[0].Ports {
+		svcCfg.Ports = append(svcCfg.Ports, anysched.PortCfg{
+			Name:          k8sContainerPort.Name,
+			ContainerPort: int(k8sContainerPort.ContainerPort),
+			Protocol:      strings.ToLower(string(k8sContainerPort.Protocol)),
+		})
+	}
+	for _, envVar := range containers[0].Env {
+		if envVar.ValueFrom != nil {
+			continue
+		}
+		if svcCfg.Env == nil {
+			svcCfg.Env = map[string]string{}
+		}
+		svcCfg.Env[envVar.Name] = envVar.Value
+	}
+	svcCfg.Resources = resourcesFromK8sResourceRequirements(containers[0].Resources)
+	return svcCfg
+}
+
+// resourcesFromK8sResourceRequirements returns the resources of a container
+// with its requests and limits.
+func resourcesFromK8sResourceRequirements(requirements apiv1.ResourceRequirements) *anysched.Resources {
+	return &anysched.Resources{
+		CPU:         float64(requirements.Requests.Cpu().MilliValue()) / 1000,
+		CPULimit:    float64(requirements.Limits.Cpu().MilliValue()) / 1000,
+		Memory:      requirements.Requests.Memory().Value(),
+		MemoryLimit: requirements.Limits.Memory().Value(),
+		Disk:        requirements.Requests.StorageEphemeral().Value(),
+	}
+}
+
+// inNamespace returns a manager like mgr that works in namespace.
+func (mgr *manager) inNamespace(namespace string) *manager {
+	return newManager(mgr.clientset, namespace, mgr.createNamespace)
+}
+
+// svcManager returns the manager for the namespace of a service, which is
+// svcCfg.Namespace, or mgr's if it's blank. If createNamespace is set, it
+// creates the namespace if it doesn't exist.
+func (mgr *manager) svcManager(svcCfg anysched.SvcCfg) (*manager, error) {
+	svcMgr := mgr
+	if svcCfg.Namespace != "" {
+		svcMgr = mgr.inNamespace(svcCfg.Namespace)
+	}
+	if err := svcMgr.checkNamespace(); err != nil {
+		return nil, fmt.Errorf("service %q has no namespace, and %s", svcCfg.ID, err)
+	}
+	if svcMgr.createNamespace {
+		if err := svcMgr.createNamespaceIfMissing(); err != nil {
+			return nil, err
+		}
+	}
+	return svcMgr, nil
+}
+
+// checkNamespace returns an error if the manager is in all namespaces, for the
+// methods that need one.
+func (mgr *manager) checkNamespace() error {
+	if mgr.namespace == metav1.NamespaceAll {
+		return fmt.Errorf("the manager is in all namespaces")
+	}
+	return nil
+}
+
+// createNamespaceIfMissing creates the manager's namespace if it doesn't exist.
+// It looks first, so that it only needs permission to create namespaces if one
+// is missing.
+func (mgr *manager) createNamespaceIfMissing() error {
+	_, err := mgr.namespacesClient.Get(mgr.namespace, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "kubernetes.manager.createNamespaceIfMissing: namespacesClient.Get failed")
+	}
+	_, err = mgr.namespacesClient.Create(&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: mgr.namespace}})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "kubernetes.manager.createNamespaceIfMissing: namespacesClient.Create failed")
+	}
+	return nil
+}
+
+// DestroySvc destroys a service, returning an Operation that is done once its
+// pods are gone.
 func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
-	err := mgr.deploymentsClient.Delete(svcID, &metav1.DeleteOptions{})
+	if err := mgr.checkNamespace(); err != nil {
+		return nil, errors.Wrap(err, "kubernetes.manager.DestroySvc: checkNamespace failed")
+	}
+	// Deleting the Deployment in the foreground keeps it around until its
+	// pods are gone, which the deletion waits for.
+	propagationPolicy := metav1.DeletePropagationForeground
+	err := mgr.deploymentsClient.Delete(svcID, &metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
 	if err != nil {
 		return nil, errors.Wrap(err, "kubernetes.manager.DestroySvc: deploymentsClient.Delete failed")
 	}
-	return nil, nil
+	// Services without ports don't have a companion Service
+	err = mgr.servicesClient.Delete(svcID, &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "kubernetes.manager.DestroySvc: servicesClient.Delete failed")
+	}
+	return deletion{manager: mgr, name: svcID, timeoutDuration: 60 * time.Second}, nil
+}
+
+// CreateSecret creates a Secret.
+func (mgr *manager) CreateSecret(secret anysched.Secret) error {
+	if err := secret.Validate(); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.CreateSecret: secret.Validate failed")
+	}
+	if err := mgr.checkNamespace(); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.CreateSecret: checkNamespace failed")
+	}
+	if _, err := mgr.secretsClient.Create(k8sSecret(secret)); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.CreateSecret: secretsClient.Create failed")
+	}
+	return nil
+}
+
+// UpdateSecret replaces the values of an existing Secret.
+func (mgr *manager) UpdateSecret(secret anysched.Secret) error {
+	if err := secret.Validate(); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.UpdateSecret: secret.Validate failed")
+	}
+	if err := mgr.checkNamespace(); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.UpdateSecret: checkNamespace failed")
+	}
+	if _, err := mgr.secretsClient.Update(k8sSecret(secret)); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.UpdateSecret: secretsClient.Update failed")
+	}
+	return nil
+}
+
+// DeleteSecret deletes a Secret.
+func (mgr *manager) DeleteSecret(name string) error {
+	if err := mgr.checkNamespace(); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.DeleteSecret: checkNamespace failed")
+	}
+	if err := mgr.secretsClient.Delete(name, &metav1.DeleteOptions{}); err != nil {
+		return errors.Wrap(err, "kubernetes.manager.DeleteSecret: secretsClient.Delete failed")
+	}
+	return nil
+}
+
+func k8sSecret(secret anysched.Secret) *apiv1.Secret {
+	return &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secret.Name},
+		Type:       apiv1.SecretTypeOpaque,
+		Data:       secret.Data,
+	}
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that Kubernetes
+// cannot express. The Kubernetes API that this manager uses has no topology
+// spread constraints, so tasks cannot be spread by an attribute.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	for _, constraint := range svcCfg.Constraints {
+		if constraint.Operator == anysched.ConstraintSpread {
+			return fmt.Errorf("service %q: Kubernetes cannot spread tasks by %q", svcCfg.ID, constraint.Attribute)
+		}
+	}
+	return nil
 }
 
 func getK8sDeploymentRequest(svcCfg anysched.SvcCfg) (*appsv1.Deployment, error) {
@@ -192,9 +726,267 @@ func getK8sDeploymentRequest(svcCfg anysched.SvcCfg) (*appsv1.Deployment, error)
 	if err != nil {
 		return nil, errors.Wrap(err, "kubernetes.getK8sDeploymentRequest: decodeYAMLOrJSON failed")
 	}
+	setK8sLabels(&k8sDeploymentRequest.ObjectMeta, svcCfg.Labels)
+	setK8sLabels(&k8sDeploymentRequest.Spec.Template.ObjectMeta, svcCfg.Labels)
+	k8sDeploymentRequest.Spec.Template.Spec.NodeSelector = getK8sNodeSelector(svcCfg.Constraints)
+	k8sDeploymentRequest.Spec.Template.Spec.Affinity = getK8sAffinity(svcCfg)
+	container := &k8sDeploymentRequest.Spec.Template.Spec.Containers[0]
+	if svcCfg.Resources != nil {
+		container.Resources = getK8sResourceRequirements(svcCfg.Resources)
+	}
+	container.LivenessProbe = getK8sProbe(svcCfg.HealthCheck)
+	container.ReadinessProbe = getK8sProbe(svcCfg.ReadinessCheck)
+	k8sDeploymentRequest.Spec.Template.Spec.Volumes, container.VolumeMounts = getK8sVolumes(svcCfg.Volumes)
+	addK8sSecretRefs(&k8sDeploymentRequest.Spec.Template.Spec, svcCfg.Secrets)
+	// Appending can move the main container, so this comes after everything
+	// that changes it
+	podSpec := &k8sDeploymentRequest.Spec.Template.Spec
+	podSpec.Containers = append(podSpec.Containers, getK8sContainers(svcCfg.Sidecars)...)
+	podSpec.InitContainers = getK8sContainers(svcCfg.InitContainers)
 	return &k8sDeploymentRequest, nil
 }
 
+// getK8sContainers returns the containers of a pod for sidecars or init
+// containers, or nil if there aren't any.
+func getK8sContainers(containerCfgs []anysched.ContainerCfg) []apiv1.Container {
+	var k8sContainers []apiv1.Container
+	for _, containerCfg := range containerCfgs {
+		k8sContainer := apiv1.Container{
+			Name:    containerCfg.Name,
+			Image:   containerCfg.Image,
+			Command: containerCfg.Command,
+			Args:    containerCfg.Args,
+		}
+		for _, envVar := range containerCfg.EnvVars() {
+			k8sContainer.Env = append(k8sContainer.Env, apiv1.EnvVar{Name: envVar.Name, Value: envVar.Value})
+		}
+		for _, portCfg := range containerCfg.Ports {
+			k8sContainer.Ports = append(k8sContainer.Ports, apiv1.ContainerPort{
+				Name:          portCfg.Name,
+				ContainerPort: int32(portCfg.ContainerPort),
+				Protocol:      apiv1.Protocol(strings.ToUpper(portCfg.ProtocolOrDefault())),
+			})
+		}
+		if containerCfg.Resources != nil {
+			k8sContainer.Resources = getK8sResourceRequirements(containerCfg.Resources)
+		}
+		k8sContainers = append(k8sContainers, k8sContainer)
+	}
+	return k8sContainers
+}
+
+// getK8sVolumes returns the volumes of the pod and the volume mounts of its
+// container for the volumes of a service. Named volumes are references to
+// PersistentVolumeClaims, which must already exist.
+func getK8sVolumes(volumes []anysched.VolumeCfg) ([]apiv1.Volume, []apiv1.VolumeMount) {
+	var (
+		k8sVolumes      []apiv1.Volume
+		k8sVolumeMounts []apiv1.VolumeMount
+	)
+	for i, volumeCfg := range volumes {
+		k8sVolume := apiv1.Volume{Name: fmt.Sprintf("volume-%d", i)}
+		switch volumeCfg.Type {
+		case anysched.VolumeHostPath:
+			k8sVolume.HostPath = &apiv1.HostPathVolumeSource{Path: volumeCfg.Source}
+		case anysched.VolumeNamed:
+			k8sVolume.PersistentVolumeClaim = &apiv1.PersistentVolumeClaimVolumeSource{
+				ClaimName: volumeCfg.Source,
+				ReadOnly:  volumeCfg.ReadOnly,
+			}
+		default:
+			k8sVolume.EmptyDir = &apiv1.EmptyDirVolumeSource{}
+		}
+		k8sVolumes = append(k8sVolumes, k8sVolume)
+		k8sVolumeMounts = append(k8sVolumeMounts, apiv1.VolumeMount{
+			Name:      k8sVolume.Name,
+			MountPath: volumeCfg.MountPath,
+			ReadOnly:  volumeCfg.ReadOnly,
+		})
+	}
+	return k8sVolumes, k8sVolumeMounts
+}
+
+// addK8sSecretRefs exposes the keys of Secrets to the container of a pod:
+// environment variables from secretKeyRefs, and files from secret volumes that
+// each mount one key with a subPath, so that the rest of the directory that
+// the file is in is left alone.
+func addK8sSecretRefs(podSpec *apiv1.PodSpec, secretRefs []anysched.SecretRef) {
+	container := &podSpec.Containers[0]
+	for i, secretRef := range secretRefs {
+		if secretRef.EnvVar != "" {
+			container.Env = append(container.Env, apiv1.EnvVar{
+				Name: secretRef.EnvVar,
+				ValueFrom: &apiv1.EnvVarSource{SecretKeyRef: &apiv1.SecretKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: secretRef.Secret},
+					Key:                  secretRef.Key,
+				}},
+			})
+			continue
+		}
+		name := fmt.Sprintf("secret-%d", i)
+		podSpec.Volumes = append(podSpec.Volumes, apiv1.Volume{
+			Name: name,
+			VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{
+				SecretName: secretRef.Secret,
+				Items:      []apiv1.KeyToPath{{Key: secretRef.Key, Path: secretRef.Key}},
+			}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+			Name:      name,
+			MountPath: secretRef.MountPath,
+			SubPath:   secretRef.Key,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// setK8sLabels adds labels to the labels of an object, without overriding the
+// ones that it already has, e.g. the appID label that pods are selected by.
+func setK8sLabels(objectMeta *metav1.ObjectMeta, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	if objectMeta.Labels == nil {
+		objectMeta.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		if _, ok := objectMeta.Labels[key]; !ok {
+			objectMeta.Labels[key] = value
+		}
+	}
+}
+
+// getK8sNodeSelector returns the node selector for the equals constraints of a
+// service, or nil if there aren't any.
+func getK8sNodeSelector(constraints []anysched.Constraint) map[string]string {
+	var nodeSelector map[string]string
+	for _, constraint := range constraints {
+		if constraint.Operator == anysched.ConstraintEquals {
+			if nodeSelector == nil {
+				nodeSelector = map[string]string{}
+			}
+			nodeSelector[k8sNodeLabel(constraint.Attribute)] = constraint.Values[0]
+		}
+	}
+	return nodeSelector
+}
+
+// k8sNodeSelectorOperators are the node affinity operators for the operators of
+// the constraints that go in node affinity.
+var k8sNodeSelectorOperators = map[string]apiv1.NodeSelectorOperator{
+	anysched.ConstraintNotEquals: apiv1.NodeSelectorOpNotIn,
+	anysched.ConstraintIn:        apiv1.NodeSelectorOpIn,
+}
+
+// getK8sAffinity returns the affinity for the constraints of a service that
+// the node selector can't express, or nil if there aren't any: node affinity
+// for not equals and in constraints, and anti-affinity to the other pods of
+// the service on the same host for unique-per-host. validateSvcCfg makes sure
+// that there are no spread constraints.
+func getK8sAffinity(svcCfg anysched.SvcCfg) *apiv1.Affinity {
+	var (
+		matchExpressions []apiv1.NodeSelectorRequirement
+		affinity         apiv1.Affinity
+	)
+	for _, constraint := range svcCfg.Constraints {
+		if constraint.Operator == anysched.ConstraintUniquePerHost {
+			affinity.PodAntiAffinity = &apiv1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []apiv1.PodAffinityTerm{{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"appID": svcCfg.ID}},
+					TopologyKey:   k8sHostnameLabel,
+				}},
+			}
+		}
+		if operator, ok := k8sNodeSelectorOperators[constraint.Operator]; ok {
+			matchExpressions = append(matchExpressions, apiv1.NodeSelectorRequirement{
+				Key:      k8sNodeLabel(constraint.Attribute),
+				Operator: operator,
+				Values:   constraint.Values,
+			})
+		}
+	}
+	if matchExpressions != nil {
+		affinity.NodeAffinity = &apiv1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{
+				NodeSelectorTerms: []apiv1.NodeSelectorTerm{{MatchExpressions: matchExpressions}},
+			},
+		}
+	}
+	if affinity == (apiv1.Affinity{}) {
+		return nil
+	}
+	return &affinity
+}
+
+// k8sNodeLabel returns the node label for an attribute of a constraint.
+func k8sNodeLabel(attribute string) string {
+	if attribute == anysched.ConstraintAttributeHostname {
+		return k8sHostnameLabel
+	}
+	return attribute
+}
+
+// getK8sProbe returns a probe for a health check, or nil if healthCheck is
+// nil.
+func getK8sProbe(healthCheck *anysched.HealthCheck) *apiv1.Probe {
+	if healthCheck == nil {
+		return nil
+	}
+	probe := &apiv1.Probe{
+		InitialDelaySeconds: int32(healthCheck.GracePeriod.Seconds()),
+		PeriodSeconds:       int32(math.Ceil(healthCheck.IntervalOrDefault().Seconds())),
+		TimeoutSeconds:      int32(math.Ceil(healthCheck.TimeoutOrDefault().Seconds())),
+		FailureThreshold:    int32(healthCheck.FailureThresholdOrDefault()),
+	}
+	switch healthCheck.Kind() {
+	case anysched.HealthCheckHTTP:
+		probe.HTTPGet = &apiv1.HTTPGetAction{Path: healthCheck.HTTPPath, Port: intstr.FromInt(healthCheck.Port)}
+	case anysched.HealthCheckTCP:
+		probe.TCPSocket = &apiv1.TCPSocketAction{Port: intstr.FromInt(healthCheck.Port)}
+	case anysched.HealthCheckCommand:
+		probe.Exec = &apiv1.ExecAction{Command: healthCheck.Command}
+	}
+	return probe
+}
+
+// getK8sResourceRequirements returns the requests and limits of a container.
+// Disk is requested as ephemeral storage.
+func getK8sResourceRequirements(resources *anysched.Resources) apiv1.ResourceRequirements {
+	requests, limits := apiv1.ResourceList{}, apiv1.ResourceList{}
+	setQuantity(requests, apiv1.ResourceCPU, cpuQuantity(resources.CPU))
+	setQuantity(requests, apiv1.ResourceMemory, resource.NewQuantity(resources.Memory, resource.BinarySI))
+	setQuantity(requests, apiv1.ResourceEphemeralStorage, resource.NewQuantity(resources.Disk, resource.BinarySI))
+	setQuantity(limits, apiv1.ResourceCPU, cpuQuantity(resources.CPULimit))
+	setQuantity(limits, apiv1.ResourceMemory, resource.NewQuantity(resources.MemoryLimit, resource.BinarySI))
+	return apiv1.ResourceRequirements{Requests: requests, Limits: limits}
+}
+
+// setQuantity sets a resource in resourceList, unless quantity is zero.
+func setQuantity(resourceList apiv1.ResourceList, name apiv1.ResourceName, quantity *resource.Quantity) {
+	if !quantity.IsZero() {
+		resourceList[name] = *quantity
+	}
+}
+
+// cpuQuantity returns a number of CPU cores as millicores, rounded up.
+func cpuQuantity(cpu float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(math.Ceil(cpu*1000)), resource.DecimalSI)
+}
+
+func getK8sServiceRequest(svcCfg anysched.SvcCfg) (*apiv1.Service, error) {
+	var k8sServiceRequest apiv1.Service
+	data, err := utils.RenderTemplateToBytes("kubernetes-service", serviceYAMLTemplateString, svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.getK8sServiceRequest: RenderTemplateToBytes failed")
+	}
+	err = decodeYAMLOrJSON(data, &k8sServiceRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.getK8sServiceRequest: decodeYAMLOrJSON failed")
+	}
+	setK8sLabels(&k8sServiceRequest.ObjectMeta, svcCfg.Labels)
+	return &k8sServiceRequest, nil
+}
+
 // decodeYAMLOrJSON takes as input `inYAMLOrJSONBytes`: a []byte with YAML or
 // JSON and decodes into the parameter called `out`.
 func decodeYAMLOrJSON(inYAMLOrJSONBytes []byte, out runtime.Object) error {
@@ -224,4 +1016,52 @@ spec:
     spec:
       containers:
         - name: {{.ID}}
-          image: {{.Image}}`
+          image: {{.Image}}
+{{- if .Command}}
+          command:
+{{- range .Command}}
+            - {{printf "%q" .}}
+{{- end}}
+{{- end}}
+{{- if .Args}}
+          args:
+{{- range .Args}}
+            - {{printf "%q" .}}
+{{- end}}
+{{- end}}
+{{- if .Env}}
+          env:
+{{- range .EnvVars}}
+            - name: {{printf "%q" .Name}}
+              value: {{printf "%q" .Value}}
+{{- end}}
+{{- end}}
+{{- if .Ports}}
+          ports:
+{{- range .Ports}}
+            - containerPort: {{.ContainerPort}}
+              protocol: {{if eq .ProtocolOrDefault "udp"}}UDP{{else}}TCP{{end}}
+{{- if .Name}}
+              name: {{printf "%q" .Name}}
+{{- end}}
+{{- end}}
+{{- end}}`
+
+// serviceYAMLTemplateString is for the Service that exposes the ports of a
+// service. Its ports need names, since there can be more than one.
+var serviceYAMLTemplateString = `
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{.ID}}
+spec:
+  selector:
+    appID: {{.ID}}
+  ports:
+{{- range .Ports}}
+    - name: {{if .Name}}{{printf "%q" .Name}}{{else}}{{.ProtocolOrDefault}}-{{.ContainerPort}}{{end}}
+      port: {{if .HostPort}}{{.HostPort}}{{else}}{{.ContainerPort}}{{end}}
+      targetPort: {{.ContainerPort}}
+      protocol: {{if eq .ProtocolOrDefault "udp"}}UDP{{else}}TCP{{end}}
+{{- end}}`
@@ -9,6 +9,8 @@ import (
 	"os"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	. "github.com/onsi/ginkgo"
@@ -34,6 +36,21 @@ func NewTestServerJSONResponses(jsonResponseFilePaths ...string) *httptest.Serve
 	}))
 }
 
+// NewTestServerJSONRoutes returns a test server that responds to requests for
+// each path in routes with the JSON in a file, and records the method and path
+// of each request in requests.
+func NewTestServerJSONRoutes(routes map[string]string, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+		jsonResponseFilePath, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		writeJSONResponseFromFile(w, jsonResponseFilePath)
+	}))
+}
+
 func writeJSONResponseFromFile(w http.ResponseWriter, jsonResponseFilePath string) {
 	bytes, err := ioutil.ReadFile(jsonResponseFilePath)
 	if err != nil {
@@ -79,6 +96,83 @@ var _ = Describe("kubernetes/manager.go", func() {
 			Expect(manager).To(BeNil())
 		})
 
+		It("fails with an unknown option in the address", func() {
+			manager, err := NewManager("http://1.2.3.4:8080?namespaces=payments")
+			Expect(err).To(MatchError(
+				`kubernetes.parseAddress: unknown option "namespaces" in "http://1.2.3.4:8080?namespaces=payments"`))
+			Expect(manager).To(BeNil())
+		})
+
+		Context("with a kubeconfig with more than one context", func() {
+			var (
+				ts            *httptest.Server
+				authorization string
+				path          string
+			)
+
+			// client-go only sends bearer tokens over TLS, and the clusters of the
+			// kubeconfig skip verifying the test server's certificate.
+			BeforeEach(func() {
+				ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					authorization, path = r.Header.Get("Authorization"), r.URL.Path
+					writeJSONResponseFromFile(w, "testdata/deployments_list.json")
+				}))
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("uses the current context", func() {
+				manager, err := NewManager(ts.URL + "?kubeconfig=testdata/contexts.kubeconfig")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = manager.Svcs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authorization).To(Equal("Bearer dev-token"))
+				Expect(path).To(Equal("/apis/apps/v1/namespaces/dev/deployments"))
+			})
+
+			It("uses the user and namespace of another context", func() {
+				manager, err := NewManager(ts.URL + "?kubeconfig=testdata/contexts.kubeconfig&context=prod")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = manager.Svcs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authorization).To(Equal("Bearer deploy-bot-token"))
+				Expect(path).To(Equal("/apis/apps/v1/namespaces/payments/deployments"))
+			})
+
+			It("uses the token in a file", func() {
+				manager, err := NewManager(ts.URL + "?kubeconfig=testdata/contexts.kubeconfig&tokenFile=testdata/token")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = manager.Svcs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authorization).To(Equal("Bearer file-token"))
+			})
+
+			It("fails with a context that does not exist", func() {
+				manager, err := NewManager("kubeconfig?kubeconfig=testdata/contexts.kubeconfig&context=staging")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("kubernetes.getRestConfig: clientConfig.ClientConfig failed"))
+				Expect(manager).To(BeNil())
+			})
+		})
+
+		It("fails with the in-cluster config outside of a cluster", func() {
+			oldHost := os.Getenv("KUBERNETES_SERVICE_HOST")
+			os.Unsetenv("KUBERNETES_SERVICE_HOST")
+			defer func() { os.Setenv("KUBERNETES_SERVICE_HOST", oldHost) }()
+			manager, err := NewManager("in-cluster")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("kubernetes.getInClusterConfig: rest.InClusterConfig failed"))
+			Expect(manager).To(BeNil())
+		})
+
+		It("fails with the in-cluster config and an option of the kubeconfig", func() {
+			manager, err := NewManager("in-cluster?context=prod")
+			Expect(err).To(MatchError(`kubernetes.getInClusterConfig: option "context" only applies to the kubeconfig`))
+			Expect(manager).To(BeNil())
+		})
+
 		It("fails with a non-existent kubeconfig file", func() {
 			manager, err := NewManager("/dev/does-not-exist")
 			Expect(err).To(HaveOccurred())
@@ -147,10 +241,12 @@ var _ = Describe("kubernetes/manager.go", func() {
 				Expect(svcs).ToNot(BeNil())
 				Expect(svcs).To(HaveLen(1))
 				Expect(svcs[0].ID).To(Equal("httpbin"))
+				Expect(svcs[0].Namespace).To(Equal("default"))
 				Expect(*svcs[0].TasksRunning).To(Equal(3))
 				Expect(*svcs[0].TasksHealthy).To(Equal(3))
 				Expect(*svcs[0].TasksUnhealthy).To(Equal(0))
 				Expect((*svcs[0].CreationTime).Format(time.RFC3339)).To(Equal("2018-07-20T11:38:03-07:00"))
+				Expect(svcs[0].Labels).To(Equal(map[string]string{"team": "payments"}))
 			})
 		})
 
@@ -175,6 +271,84 @@ var _ = Describe("kubernetes/manager.go", func() {
 		})
 	})
 
+	Describe("SvcsWithSelector", func() {
+		It("lists the deployments with a label selector", func() {
+			var labelSelector string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				labelSelector = r.URL.Query().Get("labelSelector")
+				writeJSONResponseFromFile(w, "testdata/deployments_list.json")
+			}))
+			defer ts.Close()
+			manager := NewManagerWithTestServer(ts)
+			selector, err := anysched.ParseSelector("team=payments,tier in (web,api)")
+			Expect(err).ToNot(HaveOccurred())
+
+			svcs, err := manager.(anysched.SvcsWithSelectorGetter).SvcsWithSelector(selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(labelSelector).To(Equal("team=payments,tier in (web,api)"))
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ID).To(Equal("httpbin"))
+		})
+	})
+
+	Describe("Svc", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the deployment with the host ports of its Service", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+				"/api/v1/namespaces/default/services/httpbin":          "testdata/service_create_httpbin.json",
+			}, &requests)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(3))
+			Expect(*svcDetail.Svc.TasksHealthy).To(Equal(3))
+			Expect(svcDetail.SvcCfg).To(Equal(anysched.SvcCfg{
+				ID:        "httpbin",
+				Namespace: "default",
+				Image:     "citizenstig/httpbin:latest",
+				Count:     3,
+				Ports:     []anysched.PortCfg{{Name: "http", ContainerPort: 8000, Protocol: "tcp", HostPort: 80}},
+				Labels:    map[string]string{"team": "payments"},
+				Resources: &anysched.Resources{},
+			}))
+			Expect(*svcDetail.Revision).To(Equal(int64(1)))
+			Expect(svcDetail.Native).To(BeAssignableToTypeOf(&appsv1.Deployment{}))
+		})
+
+		It("leaves out the host ports if there is no Service", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+			}, &requests)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.SvcCfg.Ports).To(Equal([]anysched.PortCfg{{Name: "http", ContainerPort: 8000, Protocol: "tcp"}}))
+		})
+
+		It("returns an error if the deployment does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, &requests)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("kubernetes.manager.Svc: deploymentsClient.Get failed"))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
 	Describe("Tasks", func() {
 		var (
 			manager anysched.Manager
@@ -318,6 +492,62 @@ var _ = Describe("kubernetes/manager.go", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(deployment).ToNot(BeNil())
 			})
+
+			It("returns an error for a spread constraint", func() {
+				svcCfg.Constraints = []anysched.Constraint{{Attribute: "zone", Operator: anysched.ConstraintSpread}}
+				deployment, err := manager.DeploySvc(svcCfg)
+				Expect(err).To(MatchError(`kubernetes.manager.DeploySvc: validateSvcCfg failed: ` +
+					`service "httpbin": Kubernetes cannot spread tasks by "zone"`))
+				Expect(deployment).To(BeNil())
+			})
+		})
+
+		Context("service with ports", func() {
+			var requests []string
+
+			BeforeEach(func() {
+				requests = nil
+				ts = NewTestServerJSONRoutes(map[string]string{
+					"/apis/apps/v1/namespaces/default/deployments": "testdata/deployment_create.json",
+					"/api/v1/namespaces/default/services":          "testdata/service_create_httpbin.json",
+				}, &requests)
+				manager = NewManagerWithTestServer(ts)
+				svcCfg = anysched.SvcCfg{
+					ID:    "httpbin",
+					Image: "citizenstig/httpbin",
+					Count: 3,
+					Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}},
+				}
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("creates a companion Service", func() {
+				deployment, err := manager.DeploySvc(svcCfg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deployment).ToNot(BeNil())
+				Expect(requests).To(Equal([]string{
+					"POST /apis/apps/v1/namespaces/default/deployments",
+					"POST /api/v1/namespaces/default/services",
+				}))
+			})
+		})
+
+		Context("invalid SvcCfg", func() {
+			It("returns an error without creating anything", func() {
+				manager, err := NewManager("http://127.0.0.1:1")
+				Expect(err).ToNot(HaveOccurred())
+				deployment, err := manager.DeploySvc(anysched.SvcCfg{
+					ID:        "httpbin",
+					Image:     "citizenstig/httpbin",
+					Resources: &anysched.Resources{Memory: 512 << 20, MemoryLimit: 256 << 20},
+				})
+				Expect(err).To(MatchError(`kubernetes.manager.DeploySvc: svcCfg.Validate failed: service "httpbin": ` +
+					`invalid resources: memory limit 268435456 is less than memory request 536870912`))
+				Expect(deployment).To(BeNil())
+			})
 		})
 
 		Context("k8s deployment creation fails with HTTP 500", func() {
@@ -342,6 +572,713 @@ var _ = Describe("kubernetes/manager.go", func() {
 		})
 	})
 
+	Describe("UpdateSvc", func() {
+		var (
+			manager  anysched.Manager
+			ts       *httptest.Server
+			requests []string
+			svcCfg   anysched.SvcCfg
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			svcCfg = anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 3}
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the deployment and its companion Service", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+				"/api/v1/namespaces/default/services/httpbin":          "testdata/service_create_httpbin.json",
+			}, &requests)
+			manager = NewManagerWithTestServer(ts)
+			svcCfg.Ports = []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}}
+			deployment, err := manager.(anysched.SvcUpdater).UpdateSvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment).ToNot(BeNil())
+			Expect(requests).To(Equal([]string{
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin",
+				"PUT /apis/apps/v1/namespaces/default/deployments/httpbin",
+				"GET /api/v1/namespaces/default/services/httpbin",
+				"PUT /api/v1/namespaces/default/services/httpbin",
+			}))
+		})
+
+		It("creates the companion Service of a service that gets ports", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+				"/api/v1/namespaces/default/services":                  "testdata/service_create_httpbin.json",
+			}, &requests)
+			manager = NewManagerWithTestServer(ts)
+			svcCfg.Ports = []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}}
+			_, err := manager.(anysched.SvcUpdater).UpdateSvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(ContainElement("POST /api/v1/namespaces/default/services"))
+		})
+
+		It("deletes the companion Service of a service that no longer has ports", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+				"/api/v1/namespaces/default/services/httpbin":          "testdata/service_create_httpbin.json",
+			}, &requests)
+			manager = NewManagerWithTestServer(ts)
+			_, err := manager.(anysched.SvcUpdater).UpdateSvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(ContainElement("DELETE /api/v1/namespaces/default/services/httpbin"))
+		})
+
+		It("returns an error if the deployment does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, &requests)
+			manager = NewManagerWithTestServer(ts)
+			deployment, err := manager.(anysched.SvcUpdater).UpdateSvc(svcCfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("kubernetes.manager.updateSvc: deploymentsClient.Get failed"))
+			Expect(deployment).To(BeNil())
+			Expect(requests).To(Equal([]string{"GET /apis/apps/v1/namespaces/default/deployments/httpbin"}))
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the scale of the deployment", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin":       "testdata/deployment_get_httpbin.json",
+				"/apis/apps/v1/namespaces/default/deployments/httpbin/scale": "testdata/deployment_scale_httpbin.json",
+			}, &requests)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()["name"]).To(Equal("httpbin"))
+			Expect(requests).To(Equal([]string{
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin/scale",
+				"PUT /apis/apps/v1/namespaces/default/deployments/httpbin/scale",
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin",
+			}))
+		})
+
+		It("returns an error if the deployment does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, &requests)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 5)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("getting the scale of the deployment failed"))
+			Expect(op).To(BeNil())
+			Expect(requests).To(Equal([]string{"GET /apis/apps/v1/namespaces/default/deployments/httpbin/scale"}))
+		})
+	})
+
+	Describe("SvcHistory", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the ReplicaSets of the deployment by revision", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin_revision_2.json",
+				"/apis/apps/v1/namespaces/default/replicasets":         "testdata/replicasets_list_httpbin.json",
+			}, &requests)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(2))
+			Expect(revisions[0].Revision).To(Equal(int64(1)))
+			Expect(revisions[0].Version).To(Equal("httpbin-5d7c976bcd"))
+			Expect(revisions[0].Image).To(Equal("citizenstig/httpbin:latest"))
+			Expect(revisions[0].CreationTime.UTC()).To(Equal(time.Date(2018, 7, 26, 3, 19, 1, 0, time.UTC)))
+			Expect(revisions[0].Current).To(BeFalse())
+			Expect(revisions[1].Revision).To(Equal(int64(2)))
+			Expect(revisions[1].Image).To(Equal("citizenstig/httpbin:v2"))
+			Expect(revisions[1].Current).To(BeTrue())
+		})
+
+		It("returns an error if the deployment does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, &requests)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			_, err := manager.SvcHistory("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("kubernetes.manager.SvcHistory: deploymentsClient.Get failed"))
+		})
+	})
+
+	Describe("RollbackSvc", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin_revision_2.json",
+				"/apis/apps/v1/namespaces/default/replicasets":         "testdata/replicasets_list_httpbin.json",
+			}, &requests)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("updates the deployment to the template of a ReplicaSet", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()["name"]).To(Equal("httpbin"))
+			Expect(requests).To(Equal([]string{
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin",
+				"GET /apis/apps/v1/namespaces/default/replicasets",
+				"PUT /apis/apps/v1/namespaces/default/deployments/httpbin",
+			}))
+		})
+
+		It("returns an error for a revision that does not exist", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 3)
+			Expect(err).To(MatchError(`kubernetes.manager.RollbackSvc: service "httpbin" has no revision 3`))
+			Expect(requests).ToNot(ContainElement("PUT /apis/apps/v1/namespaces/default/deployments/httpbin"))
+		})
+
+		It("returns an error for the current revision", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 2)
+			Expect(err).To(MatchError(`kubernetes.manager.RollbackSvc: service "httpbin" is already at revision 2`))
+		})
+	})
+
+	Describe("DiffSvc", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the settings that differ from the deployment", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/namespaces/default/deployments/httpbin": "testdata/deployment_get_httpbin.json",
+			}, &requests)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcCfg := anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin:latest",
+				Count:  3,
+				Labels: map[string]string{"team": "payments"},
+				Ports:  []anysched.PortCfg{{Name: "http", ContainerPort: 8000}},
+			}
+			svcDiff, err := manager.DiffSvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(BeEmpty())
+			Expect(svcDiff.Unknown).To(BeEmpty())
+			svcCfg.Image = "citizenstig/httpbin:v2"
+			svcCfg.Env = map[string]string{"VERSION": "2"}
+			svcCfg.Labels = nil
+			svcCfg.Resources = &anysched.Resources{CPU: 0.25, Memory: 256 << 20}
+			svcCfg.HealthCheck = &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000}
+			svcDiff, err = manager.DiffSvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(Equal([]string{"image", "env", "labels", "resources"}))
+			Expect(svcDiff.Unknown).To(Equal([]string{"health-check"}))
+			Expect(requests).To(Equal([]string{
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin",
+				"GET /apis/apps/v1/namespaces/default/deployments/httpbin",
+			}))
+		})
+
+		It("returns nil if the deployment does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, &requests)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff).To(BeNil())
+		})
+	})
+
+	Describe("svcCfgFromK8sDeployment", func() {
+		It("reads back what getK8sDeploymentRequest renders", func() {
+			svcCfg := anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Env:       map[string]string{"PORT": "8000"},
+				Ports:     []anysched.PortCfg{{Name: "http", ContainerPort: 8000, Protocol: "tcp"}},
+				Labels:    map[string]string{"team": "payments"},
+				Secrets:   []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+				Resources: &anysched.Resources{CPU: 0.25, CPULimit: 1, Memory: 256 << 20, Disk: 1 << 30},
+			}
+			k8sDeployment, err := getK8sDeploymentRequest(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcCfgFromK8sDeployment(k8sDeployment)).To(Equal(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Env:       map[string]string{"PORT": "8000"},
+				Ports:     []anysched.PortCfg{{Name: "http", ContainerPort: 8000, Protocol: "tcp"}},
+				Labels:    map[string]string{"team": "payments"},
+				Resources: &anysched.Resources{CPU: 0.25, CPULimit: 1, Memory: 256 << 20, Disk: 1 << 30},
+			}))
+		})
+	})
+
+	Describe("getK8sDeploymentRequest", func() {
+		It("renders the environment variables in order", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 3,
+				Env:   map[string]string{"PORT": "8000", "GREETING": `Hello, "world": #1`},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*k8sDeployment.Spec.Replicas).To(Equal(int32(3)))
+			container := k8sDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("citizenstig/httpbin"))
+			Expect(container.Env).To(HaveLen(2))
+			Expect(container.Env[0].Name).To(Equal("GREETING"))
+			Expect(container.Env[0].Value).To(Equal(`Hello, "world": #1`))
+			Expect(container.Env[1].Name).To(Equal("PORT"))
+			Expect(container.Env[1].Value).To(Equal("8000"))
+		})
+
+		It("mounts host paths, claims and emptyDirs", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeHostPath, Source: "/var/log", MountPath: "/logs", ReadOnly: true},
+					{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data"},
+					{Type: anysched.VolumeScratch, MountPath: "/tmp"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			podSpec := k8sDeployment.Spec.Template.Spec
+			Expect(podSpec.Volumes).To(Equal([]apiv1.Volume{
+				{Name: "volume-0", VolumeSource: apiv1.VolumeSource{HostPath: &apiv1.HostPathVolumeSource{Path: "/var/log"}}},
+				{Name: "volume-1", VolumeSource: apiv1.VolumeSource{
+					PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: "httpbin-data"},
+				}},
+				{Name: "volume-2", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}},
+			}))
+			Expect(podSpec.Containers[0].VolumeMounts).To(Equal([]apiv1.VolumeMount{
+				{Name: "volume-0", MountPath: "/logs", ReadOnly: true},
+				{Name: "volume-1", MountPath: "/data"},
+				{Name: "volume-2", MountPath: "/tmp"},
+			}))
+		})
+
+		It("exposes secrets as environment variables and files", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Env:   map[string]string{"PORT": "8000"},
+				Secrets: []anysched.SecretRef{
+					{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"},
+					{Secret: "tls", Key: "key", MountPath: "/etc/tls/key.pem"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			podSpec := k8sDeployment.Spec.Template.Spec
+			Expect(podSpec.Containers[0].Env).To(Equal([]apiv1.EnvVar{
+				{Name: "PORT", Value: "8000"},
+				{Name: "DB_PASSWORD", ValueFrom: &apiv1.EnvVarSource{SecretKeyRef: &apiv1.SecretKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: "db"},
+					Key:                  "password",
+				}}},
+			}))
+			Expect(podSpec.Volumes).To(Equal([]apiv1.Volume{{
+				Name: "secret-1",
+				VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{
+					SecretName: "tls",
+					Items:      []apiv1.KeyToPath{{Key: "key", Path: "key"}},
+				}},
+			}}))
+			Expect(podSpec.Containers[0].VolumeMounts).To(Equal([]apiv1.VolumeMount{
+				{Name: "secret-1", MountPath: "/etc/tls/key.pem", SubPath: "key", ReadOnly: true},
+			}))
+		})
+
+		It("adds sidecars and init containers to the pod", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+				Sidecars: []anysched.ContainerCfg{{
+					Name:      "log-forwarder",
+					Image:     "fluent/fluent-bit:0.13",
+					Env:       map[string]string{"FLB_LOG_LEVEL": "info"},
+					Ports:     []anysched.PortCfg{{Name: "metrics", ContainerPort: 2020}},
+					Resources: &anysched.Resources{CPU: 0.1},
+				}},
+				InitContainers: []anysched.ContainerCfg{
+					{Name: "migrate", Image: "example/payments:1.2", Args: []string{"migrate", "--yes"}},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			podSpec := k8sDeployment.Spec.Template.Spec
+			Expect(podSpec.Containers).To(HaveLen(2))
+			Expect(podSpec.Containers[0].Name).To(Equal("httpbin"))
+			Expect(podSpec.Containers[0].Env).To(HaveLen(1))
+			sidecar := podSpec.Containers[1]
+			Expect(sidecar.Name).To(Equal("log-forwarder"))
+			Expect(sidecar.Image).To(Equal("fluent/fluent-bit:0.13"))
+			Expect(sidecar.Env).To(Equal([]apiv1.EnvVar{{Name: "FLB_LOG_LEVEL", Value: "info"}}))
+			Expect(sidecar.Ports).To(Equal([]apiv1.ContainerPort{
+				{Name: "metrics", ContainerPort: 2020, Protocol: apiv1.ProtocolTCP},
+			}))
+			Expect(sidecar.Resources.Requests.Cpu().String()).To(Equal("100m"))
+			Expect(podSpec.InitContainers).To(Equal([]apiv1.Container{
+				{Name: "migrate", Image: "example/payments:1.2", Args: []string{"migrate", "--yes"}},
+			}))
+		})
+
+		It("renders the command and arguments", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Command: []string{"gunicorn"},
+				Args:    []string{"--bind=0.0.0.0:8000", "--worker-class", "gevent", "httpbin:app"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			container := k8sDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Command).To(Equal([]string{"gunicorn"}))
+			Expect(container.Args).To(Equal([]string{"--bind=0.0.0.0:8000", "--worker-class", "gevent", "httpbin:app"}))
+		})
+
+		It("renders the container ports", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			ports := k8sDeployment.Spec.Template.Spec.Containers[0].Ports
+			Expect(ports).To(HaveLen(2))
+			Expect(ports[0].Name).To(Equal("http"))
+			Expect(ports[0].ContainerPort).To(Equal(int32(8000)))
+			Expect(ports[0].Protocol).To(Equal(apiv1.ProtocolTCP))
+			Expect(ports[1].Name).To(BeEmpty())
+			Expect(ports[1].ContainerPort).To(Equal(int32(53)))
+			Expect(ports[1].Protocol).To(Equal(apiv1.ProtocolUDP))
+		})
+
+		It("sets the resource requests and limits", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Resources: &anysched.Resources{CPU: 0.25, CPULimit: 1, Memory: 256 << 20, Disk: 1 << 30},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			resources := k8sDeployment.Spec.Template.Spec.Containers[0].Resources
+			Expect(resources.Requests).To(HaveLen(3))
+			Expect(resources.Requests.Cpu().String()).To(Equal("250m"))
+			Expect(resources.Requests.Memory().String()).To(Equal("256Mi"))
+			Expect(resources.Requests.StorageEphemeral().String()).To(Equal("1Gi"))
+			Expect(resources.Limits).To(HaveLen(1))
+			Expect(resources.Limits.Cpu().String()).To(Equal("1"))
+		})
+
+		It("sets the liveness and readiness probes", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				HealthCheck: &anysched.HealthCheck{
+					Port:             8000,
+					Interval:         30 * time.Second,
+					GracePeriod:      time.Minute,
+					FailureThreshold: 5,
+				},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000, Timeout: 1500 * time.Millisecond},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			container := k8sDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.TCPSocket.Port.IntValue()).To(Equal(8000))
+			Expect(container.LivenessProbe.HTTPGet).To(BeNil())
+			Expect(container.LivenessProbe.InitialDelaySeconds).To(Equal(int32(60)))
+			Expect(container.LivenessProbe.PeriodSeconds).To(Equal(int32(30)))
+			Expect(container.LivenessProbe.TimeoutSeconds).To(Equal(int32(5)))
+			Expect(container.LivenessProbe.FailureThreshold).To(Equal(int32(5)))
+			Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/status/200"))
+			Expect(container.ReadinessProbe.HTTPGet.Port.IntValue()).To(Equal(8000))
+			Expect(container.ReadinessProbe.TimeoutSeconds).To(Equal(int32(2)))
+			Expect(container.ReadinessProbe.FailureThreshold).To(Equal(int32(3)))
+		})
+
+		It("runs a command as a probe", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:          "postgres",
+				Image:       "postgres",
+				HealthCheck: &anysched.HealthCheck{Command: []string{"pg_isready", "-U", "postgres"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			container := k8sDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.Exec.Command).To(Equal([]string{"pg_isready", "-U", "postgres"}))
+			Expect(container.ReadinessProbe).To(BeNil())
+		})
+
+		It("sets the labels of the deployment and its pods", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin",
+				Labels: map[string]string{"team": "payments", "appID": "other"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sDeployment.Labels).To(Equal(map[string]string{"team": "payments", "appID": "other"}))
+			Expect(k8sDeployment.Spec.Template.Labels).To(Equal(map[string]string{"team": "payments", "appID": "httpbin"}))
+			Expect(k8sDeployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{"appID": "httpbin"}))
+		})
+
+		It("translates the constraints to a node selector and affinity", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{
+					{Attribute: "disk", Operator: anysched.ConstraintEquals, Values: []string{"ssd"}},
+					{Attribute: "hostname", Operator: anysched.ConstraintNotEquals, Values: []string{"node-1"}},
+					{Attribute: "zone", Operator: anysched.ConstraintIn, Values: []string{"a", "b"}},
+					{Operator: anysched.ConstraintUniquePerHost},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			podSpec := k8sDeployment.Spec.Template.Spec
+			Expect(podSpec.NodeSelector).To(Equal(map[string]string{"disk": "ssd"}))
+			nodeSelector := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+			Expect(nodeSelector.NodeSelectorTerms).To(Equal([]apiv1.NodeSelectorTerm{{
+				MatchExpressions: []apiv1.NodeSelectorRequirement{
+					{Key: "kubernetes.io/hostname", Operator: apiv1.NodeSelectorOpNotIn, Values: []string{"node-1"}},
+					{Key: "zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+				},
+			}}))
+			podAffinityTerms := podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+			Expect(podAffinityTerms).To(HaveLen(1))
+			Expect(podAffinityTerms[0].LabelSelector.MatchLabels).To(Equal(map[string]string{"appID": "httpbin"}))
+			Expect(podAffinityTerms[0].TopologyKey).To(Equal("kubernetes.io/hostname"))
+
+			k8sDeployment, err = getK8sDeploymentRequest(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sDeployment.Spec.Template.Spec.NodeSelector).To(BeNil())
+			Expect(k8sDeployment.Spec.Template.Spec.Affinity).To(BeNil())
+		})
+
+		It("leaves out env if there are no environment variables", func() {
+			k8sDeployment, err := getK8sDeploymentRequest(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sDeployment.Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+		})
+	})
+
+	Describe("getK8sServiceRequest", func() {
+		It("exposes each port on its host port, or else its container port", func() {
+			k8sService, err := getK8sServiceRequest(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sService.Name).To(Equal("httpbin"))
+			Expect(k8sService.Spec.Selector).To(Equal(map[string]string{"appID": "httpbin"}))
+			ports := k8sService.Spec.Ports
+			Expect(ports).To(HaveLen(2))
+			Expect(ports[0].Name).To(Equal("http"))
+			Expect(ports[0].Port).To(Equal(int32(80)))
+			Expect(ports[0].TargetPort.IntValue()).To(Equal(8000))
+			Expect(ports[0].Protocol).To(Equal(apiv1.ProtocolTCP))
+			Expect(ports[1].Name).To(Equal("udp-53"))
+			Expect(ports[1].Port).To(Equal(int32(53)))
+			Expect(ports[1].TargetPort.IntValue()).To(Equal(53))
+			Expect(ports[1].Protocol).To(Equal(apiv1.ProtocolUDP))
+		})
+
+		It("sets the labels of the service", func() {
+			k8sService, err := getK8sServiceRequest(anysched.SvcCfg{
+				ID:     "httpbin",
+				Ports:  []anysched.PortCfg{{ContainerPort: 8000}},
+				Labels: map[string]string{"team": "payments"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sService.Labels).To(Equal(map[string]string{"team": "payments"}))
+		})
+	})
+
+	Describe("SecretsManager", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/api/v1/namespaces/default/secrets":    "testdata/secret_create_db.json",
+				"/api/v1/namespaces/default/secrets/db": "testdata/secret_create_db.json",
+			}, &requests)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("creates a secret", func() {
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.CreateSecret(anysched.Secret{
+				Name: "db",
+				Data: map[string][]byte{"password": []byte("hunter2")},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{"POST /api/v1/namespaces/default/secrets"}))
+		})
+
+		It("updates a secret", func() {
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.UpdateSecret(anysched.Secret{
+				Name: "db",
+				Data: map[string][]byte{"password": []byte("hunter3")},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{"PUT /api/v1/namespaces/default/secrets/db"}))
+		})
+
+		It("deletes a secret", func() {
+			ts.Close()
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/api/v1/namespaces/default/secrets/db": "testdata/secret_delete_db.json",
+			}, &requests)
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			Expect(secretsManager.DeleteSecret("db")).To(Succeed())
+			Expect(requests).To(Equal([]string{"DELETE /api/v1/namespaces/default/secrets/db"}))
+		})
+
+		It("returns an error for an invalid secret", func() {
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.CreateSecret(anysched.Secret{Name: "db"})
+			Expect(err).To(MatchError(`kubernetes.manager.CreateSecret: secret.Validate failed: secret "db" has no values`))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("returns an error if the secret does not exist", func() {
+			secretsManager := NewManagerWithTestServer(ts).(anysched.SecretsManager)
+			err := secretsManager.DeleteSecret("cache")
+			Expect(err.Error()).To(ContainSubstring("kubernetes.manager.DeleteSecret: secretsClient.Delete failed"))
+		})
+	})
+
+	Describe("namespaces", func() {
+		var (
+			ts       *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/apis/apps/v1/deployments":                     "testdata/deployments_list.json",
+				"/apis/apps/v1/namespaces/payments/deployments": "testdata/deployment_create.json",
+				"/apis/apps/v1/namespaces/billing/deployments":  "testdata/deployments_list.json",
+				"/api/v1/pods":       "testdata/pods_list.json",
+				"/api/v1/namespaces": "testdata/namespace_create_payments.json",
+			}, &requests)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("works in the namespace of the address", func() {
+			manager, err := NewManager(ts.URL + "?namespace=billing")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{"GET /apis/apps/v1/namespaces/billing/deployments"}))
+		})
+
+		It("works in the Namespace of the ManagerConfig rather than the address's", func() {
+			manager, err := NewManagerWithConfig(anysched.ManagerConfig{
+				Address:   ts.URL + "?namespace=payments",
+				Namespace: "billing",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]string{"GET /apis/apps/v1/namespaces/billing/deployments"}))
+		})
+
+		It("lists the services and tasks of all namespaces", func() {
+			manager, err := NewManagerWithConfig(anysched.ManagerConfig{Address: ts.URL, Namespace: anysched.AllNamespaces})
+			Expect(err).ToNot(HaveOccurred())
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].Namespace).To(Equal("default"))
+			tasks, err := manager.Tasks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(3))
+			Expect(tasks[0].Namespace).To(Equal("default"))
+			Expect(requests).To(Equal([]string{"GET /apis/apps/v1/deployments", "GET /api/v1/pods"}))
+		})
+
+		It("deploys a service in its namespace", func() {
+			manager := NewManagerWithTestServer(ts)
+			deployment, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:        "httpbin",
+				Namespace: "payments",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment).ToNot(BeNil())
+			Expect(requests).To(Equal([]string{"POST /apis/apps/v1/namespaces/payments/deployments"}))
+		})
+
+		It("creates the namespace of a service if it doesn't exist", func() {
+			manager, err := NewManager(ts.URL + "?namespace=payments&createNamespace=true")
+			Expect(err).ToNot(HaveOccurred())
+			deployment, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment).ToNot(BeNil())
+			Expect(requests).To(Equal([]string{
+				"GET /api/v1/namespaces/payments",
+				"POST /api/v1/namespaces",
+				"POST /apis/apps/v1/namespaces/payments/deployments",
+			}))
+		})
+
+		It("returns an error for a service without a namespace in all namespaces", func() {
+			manager, err := NewManagerWithConfig(anysched.ManagerConfig{Address: ts.URL, Namespace: anysched.AllNamespaces})
+			Expect(err).ToNot(HaveOccurred())
+			deployment, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 3})
+			Expect(err).To(MatchError(`kubernetes.manager.DeploySvc: svcManager failed: ` +
+				`service "httpbin" has no namespace, and the manager is in all namespaces`))
+			Expect(deployment).To(BeNil())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
 	Describe("DestroySvc", func() {
 		var (
 			manager anysched.Manager
@@ -361,7 +1298,7 @@ var _ = Describe("kubernetes/manager.go", func() {
 			It("works", func() {
 				destroy, err := manager.DestroySvc("httpbin")
 				Expect(err).ToNot(HaveOccurred())
-				Expect(destroy).To(BeNil())
+				Expect(destroy.(deletion).String()).To(Equal(`<kubernetes.deletion name="httpbin" />`))
 			})
 		})
 
@@ -0,0 +1,245 @@
+package marathon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	goMarathon "github.com/gambol99/go-marathon"
+	"github.com/pkg/errors"
+)
+
+// marathonApp is a Marathon app with the readiness checks and secrets that
+// go-marathon v0.7.1 has no fields for. go-marathon can neither send them nor
+// read an app whose environment variables refer to secrets, so the manager
+// sends and reads apps with the methods below instead of goMarathonClient's.
+type marathonApp struct {
+	*goMarathon.Application
+	ReadinessChecks []marathonReadinessCheck
+	Secrets         map[string]marathonSecret
+}
+
+// marathonReadinessCheck is a readiness check of a Marathon app.
+type marathonReadinessCheck struct {
+	Name            string `json:"name,omitempty"`
+	Protocol        string `json:"protocol,omitempty"`
+	Path            string `json:"path,omitempty"`
+	PortName        string `json:"portName,omitempty"`
+	IntervalSeconds int    `json:"intervalSeconds,omitempty"`
+	TimeoutSeconds  int    `json:"timeoutSeconds,omitempty"`
+}
+
+// marathonSecret is a secret of a Marathon app, which the environment variable
+// EnvVar refers to by the name of the secret in the app.
+type marathonSecret struct {
+	EnvVar string `json:"-"`
+	Source string `json:"source"`
+}
+
+// MarshalJSON returns the JSON of the go-marathon app with the readiness checks
+// and secrets added to it, and the environment variables that refer to the
+// secrets added to its env.
+func (app *marathonApp) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(app.Application)
+	if err != nil {
+		return nil, err
+	}
+	if len(app.ReadinessChecks) == 0 && len(app.Secrets) == 0 {
+		return data, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if len(app.ReadinessChecks) > 0 {
+		if fields["readinessChecks"], err = json.Marshal(app.ReadinessChecks); err != nil {
+			return nil, err
+		}
+	}
+	if len(app.Secrets) > 0 {
+		env := map[string]interface{}{}
+		if app.Env != nil {
+			for name, value := range *app.Env {
+				env[name] = value
+			}
+		}
+		for secretName, secret := range app.Secrets {
+			env[secret.EnvVar] = map[string]string{"secret": secretName}
+		}
+		if fields["env"], err = json.Marshal(env); err != nil {
+			return nil, err
+		}
+		if fields["secrets"], err = json.Marshal(app.Secrets); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads the JSON of an app into the go-marathon app, apart from
+// the readiness checks, the secrets and the environment variables that refer to
+// them, which it reads into ReadinessChecks and Secrets.
+func (app *marathonApp) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	env := map[string]json.RawMessage{}
+	if err := unmarshalField(fields, "env", &env); err != nil {
+		return err
+	}
+	if err := unmarshalField(fields, "readinessChecks", &app.ReadinessChecks); err != nil {
+		return err
+	}
+	if err := unmarshalField(fields, "secrets", &app.Secrets); err != nil {
+		return err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	app.Application = &goMarathon.Application{}
+	if err := json.Unmarshal(data, app.Application); err != nil {
+		return err
+	}
+	for name, valueData := range env {
+		var value string
+		if err := json.Unmarshal(valueData, &value); err == nil {
+			app.AddEnv(name, value)
+			continue
+		}
+		var secretRef struct {
+			Secret string `json:"secret"`
+		}
+		if err := json.Unmarshal(valueData, &secretRef); err != nil {
+			return err
+		}
+		if secret, ok := app.Secrets[secretRef.Secret]; ok {
+			secret.EnvVar = name
+			app.Secrets[secretRef.Secret] = secret
+		}
+	}
+	return nil
+}
+
+// unmarshalField reads the JSON of a field, if there is one, into value and
+// removes it from fields.
+func unmarshalField(fields map[string]json.RawMessage, name string, value interface{}) error {
+	data, ok := fields[name]
+	if !ok {
+		return nil
+	}
+	delete(fields, name)
+	return json.Unmarshal(data, value)
+}
+
+// app returns an app, like goMarathonClient.ApplicationBy.
+func (mgr *manager) app(svcID string, query url.Values) (*marathonApp, error) {
+	var wrapper struct {
+		App *marathonApp `json:"app"`
+	}
+	if err := mgr.marathonAPICall("GET", appPath(svcID)+queryString(query), nil, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.App, nil
+}
+
+// apps returns all apps, like goMarathonClient.Applications.
+func (mgr *manager) apps(query url.Values) ([]*marathonApp, error) {
+	var wrapper struct {
+		Apps []*marathonApp `json:"apps"`
+	}
+	if err := mgr.marathonAPICall("GET", "/v2/apps"+queryString(query), nil, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.Apps, nil
+}
+
+// appByVersion returns a version of an app, like
+// goMarathonClient.ApplicationByVersion.
+func (mgr *manager) appByVersion(svcID, version string) (*marathonApp, error) {
+	app := &marathonApp{}
+	if err := mgr.marathonAPICall("GET", appPath(svcID)+"/versions/"+version, nil, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// createApp creates an app, like goMarathonClient.CreateApplication.
+func (mgr *manager) createApp(app *marathonApp) (*goMarathon.Application, error) {
+	createdGoMarathonApp := &goMarathon.Application{}
+	if err := mgr.marathonAPICall("POST", "/v2/apps", app, createdGoMarathonApp); err != nil {
+		return nil, err
+	}
+	return createdGoMarathonApp, nil
+}
+
+// updateApp replaces an app, like goMarathonClient.UpdateApplication without
+// force.
+func (mgr *manager) updateApp(app *marathonApp) (*goMarathon.DeploymentID, error) {
+	marathonDeploymentID := &goMarathon.DeploymentID{}
+	if err := mgr.marathonAPICall("PUT", appPath(app.ID), app, marathonDeploymentID); err != nil {
+		return nil, err
+	}
+	return marathonDeploymentID, nil
+}
+
+// appPath returns the path of an app in the Marathon API.
+func appPath(svcID string) string {
+	return "/v2/apps/" + strings.TrimPrefix(svcID, "/")
+}
+
+// svcIDOf returns the ID of the service of an app, which is the ID of the app
+// without the "/" that Marathon prefixes it with.
+func svcIDOf(marathonAppID string) string {
+	return strings.TrimPrefix(marathonAppID, "/")
+}
+
+// queryString returns the query string for query, with its "?", or "" if it is
+// empty.
+func queryString(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// marathonAPICall sends body, if it isn't nil, as JSON to a path of the
+// Marathon API and decodes the JSON of the response into result. It talks to the first Marathon of the
+// address, and returns a *goMarathon.APIError for an error response, like
+// go-marathon does.
+func (mgr *manager) marathonAPICall(method, path string, body, result interface{}) error {
+	var requestBody []byte
+	if body != nil {
+		var err error
+		if requestBody, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "json.Marshal failed")
+		}
+	}
+	marathonURL := strings.TrimSuffix(strings.Split(mgr.url, ",")[0], "/")
+	request, err := http.NewRequest(method, marathonURL+path, bytes.NewReader(requestBody))
+	if err != nil {
+		return errors.Wrap(err, "http.NewRequest failed")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	response, err := mgr.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s: ioutil.ReadAll failed", method, path)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return goMarathon.NewAPIError(response.StatusCode, responseBody)
+	}
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		return errors.Wrapf(err, "%s %s: json.Unmarshal failed", method, path)
+	}
+	return nil
+}
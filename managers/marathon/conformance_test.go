@@ -0,0 +1,312 @@
+package marathon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goMarathon "github.com/gambol99/go-marathon"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+var _ = conformance.DescribeManager("marathon", func() anysched.Manager {
+	return NewManagerWithTestServer(newMarathonStandIn())
+})
+
+// marathonStandIn is a stand-in for the Marathon API, for the conformance
+// suite. It keeps the apps that it is asked to create and every version of
+// them, and runs their tasks right away, so that its deployments are done as
+// soon as they start. Like Marathon, it prefixes app IDs with a "/", updates
+// the settings of an app that are in the body of a PUT and leaves the others,
+// and creates an app that a PUT is for if there isn't one. A new version that
+// only changes the instances of an app starts or kills tasks to match, and one
+// that changes anything else replaces all of its tasks.
+type marathonStandIn struct {
+	mutex  sync.Mutex
+	apps   map[string]*standInApp
+	lastID int
+	clock  time.Time
+}
+
+// standInApp is an app of the marathonStandIn, with its versions, oldest first,
+// which are the JSON fields of the app at each of them.
+type standInApp struct {
+	versions []map[string]json.RawMessage
+	tasks    []*goMarathon.Task
+}
+
+func newMarathonStandIn() *httptest.Server {
+	return httptest.NewServer(&marathonStandIn{apps: map[string]*standInApp{}})
+}
+
+func (standIn *marathonStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	switch path := r.URL.Path; {
+	case path == "/ping":
+		w.Write([]byte("pong"))
+	case path == "/v2/deployments":
+		writeJSON(w, http.StatusOK, []goMarathon.Deployment{})
+	case path == "/v2/tasks":
+		tasks := []*goMarathon.Task{}
+		for _, appID := range standIn.appIDs() {
+			tasks = append(tasks, standIn.apps[appID].tasks...)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"tasks": tasks})
+	case path == "/v2/apps" && r.Method == "GET":
+		apps := []map[string]json.RawMessage{}
+		for _, appID := range standIn.appIDs() {
+			apps = append(apps, standIn.apps[appID].fields())
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apps": apps})
+	case path == "/v2/apps" && r.Method == "POST":
+		standIn.createApp(w, r)
+	case strings.HasPrefix(path, "/v2/apps/"):
+		standIn.serveApp(w, r, strings.Split(strings.TrimPrefix(path, "/v2/apps/"), "/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", path))
+	}
+}
+
+func (standIn *marathonStandIn) createApp(w http.ResponseWriter, r *http.Request) {
+	fields := decodeFields(r)
+	appID := appIDOf(fields)
+	if standIn.apps[appID] != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("An app with id [%s] already exists.", appID))
+		return
+	}
+	fields["id"] = mustMarshal(appID)
+	app := &standInApp{}
+	standIn.apps[appID] = app
+	deploymentID := standIn.addVersion(app, fields)
+	appFields := app.fields()
+	appFields["deployments"] = mustMarshal([]map[string]string{{"id": deploymentID.DeploymentID}})
+	writeJSON(w, http.StatusCreated, appFields)
+}
+
+func (standIn *marathonStandIn) serveApp(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	appID := "/" + pathParts[0]
+	app := standIn.apps[appID]
+	switch {
+	case r.Method == "PUT" && len(pathParts) == 1:
+		standIn.putApp(w, r, appID, app)
+	case app == nil:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("App '%s' does not exist", appID))
+	case r.Method == "GET" && len(pathParts) == 1:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"app": app.fields()})
+	case r.Method == "DELETE" && len(pathParts) == 1:
+		delete(standIn.apps, appID)
+		writeJSON(w, http.StatusOK, standIn.newDeploymentID())
+	case r.Method == "GET" && len(pathParts) == 2 && pathParts[1] == "tasks":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"tasks": app.tasks})
+	case r.Method == "GET" && len(pathParts) == 2 && pathParts[1] == "versions":
+		versions := []string{}
+		for i := len(app.versions) - 1; i >= 0; i-- {
+			versions = append(versions, versionOf(app.versions[i]))
+		}
+		writeJSON(w, http.StatusOK, &goMarathon.ApplicationVersions{Versions: versions})
+	case r.Method == "GET" && len(pathParts) == 3 && pathParts[1] == "versions":
+		if fields := app.version(pathParts[2]); fields != nil {
+			writeJSON(w, http.StatusOK, fields)
+		} else {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("App '%s' does not exist in version %s", appID, pathParts[2]))
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", r.URL.Path))
+	}
+}
+
+// putApp updates an app with the settings in the body of a PUT, or sets it
+// back to the version in the body, or creates it if there is no app.
+func (standIn *marathonStandIn) putApp(w http.ResponseWriter, r *http.Request, appID string,
+	app *standInApp) {
+	fields := decodeFields(r)
+	if app == nil {
+		if _, ok := fields["container"]; !ok {
+			writeError(w, 422, "Object is not valid")
+			return
+		}
+		fields["id"] = mustMarshal(appID)
+		app = &standInApp{}
+		standIn.apps[appID] = app
+		writeJSON(w, http.StatusOK, standIn.addVersion(app, fields))
+		return
+	}
+	var newFields map[string]json.RawMessage
+	if _, ok := fields["version"]; ok {
+		if newFields = app.version(versionOf(fields)); newFields == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("App '%s' does not exist in version %s",
+				appID, versionOf(fields)))
+			return
+		}
+	} else {
+		newFields = map[string]json.RawMessage{}
+		for name, value := range app.versions[len(app.versions)-1] {
+			newFields[name] = value
+		}
+		for name, value := range fields {
+			newFields[name] = value
+		}
+	}
+	newFields["id"] = mustMarshal(appID)
+	writeJSON(w, http.StatusOK, standIn.addVersion(app, newFields))
+}
+
+// addVersion adds a new version of an app with fields, and starts and kills its
+// tasks for it, returning the ID of the deployment that did that.
+func (standIn *marathonStandIn) addVersion(app *standInApp,
+	fields map[string]json.RawMessage) *goMarathon.DeploymentID {
+	version := standIn.now().Format("2006-01-02T15:04:05.000Z")
+	newFields := map[string]json.RawMessage{}
+	for name, value := range fields {
+		newFields[name] = value
+	}
+	newFields["version"] = mustMarshal(version)
+	if len(app.versions) > 0 && !sameConfig(app.versions[len(app.versions)-1], newFields) {
+		app.tasks = nil
+	}
+	app.versions = append(app.versions, newFields)
+	var instances int
+	Expect(json.Unmarshal(newFields["instances"], &instances)).To(Succeed())
+	if len(app.tasks) > instances {
+		app.tasks = app.tasks[:instances]
+	}
+	for len(app.tasks) < instances {
+		app.tasks = append(app.tasks, standIn.newTask(appIDOf(newFields), version))
+	}
+	deploymentID := standIn.newDeploymentID()
+	deploymentID.Version = version
+	return deploymentID
+}
+
+func (standIn *marathonStandIn) newTask(appID, version string) *goMarathon.Task {
+	standIn.lastID++
+	timestamp := standIn.now().Format("2006-01-02T15:04:05.000Z")
+	return &goMarathon.Task{
+		ID:          fmt.Sprintf("%s.%08d", strings.TrimPrefix(appID, "/"), standIn.lastID),
+		AppID:       appID,
+		Host:        "10.0.0.1",
+		Ports:       []int{},
+		SlaveID:     "conformance-agent",
+		StagedAt:    timestamp,
+		StartedAt:   timestamp,
+		State:       "TASK_RUNNING",
+		IPAddresses: []*goMarathon.IPAddress{{IPAddress: "172.17.0.2", Protocol: "IPv4"}},
+		Version:     version,
+	}
+}
+
+func (standIn *marathonStandIn) newDeploymentID() *goMarathon.DeploymentID {
+	standIn.lastID++
+	return &goMarathon.DeploymentID{
+		DeploymentID: fmt.Sprintf("%08d-0000-0000-0000-000000000000", standIn.lastID),
+		Version:      standIn.now().Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+// now returns the time, or a millisecond after the last time that it returned
+// if that isn't earlier, so that versions are in order.
+func (standIn *marathonStandIn) now() time.Time {
+	now := time.Now().UTC()
+	if !now.After(standIn.clock) {
+		now = standIn.clock.Add(time.Millisecond)
+	}
+	standIn.clock = now
+	return now
+}
+
+func (standIn *marathonStandIn) appIDs() []string {
+	appIDs := []string{}
+	for appID := range standIn.apps {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	return appIDs
+}
+
+// fields returns the JSON fields of the current version of an app, with its
+// tasks.
+func (app *standInApp) fields() map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	for name, value := range app.versions[len(app.versions)-1] {
+		fields[name] = value
+	}
+	fields["tasks"] = mustMarshal(app.tasks)
+	fields["tasksRunning"] = mustMarshal(len(app.tasks))
+	fields["tasksStaged"] = mustMarshal(0)
+	fields["tasksHealthy"] = mustMarshal(0)
+	fields["tasksUnhealthy"] = mustMarshal(0)
+	fields["deployments"] = mustMarshal([]string{})
+	return fields
+}
+
+// version returns the JSON fields of a version of an app, or nil if there is
+// no such version.
+func (app *standInApp) version(version string) map[string]json.RawMessage {
+	for _, fields := range app.versions {
+		if versionOf(fields) == version {
+			return fields
+		}
+	}
+	return nil
+}
+
+// sameConfig returns whether two versions of an app differ in nothing but
+// their instances.
+func sameConfig(fields, otherFields map[string]json.RawMessage) bool {
+	config := func(fields map[string]json.RawMessage) []byte {
+		configFields := map[string]json.RawMessage{}
+		for name, value := range fields {
+			if name != "instances" && name != "version" {
+				configFields[name] = value
+			}
+		}
+		return mustMarshal(configFields)
+	}
+	return bytes.Equal(config(fields), config(otherFields))
+}
+
+func decodeFields(r *http.Request) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	Expect(json.NewDecoder(r.Body).Decode(&fields)).To(Succeed())
+	return fields
+}
+
+func appIDOf(fields map[string]json.RawMessage) string {
+	var appID string
+	Expect(json.Unmarshal(fields["id"], &appID)).To(Succeed())
+	return "/" + strings.TrimPrefix(appID, "/")
+}
+
+func versionOf(fields map[string]json.RawMessage) string {
+	var version string
+	Expect(json.Unmarshal(fields["version"], &version)).To(Succeed())
+	return version
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	bytes, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	return bytes
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	bytes := mustMarshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytes)))
+	w.WriteHeader(statusCode)
+	w.Write(bytes)
+}
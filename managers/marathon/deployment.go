@@ -3,6 +3,7 @@ package marathon
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"time"
 
 	goMarathon "github.com/gambol99/go-marathon"
@@ -14,6 +15,7 @@ import (
 type deployment struct {
 	*manager
 	svcID                 string
+	version               string // the app version that an update rolls out
 	marathonDeploymentIDs []string
 	timeoutDuration       time.Duration
 }
@@ -30,18 +32,21 @@ func (d *deployment) GetProperties() (propertiesMap map[string]interface{}) {
 }
 
 func (d *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
-	opts := &goMarathon.GetAppOpts{
-		Embed: []string{
+	query := url.Values{
+		"embed": []string{
 			"app.tasks", "app.counts", "app.deployments",
 			"app.readiness", "app.lastTaskFailure", "app.taskStats",
 		},
 	}
-	goMarathonApp, err := d.manager.goMarathonClient.ApplicationBy(d.svcID, opts)
+	app, err := d.manager.app(d.svcID, query)
 	if err != nil {
-		return nil, errors.Wrapf(err,
-			"marathon.deployment.GetStatus: goMarathonClient.ApplicationBy(%q) failed", d.svcID)
+		return nil, errors.Wrapf(err, "marathon.deployment.GetStatus: manager.app(%q) failed", d.svcID)
 	}
+	goMarathonApp := app.Application
 
+	if d.version != "" {
+		return updateStatus(goMarathonApp, d.version), nil
+	}
 	if !goMarathonApp.AllTaskRunning() {
 		return notAllTasksRunningStatus(goMarathonApp), nil
 	}
@@ -66,6 +71,31 @@ func allTasksRunningStatus(goMarathonApp *goMarathon.Application) *anysched.Oper
 	)
 }
 
+// updateStatus returns the status of the update of goMarathonApp to version,
+// which is done once all of its instances run that version.
+func updateStatus(goMarathonApp *goMarathon.Application, version string) *anysched.OperationStatus {
+	updated, old := 0, 0
+	for _, task := range goMarathonApp.Tasks {
+		switch {
+		case task.Version != version:
+			old++
+		case task.State == "TASK_RUNNING":
+			updated++
+		}
+	}
+	instances := 0
+	if goMarathonApp.Instances != nil {
+		instances = *goMarathonApp.Instances
+	}
+	return statusWithTimestamps(
+		&anysched.OperationStatus{
+			Msg: fmt.Sprintf("%d of %d task(s) running the new version, %d old task(s) left.",
+				updated, instances, old),
+			Done: updated == instances && old == 0,
+		},
+	)
+}
+
 func statusWithTimestamps(status *anysched.OperationStatus) *anysched.OperationStatus {
 	status.ClientTime = time.Now()
 	status.LastUpdateTime = time.Now()
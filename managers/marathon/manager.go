@@ -1,13 +1,20 @@
 package marathon
 
 import (
+	"fmt"
+	"math"
+	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	goMarathon "github.com/gambol99/go-marathon"
 	"github.com/pkg/errors"
 
 	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/utils"
 )
 
 var (
@@ -20,6 +27,7 @@ var (
 
 type manager struct {
 	goMarathonClient goMarathon.Marathon
+	httpClient       *http.Client
 	url              string
 }
 
@@ -35,31 +43,55 @@ func NewManager(url string) (anysched.Manager, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "marathon.NewManager: goMarathon.NewClient failed")
 	}
-	mgr := &manager{goMarathonClient: client, url: url}
+	mgr := &manager{goMarathonClient: client, httpClient: http.DefaultClient, url: url}
 	return mgr, nil
 }
 
 // Svcs returns info about all running services.
 func (mgr *manager) Svcs() ([]anysched.Svc, error) {
-	goMarathonAppsStruct, err := mgr.goMarathonClient.Applications(goMarathonEmbedTasks)
+	goMarathonApps, err := mgr.apps(goMarathonEmbedTasks)
 	if err != nil {
-		return nil, errors.Wrap(err, "marathon.manager.Svcs: goMarathonClient.Svcs failed")
+		return nil, errors.Wrap(err, "marathon.manager.Svcs: mgr.apps failed")
 	}
-	goMarathonAppsSlice := goMarathonAppsStruct.Apps
-	svcs := make([]anysched.Svc, len(goMarathonAppsSlice))
-	for i, goMarathonApp := range goMarathonAppsSlice {
-		svcs[i] = svcFromMarathonApp(goMarathonApp)
+	svcs := make([]anysched.Svc, len(goMarathonApps))
+	for i, goMarathonApp := range goMarathonApps {
+		svcs[i] = svcFromMarathonApp(*goMarathonApp.Application)
 	}
 	return svcs, nil
 }
 
 func svcFromMarathonApp(goMarathonApp goMarathon.Application) anysched.Svc {
-	return anysched.Svc{
-		ID:             goMarathonApp.ID,
+	svc := anysched.Svc{
+		ID:             svcIDOf(goMarathonApp.ID),
 		TasksRunning:   &goMarathonApp.TasksRunning,
 		TasksHealthy:   &goMarathonApp.TasksHealthy,
 		TasksUnhealthy: &goMarathonApp.TasksUnhealthy,
 	}
+	if goMarathonApp.Labels != nil {
+		svc.Labels = *goMarathonApp.Labels
+	}
+	return svc
+}
+
+// Svc returns a service with the SvcCfg of its app. Revision is the number of
+// versions that Marathon remembers, as in SvcHistory, and Native is the
+// *goMarathon.Application.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	goMarathonApp, err := mgr.app(svcID, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.Svc: mgr.app failed")
+	}
+	marathonVersions, err := mgr.marathonVersions(svcID)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.Svc")
+	}
+	revision := int64(len(marathonVersions))
+	return &anysched.SvcDetail{
+		Svc:      svcFromMarathonApp(*goMarathonApp.Application),
+		SvcCfg:   svcCfgFromGoMarathonApp(goMarathonApp),
+		Revision: &revision,
+		Native:   goMarathonApp.Application,
+	}, nil
 }
 
 // SvcTasks returns info about the running tasks for a service.
@@ -115,7 +147,7 @@ func ourTaskForGoMarathonTask(goMarathonTask goMarathon.Task) (*anysched.Task, e
 	}
 	return &anysched.Task{
 		Name:         goMarathonTask.ID,
-		AppID:        goMarathonTask.AppID,
+		AppID:        svcIDOf(goMarathonTask.AppID),
 		HostName:     goMarathonTask.Host,
 		IPAddresses:  ipAddresses,
 		Ports:        goMarathonTask.Ports,
@@ -144,13 +176,235 @@ func parseMarathonTime(marathonTime string) (*time.Time, error) {
 
 // DeploySvc takes a SvcCfg and deploys it, returning an Operation.
 func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
-	goMarathonApp, err := mgr.goMarathonClient.CreateApplication(goMarathonApp(svcCfg))
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.DeploySvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.DeploySvc: validateSvcCfg failed")
+	}
+	goMarathonApp, err := mgr.createApp(goMarathonApp(svcCfg))
 	if err != nil {
-		return nil, errors.Wrap(err, "marathon.manager.DeploySvc: goMarathonClient.CreateApplication failed")
+		return nil, errors.Wrap(err, "marathon.manager.DeploySvc: mgr.createApp failed")
 	}
 	return mgr.newDeploymentFromGoMarathonApp(goMarathonApp), nil
 }
 
+// UpdateSvc takes the new SvcCfg of a deployed service and updates its app,
+// which makes Marathon replace its tasks following the app's upgrade strategy,
+// returning an Operation. Marathon would create an app that doesn't exist, so
+// UpdateSvc checks that it does first.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.UpdateSvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.UpdateSvc: validateSvcCfg failed")
+	}
+	if _, err := mgr.app(svcCfg.ID, nil); err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.UpdateSvc: mgr.app failed")
+	}
+	marathonDeploymentID, err := mgr.updateApp(goMarathonApp(svcCfg))
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.UpdateSvc: mgr.updateApp failed")
+	}
+	op := &deployment{
+		svcID:                 svcCfg.ID,
+		version:               marathonDeploymentID.Version,
+		marathonDeploymentIDs: []string{marathonDeploymentID.DeploymentID},
+		manager:               mgr,
+		timeoutDuration:       60 * time.Second,
+	}
+	return op, nil
+}
+
+// ScaleSvc changes the number of instances of a service's app to count, which
+// makes Marathon start or kill tasks of the app's current version to match,
+// returning an Operation.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("marathon.manager.ScaleSvc: service %q cannot be scaled to %d instances", svcID, count)
+	}
+	force := false
+	marathonDeploymentID, err := mgr.goMarathonClient.ScaleApplicationInstances(svcID, count, force)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.ScaleSvc: goMarathonClient.ScaleApplicationInstances failed")
+	}
+	op := &deployment{
+		svcID:                 svcID,
+		marathonDeploymentIDs: []string{marathonDeploymentID.DeploymentID},
+		manager:               mgr,
+		timeoutDuration:       60 * time.Second,
+	}
+	return op, nil
+}
+
+// SvcHistory returns the revisions of a service, oldest first, which are the
+// versions of its app that Marathon keeps, numbered from the oldest one. Marathon
+// makes a version for every change to an app, scaling included, and forgets the
+// oldest ones past its max_versions, so the number of a version goes down when
+// Marathon forgets older ones. Version is the version's timestamp.
+func (mgr *manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	marathonVersions, err := mgr.marathonVersions(svcID)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.SvcHistory")
+	}
+	revisions := make([]anysched.SvcRevision, len(marathonVersions))
+	for i, marathonVersion := range marathonVersions {
+		goMarathonApp, err := mgr.appByVersion(svcID, marathonVersion)
+		if err != nil {
+			return nil, errors.Wrap(err, "marathon.manager.SvcHistory: mgr.appByVersion failed")
+		}
+		creationTime, err := parseMarathonTime(marathonVersion)
+		if err != nil {
+			return nil, errors.Wrap(err, "marathon.manager.SvcHistory")
+		}
+		revisions[i] = anysched.SvcRevision{
+			Revision:     int64(i + 1),
+			Version:      marathonVersion,
+			Image:        svcCfgFromGoMarathonApp(goMarathonApp).Image,
+			CreationTime: creationTime,
+			Current:      i == len(marathonVersions)-1,
+		}
+	}
+	return revisions, nil
+}
+
+// RollbackSvc sets the app of a service back to one of its versions, as
+// numbered by SvcHistory, returning an Operation. Marathon deploys the whole
+// app of that version, instances included, as a new version.
+func (mgr *manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	marathonVersions, err := mgr.marathonVersions(svcID)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.RollbackSvc")
+	}
+	if revision < 1 || revision > int64(len(marathonVersions)) {
+		return nil, fmt.Errorf("marathon.manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	}
+	if revision == int64(len(marathonVersions)) {
+		return nil, fmt.Errorf("marathon.manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	}
+	marathonVersion := &goMarathon.ApplicationVersion{Version: marathonVersions[revision-1]}
+	marathonDeploymentID, err := mgr.goMarathonClient.SetApplicationVersion(svcID, marathonVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.RollbackSvc: goMarathonClient.SetApplicationVersion failed")
+	}
+	op := &deployment{
+		svcID:                 svcID,
+		version:               marathonDeploymentID.Version,
+		marathonDeploymentIDs: []string{marathonDeploymentID.DeploymentID},
+		manager:               mgr,
+		timeoutDuration:       60 * time.Second,
+	}
+	return op, nil
+}
+
+// marathonVersions returns the versions of an app, oldest first. Marathon lists
+// them newest first, and their timestamps, which all have the same format, sort
+// as strings.
+func (mgr *manager) marathonVersions(svcID string) ([]string, error) {
+	goMarathonAppVersions, err := mgr.goMarathonClient.ApplicationVersions(svcID)
+	if err != nil {
+		return nil, errors.Wrap(err, "goMarathonClient.ApplicationVersions failed")
+	}
+	marathonVersions := append([]string{}, goMarathonAppVersions.Versions...)
+	sort.Strings(marathonVersions)
+	return marathonVersions, nil
+}
+
+// DiffSvc returns how the app of the service with the ID of svcCfg differs from
+// the one that DeploySvc would create for svcCfg, or nil if there is no such
+// app.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	deployedGoMarathonApp, err := mgr.app(svcCfg.ID, nil)
+	if apiErr, ok := err.(*goMarathon.APIError); ok && apiErr.ErrCode == goMarathon.ErrCodeNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "marathon.manager.DiffSvc: mgr.app failed")
+	}
+	svcDiff := anysched.DiffSvcCfgs(svcCfgFromGoMarathonApp(deployedGoMarathonApp),
+		svcCfgFromGoMarathonApp(goMarathonApp(svcCfg)))
+	svcDiff.Unknown = anysched.UnknownSvcSettings(svcCfg, svcCfgFromGoMarathonAppSettings...)
+	return svcDiff, nil
+}
+
+// svcCfgFromGoMarathonAppSettings is the settings that svcCfgFromGoMarathonApp
+// reads back from an app.
+var svcCfgFromGoMarathonAppSettings = []string{"image", "count", "env", "secrets", "labels", "ports", "resources"}
+
+// svcCfgFromGoMarathonApp returns a SvcCfg with the image, count, environment
+// variables, secrets, ports, labels and resources of a Marathon app.
+func svcCfgFromGoMarathonApp(goMarathonApp *marathonApp) anysched.SvcCfg {
+	svcCfg := anysched.SvcCfg{ID: svcIDOf(goMarathonApp.ID)}
+	if goMarathonApp.Instances != nil {
+		svcCfg.Count = *goMarathonApp.Instances
+	}
+	if goMarathonApp.Container != nil && goMarathonApp.Container.Docker != nil {
+		svcCfg.Image = goMarathonApp.Container.Docker.Image
+		svcCfg.Ports = portCfgsFromGoMarathonDocker(goMarathonApp.Container.Docker)
+	}
+	if goMarathonApp.Labels != nil {
+		svcCfg.Labels = *goMarathonApp.Labels
+	}
+	if goMarathonApp.Env != nil && len(*goMarathonApp.Env) > 0 {
+		svcCfg.Env = map[string]string{}
+		for name, value := range *goMarathonApp.Env {
+			svcCfg.Env[name] = value
+		}
+	}
+	svcCfg.Secrets = secretRefsFromMarathonSecrets(goMarathonApp.Secrets)
+	resources := &anysched.Resources{CPU: goMarathonApp.CPUs}
+	if goMarathonApp.Mem != nil {
+		resources.Memory = int64(*goMarathonApp.Mem * (1 << 20))
+	}
+	if goMarathonApp.Disk != nil {
+		resources.Disk = int64(*goMarathonApp.Disk * (1 << 20))
+	}
+	svcCfg.Resources = resources
+	return svcCfg
+}
+
+// secretRefsFromMarathonSecrets returns the secrets of an app that goMarathonApp
+// makes, sorted by the names that it gives them, or nil if there aren't any.
+func secretRefsFromMarathonSecrets(marathonSecrets map[string]marathonSecret) []anysched.SecretRef {
+	secretNames := make([]string, 0, len(marathonSecrets))
+	for secretName := range marathonSecrets {
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+	var secretRefs []anysched.SecretRef
+	for _, secretName := range secretNames {
+		marathonSecret := marathonSecrets[secretName]
+		secretRef := anysched.SecretRef{Secret: marathonSecret.Source, EnvVar: marathonSecret.EnvVar}
+		if i := strings.LastIndex(marathonSecret.Source, "/"); i >= 0 {
+			secretRef.Secret, secretRef.Key = marathonSecret.Source[:i], marathonSecret.Source[i+1:]
+		}
+		secretRefs = append(secretRefs, secretRef)
+	}
+	return secretRefs
+}
+
+// portCfgsFromGoMarathonDocker returns the ports of the port mappings of a
+// Docker container. The names that portName makes up are left out.
+func portCfgsFromGoMarathonDocker(goMarathonDocker *goMarathon.Docker) []anysched.PortCfg {
+	if goMarathonDocker.PortMappings == nil {
+		return nil
+	}
+	var portCfgs []anysched.PortCfg
+	for _, portMapping := range *goMarathonDocker.PortMappings {
+		portCfg := anysched.PortCfg{
+			Name:          portMapping.Name,
+			ContainerPort: portMapping.ContainerPort,
+			HostPort:      portMapping.HostPort,
+			Protocol:      portMapping.Protocol,
+		}
+		if portCfg.Name == portName(anysched.PortCfg{ContainerPort: portCfg.ContainerPort}) {
+			portCfg.Name = ""
+		}
+		portCfgs = append(portCfgs, portCfg)
+	}
+	return portCfgs
+}
+
 // DestroySvc destroys a service.
 func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
 	force := false
@@ -176,15 +430,276 @@ func (mgr *manager) newDeploymentFromGoMarathonApp(goMarathonApp *goMarathon.App
 	}
 }
 
-func goMarathonApp(svcCfg anysched.SvcCfg) *goMarathon.Application {
-	goMarathonApp := goMarathon.NewDockerApplication()
+func goMarathonApp(svcCfg anysched.SvcCfg) *marathonApp {
+	goMarathonApp := &marathonApp{Application: goMarathon.NewDockerApplication()}
 	goMarathonApp.ID = svcCfg.ID
 	goMarathonApp.Container.Docker.Bridged()
 	goMarathonApp.Container.Docker.Container(svcCfg.Image)
 	goMarathonApp.Count(svcCfg.Count)
+	setGoMarathonAppCommand(goMarathonApp.Application, svcCfg)
+	for _, portCfg := range svcCfg.Ports {
+		goMarathonApp.Container.Docker.ExposePort(goMarathon.PortMapping{
+			Name:          portName(portCfg),
+			ContainerPort: portCfg.ContainerPort,
+			HostPort:      portCfg.HostPort,
+			Protocol:      portCfg.ProtocolOrDefault(),
+		})
+	}
+	goMarathonApp.Container.Volumes = goMarathonVolumes(svcCfg.Volumes)
+	for _, envVar := range svcCfg.EnvVars() {
+		goMarathonApp.AddEnv(envVar.Name, envVar.Value)
+	}
+	if len(svcCfg.Secrets) > 0 {
+		goMarathonApp.Secrets = map[string]marathonSecret{}
+	}
+	for i, secretRef := range svcCfg.Secrets {
+		goMarathonApp.Secrets[fmt.Sprintf("secret%d", i)] = marathonSecret{
+			EnvVar: secretRef.EnvVar,
+			Source: secretRef.Secret + "/" + secretRef.Key,
+		}
+	}
+	for key, value := range svcCfg.Labels {
+		goMarathonApp.AddLabel(key, value)
+	}
+	for _, constraint := range svcCfg.Constraints {
+		goMarathonApp.AddConstraint(goMarathonConstraint(constraint)...)
+	}
+	if svcCfg.Resources != nil {
+		setGoMarathonAppResources(goMarathonApp.Application, svcCfg.Resources)
+	}
+	if svcCfg.HealthCheck != nil {
+		goMarathonApp.AddHealthCheck(goMarathonHealthCheck(svcCfg, svcCfg.HealthCheck))
+	}
+	if svcCfg.ReadinessCheck != nil {
+		goMarathonApp.ReadinessChecks = []marathonReadinessCheck{goMarathonReadinessCheck(svcCfg, svcCfg.ReadinessCheck)}
+	}
 	return goMarathonApp
 }
 
+// goMarathonConstraint returns a Marathon constraint. Values of LIKE and UNLIKE
+// constraints are regular expressions that have to match the whole attribute,
+// so values are escaped.
+func goMarathonConstraint(constraint anysched.Constraint) []string {
+	quotedValues := make([]string, len(constraint.Values))
+	for i, value := range constraint.Values {
+		quotedValues[i] = regexp.QuoteMeta(value)
+	}
+	switch constraint.Operator {
+	case anysched.ConstraintEquals:
+		return []string{constraint.Attribute, "CLUSTER", constraint.Values[0]}
+	case anysched.ConstraintNotEquals:
+		return []string{constraint.Attribute, "UNLIKE", quotedValues[0]}
+	case anysched.ConstraintIn:
+		return []string{constraint.Attribute, "LIKE", strings.Join(quotedValues, "|")}
+	case anysched.ConstraintUniquePerHost:
+		return []string{anysched.ConstraintAttributeHostname, "UNIQUE"}
+	}
+	return []string{constraint.Attribute, "GROUP_BY"}
+}
+
+// goMarathonVolumes returns the volumes of an app's container, or nil if there
+// aren't any. Scratch volumes are directories in the task's sandbox, which is
+// what a relative host path is, and named volumes are external volumes that
+// the Docker volume driver interface (DVDI) provides.
+func goMarathonVolumes(volumes []anysched.VolumeCfg) *[]goMarathon.Volume {
+	if len(volumes) == 0 {
+		return nil
+	}
+	goMarathonVolumes := make([]goMarathon.Volume, len(volumes))
+	for i, volumeCfg := range volumes {
+		goMarathonVolume := goMarathon.Volume{ContainerPath: volumeCfg.MountPath, Mode: "RW"}
+		if volumeCfg.ReadOnly {
+			goMarathonVolume.Mode = "RO"
+		}
+		switch volumeCfg.Type {
+		case anysched.VolumeHostPath:
+			goMarathonVolume.HostPath = volumeCfg.Source
+		case anysched.VolumeNamed:
+			goMarathonVolume.External = &goMarathon.ExternalVolume{
+				Name:     volumeCfg.Source,
+				Provider: "dvdi",
+				Options:  &map[string]string{"dvdi/driver": volumeCfg.Driver},
+			}
+		default:
+			goMarathonVolume.HostPath = fmt.Sprintf("volume-%d", i)
+		}
+		goMarathonVolumes[i] = goMarathonVolume
+	}
+	return &goMarathonVolumes
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that Marathon
+// cannot express. Marathon's readiness checks can only use HTTP, with a port
+// that is one of the service's ports, its external volumes need a driver, and
+// this manager can only expose secrets as environment variables. This manager
+// deploys apps, which have one container, so there are no sidecars or init
+// containers either, and Marathon has no namespaces.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	if svcCfg.Namespace != "" {
+		return fmt.Errorf("service %q: Marathon has no namespaces", svcCfg.ID)
+	}
+	if err := validateReadinessCheck(svcCfg); err != nil {
+		return err
+	}
+	if len(svcCfg.Sidecars) > 0 || len(svcCfg.InitContainers) > 0 {
+		return fmt.Errorf("service %q: Marathon apps have one container, so they cannot have sidecars or init containers",
+			svcCfg.ID)
+	}
+	for _, volumeCfg := range svcCfg.Volumes {
+		if volumeCfg.Type == anysched.VolumeNamed && volumeCfg.Driver == "" {
+			return fmt.Errorf("service %q: named volume %q needs a driver for Marathon", svcCfg.ID, volumeCfg.Source)
+		}
+	}
+	return validateSecretRefs(svcCfg)
+}
+
+// validateSecretRefs returns an error if a secret is exposed as a file, since
+// this manager can only expose them as environment variables.
+func validateSecretRefs(svcCfg anysched.SvcCfg) error {
+	for _, secretRef := range svcCfg.Secrets {
+		if secretRef.MountPath != "" {
+			return fmt.Errorf("service %q: secret %q can only be exposed as an environment variable in Marathon",
+				svcCfg.ID, secretRef.String())
+		}
+	}
+	return nil
+}
+
+// validateReadinessCheck returns an error if the readiness check of a service
+// doesn't use HTTP or isn't on one of its ports.
+func validateReadinessCheck(svcCfg anysched.SvcCfg) error {
+	readinessCheck := svcCfg.ReadinessCheck
+	if readinessCheck == nil {
+		return nil
+	}
+	if readinessCheck.Kind() != anysched.HealthCheckHTTP {
+		return fmt.Errorf("service %q: Marathon readiness checks must use HTTP, not %s",
+			svcCfg.ID, readinessCheck.Kind())
+	}
+	if _, portCfg := findPortCfg(svcCfg, readinessCheck.Port); portCfg == nil {
+		return fmt.Errorf("service %q: the port of a Marathon readiness check, %d, must be in Ports",
+			svcCfg.ID, readinessCheck.Port)
+	}
+	return nil
+}
+
+// portName returns the name of the port mapping of a port, which readiness
+// checks refer to it by.
+func portName(portCfg anysched.PortCfg) string {
+	if portCfg.Name != "" {
+		return portCfg.Name
+	}
+	return fmt.Sprintf("port%d", portCfg.ContainerPort)
+}
+
+// findPortCfg returns the index and PortCfg of the port of a service with
+// ContainerPort port, or -1 and nil if there isn't one.
+func findPortCfg(svcCfg anysched.SvcCfg, port int) (int, *anysched.PortCfg) {
+	for i := range svcCfg.Ports {
+		if svcCfg.Ports[i].ContainerPort == port {
+			return i, &svcCfg.Ports[i]
+		}
+	}
+	return -1, nil
+}
+
+// goMarathonHealthCheck returns a Mesos health check. HTTP and TCP checks use
+// the index of their port in the port mappings if it is one of the service's
+// ports.
+func goMarathonHealthCheck(svcCfg anysched.SvcCfg, healthCheck *anysched.HealthCheck) goMarathon.HealthCheck {
+	goMarathonHealthCheck := goMarathon.HealthCheck{
+		GracePeriodSeconds:     int(healthCheck.GracePeriod.Seconds()),
+		IntervalSeconds:        seconds(healthCheck.IntervalOrDefault()),
+		TimeoutSeconds:         seconds(healthCheck.TimeoutOrDefault()),
+		MaxConsecutiveFailures: utils.Iptr(healthCheck.FailureThresholdOrDefault()),
+	}
+	switch healthCheck.Kind() {
+	case anysched.HealthCheckCommand:
+		goMarathonHealthCheck.Protocol = "COMMAND"
+		goMarathonHealthCheck.Command = &goMarathon.Command{Value: shellJoin(healthCheck.Command)}
+		return goMarathonHealthCheck
+	case anysched.HealthCheckHTTP:
+		goMarathonHealthCheck.Protocol = "MESOS_HTTP"
+		goMarathonHealthCheck.Path = utils.Sptr(healthCheck.HTTPPath)
+	case anysched.HealthCheckTCP:
+		goMarathonHealthCheck.Protocol = "MESOS_TCP"
+	}
+	if portIndex, _ := findPortCfg(svcCfg, healthCheck.Port); portIndex >= 0 {
+		goMarathonHealthCheck.PortIndex = utils.Iptr(portIndex)
+	} else {
+		goMarathonHealthCheck.Port = utils.Iptr(healthCheck.Port)
+	}
+	return goMarathonHealthCheck
+}
+
+// goMarathonReadinessCheck returns a readiness check, which Marathon uses
+// while it deploys an app. validateSvcCfg makes sure that it uses HTTP and
+// that its port is one of the service's ports.
+func goMarathonReadinessCheck(svcCfg anysched.SvcCfg, readinessCheck *anysched.HealthCheck) marathonReadinessCheck {
+	_, portCfg := findPortCfg(svcCfg, readinessCheck.Port)
+	return marathonReadinessCheck{
+		Name:            "readiness",
+		Protocol:        "HTTP",
+		Path:            readinessCheck.HTTPPath,
+		PortName:        portName(*portCfg),
+		IntervalSeconds: seconds(readinessCheck.IntervalOrDefault()),
+		TimeoutSeconds:  seconds(readinessCheck.TimeoutOrDefault()),
+	}
+}
+
+// seconds returns a duration in whole seconds, rounded up.
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// setGoMarathonAppResources sets the cpus, mem and disk of an app. Mesos kills
+// a task that uses more memory than its mem, so mem is the memory limit, if
+// there is one.
+func setGoMarathonAppResources(goMarathonApp *goMarathon.Application, resources *anysched.Resources) {
+	if resources.CPU != 0 {
+		goMarathonApp.CPU(resources.CPU)
+	}
+	if memory := resources.EffectiveMemoryLimit(); memory != 0 {
+		goMarathonApp.Memory(mebibytes(memory))
+	}
+	if resources.Disk != 0 {
+		goMarathonApp.Storage(mebibytes(resources.Disk))
+	}
+}
+
+// mebibytes converts bytes to the MiB that Marathon takes.
+func mebibytes(bytes int64) float64 {
+	return float64(bytes) / (1 << 20)
+}
+
+// setGoMarathonAppCommand sets the command of a Marathon app. Marathon can't
+// have both a cmd and args, so if the entrypoint is overridden, the command and
+// its arguments become a cmd, which Marathon runs with a shell.
+func setGoMarathonAppCommand(goMarathonApp *goMarathon.Application, svcCfg anysched.SvcCfg) {
+	switch {
+	case len(svcCfg.Command) > 0:
+		goMarathonApp.Command(shellJoin(append(append([]string{}, svcCfg.Command...), svcCfg.Args...)))
+	case len(svcCfg.Args) > 0:
+		goMarathonApp.AddArgs(svcCfg.Args...)
+	}
+}
+
+var shellSafeWordRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin joins words into a command line for a POSIX shell, quoting the
+// words that need it.
+func shellJoin(words []string) string {
+	quotedWords := make([]string, len(words))
+	for i, word := range words {
+		if shellSafeWordRegexp.MatchString(word) {
+			quotedWords[i] = word
+		} else {
+			quotedWords[i] = "'" + strings.Replace(word, "'", `'"'"'`, -1) + "'"
+		}
+	}
+	return strings.Join(quotedWords, " ")
+}
+
 func marathonDeploymentIDs(goMarathonApp *goMarathon.Application) (marathonDeploymentIDs []string) {
 	marathonDeploymentIDStructs := goMarathonApp.DeploymentIDs()
 	marathonDeploymentIDs = make([]string, len(marathonDeploymentIDStructs))
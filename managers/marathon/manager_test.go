@@ -1,10 +1,75 @@
 package marathon
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	goMarathon "github.com/gambol99/go-marathon"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
 )
 
+// NewTestServerJSONRoutes returns a stand-in for the Marathon API that responds
+// to each method and URL path in routes, e.g. "POST /v2/apps", with the
+// contents of the corresponding JSON file, and with HTTP 404 for any other
+// request. Each request is passed to onRequest, if it is not nil.
+func NewTestServerJSONRoutes(routes map[string]string, onRequest func(r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onRequest != nil {
+			onRequest(r)
+		}
+		jsonResponseFilePath, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"message": "App '/httpbin' does not exist"}`))
+			return
+		}
+		writeJSONResponseFromFile(w, jsonResponseFilePath)
+	}))
+}
+
+func writeJSONResponseFromFile(w http.ResponseWriter, jsonResponseFilePath string) {
+	bytes, err := ioutil.ReadFile(jsonResponseFilePath)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytes)))
+	w.Write(bytes)
+}
+
+func NewManagerWithTestServer(ts *httptest.Server) anysched.Manager {
+	manager, err := NewManager(ts.URL)
+	if err != nil {
+		panic(err)
+	}
+	return manager
+}
+
+// httpbinVersionsRoutes are the routes of the versions of the httpbin app.
+var httpbinVersionsRoutes = map[string]string{
+	"GET /v2/apps/httpbin/versions":                          "testdata/app_httpbin_versions.json",
+	"GET /v2/apps/httpbin/versions/2018-07-26T03:10:10.123Z": "testdata/app_httpbin_version_1.json",
+	"GET /v2/apps/httpbin/versions/2018-07-27T03:10:10.123Z": "testdata/app_httpbin_version_2.json",
+}
+
+// readBody returns an onRequest for NewTestServerJSONRoutes that reads the body
+// of the requests with method into body.
+func readBody(method string, body *string) func(r *http.Request) {
+	return func(r *http.Request) {
+		if r.Method == method {
+			bytes, _ := ioutil.ReadAll(r.Body)
+			*body = string(bytes)
+		}
+	}
+}
+
 var _ = Describe("marathon/manager.go", func() {
 	Describe("NewManager", func() {
 		It("works", func() {
@@ -20,4 +85,577 @@ var _ = Describe("marathon/manager.go", func() {
 			Expect(manager).To(BeNil())
 		})
 	})
+
+	Describe("DeploySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("creates an app with the readiness checks that go-marathon has no field for", func() {
+			var appCreateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"POST /v2/apps": "testdata/app_create_httpbin.json"},
+				readBody("POST", &appCreateBody))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Count:          2,
+				Ports:          []anysched.PortCfg{{ContainerPort: 8000}},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.(*deployment).marathonDeploymentIDs).To(Equal([]string{"5ed4c0c5-9ff8-4a6f-a0cd-f57f59a34b43"}))
+			Expect(appCreateBody).To(ContainSubstring(`"id":"httpbin"`))
+			Expect(appCreateBody).To(ContainSubstring(`"instances":2`))
+			Expect(appCreateBody).To(ContainSubstring(`"readinessChecks":[{"name":"readiness","protocol":"HTTP",` +
+				`"path":"/status/200","portName":"port8000","intervalSeconds":10,"timeoutSeconds":5}]`))
+		})
+
+		It("returns an error if Marathon does", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("marathon.manager.DeploySvc: mgr.createApp failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("replaces the app with the readiness checks that go-marathon has no field for", func() {
+			var appUpdateBody string
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"GET /v2/apps/httpbin": "testdata/app_httpbin.json",
+				"PUT /v2/apps/httpbin": "testdata/app_update_httpbin.json",
+			}, readBody("PUT", &appUpdateBody))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin:2",
+				Count:          2,
+				Ports:          []anysched.PortCfg{{ContainerPort: 8000}},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.(*deployment).version).To(Equal("2018-07-28T03:10:10.123Z"))
+			Expect(op.(*deployment).marathonDeploymentIDs).To(Equal([]string{"83b215a6-4e26-4e44-9333-5c385eda6438"}))
+			Expect(appUpdateBody).To(ContainSubstring(`"image":"citizenstig/httpbin:2"`))
+			Expect(appUpdateBody).To(ContainSubstring(`"readinessChecks":[{"name":"readiness"`))
+		})
+
+		It("returns an error if the app does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:2", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("marathon.manager.UpdateSvc: mgr.app failed"))
+			Expect(err.Error()).To(ContainSubstring("App '/httpbin' does not exist"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("changes the instances of the app", func() {
+			var appScaleBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"PUT /v2/apps/httpbin": "testdata/app_scale_httpbin.json"},
+				readBody("PUT", &appScaleBody))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.(*deployment).svcID).To(Equal("httpbin"))
+			Expect(op.(*deployment).marathonDeploymentIDs).To(Equal([]string{"0b1467fc-d5cd-4bbc-bac2-2805351cee1e"}))
+			Expect(appScaleBody).To(ContainSubstring(`"id":"/httpbin"`))
+			Expect(appScaleBody).To(ContainSubstring(`"instances":3`))
+			Expect(appScaleBody).ToNot(ContainSubstring(`"container"`))
+		})
+
+		It("scales the app to no instances", func() {
+			var appScaleBody string
+			ts = NewTestServerJSONRoutes(map[string]string{"PUT /v2/apps/httpbin": "testdata/app_scale_httpbin.json"},
+				readBody("PUT", &appScaleBody))
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(appScaleBody).To(ContainSubstring(`"instances":0`))
+		})
+
+		It("returns an error for a negative count without changing the app", func() {
+			requests := 0
+			ts = NewTestServerJSONRoutes(map[string]string{}, func(r *http.Request) { requests++ })
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", -1)
+			Expect(err).To(MatchError(`marathon.manager.ScaleSvc: service "httpbin" cannot be scaled to -1 instances`))
+			Expect(op).To(BeNil())
+			Expect(requests).To(Equal(0))
+		})
+
+		It("returns an error if the app does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(
+				"marathon.manager.ScaleSvc: goMarathonClient.ScaleApplicationInstances failed"))
+			Expect(err.Error()).To(ContainSubstring("App '/httpbin' does not exist"))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if a deployment of the app is in progress", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"message": "App is locked by one or more deployments.",` +
+					` "deployments": [{"id": "5ed4c0c5-9ff8-4a6f-a0cd-f57f59a34b43"}]}`))
+			}))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("App is locked by one or more deployments."))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("SvcHistory", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the versions of the app, oldest first", func() {
+			ts = NewTestServerJSONRoutes(httpbinVersionsRoutes, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(2))
+			Expect(revisions[0].Revision).To(Equal(int64(1)))
+			Expect(revisions[0].Version).To(Equal("2018-07-26T03:10:10.123Z"))
+			Expect(revisions[0].Image).To(Equal("citizenstig/httpbin:1"))
+			Expect(revisions[0].CreationTime.Equal(time.Date(2018, 7, 26, 3, 10, 10, 123000000, time.UTC))).To(BeTrue())
+			Expect(revisions[0].Current).To(BeFalse())
+			Expect(revisions[1].Revision).To(Equal(int64(2)))
+			Expect(revisions[1].Version).To(Equal("2018-07-27T03:10:10.123Z"))
+			Expect(revisions[1].Image).To(Equal("citizenstig/httpbin"))
+			Expect(revisions[1].Current).To(BeTrue())
+		})
+
+		It("returns an error if the app does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(
+				"marathon.manager.SvcHistory: goMarathonClient.ApplicationVersions failed"))
+			Expect(revisions).To(BeNil())
+		})
+	})
+
+	Describe("RollbackSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("sets the app back to the version of the revision", func() {
+			var appRollbackBody string
+			routes := map[string]string{"PUT /v2/apps/httpbin": "testdata/app_rollback_httpbin.json"}
+			for route, jsonResponseFilePath := range httpbinVersionsRoutes {
+				routes[route] = jsonResponseFilePath
+			}
+			ts = NewTestServerJSONRoutes(routes, readBody("PUT", &appRollbackBody))
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(appRollbackBody).To(MatchJSON(`{"version": "2018-07-26T03:10:10.123Z"}`))
+			Expect(op.(*deployment).version).To(Equal("2018-07-28T05:10:10.123Z"))
+			Expect(op.(*deployment).marathonDeploymentIDs).To(Equal([]string{"9f2c4c1e-7d34-4b36-a6e1-0c8f2e0d7a55"}))
+		})
+
+		It("returns an error for a revision that the app does not have", func() {
+			ts = NewTestServerJSONRoutes(httpbinVersionsRoutes, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			for _, revision := range []int64{0, 3} {
+				op, err := manager.RollbackSvc("httpbin", revision)
+				Expect(err).To(MatchError(fmt.Sprintf(
+					`marathon.manager.RollbackSvc: service "httpbin" has no revision %d`, revision)))
+				Expect(op).To(BeNil())
+			}
+		})
+
+		It("returns an error for the current revision", func() {
+			ts = NewTestServerJSONRoutes(httpbinVersionsRoutes, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 2)
+			Expect(err).To(MatchError(`marathon.manager.RollbackSvc: service "httpbin" is already at revision 2`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if the app does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(
+				"marathon.manager.RollbackSvc: goMarathonClient.ApplicationVersions failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("Svc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("reads an app whose environment variables refer to secrets", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"GET /v2/apps/httpbin":          "testdata/app_httpbin.json",
+				"GET /v2/apps/httpbin/versions": "testdata/app_httpbin_versions.json",
+			}, nil)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(2))
+			Expect(*svcDetail.Revision).To(Equal(int64(2)))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("citizenstig/httpbin"))
+			Expect(svcDetail.SvcCfg.Env).To(Equal(map[string]string{"PORT": "8000"}))
+			Expect(svcDetail.SvcCfg.Secrets).To(Equal([]anysched.SecretRef{
+				{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"},
+			}))
+			Expect(svcDetail.Native).To(BeAssignableToTypeOf(&goMarathon.Application{}))
+		})
+
+		It("returns an error for an app that does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{}, nil)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("marathon.manager.Svc: mgr.app failed"))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("validateSvcCfg", func() {
+		It("returns an error for a readiness check that does not use HTTP", func() {
+			err := validateSvcCfg(anysched.SvcCfg{ID: "httpbin", ReadinessCheck: &anysched.HealthCheck{Port: 8000}})
+			Expect(err).To(MatchError(`service "httpbin": Marathon readiness checks must use HTTP, not tcp`))
+		})
+
+		It("returns an error for a readiness check on a port that is not one of the service's ports", func() {
+			err := validateSvcCfg(anysched.SvcCfg{
+				ID:             "httpbin",
+				Ports:          []anysched.PortCfg{{ContainerPort: 9000}},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/", Port: 8000},
+			})
+			Expect(err).To(MatchError(`service "httpbin": the port of a Marathon readiness check, 8000, must be in Ports`))
+		})
+
+		It("returns an error for a named volume without a driver", func() {
+			err := validateSvcCfg(anysched.SvcCfg{
+				ID:      "httpbin",
+				Volumes: []anysched.VolumeCfg{{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data"}},
+			})
+			Expect(err).To(MatchError(`service "httpbin": named volume "httpbin-data" needs a driver for Marathon`))
+		})
+
+		It("returns an error for a namespace", func() {
+			err := validateSvcCfg(anysched.SvcCfg{ID: "httpbin", Namespace: "payments"})
+			Expect(err).To(MatchError(`service "httpbin": Marathon has no namespaces`))
+		})
+
+		It("returns an error for sidecars", func() {
+			err := validateSvcCfg(anysched.SvcCfg{
+				ID:       "httpbin",
+				Sidecars: []anysched.ContainerCfg{{Name: "log-forwarder", Image: "fluent/fluent-bit:0.13"}},
+			})
+			Expect(err).To(MatchError(
+				`service "httpbin": Marathon apps have one container, so they cannot have sidecars or init containers`))
+		})
+
+		It("returns an error for a secret exposed as a file", func() {
+			err := validateSvcCfg(anysched.SvcCfg{
+				ID:      "httpbin",
+				Secrets: []anysched.SecretRef{{Secret: "tls", Key: "key", MountPath: "/etc/tls/key.pem"}},
+			})
+			Expect(err).To(MatchError(
+				`service "httpbin": secret "tls:key" can only be exposed as an environment variable in Marathon`))
+		})
+	})
+
+	Describe("goMarathonApp", func() {
+		It("sets the environment variables", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Env:   map[string]string{"PORT": "8000", "DEBUG": "1"},
+			})
+			Expect(app.ID).To(Equal("httpbin"))
+			Expect(*app.Instances).To(Equal(2))
+			Expect(*app.Env).To(HaveLen(2))
+			Expect(*app.Env).To(HaveKeyWithValue("PORT", "8000"))
+			Expect(*app.Env).To(HaveKeyWithValue("DEBUG", "1"))
+		})
+
+		It("runs an overridden entrypoint and its arguments as a shell command", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Command: []string{"gunicorn"},
+				Args:    []string{"--bind=0.0.0.0:8000", "--access-logformat", "%(h)s '%(r)s'", "httpbin:app"},
+			})
+			Expect(*app.Cmd).To(Equal(`gunicorn --bind=0.0.0.0:8000 --access-logformat '%(h)s '"'"'%(r)s'"'"'' httpbin:app`))
+			Expect(app.Args).To(BeNil())
+		})
+
+		It("passes arguments to the image's entrypoint", func() {
+			app := goMarathonApp(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Args: []string{"worker"}})
+			Expect(app.Cmd).To(BeNil())
+			Expect(*app.Args).To(Equal([]string{"worker"}))
+		})
+
+		It("maps the ports in bridged networking", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			})
+			Expect(app.Container.Docker.Network).To(Equal("BRIDGE"))
+			Expect(*app.Container.Docker.PortMappings).To(Equal([]goMarathon.PortMapping{
+				{Name: "http", ContainerPort: 8000, HostPort: 80, Protocol: "tcp"},
+				{Name: "port53", ContainerPort: 53, HostPort: 0, Protocol: "udp"},
+			}))
+		})
+
+		It("sets cpus, mem and disk", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Resources: &anysched.Resources{CPU: 0.25, Memory: 256 << 20, MemoryLimit: 512 << 20, Disk: 1 << 30},
+			})
+			Expect(app.CPUs).To(Equal(0.25))
+			Expect(*app.Mem).To(Equal(512.0))
+			Expect(*app.Disk).To(Equal(1024.0))
+		})
+
+		It("adds health and readiness checks", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Ports:          []anysched.PortCfg{{ContainerPort: 9000}, {ContainerPort: 8000}},
+				HealthCheck:    &anysched.HealthCheck{Port: 8000, GracePeriod: time.Minute},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000, Interval: 2 * time.Second},
+			})
+			Expect(*app.HealthChecks).To(HaveLen(1))
+			healthCheck := (*app.HealthChecks)[0]
+			Expect(healthCheck.Protocol).To(Equal("MESOS_TCP"))
+			Expect(*healthCheck.PortIndex).To(Equal(1))
+			Expect(healthCheck.Port).To(BeNil())
+			Expect(healthCheck.GracePeriodSeconds).To(Equal(60))
+			Expect(healthCheck.IntervalSeconds).To(Equal(10))
+			Expect(healthCheck.TimeoutSeconds).To(Equal(5))
+			Expect(*healthCheck.MaxConsecutiveFailures).To(Equal(3))
+			Expect(app.ReadinessChecks).To(HaveLen(1))
+			readinessCheck := app.ReadinessChecks[0]
+			Expect(readinessCheck.Protocol).To(Equal("HTTP"))
+			Expect(readinessCheck.Path).To(Equal("/status/200"))
+			Expect(readinessCheck.PortName).To(Equal("port8000"))
+			Expect(readinessCheck.IntervalSeconds).To(Equal(2))
+		})
+
+		It("runs a command as a health check", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:          "postgres",
+				Image:       "postgres",
+				HealthCheck: &anysched.HealthCheck{Command: []string{"pg_isready", "-U", "postgres"}},
+			})
+			healthCheck := (*app.HealthChecks)[0]
+			Expect(healthCheck.Protocol).To(Equal("COMMAND"))
+			Expect(healthCheck.Command.Value).To(Equal("pg_isready -U postgres"))
+			Expect(app.ReadinessChecks).To(BeNil())
+		})
+
+		It("leaves the environment empty if there are no environment variables", func() {
+			app := goMarathonApp(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(app.Env).To(BeNil())
+			Expect(app.Labels).To(BeNil())
+		})
+
+		It("translates the constraints", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{
+					{Attribute: "disk", Operator: anysched.ConstraintEquals, Values: []string{"ssd"}},
+					{Attribute: "hostname", Operator: anysched.ConstraintNotEquals, Values: []string{"node-1.example.com"}},
+					{Attribute: "zone", Operator: anysched.ConstraintIn, Values: []string{"a", "b"}},
+					{Operator: anysched.ConstraintUniquePerHost},
+					{Attribute: "rack", Operator: anysched.ConstraintSpread},
+				},
+			})
+			Expect(*app.Constraints).To(Equal([][]string{
+				{"disk", "CLUSTER", "ssd"},
+				{"hostname", "UNLIKE", `node-1\.example\.com`},
+				{"zone", "LIKE", "a|b"},
+				{"hostname", "UNIQUE"},
+				{"rack", "GROUP_BY"},
+			}))
+		})
+
+		It("mounts the volumes", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeHostPath, Source: "/var/log", MountPath: "/logs", ReadOnly: true},
+					{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data", Driver: "rexray"},
+					{Type: anysched.VolumeScratch, MountPath: "/tmp"},
+				},
+			})
+			Expect(*app.Container.Volumes).To(Equal([]goMarathon.Volume{
+				{ContainerPath: "/logs", HostPath: "/var/log", Mode: "RO"},
+				{ContainerPath: "/data", Mode: "RW", External: &goMarathon.ExternalVolume{
+					Name:     "httpbin-data",
+					Provider: "dvdi",
+					Options:  &map[string]string{"dvdi/driver": "rexray"},
+				}},
+				{ContainerPath: "/tmp", HostPath: "volume-2", Mode: "RW"},
+			}))
+		})
+
+		It("exposes secrets as environment variables", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Env:     map[string]string{"PORT": "8000"},
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			})
+			Expect(app.Secrets).To(Equal(map[string]marathonSecret{
+				"secret0": {EnvVar: "DB_PASSWORD", Source: "db/password"},
+			}))
+			data, err := json.Marshal(app)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"env":{"DB_PASSWORD":{"secret":"secret0"},"PORT":"8000"}`))
+			Expect(string(data)).To(ContainSubstring(`"secrets":{"secret0":{"source":"db/password"}}`))
+		})
+
+		It("sets the labels", func() {
+			app := goMarathonApp(anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin",
+				Labels: map[string]string{"team": "payments", "tier": "web"},
+			})
+			Expect(*app.Labels).To(Equal(map[string]string{"team": "payments", "tier": "web"}))
+		})
+	})
+
+	Describe("svcFromMarathonApp", func() {
+		It("returns the labels of the app", func() {
+			labels := map[string]string{"team": "payments"}
+			svc := svcFromMarathonApp(goMarathon.Application{ID: "/httpbin", TasksRunning: 2, Labels: &labels})
+			Expect(svc.ID).To(Equal("httpbin"))
+			Expect(*svc.TasksRunning).To(Equal(2))
+			Expect(svc.Labels).To(Equal(labels))
+		})
+
+		It("returns nil labels for an app without labels", func() {
+			svc := svcFromMarathonApp(goMarathon.Application{ID: "/httpbin"})
+			Expect(svc.Labels).To(BeNil())
+		})
+	})
+
+	Describe("svcCfgFromGoMarathonApp", func() {
+		It("reads back what goMarathonApp builds", func() {
+			svcCfg := anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Env:       map[string]string{"PORT": "8000"},
+				Ports:     []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 9000}},
+				Labels:    map[string]string{"team": "payments"},
+				Secrets:   []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+				Resources: &anysched.Resources{CPU: 0.5, Memory: 256 << 20, MemoryLimit: 512 << 20, Disk: 1 << 30},
+			}
+			Expect(svcCfgFromGoMarathonApp(goMarathonApp(svcCfg))).To(Equal(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 3,
+				Env:   map[string]string{"PORT": "8000"},
+				Ports: []anysched.PortCfg{
+					{Name: "http", ContainerPort: 8000, HostPort: 80, Protocol: "tcp"},
+					{ContainerPort: 9000, Protocol: "tcp"},
+				},
+				Labels:    map[string]string{"team": "payments"},
+				Secrets:   []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+				Resources: &anysched.Resources{CPU: 0.5, Memory: 512 << 20, Disk: 1 << 30},
+			}))
+		})
+
+		It("reads back the JSON of the app that goMarathonApp builds", func() {
+			svcCfg := anysched.SvcCfg{
+				ID:      "httpbin",
+				Image:   "citizenstig/httpbin",
+				Count:   3,
+				Env:     map[string]string{"PORT": "8000"},
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			}
+			data, err := json.Marshal(goMarathonApp(svcCfg))
+			Expect(err).ToNot(HaveOccurred())
+			app := &marathonApp{}
+			Expect(json.Unmarshal(data, app)).To(Succeed())
+			readSvcCfg := svcCfgFromGoMarathonApp(app)
+			Expect(readSvcCfg.Env).To(Equal(svcCfg.Env))
+			Expect(readSvcCfg.Secrets).To(Equal(svcCfg.Secrets))
+		})
+
+		It("makes an app that has Marathon's default resources the same as a SvcCfg without resources", func() {
+			mem, disk := 128.0, 0.0
+			deployedGoMarathonApp := goMarathonApp(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 1})
+			deployedGoMarathonApp.CPUs, deployedGoMarathonApp.Mem, deployedGoMarathonApp.Disk = 1, &mem, &disk
+			svcCfg := anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 1}
+			svcDiff := anysched.DiffSvcCfgs(svcCfgFromGoMarathonApp(deployedGoMarathonApp),
+				svcCfgFromGoMarathonApp(goMarathonApp(svcCfg)))
+			Expect(svcDiff.Changed).To(BeEmpty())
+		})
+	})
+
+	Describe("updateStatus", func() {
+		It("counts the tasks that run the new version and the old ones", func() {
+			instances := 2
+			goMarathonApp := &goMarathon.Application{
+				ID:        "/httpbin",
+				Instances: &instances,
+				Tasks: []*goMarathon.Task{
+					{Version: "2018-07-26T03:10:10Z", State: "TASK_RUNNING"},
+					{Version: "2018-07-27T03:10:10Z", State: "TASK_RUNNING"},
+					{Version: "2018-07-27T03:10:10Z", State: "TASK_STAGING"},
+				},
+			}
+			status := updateStatus(goMarathonApp, "2018-07-27T03:10:10Z")
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal("1 of 2 task(s) running the new version, 1 old task(s) left."))
+
+			goMarathonApp.Tasks = goMarathonApp.Tasks[1:]
+			goMarathonApp.Tasks[1].State = "TASK_RUNNING"
+			status = updateStatus(goMarathonApp, "2018-07-27T03:10:10Z")
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal("2 of 2 task(s) running the new version, 0 old task(s) left."))
+		})
+	})
 })
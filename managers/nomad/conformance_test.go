@@ -0,0 +1,320 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+	"github.com/msabramo/go-anysched/utils"
+)
+
+var _ = conformance.DescribeManager("nomad", func() anysched.Manager {
+	pollInterval = 10 * time.Millisecond
+	return NewManagerWithTestServer(newNomadStandIn())
+})
+
+// nomadStandIn is a stand-in for the HTTP API of a Nomad cluster with one
+// client node, for the conformance suite. It keeps every version of the jobs
+// that it is asked to register, and runs their allocations right away. Like
+// Nomad, it replaces the allocations of a job when its tasks change, and
+// updates them in place otherwise. It creates no Nomad deployments.
+type nomadStandIn struct {
+	mutex       sync.Mutex
+	jobVersions map[string][]*api.Job // oldest first
+	allocations []*api.Allocation
+	evaluations map[string]*api.Evaluation
+	index       uint64
+}
+
+func newNomadStandIn() *httptest.Server {
+	return httptest.NewServer(&nomadStandIn{
+		jobVersions: map[string][]*api.Job{},
+		evaluations: map[string]*api.Evaluation{},
+	})
+}
+
+func (standIn *nomadStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	switch {
+	case r.URL.Path == "/v1/nodes":
+		writeJSON(w, []*api.NodeListStub{{ID: "conformance-node", Name: "conformance-host", Status: "ready"}})
+	case r.URL.Path == "/v1/allocations":
+		writeJSON(w, standIn.allocationListStubs(""))
+	case r.URL.Path == "/v1/jobs" && r.Method == "GET":
+		standIn.listJobs(w)
+	case r.URL.Path == "/v1/jobs":
+		standIn.registerJob(w, r)
+	case len(pathParts) == 2 && pathParts[0] == "allocation":
+		standIn.serveAllocation(w, pathParts[1])
+	case len(pathParts) == 2 && pathParts[0] == "evaluation" && standIn.evaluations[pathParts[1]] != nil:
+		writeJSON(w, standIn.evaluations[pathParts[1]])
+	case len(pathParts) >= 2 && pathParts[0] == "job":
+		standIn.serveJob(w, r, pathParts[1], pathParts[2:])
+	default:
+		writeError(w, 404, "not found")
+	}
+}
+
+func (standIn *nomadStandIn) listJobs(w http.ResponseWriter) {
+	jobListStubs := []*api.JobListStub{}
+	for jobID := range standIn.jobVersions {
+		job := standIn.job(jobID)
+		jobListStubs = append(jobListStubs, &api.JobListStub{
+			ID:             jobID,
+			Name:           *job.Name,
+			Type:           *job.Type,
+			JobSummary:     standIn.jobSummary(jobID),
+			JobModifyIndex: *job.JobModifyIndex,
+			SubmitTime:     *job.SubmitTime,
+		})
+	}
+	writeJSON(w, jobListStubs)
+}
+
+func (standIn *nomadStandIn) registerJob(w http.ResponseWriter, r *http.Request) {
+	var request api.RegisterJobRequest
+	Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+	existingJob := standIn.job(*request.Job.ID)
+	if request.EnforceIndex {
+		switch {
+		case existingJob != nil && request.JobModifyIndex == 0:
+			writeError(w, 500, "Enforcing job modify index 0: job already exists")
+			return
+		case existingJob != nil && request.JobModifyIndex != *existingJob.JobModifyIndex:
+			writeError(w, 500, fmt.Sprintf("Enforcing job modify index %d: job exists with conflicting job "+
+				"modify index: %d", request.JobModifyIndex, *existingJob.JobModifyIndex))
+			return
+		case existingJob == nil && request.JobModifyIndex != 0:
+			writeError(w, 500, fmt.Sprintf("Enforcing job modify index %d: job does not exist",
+				request.JobModifyIndex))
+			return
+		}
+	}
+	writeJSON(w, standIn.register(request.Job))
+}
+
+func (standIn *nomadStandIn) serveJob(w http.ResponseWriter, r *http.Request, jobID string, pathParts []string) {
+	job := standIn.job(jobID)
+	switch {
+	case len(pathParts) == 1 && pathParts[0] == "allocations":
+		writeJSON(w, standIn.allocationListStubs(jobID))
+	case len(pathParts) == 0 && r.Method == "DELETE":
+		// Like Nomad, it deregisters a job that doesn't exist without an
+		// error. The suite's jobs are purged, so their versions are forgotten.
+		delete(standIn.jobVersions, jobID)
+		standIn.stopAllocations(jobID, func(*api.Allocation) bool { return true })
+		writeJSON(w, api.JobDeregisterResponse{EvalID: standIn.newEvaluation(jobID)})
+	case job == nil:
+		writeError(w, 404, "job not found")
+	case len(pathParts) == 0 && r.Method == "GET":
+		writeJSON(w, job)
+	case pathParts[0] == "summary":
+		writeJSON(w, standIn.jobSummary(jobID))
+	case pathParts[0] == "deployment":
+		writeJSON(w, nil)
+	case pathParts[0] == "versions":
+		versions := standIn.jobVersions[jobID]
+		newestFirst := make([]*api.Job, len(versions))
+		for i, version := range versions {
+			newestFirst[len(versions)-1-i] = version
+		}
+		writeJSON(w, api.JobVersionsResponse{Versions: newestFirst})
+	case pathParts[0] == "revert":
+		standIn.revertJob(w, r, job)
+	default:
+		writeError(w, 404, "not found")
+	}
+}
+
+func (standIn *nomadStandIn) revertJob(w http.ResponseWriter, r *http.Request, job *api.Job) {
+	var request api.JobRevertRequest
+	Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+	if request.EnforcePriorVersion != nil && *request.EnforcePriorVersion != *job.Version {
+		writeError(w, 500, fmt.Sprintf("Current job has version %d; enforcing version %d",
+			*job.Version, *request.EnforcePriorVersion))
+		return
+	}
+	versions := standIn.jobVersions[*job.ID]
+	if request.JobVersion >= uint64(len(versions)) {
+		writeError(w, 500, fmt.Sprintf("job %q at version %d not found", *job.ID, request.JobVersion))
+		return
+	}
+	writeJSON(w, standIn.register(copyJob(versions[request.JobVersion])))
+}
+
+// register adds job as the next version of its job, and places its
+// allocations.
+func (standIn *nomadStandIn) register(job *api.Job) api.JobRegisterResponse {
+	previousJob := standIn.job(*job.ID)
+	standIn.index++
+	version, jobModifyIndex, submitTime := uint64(0), standIn.index, time.Now().UnixNano()
+	if previousJob != nil {
+		version = *previousJob.Version + 1
+	}
+	job.Version, job.JobModifyIndex, job.SubmitTime = &version, &jobModifyIndex, &submitTime
+	job.Status = utils.Sptr("running")
+	standIn.jobVersions[*job.ID] = append(standIn.jobVersions[*job.ID], job)
+	count := *job.TaskGroups[0].Count
+	if previousJob != nil && !reflect.DeepEqual(previousJob.TaskGroups[0].Tasks, job.TaskGroups[0].Tasks) {
+		standIn.stopAllocations(*job.ID, func(*api.Allocation) bool { return true })
+	}
+	standIn.stopAllocations(*job.ID, func(allocation *api.Allocation) bool {
+		return allocationIndex(allocation) >= count
+	})
+	running := map[int]bool{}
+	for _, allocation := range standIn.runningAllocations(*job.ID) {
+		allocation.Job = job
+		running[allocationIndex(allocation)] = true
+	}
+	evalID := standIn.newEvaluation(*job.ID)
+	for i := 0; i < count; i++ {
+		if !running[i] {
+			standIn.allocations = append(standIn.allocations, newAllocation(job, i, evalID))
+		}
+	}
+	return api.JobRegisterResponse{EvalID: evalID, JobModifyIndex: standIn.index}
+}
+
+func newAllocation(job *api.Job, index int, evalID string) *api.Allocation {
+	now := time.Now()
+	taskGroup := *job.TaskGroups[0].Name
+	return &api.Allocation{
+		ID:            fmt.Sprintf("%08d-0000-0000-0000-%012d", index, now.UnixNano()%1e12),
+		EvalID:        evalID,
+		Name:          fmt.Sprintf("%s.%s[%d]", *job.ID, taskGroup, index),
+		NodeID:        "conformance-node",
+		JobID:         *job.ID,
+		Job:           job,
+		TaskGroup:     taskGroup,
+		DesiredStatus: "run",
+		ClientStatus:  allocClientStatusRunning,
+		TaskStates:    map[string]*api.TaskState{*job.ID: {State: "running", StartedAt: now}},
+		CreateTime:    now.UnixNano(),
+	}
+}
+
+// allocationIndex returns the index of an allocation in its task group, from
+// its name, e.g.: 1 for "httpbin.httpbin[1]".
+func allocationIndex(allocation *api.Allocation) (index int) {
+	fmt.Sscanf(allocation.Name[strings.LastIndex(allocation.Name, "[")+1:], "%d]", &index)
+	return index
+}
+
+func (standIn *nomadStandIn) runningAllocations(jobID string) (allocations []*api.Allocation) {
+	for _, allocation := range standIn.allocations {
+		if allocation.JobID == jobID && allocation.ClientStatus == allocClientStatusRunning {
+			allocations = append(allocations, allocation)
+		}
+	}
+	return allocations
+}
+
+func (standIn *nomadStandIn) stopAllocations(jobID string, shouldStop func(*api.Allocation) bool) {
+	for _, allocation := range standIn.runningAllocations(jobID) {
+		if shouldStop(allocation) {
+			allocation.DesiredStatus = "stop"
+			allocation.ClientStatus = allocClientStatusComplete
+		}
+	}
+}
+
+// allocationListStubs returns the allocations of the job with the ID jobID, or
+// of all jobs if jobID is blank.
+func (standIn *nomadStandIn) allocationListStubs(jobID string) []*api.AllocationListStub {
+	allocationListStubs := []*api.AllocationListStub{}
+	for _, allocation := range standIn.allocations {
+		if jobID != "" && allocation.JobID != jobID {
+			continue
+		}
+		allocationListStubs = append(allocationListStubs, &api.AllocationListStub{
+			ID:            allocation.ID,
+			EvalID:        allocation.EvalID,
+			Name:          allocation.Name,
+			NodeID:        allocation.NodeID,
+			JobID:         allocation.JobID,
+			JobVersion:    *allocation.Job.Version,
+			TaskGroup:     allocation.TaskGroup,
+			DesiredStatus: allocation.DesiredStatus,
+			ClientStatus:  allocation.ClientStatus,
+			TaskStates:    allocation.TaskStates,
+			CreateTime:    allocation.CreateTime,
+		})
+	}
+	return allocationListStubs
+}
+
+func (standIn *nomadStandIn) serveAllocation(w http.ResponseWriter, allocationID string) {
+	for _, allocation := range standIn.allocations {
+		if allocation.ID == allocationID {
+			writeJSON(w, allocation)
+			return
+		}
+	}
+	writeError(w, 404, "alloc not found")
+}
+
+func (standIn *nomadStandIn) jobSummary(jobID string) *api.JobSummary {
+	job := standIn.job(jobID)
+	return &api.JobSummary{
+		JobID: jobID,
+		Summary: map[string]api.TaskGroupSummary{
+			*job.TaskGroups[0].Name: {Running: len(standIn.runningAllocations(jobID))},
+		},
+	}
+}
+
+// job returns the current version of the job with the ID jobID, or nil if
+// there is no such job.
+func (standIn *nomadStandIn) job(jobID string) *api.Job {
+	versions := standIn.jobVersions[jobID]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// newEvaluation returns the ID of a new evaluation of the job with the ID
+// jobID, which the stand-in completes right away.
+func (standIn *nomadStandIn) newEvaluation(jobID string) string {
+	standIn.index++
+	evaluation := &api.Evaluation{
+		ID:     fmt.Sprintf("%08d-0000-0000-0000-000000000000", standIn.index),
+		JobID:  jobID,
+		Status: evalStatusComplete,
+	}
+	standIn.evaluations[evaluation.ID] = evaluation
+	return evaluation.ID
+}
+
+// copyJob returns a copy of job that shares nothing with it.
+func copyJob(job *api.Job) *api.Job {
+	bytes, err := json.Marshal(job)
+	Expect(err).ToNot(HaveOccurred())
+	var jobCopy api.Job
+	Expect(json.Unmarshal(bytes, &jobCopy)).To(Succeed())
+	return &jobCopy
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.WriteHeader(statusCode)
+	fmt.Fprint(w, message)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	bytes, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	writeJSONResponseBytes(w, bytes)
+}
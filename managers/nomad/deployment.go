@@ -0,0 +1,229 @@
+package nomad
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hashicorp/nomad/api"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// Nomad evaluation and deployment statuses
+const (
+	evalStatusPending  = "pending"
+	evalStatusComplete = "complete"
+
+	deploymentStatusRunning    = "running"
+	deploymentStatusPaused     = "paused"
+	deploymentStatusSuccessful = "successful"
+)
+
+var (
+	// pollInterval is how often Wait checks the status of an operation.
+	pollInterval = 2 * time.Second
+
+	getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
+		if svcCfg.DeployTimeoutDuration == nil {
+			return 60 * time.Second
+		}
+		return *svcCfg.DeployTimeoutDuration
+	}
+)
+
+// deployment implements the anysched.Operation interface for a Nomad job
+// registration. It follows the evaluation created by the registration to the
+// Nomad deployment that the scheduler creates for the job.
+type deployment struct {
+	manager         *manager
+	jobID           string
+	evalID          string
+	desiredCount    int
+	update          bool // whether the registration updated an existing job
+	timeoutDuration time.Duration
+}
+
+func (dep *deployment) String() string {
+	return fmt.Sprintf("<nomad.deployment jobID=%q evalID=%q />", dep.jobID, dep.evalID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dep *deployment) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["jobID"] = dep.jobID
+	propertiesMap["evalID"] = dep.evalID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deployment
+func (dep *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
+	evaluation, _, err := dep.manager.evaluationsClient.Info(dep.evalID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "nomad.deployment.GetStatus: evaluationsClient.Info(%q) failed", dep.evalID)
+	}
+	switch {
+	case evaluation.Status == evalStatusPending:
+		return notDoneStatus(dep.jobID, fmt.Sprintf("Waiting for evaluation %q to complete...", evaluation.ID)), nil
+	case evaluation.Status != evalStatusComplete:
+		return nil, evaluationNotCompleteError(evaluation)
+	case evaluation.DeploymentID == "":
+		return dep.allocationsStatus()
+	}
+	nomadDeployment, _, err := dep.manager.deploymentsClient.Info(evaluation.DeploymentID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"nomad.deployment.GetStatus: deploymentsClient.Info(%q) failed", evaluation.DeploymentID)
+	}
+	return getStatusOfNomadDeployment(nomadDeployment)
+}
+
+func evaluationNotCompleteError(evaluation *api.Evaluation) error {
+	return fmt.Errorf("evaluation %q for job %q has status %q: %s",
+		evaluation.ID, evaluation.JobID, evaluation.Status, evaluation.StatusDescription)
+}
+
+func getStatusOfNomadDeployment(nomadDeployment *api.Deployment) (*anysched.OperationStatus, error) {
+	switch nomadDeployment.Status {
+	case deploymentStatusSuccessful:
+		msg := fmt.Sprintf("Deployment %q successfully rolled out. %s",
+			nomadDeployment.ID, taskGroupsProgressMsg(nomadDeployment))
+		return doneStatus(msg), nil
+	case deploymentStatusRunning, deploymentStatusPaused:
+		return notDoneStatus(nomadDeployment.JobID, taskGroupsProgressMsg(nomadDeployment)), nil
+	}
+	return nil, fmt.Errorf("deployment %q for job %q has status %q: %s",
+		nomadDeployment.ID, nomadDeployment.JobID, nomadDeployment.Status, nomadDeployment.StatusDescription)
+}
+
+// taskGroupsProgressMsg returns a message with the placed, healthy, and
+// unhealthy allocation counts for each task group in a deployment.
+func taskGroupsProgressMsg(nomadDeployment *api.Deployment) string {
+	taskGroupNames := make([]string, 0, len(nomadDeployment.TaskGroups))
+	for taskGroupName := range nomadDeployment.TaskGroups {
+		taskGroupNames = append(taskGroupNames, taskGroupName)
+	}
+	sort.Strings(taskGroupNames)
+	msgs := make([]string, len(taskGroupNames))
+	for i, taskGroupName := range taskGroupNames {
+		deploymentState := nomadDeployment.TaskGroups[taskGroupName]
+		msgs[i] = fmt.Sprintf("%s: %d of %d allocs placed, %d healthy, %d unhealthy.",
+			taskGroupName, deploymentState.PlacedAllocs, deploymentState.DesiredTotal,
+			deploymentState.HealthyAllocs, deploymentState.UnhealthyAllocs)
+	}
+	return strings.Join(msgs, " ")
+}
+
+// allocationsStatus returns the status of a job that has no Nomad deployment
+// (e.g.: because it has no update stanza), by counting its running allocations.
+func (dep *deployment) allocationsStatus() (*anysched.OperationStatus, error) {
+	allAllocs := false
+	allocationListStubs, _, err := dep.manager.jobsClient.Allocations(dep.jobID, allAllocs, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "nomad.deployment.allocationsStatus: jobsClient.Allocations(%q) failed", dep.jobID)
+	}
+	if dep.update {
+		return dep.updatedAllocationsStatus(allocationListStubs)
+	}
+	running := countAllocations(allocationListStubs, allocClientStatusRunning)
+	if running < dep.desiredCount {
+		msg := fmt.Sprintf("%d of %d allocs are running...", running, dep.desiredCount)
+		return notDoneStatus(dep.jobID, msg), nil
+	}
+	return doneStatus(fmt.Sprintf("Job %q successfully rolled out. %d allocs are running.", dep.jobID, running)), nil
+}
+
+// updatedAllocationsStatus returns the status of the update of a job that has
+// no Nomad deployment, by counting its running allocations of the current
+// version of the job, and its allocations of older versions that are left.
+func (dep *deployment) updatedAllocationsStatus(allocationListStubs []*api.AllocationListStub) (
+	*anysched.OperationStatus, error) {
+	job, _, err := dep.manager.jobsClient.Info(dep.jobID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "nomad.deployment.updatedAllocationsStatus: jobsClient.Info(%q) failed", dep.jobID)
+	}
+	updated, old := 0, 0
+	for _, allocationListStub := range allocationListStubs {
+		switch {
+		case allocationIsTerminal(allocationListStub.ClientStatus):
+		case allocationListStub.JobVersion != *job.Version:
+			old++
+		case allocationListStub.ClientStatus == allocClientStatusRunning:
+			updated++
+		}
+	}
+	if updated < dep.desiredCount || old > 0 {
+		msg := fmt.Sprintf("%d of %d allocs of version %d are running, %d old allocs are left...",
+			updated, dep.desiredCount, *job.Version, old)
+		return notDoneStatus(dep.jobID, msg), nil
+	}
+	msg := fmt.Sprintf("Job %q successfully updated to version %d. %d allocs are running.",
+		dep.jobID, *job.Version, updated)
+	return doneStatus(msg), nil
+}
+
+func countAllocations(allocationListStubs []*api.AllocationListStub, clientStatus string) (count int) {
+	for _, allocationListStub := range allocationListStubs {
+		if allocationListStub.ClientStatus == clientStatus {
+			count++
+		}
+	}
+	return count
+}
+
+func notDoneStatus(jobID, msg string) *anysched.OperationStatus {
+	msg = fmt.Sprintf("Waiting for job %q to finish: %s", jobID, msg)
+	return status(msg, false)
+}
+
+func doneStatus(msg string) *anysched.OperationStatus {
+	return status(msg, true)
+}
+
+// status returns an OperationStatus with the timestamps set to the current
+// time, because Nomad evaluations and deployments don't carry timestamps.
+func status(msg string, done bool) *anysched.OperationStatus {
+	now := time.Now()
+	return &anysched.OperationStatus{
+		ClientTime:         now,
+		LastTransitionTime: now,
+		LastUpdateTime:     now,
+		Msg:                msg,
+		Done:               done,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (dep *deployment) Wait(ctx context.Context) (result interface{}, err error) {
+	if err = waitForOperation(ctx, dep, dep.timeoutDuration); err != nil {
+		return nil, errors.Wrap(err, "nomad.deployment.Wait")
+	}
+	return dep, nil
+}
+
+// waitForOperation polls the status of op until it is done, it fails, or ctx
+// or the timeout expires.
+func waitForOperation(ctx context.Context, op anysched.Operation, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "Timed out after %s", timeout)
+		case <-time.After(pollInterval):
+			status, err := op.GetStatus()
+			if err != nil {
+				return err
+			}
+			if status.Done {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package nomad
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hashicorp/nomad/api"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// deregistration implements the anysched.Operation interface for a Nomad job
+// deregistration. It is done once all of the job's allocations have stopped.
+type deregistration struct {
+	manager         *manager
+	jobID           string
+	evalID          string
+	timeoutDuration time.Duration
+}
+
+func (dereg *deregistration) String() string {
+	return fmt.Sprintf("<nomad.deregistration jobID=%q evalID=%q />", dereg.jobID, dereg.evalID)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dereg *deregistration) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["jobID"] = dereg.jobID
+	propertiesMap["evalID"] = dereg.evalID
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deregistration
+func (dereg *deregistration) GetStatus() (status *anysched.OperationStatus, err error) {
+	allAllocs := false
+	allocationListStubs, _, err := dereg.manager.jobsClient.Allocations(dereg.jobID, allAllocs, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"nomad.deregistration.GetStatus: jobsClient.Allocations(%q) failed", dereg.jobID)
+	}
+	stillRunning := 0
+	for _, allocationListStub := range allocationListStubs {
+		if !allocationIsTerminal(allocationListStub.ClientStatus) {
+			stillRunning++
+		}
+	}
+	if stillRunning > 0 {
+		return notDoneStatus(dereg.jobID, fmt.Sprintf("%d allocs are pending termination...", stillRunning)), nil
+	}
+	return doneStatus(fmt.Sprintf("Job %q successfully stopped. 0 allocs are running.", dereg.jobID)), nil
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (dereg *deregistration) Wait(ctx context.Context) (result interface{}, err error) {
+	if err = waitForOperation(ctx, dereg, dereg.timeoutDuration); err != nil {
+		return nil, errors.Wrap(err, "nomad.deregistration.Wait")
+	}
+	return dereg, nil
+}
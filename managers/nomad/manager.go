@@ -2,6 +2,12 @@ package nomad
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -11,10 +17,26 @@ import (
 	"github.com/msabramo/go-anysched/utils"
 )
 
+// Nomad allocation client statuses
+const (
+	allocClientStatusRunning  = "running"
+	allocClientStatusComplete = "complete"
+	allocClientStatusFailed   = "failed"
+	allocClientStatusLost     = "lost"
+)
+
+// cpuMHzPerCore is how many MHz of CPU are allocated in Nomad for each CPU
+// core in anysched.Resources.
+const cpuMHzPerCore = 1000
+
 type manager struct {
-	client     *api.Client
-	jobsClient *api.Jobs
-	url        string
+	client            *api.Client
+	jobsClient        *api.Jobs
+	allocationsClient *api.Allocations
+	evaluationsClient *api.Evaluations
+	deploymentsClient *api.Deployments
+	nodesClient       *api.Nodes
+	url               string
 }
 
 func init() {
@@ -28,44 +50,892 @@ func NewManager(url string) (anysched.Manager, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "nomad.NewManager: api.NewClient failed")
 	}
-	return &manager{client: client, jobsClient: client.Jobs(), url: url}, nil
+	mgr := &manager{
+		client:            client,
+		jobsClient:        client.Jobs(),
+		allocationsClient: client.Allocations(),
+		evaluationsClient: client.Evaluations(),
+		deploymentsClient: client.Deployments(),
+		nodesClient:       client.Nodes(),
+		url:               url,
+	}
+	return mgr, nil
 }
 
-// Svcs returns info about all running services.
+// Svcs returns info about all running services. The full job is fetched for
+// each one, because the list stubs don't include the meta that its labels are
+// in.
 func (mgr *manager) Svcs() ([]anysched.Svc, error) {
-	return nil, errors.New("nomad.manager.Svcs: Not implemented")
+	jobListStubs, _, err := mgr.jobsClient.List(&api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Svcs: jobsClient.List failed")
+	}
+	svcs := []anysched.Svc{}
+	for _, jobListStub := range jobListStubs {
+		if jobListStub.Type != api.JobTypeService {
+			continue
+		}
+		job, _, err := mgr.jobsClient.Info(jobListStub.ID, &api.QueryOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "nomad.manager.Svcs: jobsClient.Info(%q) failed", jobListStub.ID)
+		}
+		nomadDeployment, _, err := mgr.jobsClient.LatestDeployment(jobListStub.ID, &api.QueryOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err,
+				"nomad.manager.Svcs: jobsClient.LatestDeployment(%q) failed", jobListStub.ID)
+		}
+		svcs = append(svcs, svcFromNomadJob(job, jobListStub.JobSummary, nomadDeployment))
+	}
+	return svcs, nil
+}
+
+func svcFromNomadJob(job *api.Job, jobSummary *api.JobSummary, nomadDeployment *api.Deployment) anysched.Svc {
+	svc := anysched.Svc{Labels: job.Meta}
+	if job.ID != nil {
+		svc.ID = *job.ID
+	}
+	if jobSummary != nil {
+		tasksRunning := 0
+		for _, taskGroupSummary := range jobSummary.Summary {
+			tasksRunning += taskGroupSummary.Running
+		}
+		svc.TasksRunning = &tasksRunning
+	}
+	if nomadDeployment != nil {
+		tasksHealthy, tasksUnhealthy := 0, 0
+		for _, deploymentState := range nomadDeployment.TaskGroups {
+			tasksHealthy += deploymentState.HealthyAllocs
+			tasksUnhealthy += deploymentState.UnhealthyAllocs
+		}
+		svc.TasksHealthy = &tasksHealthy
+		svc.TasksUnhealthy = &tasksUnhealthy
+	}
+	if job.SubmitTime != nil && *job.SubmitTime != 0 {
+		creationTime := time.Unix(0, *job.SubmitTime)
+		svc.CreationTime = &creationTime
+	}
+	return svc
+}
+
+// Svc returns a service with the SvcCfg of its job. Revision is the version of
+// the job, and Native is the *api.Job.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	job, _, err := mgr.jobsClient.Info(svcID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Svc: mgr.jobsClient.Info failed")
+	}
+	jobSummary, _, err := mgr.jobsClient.Summary(svcID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Svc: mgr.jobsClient.Summary failed")
+	}
+	nomadDeployment, _, err := mgr.jobsClient.LatestDeployment(svcID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Svc: mgr.jobsClient.LatestDeployment failed")
+	}
+	svcDetail := &anysched.SvcDetail{
+		Svc:    svcFromNomadJob(job, jobSummary, nomadDeployment),
+		SvcCfg: svcCfgFromNomadJob(job),
+		Native: job,
+	}
+	if job.Version != nil {
+		revision := int64(*job.Version)
+		svcDetail.Revision = &revision
+	}
+	return svcDetail, nil
 }
 
 // SvcTasks returns info about the running tasks for a service.
 func (mgr *manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
-	return nil, errors.New("nomad.manager.SvcTasks: Not implemented")
+	allAllocs := false
+	allocationListStubs, _, err := mgr.jobsClient.Allocations(svcCfg.ID, allAllocs, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "nomad.manager.SvcTasks: jobsClient.Allocations(%q) failed", svcCfg.ID)
+	}
+	tasks, err := mgr.tasksFromAllocationListStubs(allocationListStubs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "nomad.manager.SvcTasks: tasksFromAllocationListStubs failed for %q", svcCfg.ID)
+	}
+	return tasks, nil
 }
 
 // Tasks returns info about all running tasks.
 func (mgr *manager) Tasks() ([]anysched.Task, error) {
-	return nil, errors.New("nomad.manager.Tasks: Not implemented")
+	allocationListStubs, _, err := mgr.allocationsClient.List(&api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Tasks: allocationsClient.List failed")
+	}
+	tasks, err := mgr.tasksFromAllocationListStubs(allocationListStubs)
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.Tasks: tasksFromAllocationListStubs failed")
+	}
+	return tasks, nil
+}
+
+// tasksFromAllocationListStubs converts the non-terminal allocations in
+// allocationListStubs to tasks. The full allocation is fetched for each one,
+// because the list stubs don't include the allocated networks.
+func (mgr *manager) tasksFromAllocationListStubs(
+	allocationListStubs []*api.AllocationListStub,
+) ([]anysched.Task, error) {
+	nodeNames, err := mgr.nodeNames()
+	if err != nil {
+		return nil, err
+	}
+	tasks := []anysched.Task{}
+	for _, allocationListStub := range allocationListStubs {
+		if allocationIsTerminal(allocationListStub.ClientStatus) {
+			continue
+		}
+		allocation, _, err := mgr.allocationsClient.Info(allocationListStub.ID, &api.QueryOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "allocationsClient.Info(%q) failed", allocationListStub.ID)
+		}
+		tasks = append(tasks, taskFromNomadAllocation(allocation, nodeNames[allocation.NodeID]))
+	}
+	return tasks, nil
+}
+
+// nodeNames returns a map of Nomad node IDs to node names.
+func (mgr *manager) nodeNames() (map[string]string, error) {
+	nodeListStubs, _, err := mgr.nodesClient.List(&api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nodesClient.List failed")
+	}
+	nodeNames := make(map[string]string, len(nodeListStubs))
+	for _, nodeListStub := range nodeListStubs {
+		nodeNames[nodeListStub.ID] = nodeListStub.Name
+	}
+	return nodeNames, nil
+}
+
+func allocationIsTerminal(clientStatus string) bool {
+	switch clientStatus {
+	case allocClientStatusComplete, allocClientStatusFailed, allocClientStatusLost:
+		return true
+	}
+	return false
+}
+
+func taskFromNomadAllocation(allocation *api.Allocation, nodeName string) anysched.Task {
+	ipAddresses, ports := allocationNetworks(allocation)
+	task := anysched.Task{
+		Name:        allocation.ID,
+		AppID:       allocation.JobID,
+		HostName:    nodeName,
+		IPAddresses: ipAddresses,
+		Ports:       ports,
+		StartTime:   allocationStartTime(allocation),
+		State:       allocation.ClientStatus,
+	}
+	if len(ipAddresses) > 0 {
+		task.HostIP = ipAddresses[0]
+		task.TaskIP = ipAddresses[0]
+	}
+	if allocation.Job != nil && allocation.Job.Version != nil {
+		task.Version = strconv.FormatUint(*allocation.Job.Version, 10)
+	}
+	if allocation.CreateTime != 0 {
+		stageTime := time.Unix(0, allocation.CreateTime)
+		task.StageTime = &stageTime
+	}
+	return task
+}
+
+// allocationNetworks returns the IP addresses and the reserved and dynamic
+// ports of all networks allocated to the tasks in an allocation.
+func allocationNetworks(allocation *api.Allocation) (ipAddresses []string, ports []int) {
+	seenIPAddresses := map[string]bool{}
+	for _, taskName := range sortedTaskNames(allocation.TaskResources) {
+		for _, network := range allocation.TaskResources[taskName].Networks {
+			if network.IP != "" && !seenIPAddresses[network.IP] {
+				seenIPAddresses[network.IP] = true
+				ipAddresses = append(ipAddresses, network.IP)
+			}
+			for _, port := range network.ReservedPorts {
+				ports = append(ports, port.Value)
+			}
+			for _, port := range network.DynamicPorts {
+				ports = append(ports, port.Value)
+			}
+		}
+	}
+	return ipAddresses, ports
+}
+
+func sortedTaskNames(taskResources map[string]*api.Resources) []string {
+	taskNames := make([]string, 0, len(taskResources))
+	for taskName := range taskResources {
+		taskNames = append(taskNames, taskName)
+	}
+	sort.Strings(taskNames)
+	return taskNames
+}
+
+// allocationStartTime returns the earliest time that any of the tasks in an
+// allocation was started, or nil if none of them have started yet.
+func allocationStartTime(allocation *api.Allocation) *time.Time {
+	var startTime *time.Time
+	for _, taskState := range allocation.TaskStates {
+		if taskState.StartedAt.IsZero() {
+			continue
+		}
+		if startTime == nil || taskState.StartedAt.Before(*startTime) {
+			taskStartedAt := taskState.StartedAt
+			startTime = &taskStartedAt
+		}
+	}
+	return startTime
 }
 
 // DeploySvc takes a SvcCfg and deploys it, returning an Operation.
 func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.DeploySvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.DeploySvc: validateSvcCfg failed")
+	}
 	job := getJob(svcCfg)
-	jobRegisterResponse, writeMeta, err := mgr.jobsClient.Register(job, &api.WriteOptions{})
-	fmt.Printf("*** jobRegisterResponse = %+v; writeMeta = %+v; err = %+v\n", jobRegisterResponse, writeMeta, err)
+	// Nomad would update a job that already exists, but enforcing a modify
+	// index of 0 makes the registration fail for one instead.
+	jobRegisterResponse, _, err := mgr.jobsClient.EnforceRegister(job, 0, &api.WriteOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.DeploySvc: mgr.jobsClient.EnforceRegister failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		jobID:           svcCfg.ID,
+		evalID:          jobRegisterResponse.EvalID,
+		desiredCount:    svcCfg.Count,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and registers its job
+// again, which makes Nomad replace its allocations, returning an Operation.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.UpdateSvc: svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.UpdateSvc: validateSvcCfg failed")
+	}
+	oldJob, _, err := mgr.jobsClient.Info(svcCfg.ID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.UpdateSvc: mgr.jobsClient.Info failed")
+	}
+	jobRegisterResponse, err := mgr.reregisterJob(getJob(svcCfg), oldJob)
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.UpdateSvc: mgr.reregisterJob failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		jobID:           svcCfg.ID,
+		evalID:          jobRegisterResponse.EvalID,
+		desiredCount:    svcCfg.Count,
+		update:          true,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// reregisterJob registers job in place of oldJob, the job as it was read
+// before. Nomad only registers it while the modify index is still that of
+// oldJob, so a change that someone else made since then isn't lost.
+func (mgr *manager) reregisterJob(job, oldJob *api.Job) (*api.JobRegisterResponse, error) {
+	jobRegisterResponse, _, err := mgr.jobsClient.EnforceRegister(job, *oldJob.JobModifyIndex, &api.WriteOptions{})
 	if err != nil {
-		return nil, errors.Wrap(err, "nomad.manager.DeploySvc: mgr.jobsClient.Register failed")
+		return nil, errors.Wrap(err, "mgr.jobsClient.EnforceRegister failed")
+	}
+	return jobRegisterResponse, nil
+}
+
+// ScaleSvc changes the count of the task group of a service's job to count,
+// returning an Operation. The Nomad API client that we use predates the job
+// scale endpoint, so it registers the job again with only the count changed,
+// which makes Nomad place or stop allocations without replacing the others.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("nomad.manager.ScaleSvc: service %q cannot be scaled to %d allocs", svcID, count)
+	}
+	job, _, err := mgr.jobsClient.Info(svcID, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.ScaleSvc: mgr.jobsClient.Info failed")
+	}
+	if len(job.TaskGroups) == 0 {
+		return nil, fmt.Errorf("nomad.manager.ScaleSvc: job %q has no task groups", svcID)
+	}
+	job.TaskGroups[0].Count = &count
+	jobRegisterResponse, err := mgr.reregisterJob(job, job)
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.ScaleSvc: mgr.reregisterJob failed")
+	}
+	dep := &deployment{
+		manager:         mgr,
+		jobID:           svcID,
+		evalID:          jobRegisterResponse.EvalID,
+		desiredCount:    count,
+		timeoutDuration: getDeployTimeoutDuration(anysched.SvcCfg{}),
+	}
+	return dep, nil
+}
+
+// SvcHistory returns the revisions of a service, oldest first, which are the
+// versions of its job that Nomad keeps. Revision is the version of the job,
+// which Nomad numbers from 0.
+func (mgr *manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	jobs, _, _, err := mgr.jobsClient.Versions(svcID, false, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.SvcHistory: mgr.jobsClient.Versions failed")
+	}
+	// Nomad lists the versions newest first.
+	revisions := make([]anysched.SvcRevision, len(jobs))
+	for i, job := range jobs {
+		revision := anysched.SvcRevision{
+			Image:   svcCfgFromNomadJob(job).Image,
+			Current: i == 0,
+		}
+		if job.Version != nil {
+			revision.Revision = int64(*job.Version)
+		}
+		if job.SubmitTime != nil {
+			creationTime := time.Unix(0, *job.SubmitTime)
+			revision.CreationTime = &creationTime
+		}
+		revisions[len(jobs)-1-i] = revision
+	}
+	return revisions, nil
+}
+
+// RollbackSvc reverts the job of a service to one of its versions, returning an
+// Operation. Nomad registers the job of that version, count included, as a new
+// version.
+func (mgr *manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	jobs, _, _, err := mgr.jobsClient.Versions(svcID, false, &api.QueryOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.RollbackSvc: mgr.jobsClient.Versions failed")
+	}
+	var job *api.Job
+	for _, jobVersion := range jobs {
+		if jobVersion.Version != nil && int64(*jobVersion.Version) == revision {
+			job = jobVersion
+		}
+	}
+	switch {
+	case job == nil:
+		return nil, fmt.Errorf("nomad.manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	case job == jobs[0]:
+		return nil, fmt.Errorf("nomad.manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	}
+	// Enforcing the current version guards the revert like reregisterJob
+	jobRegisterResponse, _, err := mgr.jobsClient.Revert(svcID, *job.Version, jobs[0].Version, &api.WriteOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.RollbackSvc: mgr.jobsClient.Revert failed")
+	}
+	svcCfg := svcCfgFromNomadJob(job)
+	dep := &deployment{
+		manager:         mgr,
+		jobID:           svcID,
+		evalID:          jobRegisterResponse.EvalID,
+		desiredCount:    svcCfg.Count,
+		update:          true,
+		timeoutDuration: getDeployTimeoutDuration(svcCfg),
+	}
+	return dep, nil
+}
+
+// DiffSvc returns how the job of the service with the ID of svcCfg differs from
+// the one that DeploySvc would register for svcCfg, or nil if there is no such
+// job.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	job, _, err := mgr.jobsClient.Info(svcCfg.ID, &api.QueryOptions{})
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.DiffSvc: mgr.jobsClient.Info failed")
 	}
-	return nil, nil
+	svcDiff := anysched.DiffSvcCfgs(svcCfgFromNomadJob(job), svcCfgFromNomadJob(getJob(svcCfg)))
+	svcDiff.Unknown = anysched.UnknownSvcSettings(svcCfg, svcCfgFromNomadJobSettings...)
+	return svcDiff, nil
 }
 
-// DestroySvc destroys a service.
+// svcCfgFromNomadJobSettings is the settings that svcCfgFromNomadJob reads back
+// from a job.
+var svcCfgFromNomadJobSettings = []string{"image", "count", "env", "labels", "ports", "resources"}
+
+// isNotFound returns whether err is the error that the Nomad API client returns
+// for HTTP 404, which it has no type for.
+func isNotFound(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "Unexpected response code: 404")
+}
+
+// svcCfgFromNomadJob returns a SvcCfg with the meta of a job as labels, the
+// count of its task group, and the image, environment variables, ports and
+// resources of its main task. Environment variables from secrets are rendered
+// by templates, so they are left out.
+func svcCfgFromNomadJob(job *api.Job) anysched.SvcCfg {
+	var svcCfg anysched.SvcCfg
+	if job.ID != nil {
+		svcCfg.ID = *job.ID
+	}
+	if len(job.TaskGroups) == 0 || len(job.TaskGroups[0].Tasks) == 0 {
+		return svcCfg
+	}
+	taskGroup, task := job.TaskGroups[0], job.TaskGroups[0].Tasks[0]
+	if taskGroup.Count != nil {
+		svcCfg.Count = *taskGroup.Count
+	}
+	svcCfg.Labels = job.Meta
+	svcCfg.Image, _ = task.Config["image"].(string)
+	svcCfg.Ports = portCfgsFromNomadTask(task)
+	if len(task.Env) > 0 {
+		svcCfg.Env = map[string]string{}
+		for name, value := range task.Env {
+			svcCfg.Env[name] = value
+		}
+	}
+	svcCfg.Resources = resourcesFromNomadTask(task.Resources, taskGroup.EphemeralDisk)
+	return svcCfg
+}
+
+// portCfgsFromNomadTask returns the ports of a task, with the host ports of its
+// network stanza and the container ports of its "port_map". It is the reverse
+// of getNetworks, except that reserved ports come first. The labels that
+// portLabel makes up are left out, and so is the protocol, since the docker
+// driver maps both.
+func portCfgsFromNomadTask(task *api.Task) []anysched.PortCfg {
+	if task.Resources == nil || len(task.Resources.Networks) == 0 {
+		return nil
+	}
+	portMap := dockerPortMap(task.Config)
+	network := task.Resources.Networks[0]
+	var portCfgs []anysched.PortCfg
+	for _, port := range append(append([]api.Port{}, network.ReservedPorts...), network.DynamicPorts...) {
+		portCfg := anysched.PortCfg{Name: port.Label, ContainerPort: port.Value, HostPort: port.Value}
+		// Without a "port_map", the docker driver uses the host port
+		if containerPort, ok := portMap[port.Label]; ok {
+			portCfg.ContainerPort = containerPort
+		}
+		if portCfg.Name == portLabel(anysched.PortCfg{ContainerPort: portCfg.ContainerPort}) {
+			portCfg.Name = ""
+		}
+		portCfgs = append(portCfgs, portCfg)
+	}
+	return portCfgs
+}
+
+// dockerPortMap returns the "port_map" of the docker driver config of a task,
+// which is a []map[string]int in a job that getJob returns, and decoded JSON in
+// one that Nomad returns.
+func dockerPortMap(config map[string]interface{}) map[string]int {
+	portMap := map[string]int{}
+	switch configPortMap := config["port_map"].(type) {
+	case []map[string]int:
+		for _, labelPorts := range configPortMap {
+			for label, port := range labelPorts {
+				portMap[label] = port
+			}
+		}
+	case []interface{}:
+		for _, labelPorts := range configPortMap {
+			labelPorts, _ := labelPorts.(map[string]interface{})
+			for label, port := range labelPorts {
+				if port, ok := port.(float64); ok {
+					portMap[label] = int(port)
+				}
+			}
+		}
+	}
+	return portMap
+}
+
+// resourcesFromNomadTask returns the resources of a task with its resources
+// and the ephemeral disk of its task group. It is the reverse of getResources
+// and getEphemeralDisk, except that the memory is the request.
+func resourcesFromNomadTask(taskResources *api.Resources, ephemeralDisk *api.EphemeralDisk) *anysched.Resources {
+	resources := &anysched.Resources{}
+	if taskResources != nil && taskResources.CPU != nil {
+		resources.CPU = float64(*taskResources.CPU) / cpuMHzPerCore
+	}
+	if taskResources != nil && taskResources.MemoryMB != nil {
+		resources.Memory = int64(*taskResources.MemoryMB) << 20
+	}
+	if ephemeralDisk != nil && ephemeralDisk.SizeMB != nil {
+		resources.Disk = int64(*ephemeralDisk.SizeMB) << 20
+	}
+	return resources
+}
+
+// DestroySvc deregisters and purges the job of a service, returning an
+// Operation that is done once its allocations have stopped. Nomad deregisters
+// a job that doesn't exist without an error, so DestroySvc looks it up first.
 func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
+	if _, _, err := mgr.jobsClient.Info(svcID, &api.QueryOptions{}); err != nil {
+		return nil, errors.Wrap(err, "nomad.manager.DestroySvc: mgr.jobsClient.Info failed")
+	}
 	purge := true
-	jobDeregisterResponse, writeMeta, err := mgr.jobsClient.Deregister(svcID, purge, &api.WriteOptions{})
-	fmt.Printf("*** jobDeregisterResponse = %+v; writeMeta = %+v; err = %+v\n", jobDeregisterResponse, writeMeta, err)
+	evalID, _, err := mgr.jobsClient.Deregister(svcID, purge, &api.WriteOptions{})
 	if err != nil {
 		return nil, errors.Wrap(err, "nomad.manager.DestroySvc: mgr.jobsClient.Deregister failed")
 	}
-	return nil, err
+	dereg := &deregistration{
+		manager:         mgr,
+		jobID:           svcID,
+		evalID:          evalID,
+		timeoutDuration: 60 * time.Second,
+	}
+	return dereg, nil
+}
+
+// getDockerDriverConfig returns the config of a task for Nomad's docker driver,
+// which takes the entrypoint override as a "command" and its arguments.
+func getDockerDriverConfig(svcCfg anysched.SvcCfg) map[string]interface{} {
+	config := map[string]interface{}{
+		"image": svcCfg.Image,
+	}
+	args := svcCfg.Args
+	if len(svcCfg.Command) > 0 {
+		config["command"] = svcCfg.Command[0]
+		args = append(append([]string{}, svcCfg.Command[1:]...), svcCfg.Args...)
+	}
+	if len(args) > 0 {
+		config["args"] = args
+	}
+	if len(svcCfg.Ports) > 0 {
+		portMap := map[string]int{}
+		for _, portCfg := range svcCfg.Ports {
+			portMap[portLabel(portCfg)] = portCfg.ContainerPort
+		}
+		config["port_map"] = []map[string]int{portMap}
+	}
+	if volumes := getDockerVolumes(svcCfg); len(volumes) > 0 {
+		config["volumes"] = volumes
+	}
+	if volumeDriver, _ := getVolumeDriver(svcCfg.Volumes); volumeDriver != "" {
+		config["volume_driver"] = volumeDriver
+	}
+	return config
+}
+
+// getDockerVolumes returns the "volumes" of the docker driver config of a task,
+// in the form "SOURCE:MOUNT_PATH[:ro]". The Nomad API that this manager uses
+// has no volume_mount stanza, so scratch volumes are directories in the task's
+// local directory, which is what a relative source is, and secrets are files
+// that templates render in its secrets directory. Host paths and named volumes
+// need docker.volumes.enabled on the Nomad clients.
+func getDockerVolumes(svcCfg anysched.SvcCfg) []string {
+	var dockerVolumes []string
+	for i, volumeCfg := range svcCfg.Volumes {
+		source := volumeCfg.Source
+		if volumeCfg.Type == anysched.VolumeScratch {
+			source = fmt.Sprintf("local/volume-%d", i)
+		}
+		dockerVolume := source + ":" + volumeCfg.MountPath
+		if volumeCfg.ReadOnly {
+			dockerVolume += ":ro"
+		}
+		dockerVolumes = append(dockerVolumes, dockerVolume)
+	}
+	for i, secretRef := range svcCfg.Secrets {
+		if secretRef.MountPath != "" {
+			dockerVolumes = append(dockerVolumes, secretPath(i)+":"+secretRef.MountPath+":ro")
+		}
+	}
+	return dockerVolumes
+}
+
+// getTemplates returns the templates that render the secrets of a task from
+// Vault, or nil if it has none: one with the environment variables, which
+// Nomad sets in the task, and one for each file, which the docker driver
+// mounts.
+func getTemplates(secretRefs []anysched.SecretRef) []*api.Template {
+	var (
+		templates []*api.Template
+		envLines  []string
+	)
+	for i, secretRef := range secretRefs {
+		if secretRef.EnvVar != "" {
+			// toJSON quotes the value, so that it can have spaces or newlines
+			envLines = append(envLines, secretRef.EnvVar+"="+vaultTemplate(secretRef, " | toJSON"))
+			continue
+		}
+		templates = append(templates, &api.Template{
+			DestPath:     utils.Sptr(secretPath(i)),
+			EmbeddedTmpl: utils.Sptr(vaultTemplate(secretRef, "")),
+		})
+	}
+	if envLines != nil {
+		templates = append(templates, &api.Template{
+			DestPath:     utils.Sptr("secrets/secrets.env"),
+			EmbeddedTmpl: utils.Sptr(strings.Join(envLines, "\n") + "\n"),
+			Envvars:      utils.Bptr(true),
+		})
+	}
+	return templates
+}
+
+// vaultTemplate returns a template that renders the value of the key of a
+// secret in Vault, piped to filters.
+func vaultTemplate(secretRef anysched.SecretRef, filters string) string {
+	return fmt.Sprintf(`{{ with secret %q }}{{ index .Data %q%s }}{{ end }}`, secretRef.Secret, secretRef.Key, filters)
+}
+
+// getVault returns the vault stanza of a task that has secrets, which gets it
+// a Vault token for the policy named after the service, or nil if it has none.
+func getVault(svcCfg anysched.SvcCfg) *api.Vault {
+	if len(svcCfg.Secrets) == 0 {
+		return nil
+	}
+	return &api.Vault{Policies: []string{svcCfg.ID}}
+}
+
+// secretPath returns the path, relative to the task directory, of the file
+// that the template for the i-th secret of a task renders.
+func secretPath(i int) string {
+	return fmt.Sprintf("secrets/secret-%d", i)
+}
+
+// getVolumeDriver returns the driver of the named volumes of a task, or an
+// error if they have different drivers, since the docker driver has one
+// "volume_driver" for all of them.
+func getVolumeDriver(volumes []anysched.VolumeCfg) (string, error) {
+	var volumeDriver string
+	for _, volumeCfg := range volumes {
+		if volumeCfg.Driver == "" || volumeCfg.Driver == volumeDriver {
+			continue
+		}
+		if volumeDriver != "" {
+			return "", fmt.Errorf("volumes with different drivers, %q and %q, cannot be mounted in one Nomad task",
+				volumeDriver, volumeCfg.Driver)
+		}
+		volumeDriver = volumeCfg.Driver
+	}
+	return volumeDriver, nil
+}
+
+// getNetworks returns the network stanza of a task, which asks Nomad for a
+// port on the host for each port of the service: HostPort if it is set, or
+// else a dynamic port. The docker driver maps each of them to ContainerPort
+// with "port_map", for both TCP and UDP.
+func getNetworks(svcCfg anysched.SvcCfg) []*api.NetworkResource {
+	if len(svcCfg.Ports) == 0 {
+		return nil
+	}
+	network := &api.NetworkResource{}
+	for _, portCfg := range svcCfg.Ports {
+		port := api.Port{Label: portLabel(portCfg), Value: portCfg.HostPort}
+		if portCfg.HostPort == 0 {
+			network.DynamicPorts = append(network.DynamicPorts, port)
+		} else {
+			network.ReservedPorts = append(network.ReservedPorts, port)
+		}
+	}
+	return []*api.NetworkResource{network}
+}
+
+// getResources returns the resources of a task. Nomad allocates CPU in MHz,
+// so cores are converted with cpuMHzPerCore. Nomad kills a task that uses more
+// memory than its MemoryMB, so it is the memory limit, if there is one.
+func getResources(svcCfg anysched.SvcCfg) *api.Resources {
+	resources := &api.Resources{Networks: getNetworks(svcCfg)}
+	if svcCfg.Resources == nil {
+		return resources
+	}
+	if svcCfg.Resources.CPU != 0 {
+		resources.CPU = utils.Iptr(int(math.Ceil(svcCfg.Resources.CPU * cpuMHzPerCore)))
+	}
+	if memory := svcCfg.Resources.EffectiveMemoryLimit(); memory != 0 {
+		resources.MemoryMB = utils.Iptr(mebibytes(memory))
+	}
+	return resources
+}
+
+// getEphemeralDisk returns the disk of a task group, or nil for Nomad's
+// default.
+func getEphemeralDisk(resources *anysched.Resources) *api.EphemeralDisk {
+	if resources == nil || resources.Disk == 0 {
+		return nil
+	}
+	return &api.EphemeralDisk{SizeMB: utils.Iptr(mebibytes(resources.Disk))}
+}
+
+// mebibytes converts bytes to MiB, rounded up.
+func mebibytes(bytes int64) int {
+	return int((bytes + (1 << 20) - 1) >> 20)
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that Nomad cannot
+// express. Consul runs HTTP and TCP checks against a port on the host, so their
+// ports have to be in Ports, the Nomad API that this manager uses has no
+// spread stanza, so tasks cannot be spread by an attribute, or lifecycle
+// hooks, so there are no init containers, and the docker driver has one volume
+// driver for all of the volumes of a task. Namespaces are a Nomad Enterprise
+// feature, which this manager doesn't support.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	if svcCfg.Namespace != "" {
+		return fmt.Errorf("service %q: this manager does not support Nomad namespaces", svcCfg.ID)
+	}
+	if len(svcCfg.InitContainers) > 0 {
+		return fmt.Errorf("service %q: Nomad cannot run init containers", svcCfg.ID)
+	}
+	if _, err := getVolumeDriver(svcCfg.Volumes); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	for _, constraint := range svcCfg.Constraints {
+		if constraint.Operator == anysched.ConstraintSpread {
+			return fmt.Errorf("service %q: Nomad cannot spread tasks by %q", svcCfg.ID, constraint.Attribute)
+		}
+	}
+	return validateCheckPorts(svcCfg)
+}
+
+// validateCheckPorts returns an error if an HTTP or TCP check uses a port that
+// isn't in Ports, since Consul runs them against a port on the host.
+func validateCheckPorts(svcCfg anysched.SvcCfg) error {
+	for _, healthCheck := range []*anysched.HealthCheck{svcCfg.HealthCheck, svcCfg.ReadinessCheck} {
+		if healthCheck == nil || healthCheck.Kind() == anysched.HealthCheckCommand {
+			continue
+		}
+		if findPortCfg(svcCfg, healthCheck.Port) == nil {
+			return fmt.Errorf("service %q: the port of a %s check, %d, must be in Ports",
+				svcCfg.ID, healthCheck.Kind(), healthCheck.Port)
+		}
+	}
+	return nil
+}
+
+// findPortCfg returns the port of a service with ContainerPort port, or nil if
+// there isn't one.
+func findPortCfg(svcCfg anysched.SvcCfg, port int) *anysched.PortCfg {
+	for i := range svcCfg.Ports {
+		if svcCfg.Ports[i].ContainerPort == port {
+			return &svcCfg.Ports[i]
+		}
+	}
+	return nil
+}
+
+// getServices returns the Consul service of a task, with its health and
+// readiness checks, or nil if it has neither. Nomad restarts a task when its
+// health check fails, and its deployments wait for all of the checks to pass.
+func getServices(svcCfg anysched.SvcCfg) []*api.Service {
+	if svcCfg.HealthCheck == nil && svcCfg.ReadinessCheck == nil {
+		return nil
+	}
+	service := &api.Service{Name: svcCfg.ID}
+	if len(svcCfg.Ports) > 0 {
+		service.PortLabel = portLabel(svcCfg.Ports[0])
+	}
+	if healthCheck := svcCfg.HealthCheck; healthCheck != nil {
+		serviceCheck := getServiceCheck(svcCfg, "health", healthCheck)
+		serviceCheck.CheckRestart = &api.CheckRestart{
+			Limit: healthCheck.FailureThresholdOrDefault(),
+			Grace: &healthCheck.GracePeriod,
+		}
+		service.Checks = append(service.Checks, serviceCheck)
+	}
+	if svcCfg.ReadinessCheck != nil {
+		service.Checks = append(service.Checks, getServiceCheck(svcCfg, "readiness", svcCfg.ReadinessCheck))
+	}
+	return []*api.Service{service}
+}
+
+func getServiceCheck(svcCfg anysched.SvcCfg, name string, healthCheck *anysched.HealthCheck) api.ServiceCheck {
+	serviceCheck := api.ServiceCheck{
+		Name:     name,
+		Interval: healthCheck.IntervalOrDefault(),
+		Timeout:  healthCheck.TimeoutOrDefault(),
+	}
+	switch healthCheck.Kind() {
+	case anysched.HealthCheckCommand:
+		serviceCheck.Type = "script"
+		serviceCheck.Command = healthCheck.Command[0]
+		serviceCheck.Args = healthCheck.Command[1:]
+		return serviceCheck
+	case anysched.HealthCheckHTTP:
+		serviceCheck.Type = "http"
+		serviceCheck.Path = healthCheck.HTTPPath
+	case anysched.HealthCheckTCP:
+		serviceCheck.Type = "tcp"
+	}
+	serviceCheck.PortLabel = portLabel(*findPortCfg(svcCfg, healthCheck.Port))
+	return serviceCheck
+}
+
+// getSidecarTasks returns a task for each sidecar, which Nomad runs in the
+// same allocation as the main task. A sidecar is rendered like a service with
+// just its image, command, environment, ports and resources.
+func getSidecarTasks(sidecars []anysched.ContainerCfg) []*api.Task {
+	tasks := make([]*api.Task, len(sidecars))
+	for i, containerCfg := range sidecars {
+		sidecarSvcCfg := anysched.SvcCfg{
+			ID:        containerCfg.Name,
+			Image:     containerCfg.Image,
+			Command:   containerCfg.Command,
+			Args:      containerCfg.Args,
+			Env:       containerCfg.Env,
+			Ports:     containerCfg.Ports,
+			Resources: containerCfg.Resources,
+		}
+		tasks[i] = &api.Task{
+			Name:      containerCfg.Name,
+			Driver:    "docker",
+			Config:    getDockerDriverConfig(sidecarSvcCfg),
+			Env:       containerCfg.Env,
+			Resources: getResources(sidecarSvcCfg),
+		}
+	}
+	return tasks
+}
+
+// getConstraints returns the constraints of a job, or nil if there aren't any.
+// Values of regexp constraints are escaped, and anchored so that they have to
+// match the whole attribute. validateSvcCfg makes sure that there are no
+// spread constraints.
+func getConstraints(constraints []anysched.Constraint) []*api.Constraint {
+	var nomadConstraints []*api.Constraint
+	for _, constraint := range constraints {
+		attribute := nomadAttribute(constraint.Attribute)
+		switch constraint.Operator {
+		case anysched.ConstraintEquals:
+			nomadConstraints = append(nomadConstraints, api.NewConstraint(attribute, "=", constraint.Values[0]))
+		case anysched.ConstraintNotEquals:
+			nomadConstraints = append(nomadConstraints, api.NewConstraint(attribute, "!=", constraint.Values[0]))
+		case anysched.ConstraintIn:
+			quotedValues := make([]string, len(constraint.Values))
+			for i, value := range constraint.Values {
+				quotedValues[i] = regexp.QuoteMeta(value)
+			}
+			regexpValue := "^(" + strings.Join(quotedValues, "|") + ")$"
+			nomadConstraints = append(nomadConstraints, api.NewConstraint(attribute, "regexp", regexpValue))
+		case anysched.ConstraintUniquePerHost:
+			nomadConstraints = append(nomadConstraints, api.NewConstraint("", "distinct_hosts", "true"))
+		}
+	}
+	return nomadConstraints
+}
+
+// nomadAttribute returns the Nomad interpolation for an attribute of a
+// constraint: the node's name for anysched.ConstraintAttributeHostname, or else
+// a key of the node's meta, unless it is already an interpolation like
+// "${node.class}".
+func nomadAttribute(attribute string) string {
+	switch {
+	case attribute == anysched.ConstraintAttributeHostname:
+		return "${node.unique.name}"
+	case strings.HasPrefix(attribute, "${"):
+		return attribute
+	}
+	return "${meta." + attribute + "}"
+}
+
+// portLabel returns the label of a port in the network stanza and "port_map".
+func portLabel(portCfg anysched.PortCfg) string {
+	if portCfg.Name != "" {
+		return portCfg.Name
+	}
+	return fmt.Sprintf("port%d", portCfg.ContainerPort)
 }
 
 func getJob(svcCfg anysched.SvcCfg) *api.Job {
@@ -74,19 +944,25 @@ func getJob(svcCfg anysched.SvcCfg) *api.Job {
 		Name:        utils.Sptr(svcCfg.ID),
 		Type:        utils.Sptr(api.JobTypeService),
 		Datacenters: []string{"dc1"},
+		Meta:        svcCfg.Labels,
+		Constraints: getConstraints(svcCfg.Constraints),
 		TaskGroups: []*api.TaskGroup{
 			&api.TaskGroup{
-				Name:  utils.Sptr(svcCfg.ID),
-				Count: &svcCfg.Count,
-				Tasks: []*api.Task{
+				Name:          utils.Sptr(svcCfg.ID),
+				Count:         &svcCfg.Count,
+				EphemeralDisk: getEphemeralDisk(svcCfg.Resources),
+				Tasks: append([]*api.Task{
 					&api.Task{
-						Name:   svcCfg.ID,
-						Driver: "docker",
-						Config: map[string]interface{}{
-							"image": svcCfg.Image,
-						},
+						Name:      svcCfg.ID,
+						Driver:    "docker",
+						Config:    getDockerDriverConfig(svcCfg),
+						Env:       svcCfg.Env,
+						Templates: getTemplates(svcCfg.Secrets),
+						Vault:     getVault(svcCfg),
+						Resources: getResources(svcCfg),
+						Services:  getServices(svcCfg),
 					},
-				},
+				}, getSidecarTasks(svcCfg.Sidecars)...),
 			},
 		},
 	}
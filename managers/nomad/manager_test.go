@@ -1,10 +1,138 @@
 package nomad
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/utils"
+)
+
+// NewTestServerJSONRoutes returns a test server that responds to each URL path
+// in routes with the contents of the corresponding JSON file, and with HTTP 404
+// for any other path.
+func NewTestServerJSONRoutes(routes map[string]string) *httptest.Server {
+	routeSequences := make(map[string][]string, len(routes))
+	for path, jsonResponseFilePath := range routes {
+		routeSequences[path] = []string{jsonResponseFilePath}
+	}
+	return NewTestServerJSONRouteSequences(routeSequences)
+}
+
+// NewTestServerJSONRouteSequences is like NewTestServerJSONRoutes, but each URL
+// path responds with the next JSON file in its sequence every time that it is
+// requested, repeating the last one once the sequence is exhausted.
+func NewTestServerJSONRouteSequences(routeSequences map[string][]string) *httptest.Server {
+	var mutex sync.Mutex
+	counts := map[string]int{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		jsonResponseFilePaths, ok := routeSequences[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		writeJSONResponseFromFile(w, jsonResponseFilePaths[counts[r.URL.Path]])
+		if counts[r.URL.Path] < len(jsonResponseFilePaths)-1 {
+			counts[r.URL.Path]++
+		}
+	}))
+}
+
+func writeJSONResponseFromFile(w http.ResponseWriter, jsonResponseFilePath string) {
+	bytes, err := ioutil.ReadFile(jsonResponseFilePath)
+	if err != nil {
+		panic(err)
+	}
+	writeJSONResponseBytes(w, bytes)
+}
+
+// writeJSONResponseBytes writes a JSON response along with the headers that
+// the Nomad API client requires to be present on query responses.
+func writeJSONResponseBytes(w http.ResponseWriter, bytes []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytes)))
+	w.Header().Set("X-Nomad-Index", "31")
+	w.Header().Set("X-Nomad-LastContact", "0")
+	w.Header().Set("X-Nomad-KnownLeader", "true")
+	w.Write(bytes)
+}
+
+func NewManagerWithTestServer(ts *httptest.Server) anysched.Manager {
+	manager, err := NewManager(ts.URL)
+	if err != nil {
+		panic(err)
+	}
+	return manager
+}
+
+var (
+	httpbinRoutes = map[string]string{
+		"/v1/jobs":                    "testdata/jobs_list.json",
+		"/v1/job/httpbin":             "testdata/job_httpbin.json",
+		"/v1/job/httpbin/deployment":  "testdata/job_httpbin_deployment.json",
+		"/v1/job/httpbin/summary":     "testdata/job_httpbin_summary.json",
+		"/v1/job/httpbin/allocations": "testdata/job_httpbin_allocations.json",
+		"/v1/allocations":             "testdata/allocations_list.json",
+		"/v1/allocation/a8198d79-cfdb-6593-a999-1e9adabcba2e": "testdata/allocation_a8198d79.json",
+		"/v1/allocation/1e7a3a84-0ce4-0b6c-1d2e-5b6b3e1b8c11": "testdata/allocation_1e7a3a84.json",
+		"/v1/nodes": "testdata/nodes_list.json",
+	}
+
+	httpbinEvalPath       = "/v1/evaluation/5456bd7a-9fc0-c0dd-6131-cbee77f57577"
+	httpbinDeploymentPath = "/v1/deployment/0e4f1b6a-2c3d-4e5f-8a9b-1c2d3e4f5a6b"
 )
 
+func deployHttpbin(ts *httptest.Server) *deployment {
+	mgr := NewManagerWithTestServer(ts)
+	timeout := 5 * time.Second
+	svcCfg := anysched.SvcCfg{
+		ID:                    "httpbin",
+		Image:                 "citizenstig/httpbin",
+		Count:                 2,
+		DeployTimeoutDuration: &timeout,
+	}
+	op, err := mgr.DeploySvc(svcCfg)
+	Expect(err).ToNot(HaveOccurred())
+	return op.(*deployment)
+}
+
+func expectHttpbinTasks(tasks []anysched.Task) {
+	Expect(tasks).To(HaveLen(2))
+
+	Expect(tasks[0].Name).To(Equal("a8198d79-cfdb-6593-a999-1e9adabcba2e"))
+	Expect(tasks[0].AppID).To(Equal("httpbin"))
+	Expect(tasks[0].HostName).To(Equal("nomad-client-1"))
+	Expect(tasks[0].HostIP).To(Equal("10.0.2.15"))
+	Expect(tasks[0].TaskIP).To(Equal("10.0.2.15"))
+	Expect(tasks[0].IPAddresses).To(Equal([]string{"10.0.2.15"}))
+	Expect(tasks[0].Ports).To(Equal([]int{23476}))
+	Expect(tasks[0].State).To(Equal("running"))
+	Expect(tasks[0].Version).To(Equal("1"))
+	Expect((*tasks[0].StartTime).UTC().Format(time.RFC3339Nano)).To(Equal("2018-07-25T18:49:05.123456789Z"))
+	Expect((*tasks[0].StageTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:02Z"))
+
+	Expect(tasks[1].Name).To(Equal("1e7a3a84-0ce4-0b6c-1d2e-5b6b3e1b8c11"))
+	Expect(tasks[1].AppID).To(Equal("httpbin"))
+	Expect(tasks[1].HostName).To(Equal("nomad-client-2"))
+	Expect(tasks[1].TaskIP).To(Equal("10.0.2.16"))
+	Expect(tasks[1].Ports).To(Equal([]int{8081, 31005}))
+	Expect(tasks[1].State).To(Equal("running"))
+	Expect((*tasks[1].StartTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:07Z"))
+}
+
 var _ = Describe("nomad/manager.go", func() {
 	Describe("NewManager", func() {
 		It("works", func() {
@@ -20,4 +148,898 @@ var _ = Describe("nomad/manager.go", func() {
 			Expect(manager).To(BeNil())
 		})
 	})
+
+	Describe("Svcs", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		Context("healthy nomad", func() {
+			BeforeEach(func() {
+				ts = NewTestServerJSONRoutes(httpbinRoutes)
+				manager = NewManagerWithTestServer(ts)
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("works", func() {
+				svcs, err := manager.Svcs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(svcs).To(HaveLen(1))
+				Expect(svcs[0].ID).To(Equal("httpbin"))
+				Expect(*svcs[0].TasksRunning).To(Equal(2))
+				Expect(*svcs[0].TasksHealthy).To(Equal(1))
+				Expect(*svcs[0].TasksUnhealthy).To(Equal(1))
+				Expect((*svcs[0].CreationTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:01Z"))
+				Expect(svcs[0].Labels).To(Equal(map[string]string{"team": "payments"}))
+			})
+		})
+
+		Context("unhealthy nomad", func() {
+			BeforeEach(func() {
+				ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(500)
+				}))
+				manager = NewManagerWithTestServer(ts)
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("returns an error", func() {
+				svcs, err := manager.Svcs()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobsClient.List failed"))
+				Expect(svcs).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Svc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the job with its summary and latest deployment", func() {
+			ts = NewTestServerJSONRoutes(httpbinRoutes)
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("httpbin"))
+			Expect(*svcDetail.Svc.TasksRunning).To(Equal(2))
+			Expect(*svcDetail.Svc.TasksHealthy).To(Equal(1))
+			Expect((*svcDetail.Svc.CreationTime).UTC().Format(time.RFC3339)).To(Equal("2018-07-25T18:49:01Z"))
+			Expect(svcDetail.SvcCfg.ID).To(Equal("httpbin"))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("citizenstig/httpbin"))
+			Expect(svcDetail.SvcCfg.Count).To(Equal(2))
+			Expect(svcDetail.SvcCfg.Labels).To(Equal(map[string]string{"team": "payments"}))
+			Expect(*svcDetail.Revision).To(Equal(int64(0)))
+			Expect(svcDetail.Native).To(BeAssignableToTypeOf(&api.Job{}))
+		})
+
+		It("returns an error if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{})
+			manager := NewManagerWithTestServer(ts)
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("nomad.manager.Svc: mgr.jobsClient.Info failed"))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("Tasks", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		Context("healthy nomad", func() {
+			BeforeEach(func() {
+				ts = NewTestServerJSONRoutes(httpbinRoutes)
+				manager = NewManagerWithTestServer(ts)
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("works and skips terminal allocations", func() {
+				tasks, err := manager.Tasks()
+				Expect(err).ToNot(HaveOccurred())
+				expectHttpbinTasks(tasks)
+			})
+		})
+
+		Context("unhealthy nomad", func() {
+			BeforeEach(func() {
+				ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(500)
+				}))
+				manager = NewManagerWithTestServer(ts)
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("returns an error", func() {
+				tasks, err := manager.Tasks()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("allocationsClient.List failed"))
+				Expect(tasks).To(BeNil())
+			})
+		})
+	})
+
+	Describe("SvcTasks", func() {
+		var (
+			manager anysched.Manager
+			ts      *httptest.Server
+		)
+
+		Context("healthy nomad", func() {
+			BeforeEach(func() {
+				ts = NewTestServerJSONRoutes(httpbinRoutes)
+				manager = NewManagerWithTestServer(ts)
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("works and skips terminal allocations", func() {
+				tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "httpbin"})
+				Expect(err).ToNot(HaveOccurred())
+				expectHttpbinTasks(tasks)
+			})
+
+			It("returns an error for an unknown job", func() {
+				tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "does-not-exist"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`jobsClient.Allocations("does-not-exist") failed`))
+				Expect(tasks).To(BeNil())
+			})
+		})
+	})
+
+	Describe("DeploySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns a deployment that tracks the registration's evaluation", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/v1/jobs": "testdata/job_register.json"})
+			dep := deployHttpbin(ts)
+			Expect(dep.GetProperties()).To(Equal(map[string]interface{}{
+				"jobID":  "httpbin",
+				"evalID": "5456bd7a-9fc0-c0dd-6131-cbee77f57577",
+			}))
+			Expect(dep.String()).To(Equal(
+				`<nomad.deployment jobID="httpbin" evalID="5456bd7a-9fc0-c0dd-6131-cbee77f57577" />`))
+		})
+
+		It("registers the job only if it does not exist already", func() {
+			var jobRegisterRequest api.RegisterJobRequest
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&jobRegisterRequest)
+				writeJSONResponseFromFile(w, "testdata/job_register.json")
+			}))
+			deployHttpbin(ts)
+			Expect(jobRegisterRequest.EnforceIndex).To(BeTrue())
+			Expect(jobRegisterRequest.JobModifyIndex).To(BeZero())
+		})
+
+		It("returns an error if the job already exists", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+				w.Write([]byte("Enforcing job modify index 0: job already exists"))
+			}))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(MatchError("nomad.manager.DeploySvc: mgr.jobsClient.EnforceRegister failed: " +
+				"Unexpected response code: 500 (Enforcing job modify index 0: job already exists)"))
+			Expect(op).To(BeNil())
+		})
+
+		It("sets the environment variables of the task", func() {
+			var jobRegisterRequest struct{ Job api.Job }
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&jobRegisterRequest)
+				writeJSONResponseFromFile(w, "testdata/job_register.json")
+			}))
+			manager := NewManagerWithTestServer(ts)
+			_, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Env:   map[string]string{"PORT": "8000"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jobRegisterRequest.Job.TaskGroups[0].Tasks[0].Env).To(Equal(map[string]string{"PORT": "8000"}))
+		})
+
+		It("passes an overridden entrypoint and its arguments to the docker driver", func() {
+			config := getDockerDriverConfig(anysched.SvcCfg{
+				Image:   "citizenstig/httpbin",
+				Command: []string{"gunicorn", "--bind=0.0.0.0:8000"},
+				Args:    []string{"httpbin:app"},
+			})
+			Expect(config).To(Equal(map[string]interface{}{
+				"image":   "citizenstig/httpbin",
+				"command": "gunicorn",
+				"args":    []string{"--bind=0.0.0.0:8000", "httpbin:app"},
+			}))
+
+			config = getDockerDriverConfig(anysched.SvcCfg{Image: "citizenstig/httpbin", Args: []string{"worker"}})
+			Expect(config).To(Equal(map[string]interface{}{
+				"image": "citizenstig/httpbin",
+				"args":  []string{"worker"},
+			}))
+		})
+
+		It("asks for a host port for each port and maps it to the container port", func() {
+			svcCfg := anysched.SvcCfg{
+				Image: "citizenstig/httpbin",
+				Ports: []anysched.PortCfg{{Name: "http", ContainerPort: 8000, HostPort: 80}, {ContainerPort: 53, Protocol: "udp"}},
+			}
+			Expect(getDockerDriverConfig(svcCfg)).To(Equal(map[string]interface{}{
+				"image":    "citizenstig/httpbin",
+				"port_map": []map[string]int{{"http": 8000, "port53": 53}},
+			}))
+			Expect(getNetworks(svcCfg)).To(Equal([]*api.NetworkResource{{
+				ReservedPorts: []api.Port{{Label: "http", Value: 80}},
+				DynamicPorts:  []api.Port{{Label: "port53"}},
+			}}))
+			Expect(getNetworks(anysched.SvcCfg{Image: "citizenstig/httpbin"})).To(BeNil())
+		})
+
+		It("sets the resources of the task and the disk of the group", func() {
+			job := getJob(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     2,
+				Resources: &anysched.Resources{CPU: 0.25, Memory: 256 << 20, Disk: 1<<30 + 1},
+			})
+			resources := job.TaskGroups[0].Tasks[0].Resources
+			Expect(*resources.CPU).To(Equal(250))
+			Expect(*resources.MemoryMB).To(Equal(256))
+			Expect(*job.TaskGroups[0].EphemeralDisk.SizeMB).To(Equal(1025))
+
+			job = getJob(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(job.TaskGroups[0].Tasks[0].Resources.CPU).To(BeNil())
+			Expect(job.TaskGroups[0].EphemeralDisk).To(BeNil())
+		})
+
+		It("translates the constraints", func() {
+			job := getJob(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{
+					{Attribute: "disk", Operator: anysched.ConstraintEquals, Values: []string{"ssd"}},
+					{Attribute: "hostname", Operator: anysched.ConstraintNotEquals, Values: []string{"node-1"}},
+					{Attribute: "${node.class}", Operator: anysched.ConstraintIn, Values: []string{"web", "web.large"}},
+					{Operator: anysched.ConstraintUniquePerHost},
+				},
+			})
+			Expect(job.Constraints).To(Equal([]*api.Constraint{
+				{LTarget: "${meta.disk}", Operand: "=", RTarget: "ssd"},
+				{LTarget: "${node.unique.name}", Operand: "!=", RTarget: "node-1"},
+				{LTarget: "${node.class}", Operand: "regexp", RTarget: `^(web|web\.large)$`},
+				{Operand: "distinct_hosts", RTarget: "true"},
+			}))
+			Expect(getJob(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"}).Constraints).To(BeNil())
+		})
+
+		It("returns an error for a spread constraint", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:          "httpbin",
+				Image:       "citizenstig/httpbin",
+				Constraints: []anysched.Constraint{{Attribute: "zone", Operator: anysched.ConstraintSpread}},
+			})
+			Expect(err).To(MatchError(`nomad.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Nomad cannot spread tasks by "zone"`))
+			Expect(op).To(BeNil())
+		})
+
+		It("mounts the volumes with the docker driver", func() {
+			config := getDockerDriverConfig(anysched.SvcCfg{
+				Image: "citizenstig/httpbin",
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeHostPath, Source: "/var/log", MountPath: "/logs", ReadOnly: true},
+					{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data", Driver: "rexray"},
+					{Type: anysched.VolumeScratch, MountPath: "/tmp"},
+				},
+			})
+			Expect(config).To(Equal(map[string]interface{}{
+				"image":         "citizenstig/httpbin",
+				"volumes":       []string{"/var/log:/logs:ro", "httpbin-data:/data", "local/volume-2:/tmp"},
+				"volume_driver": "rexray",
+			}))
+		})
+
+		It("renders secrets from Vault with templates", func() {
+			job := getJob(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Secrets: []anysched.SecretRef{
+					{Secret: "secret/db", Key: "password", EnvVar: "DB_PASSWORD"},
+					{Secret: "secret/tls", Key: "key", MountPath: "/etc/tls/key.pem"},
+				},
+			})
+			task := job.TaskGroups[0].Tasks[0]
+			Expect(task.Vault).To(Equal(&api.Vault{Policies: []string{"httpbin"}}))
+			Expect(task.Templates).To(Equal([]*api.Template{
+				{
+					DestPath:     utils.Sptr("secrets/secret-1"),
+					EmbeddedTmpl: utils.Sptr(`{{ with secret "secret/tls" }}{{ index .Data "key" }}{{ end }}`),
+				},
+				{
+					DestPath: utils.Sptr("secrets/secrets.env"),
+					EmbeddedTmpl: utils.Sptr(
+						`DB_PASSWORD={{ with secret "secret/db" }}{{ index .Data "password" | toJSON }}{{ end }}` + "\n"),
+					Envvars: utils.Bptr(true),
+				},
+			}))
+			Expect(task.Config["volumes"]).To(Equal([]string{"secrets/secret-1:/etc/tls/key.pem:ro"}))
+
+			job = getJob(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"})
+			Expect(job.TaskGroups[0].Tasks[0].Templates).To(BeNil())
+			Expect(job.TaskGroups[0].Tasks[0].Vault).To(BeNil())
+		})
+
+		It("runs each sidecar as a task in the group", func() {
+			job := getJob(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Count: 2,
+				Sidecars: []anysched.ContainerCfg{{
+					Name:      "log-forwarder",
+					Image:     "fluent/fluent-bit:0.13",
+					Env:       map[string]string{"FLB_LOG_LEVEL": "info"},
+					Ports:     []anysched.PortCfg{{Name: "metrics", ContainerPort: 2020}},
+					Resources: &anysched.Resources{Memory: 64 << 20},
+				}},
+			})
+			tasks := job.TaskGroups[0].Tasks
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Name).To(Equal("httpbin"))
+			sidecar := tasks[1]
+			Expect(sidecar.Name).To(Equal("log-forwarder"))
+			Expect(sidecar.Driver).To(Equal("docker"))
+			Expect(sidecar.Config).To(Equal(map[string]interface{}{
+				"image":    "fluent/fluent-bit:0.13",
+				"port_map": []map[string]int{{"metrics": 2020}},
+			}))
+			Expect(sidecar.Env).To(Equal(map[string]string{"FLB_LOG_LEVEL": "info"}))
+			Expect(*sidecar.Resources.MemoryMB).To(Equal(64))
+			Expect(sidecar.Resources.Networks[0].DynamicPorts).To(Equal([]api.Port{{Label: "metrics"}}))
+			Expect(sidecar.Services).To(BeNil())
+		})
+
+		It("returns an error for a namespace", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Namespace: "payments", Image: "citizenstig/httpbin"})
+			Expect(err).To(MatchError(`nomad.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": this manager does not support Nomad namespaces`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for init containers", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				InitContainers: []anysched.ContainerCfg{{Name: "migrate", Image: "example/payments:1.2"}},
+			})
+			Expect(err).To(MatchError(`nomad.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": Nomad cannot run init containers`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for volumes with different drivers", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "httpbin",
+				Image: "citizenstig/httpbin",
+				Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeNamed, Source: "httpbin-data", MountPath: "/data", Driver: "rexray"},
+					{Type: anysched.VolumeNamed, Source: "httpbin-cache", MountPath: "/cache", Driver: "local"},
+				},
+			})
+			Expect(err).To(MatchError(`nomad.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": volumes with different drivers, "rexray" and "local", cannot be mounted in one Nomad task`))
+			Expect(op).To(BeNil())
+		})
+
+		It("puts the labels in the meta of the job", func() {
+			job := getJob(anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin",
+				Labels: map[string]string{"team": "payments"},
+			})
+			Expect(job.Meta).To(Equal(map[string]string{"team": "payments"}))
+		})
+
+		It("adds a service with health and readiness checks", func() {
+			services := getServices(anysched.SvcCfg{
+				ID:             "httpbin",
+				Image:          "citizenstig/httpbin",
+				Ports:          []anysched.PortCfg{{Name: "http", ContainerPort: 8000}},
+				HealthCheck:    &anysched.HealthCheck{Command: []string{"pgrep", "gunicorn"}, GracePeriod: time.Minute},
+				ReadinessCheck: &anysched.HealthCheck{HTTPPath: "/status/200", Port: 8000, Interval: 2 * time.Second},
+			})
+			grace := time.Minute
+			Expect(services).To(Equal([]*api.Service{{
+				Name:      "httpbin",
+				PortLabel: "http",
+				Checks: []api.ServiceCheck{
+					{
+						Name:         "health",
+						Type:         "script",
+						Command:      "pgrep",
+						Args:         []string{"gunicorn"},
+						Interval:     10 * time.Second,
+						Timeout:      5 * time.Second,
+						CheckRestart: &api.CheckRestart{Limit: 3, Grace: &grace},
+					},
+					{
+						Name:      "readiness",
+						Type:      "http",
+						Path:      "/status/200",
+						PortLabel: "http",
+						Interval:  2 * time.Second,
+						Timeout:   5 * time.Second,
+					},
+				},
+			}}))
+			Expect(getServices(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin"})).To(BeNil())
+		})
+
+		It("returns an error if the port of a check is not one of the service's ports", func() {
+			ts = httptest.NewServer(http.NotFoundHandler())
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:          "httpbin",
+				Image:       "citizenstig/httpbin",
+				HealthCheck: &anysched.HealthCheck{Port: 8000},
+			})
+			Expect(err).To(MatchError(`nomad.manager.DeploySvc: validateSvcCfg failed: ` +
+				`service "httpbin": the port of a tcp check, 8000, must be in Ports`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if the job cannot be registered", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+			}))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobsClient.EnforceRegister failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("DiffSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the settings that differ from the job", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/v1/job/httpbin": "testdata/job_httpbin.json"})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{
+				ID:     "httpbin",
+				Image:  "citizenstig/httpbin",
+				Count:  2,
+				Labels: map[string]string{"team": "payments"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(BeEmpty())
+			svcDiff, err = manager.DiffSvc(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     3,
+				Labels:    map[string]string{"team": "payments"},
+				Resources: &anysched.Resources{CPU: 0.1, MemoryLimit: 512 << 20},
+				Command:   []string{"gunicorn"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff.Changed).To(Equal([]string{"count", "resources"}))
+			Expect(svcDiff.Unknown).To(Equal([]string{"command"}))
+		})
+
+		It("returns nil if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcDiffer)
+			svcDiff, err := manager.DiffSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDiff).To(BeNil())
+		})
+	})
+
+	Describe("svcCfgFromNomadJob", func() {
+		svcCfg := anysched.SvcCfg{
+			ID:        "httpbin",
+			Image:     "citizenstig/httpbin",
+			Count:     2,
+			Env:       map[string]string{"PORT": "8000"},
+			Ports:     []anysched.PortCfg{{ContainerPort: 9000, HostPort: 9000}, {Name: "http", ContainerPort: 8000}},
+			Labels:    map[string]string{"team": "payments"},
+			Secrets:   []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			Resources: &anysched.Resources{CPU: 0.5, Memory: 256 << 20, Disk: 1 << 30},
+		}
+
+		It("reads back what getJob returns", func() {
+			Expect(svcCfgFromNomadJob(getJob(svcCfg))).To(Equal(anysched.SvcCfg{
+				ID:        "httpbin",
+				Image:     "citizenstig/httpbin",
+				Count:     2,
+				Env:       map[string]string{"PORT": "8000"},
+				Ports:     []anysched.PortCfg{{ContainerPort: 9000, HostPort: 9000}, {Name: "http", ContainerPort: 8000}},
+				Labels:    map[string]string{"team": "payments"},
+				Resources: &anysched.Resources{CPU: 0.5, Memory: 256 << 20, Disk: 1 << 30},
+			}))
+		})
+
+		It("reads the port map of a job that Nomad returns", func() {
+			data, err := json.Marshal(getJob(svcCfg))
+			Expect(err).ToNot(HaveOccurred())
+			var job api.Job
+			Expect(json.Unmarshal(data, &job)).To(Succeed())
+			Expect(svcCfgFromNomadJob(&job).Ports).To(Equal(svcCfg.Ports))
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("registers the job again and waits for the allocations of the new version", func() {
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/v1/job/httpbin": {"testdata/job_httpbin.json", "testdata/job_httpbin_updated.json"},
+				"/v1/jobs":        {"testdata/job_register.json"},
+				httpbinEvalPath:   {"testdata/evaluation_complete_no_deployment.json"},
+				"/v1/job/httpbin/allocations": {
+					"testdata/job_httpbin_allocations_updating.json",
+					"testdata/job_httpbin_allocations.json",
+				},
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for job "httpbin" to finish: ` +
+				`1 of 2 allocs of version 1 are running, 1 old allocs are left...`))
+
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Job "httpbin" successfully updated to version 1. 2 allocs are running.`))
+		})
+
+		It("returns an error if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/v1/jobs": "testdata/job_register.json"})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcUpdater)
+			op, err := manager.UpdateSvc(anysched.SvcCfg{ID: "httpbin", Image: "citizenstig/httpbin:v2", Count: 2})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("nomad.manager.UpdateSvc: mgr.jobsClient.Info failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("registers the job again and waits for the allocations to be placed", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/job/httpbin":             "testdata/job_httpbin.json",
+				"/v1/jobs":                    "testdata/job_register.json",
+				httpbinEvalPath:               "testdata/evaluation_complete_no_deployment.json",
+				"/v1/job/httpbin/allocations": "testdata/job_httpbin_allocations.json",
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for job "httpbin" to finish: 2 of 3 allocs are running...`))
+		})
+
+		It("returns an error if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{"/v1/jobs": "testdata/job_register.json"})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("httpbin", 3)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("nomad.manager.ScaleSvc: mgr.jobsClient.Info failed"))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("SvcHistory", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns the versions of the job, oldest first", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/job/httpbin/versions": "testdata/job_httpbin_versions.json",
+			})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			revisions, err := manager.SvcHistory("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(2))
+			Expect(revisions[0].Revision).To(Equal(int64(0)))
+			Expect(revisions[0].Image).To(Equal("citizenstig/httpbin"))
+			Expect(revisions[0].CreationTime.Equal(time.Unix(0, 1532544541000000000))).To(BeTrue())
+			Expect(revisions[0].Current).To(BeFalse())
+			Expect(revisions[1].Revision).To(Equal(int64(1)))
+			Expect(revisions[1].Image).To(Equal("citizenstig/httpbin:v2"))
+			Expect(revisions[1].Current).To(BeTrue())
+		})
+
+		It("returns an error if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{})
+			manager := NewManagerWithTestServer(ts).(anysched.SvcHistoryGetter)
+			_, err := manager.SvcHistory("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("nomad.manager.SvcHistory: mgr.jobsClient.Versions failed"))
+		})
+	})
+
+	Describe("RollbackSvc", func() {
+		var ts *httptest.Server
+
+		BeforeEach(func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/job/httpbin/versions": "testdata/job_httpbin_versions.json",
+				"/v1/job/httpbin/revert":   "testdata/job_register.json",
+			})
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("reverts the job and follows the evaluation of the revert", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			op, err := manager.RollbackSvc("httpbin", 0)
+			Expect(err).ToNot(HaveOccurred())
+			dep := op.(*deployment)
+			Expect(dep.evalID).To(Equal("5456bd7a-9fc0-c0dd-6131-cbee77f57577"))
+			Expect(dep.desiredCount).To(Equal(2))
+			Expect(dep.update).To(BeTrue())
+		})
+
+		It("returns an error for a version that does not exist", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 5)
+			Expect(err).To(MatchError(`nomad.manager.RollbackSvc: service "httpbin" has no revision 5`))
+		})
+
+		It("returns an error for the current version", func() {
+			manager := NewManagerWithTestServer(ts).(anysched.SvcRollbacker)
+			_, err := manager.RollbackSvc("httpbin", 1)
+			Expect(err).To(MatchError(`nomad.manager.RollbackSvc: service "httpbin" is already at revision 1`))
+		})
+	})
+
+	Context("a deployment that progresses", func() {
+		var (
+			ts  *httptest.Server
+			dep *deployment
+		)
+
+		BeforeEach(func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/v1/jobs": {"testdata/job_register.json"},
+				httpbinEvalPath: {
+					"testdata/evaluation_pending.json",
+					"testdata/evaluation_complete.json",
+				},
+				httpbinDeploymentPath: {
+					"testdata/deployment_running.json",
+					"testdata/deployment_successful.json",
+				},
+			})
+			dep = deployHttpbin(ts)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		Describe("GetStatus", func() {
+			It("follows the evaluation to the deployment", func() {
+				status, err := dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeFalse())
+				Expect(status.Msg).To(Equal(`Waiting for job "httpbin" to finish: ` +
+					`Waiting for evaluation "5456bd7a-9fc0-c0dd-6131-cbee77f57577" to complete...`))
+
+				status, err = dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeFalse())
+				Expect(status.Msg).To(Equal(`Waiting for job "httpbin" to finish: ` +
+					`httpbin: 1 of 2 allocs placed, 0 healthy, 0 unhealthy.`))
+
+				status, err = dep.GetStatus()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Done).To(BeTrue())
+				Expect(status.Msg).To(Equal(`Deployment "0e4f1b6a-2c3d-4e5f-8a9b-1c2d3e4f5a6b" successfully ` +
+					`rolled out. httpbin: 2 of 2 allocs placed, 2 healthy, 0 unhealthy.`))
+			})
+		})
+
+		Describe("Wait", func() {
+			It("works", func() {
+				result, err := dep.Wait(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(dep))
+			})
+
+			It("honors the context", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := dep.Wait(ctx)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("context canceled"))
+			})
+		})
+	})
+
+	Context("a deployment that fails", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns an error from GetStatus and Wait if the deployment fails", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/jobs":            "testdata/job_register.json",
+				httpbinEvalPath:       "testdata/evaluation_complete.json",
+				httpbinDeploymentPath: "testdata/deployment_failed.json",
+			})
+			dep := deployHttpbin(ts)
+			status, err := dep.GetStatus()
+			Expect(err).To(MatchError(`deployment "0e4f1b6a-2c3d-4e5f-8a9b-1c2d3e4f5a6b" for job "httpbin" ` +
+				`has status "failed": Failed due to unhealthy allocations`))
+			Expect(status).To(BeNil())
+			_, err = dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Failed due to unhealthy allocations"))
+		})
+
+		It("returns an error from GetStatus if the evaluation fails", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/jobs":      "testdata/job_register.json",
+				httpbinEvalPath: "testdata/evaluation_failed.json",
+			})
+			dep := deployHttpbin(ts)
+			_, err := dep.GetStatus()
+			Expect(err).To(MatchError(`evaluation "5456bd7a-9fc0-c0dd-6131-cbee77f57577" for job "httpbin" ` +
+				`has status "failed": maximum attempts reached (5)`))
+		})
+
+		It("times out if the deployment never finishes", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/jobs":            "testdata/job_register.json",
+				httpbinEvalPath:       "testdata/evaluation_complete.json",
+				httpbinDeploymentPath: "testdata/deployment_running.json",
+			})
+			dep := deployHttpbin(ts)
+			dep.timeoutDuration = 100 * time.Millisecond
+			_, err := dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out after 100ms"))
+		})
+	})
+
+	Context("a job without a Nomad deployment", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("counts running allocations", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{
+				"/v1/jobs":                    "testdata/job_register.json",
+				httpbinEvalPath:               "testdata/evaluation_complete_no_deployment.json",
+				"/v1/job/httpbin/allocations": "testdata/job_httpbin_allocations.json",
+			})
+			dep := deployHttpbin(ts)
+			status, err := dep.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Job "httpbin" successfully rolled out. 2 allocs are running.`))
+		})
+	})
+
+	Describe("DestroySvc", func() {
+		var ts *httptest.Server
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("returns a deregistration that waits for allocations to stop", func() {
+			pollInterval = 10 * time.Millisecond
+			ts = NewTestServerJSONRouteSequences(map[string][]string{
+				"/v1/job/httpbin": {"testdata/job_httpbin.json", "testdata/job_deregister.json"},
+				"/v1/job/httpbin/allocations": {
+					"testdata/job_httpbin_allocations.json",
+					"testdata/job_httpbin_allocations_stopped.json",
+				},
+			})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.GetProperties()["evalID"]).To(Equal("d6c4f0a2-3b1e-7f8a-9c2d-4e5f6a7b8c9d"))
+
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(Equal(`Waiting for job "httpbin" to finish: 2 allocs are pending termination...`))
+
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the job does not exist", func() {
+			ts = NewTestServerJSONRoutes(map[string]string{})
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).To(MatchError(
+				"nomad.manager.DestroySvc: mgr.jobsClient.Info failed: Unexpected response code: 404 ()"))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error if the job cannot be deregistered", func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "DELETE" {
+					writeJSONResponseFromFile(w, "testdata/job_httpbin.json")
+					return
+				}
+				w.WriteHeader(500)
+			}))
+			manager := NewManagerWithTestServer(ts)
+			op, err := manager.DestroySvc("httpbin")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobsClient.Deregister failed"))
+			Expect(op).To(BeNil())
+		})
+	})
 })
@@ -0,0 +1,183 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var (
+	// pollInterval is how often Wait checks the status of a deployment.
+	pollInterval = 2 * time.Second
+
+	getDeployTimeoutDuration = func(svcCfg anysched.SvcCfg) time.Duration {
+		if svcCfg.DeployTimeoutDuration == nil {
+			return 60 * time.Second
+		}
+		return *svcCfg.DeployTimeoutDuration
+	}
+)
+
+// deployment implements the anysched.Operation interface for the processes of
+// a version of a service. It is done once all of them have been running for
+// at least minReadyDuration and, for an update, the processes of the old
+// version have been killed.
+type deployment struct {
+	manager         *manager
+	svcCfg          anysched.SvcCfg
+	version         int
+	timeoutDuration time.Duration
+	// scaling is true for the deployment of the processes that ScaleSvc adds
+	scaling bool
+}
+
+func newDeployment(mgr *manager, svc *svc) *deployment {
+	return &deployment{
+		manager:         mgr,
+		svcCfg:          svc.cfg,
+		version:         svc.version,
+		timeoutDuration: getDeployTimeoutDuration(svc.cfg),
+	}
+}
+
+func (dep *deployment) String() string {
+	return fmt.Sprintf("<process.deployment name=%q count=%d />", dep.svcCfg.ID, dep.svcCfg.Count)
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (dep *deployment) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = dep.svcCfg.ID
+	propertiesMap["count"] = dep.svcCfg.Count
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the deployment
+func (dep *deployment) GetStatus() (status *anysched.OperationStatus, err error) {
+	svc, old, ok := dep.manager.getSvc(dep.svcCfg.ID)
+	if !ok {
+		return nil, fmt.Errorf("process.deployment.GetStatus: service %q no longer exists", dep.svcCfg.ID)
+	}
+	if svc.version != dep.version {
+		return nil, fmt.Errorf("process.deployment.GetStatus: service %q was updated to version %d",
+			dep.svcCfg.ID, svc.version)
+	}
+	if dep.scaling {
+		return getStatusOfTasks(svc, "scaled", "scale"), nil
+	}
+	if svc.version > 1 {
+		return getStatusOfUpdate(svc, old), nil
+	}
+	return getStatusOfTasks(svc, "deployed", "start"), nil
+}
+
+// isReady returns true if all of tasks are ready.
+func isReady(tasks []*task) bool {
+	for _, task := range tasks {
+		if !task.snapshot().isReady() {
+			return false
+		}
+	}
+	return true
+}
+
+// getStatusOfTasks returns the status of the processes of svc, after they were
+// started by the operation named by verb, e.g.: "start", and pastVerb.
+func getStatusOfTasks(svc *svc, pastVerb, verb string) *anysched.OperationStatus {
+	ready, restarts, lastErr, lastUpdateTime := summarizeTasks(svc)
+	if ready == len(svc.tasks) {
+		msg := fmt.Sprintf("Service %q successfully %s. %d of %d processes are running.",
+			svc.cfg.ID, pastVerb, ready, len(svc.tasks))
+		return status(msg, true, lastUpdateTime)
+	}
+	msg := fmt.Sprintf("Waiting for service %q to %s: %d of %d processes are running, %d restarts...",
+		svc.cfg.ID, verb, ready, len(svc.tasks), restarts)
+	if lastErr != nil {
+		msg = fmt.Sprintf("%s (last exit: %s)", msg, lastErr)
+	}
+	return status(msg, false, lastUpdateTime)
+}
+
+// getStatusOfUpdate returns the status of the update of a service to svc,
+// whose old version is still running unless old is nil.
+func getStatusOfUpdate(svc, old *svc) *anysched.OperationStatus {
+	ready, restarts, lastErr, lastUpdateTime := summarizeTasks(svc)
+	if old == nil && ready == len(svc.tasks) {
+		msg := fmt.Sprintf("Service %q successfully updated. %d of %d processes are running version %d.",
+			svc.cfg.ID, ready, len(svc.tasks), svc.version)
+		return status(msg, true, lastUpdateTime)
+	}
+	oldRunning := 0
+	if old != nil {
+		oldRunning = len(old.tasks)
+	}
+	msg := fmt.Sprintf("Waiting for service %q to update: %d of %d new processes are running, "+
+		"%d old ones are still running, %d restarts...", svc.cfg.ID, ready, len(svc.tasks), oldRunning, restarts)
+	if lastErr != nil {
+		msg = fmt.Sprintf("%s (last exit: %s)", msg, lastErr)
+	}
+	return status(msg, false, lastUpdateTime)
+}
+
+// summarizeTasks returns how many of the processes of svc are ready, how many
+// times they have been restarted, the error of the last one that exited, and
+// when the last one started or became ready.
+func summarizeTasks(svc *svc) (ready, restarts int, lastErr error, lastUpdateTime time.Time) {
+	lastUpdateTime = svc.deployTime
+	for _, task := range svc.tasks {
+		snapshot := task.snapshot()
+		var updateTime time.Time
+		if snapshot.startTime != nil {
+			updateTime = *snapshot.startTime
+		}
+		if snapshot.isReady() {
+			ready++
+			updateTime = updateTime.Add(minReadyDuration)
+		}
+		restarts += snapshot.restarts
+		if snapshot.lastErr != nil {
+			lastErr = snapshot.lastErr
+		}
+		if updateTime.After(lastUpdateTime) {
+			lastUpdateTime = updateTime
+		}
+	}
+	return ready, restarts, lastErr, lastUpdateTime
+}
+
+func status(msg string, done bool, lastUpdateTime time.Time) *anysched.OperationStatus {
+	return &anysched.OperationStatus{
+		ClientTime:         time.Now(),
+		LastTransitionTime: lastUpdateTime,
+		LastUpdateTime:     lastUpdateTime,
+		Msg:                msg,
+		Done:               done,
+	}
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (dep *deployment) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, dep.timeoutDuration)
+	defer cancel()
+
+	for {
+		status, err := dep.GetStatus()
+		if err != nil {
+			return nil, errors.Wrap(err, "process.deployment.Wait: GetStatus failed")
+		}
+		if status.Done {
+			return dep, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "process.deployment.Wait: Timed out after %s: %s",
+				dep.timeoutDuration, status.Msg)
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,529 @@
+// Package process provides an anysched.Manager, registered as "process", that
+// runs services as supervised child processes of the current process, without
+// containers.
+//
+// SvcCfg.Image is the path of an executable followed by its arguments,
+// separated by spaces, e.g.: "/usr/bin/python3 -m http.server". Like with a
+// container image, SvcCfg.Command overrides the executable (and its arguments),
+// and SvcCfg.Args overrides the arguments. Each of the Count processes of a
+// service is restarted whenever it exits, until the service is destroyed.
+// Services only live as long as the Manager's process.
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var (
+	// restartDelay is how long a task waits to restart its process after it
+	// exits.
+	restartDelay = 1 * time.Second
+
+	// minReadyDuration is how long a process has to be running before its
+	// task counts as healthy, so that a process that crashes right after it
+	// starts doesn't make a deployment succeed.
+	minReadyDuration = 1 * time.Second
+)
+
+type manager struct {
+	mutex    sync.Mutex
+	svcs     map[string]*svc
+	hostName string
+}
+
+// svc is one version of a service. UpdateSvc replaces it with a new one, which
+// keeps the previous version in old until its own processes are ready.
+// ScaleSvc changes its cfg and tasks in place.
+type svc struct {
+	creationTime time.Time
+	deployTime   time.Time
+	version      int
+	argv         []string
+	ctx          context.Context
+	cancel       context.CancelFunc
+
+	// revisions are the versions of the service so far, oldest first, so
+	// that version v is revisions[v-1].
+	revisions []revision
+
+	cfg   anysched.SvcCfg // guarded by manager.mutex
+	tasks []*task         // guarded by manager.mutex
+	old   *svc            // guarded by manager.mutex
+
+	// retired is closed once retire is done with the version that this one
+	// replaced. It is nil for the first version.
+	retired chan struct{}
+}
+
+// revision is a version of a service, which SvcHistory returns and RollbackSvc
+// rolls back to.
+type revision struct {
+	cfg  anysched.SvcCfg
+	time time.Time
+}
+
+func init() {
+	anysched.RegisterManagerType("process", NewManager)
+}
+
+// NewManager returns a Manager that runs services as child processes. url is
+// ignored.
+func NewManager(url string) (anysched.Manager, error) {
+	hostName, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "process.NewManager: os.Hostname failed")
+	}
+	return &manager{svcs: map[string]*svc{}, hostName: hostName}, nil
+}
+
+// Svcs returns info about all running services.
+func (mgr *manager) Svcs() ([]anysched.Svc, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svcs := []anysched.Svc{}
+	for _, svcID := range mgr.sortedSvcIDs() {
+		svcs = append(svcs, svcInfo(mgr.svcs[svcID]))
+	}
+	return svcs, nil
+}
+
+// Svc returns a service with the SvcCfg that it was deployed, updated or
+// scaled with. Its revision is its version, and there is no native object.
+func (mgr *manager) Svc(svcID string) (*anysched.SvcDetail, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("process.manager.Svc: service %q does not exist", svcID)
+	}
+	revision := int64(svc.version)
+	return &anysched.SvcDetail{Svc: svcInfo(svc), SvcCfg: svc.cfg.Copy(), Revision: &revision}, nil
+}
+
+// svcInfo returns the info about svc that Svcs returns. The caller must hold
+// manager.mutex.
+func svcInfo(svc *svc) anysched.Svc {
+	tasksRunning, tasksHealthy, tasksUnhealthy := 0, 0, 0
+	for _, task := range svc.tasks {
+		snapshot := task.snapshot()
+		switch {
+		case snapshot.isReady():
+			tasksRunning++
+			tasksHealthy++
+		case snapshot.state == taskStateRunning:
+			tasksRunning++
+		case snapshot.state == taskStateRestarting:
+			tasksUnhealthy++
+		}
+	}
+	creationTime := svc.creationTime
+	return anysched.Svc{
+		ID:             svc.cfg.ID,
+		TasksRunning:   &tasksRunning,
+		TasksHealthy:   &tasksHealthy,
+		TasksUnhealthy: &tasksUnhealthy,
+		CreationTime:   &creationTime,
+		Labels:         svc.cfg.Labels,
+	}
+}
+
+// SvcTasks returns info about the running tasks for a service.
+func (mgr *manager) SvcTasks(svcCfg anysched.SvcCfg) ([]anysched.Task, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcCfg.ID]
+	if !ok {
+		return nil, fmt.Errorf("process.manager.SvcTasks: service %q does not exist", svcCfg.ID)
+	}
+	return mgr.svcTasks(svc), nil
+}
+
+// Tasks returns info about all running tasks.
+func (mgr *manager) Tasks() ([]anysched.Task, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	tasks := []anysched.Task{}
+	for _, svcID := range mgr.sortedSvcIDs() {
+		tasks = append(tasks, mgr.svcTasks(mgr.svcs[svcID])...)
+	}
+	return tasks, nil
+}
+
+// getSvc returns copies of the current version of a service and of the old
+// version that it is replacing, if any.
+func (mgr *manager) getSvc(svcID string) (svc, old *svc, ok bool) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	current, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, nil, false
+	}
+	svcCopy := *current
+	if current.old != nil {
+		oldCopy := *current.old
+		old = &oldCopy
+	}
+	return &svcCopy, old, true
+}
+
+// getTasks returns the current tasks of svc.
+func (mgr *manager) getTasks(svc *svc) []*task {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	return svc.tasks
+}
+
+func (mgr *manager) sortedSvcIDs() []string {
+	svcIDs := make([]string, 0, len(mgr.svcs))
+	for svcID := range mgr.svcs {
+		svcIDs = append(svcIDs, svcID)
+	}
+	sort.Strings(svcIDs)
+	return svcIDs
+}
+
+// svcTasks returns the tasks of svc, after those of the version that it is
+// replacing, if any. The caller must hold mgr.mutex.
+func (mgr *manager) svcTasks(svc *svc) []anysched.Task {
+	tasks := []anysched.Task{}
+	if svc.old != nil {
+		tasks = mgr.svcTasks(svc.old)
+	}
+	for _, task := range svc.tasks {
+		snapshot := task.snapshot()
+		stageTime := snapshot.stageTime
+		tasks = append(tasks, anysched.Task{
+			Name:      task.name,
+			AppID:     svc.cfg.ID,
+			HostName:  mgr.hostName,
+			HostIP:    "127.0.0.1",
+			PID:       snapshot.pid,
+			StageTime: &stageTime,
+			StartTime: snapshot.startTime,
+			State:     snapshot.state,
+			Version:   strconv.Itoa(svc.version),
+		})
+	}
+	return tasks
+}
+
+// validateSvcCfg returns an error for the parts of a SvcCfg that processes
+// cannot express. All of the processes run on this host and see its file
+// system, there is nowhere to get secrets from, each task is one process, and
+// there are no namespaces.
+func validateSvcCfg(svcCfg anysched.SvcCfg) error {
+	if err := validateTask(svcCfg); err != nil {
+		return err
+	}
+	switch {
+	case svcCfg.Namespace != "":
+		return fmt.Errorf("service %q cannot be deployed in a namespace", svcCfg.ID)
+	case len(svcCfg.Constraints) > 0:
+		return fmt.Errorf("service %q cannot be placed by constraints", svcCfg.ID)
+	case len(svcCfg.Volumes) > 0:
+		return fmt.Errorf("service %q cannot mount volumes", svcCfg.ID)
+	case len(svcCfg.Secrets) > 0:
+		return fmt.Errorf("service %q cannot expose secrets", svcCfg.ID)
+	case len(svcCfg.Sidecars) > 0 || len(svcCfg.InitContainers) > 0:
+		return fmt.Errorf("service %q cannot have sidecars or init containers", svcCfg.ID)
+	}
+	return nil
+}
+
+// validateTask returns an error for the parts of a SvcCfg that are about how
+// tasks run, which processes cannot express either: they listen on the host's
+// network themselves, they aren't limited, and nothing checks them.
+func validateTask(svcCfg anysched.SvcCfg) error {
+	switch {
+	case len(svcCfg.Ports) > 0:
+		return fmt.Errorf("service %q cannot publish ports", svcCfg.ID)
+	case svcCfg.Resources != nil:
+		return fmt.Errorf("service %q cannot have resources", svcCfg.ID)
+	case svcCfg.HealthCheck != nil || svcCfg.ReadinessCheck != nil:
+		return fmt.Errorf("service %q cannot have health or readiness checks", svcCfg.ID)
+	}
+	return nil
+}
+
+// DeploySvc takes a SvcCfg and deploys it, returning an Operation.
+func (mgr *manager) DeploySvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	argv, err := getExecutableArgv(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "process.manager.DeploySvc")
+	}
+
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.svcs[svcCfg.ID]; ok {
+		return nil, fmt.Errorf("process.manager.DeploySvc: service %q already exists", svcCfg.ID)
+	}
+	now := time.Now()
+	svc := startSvc(svcCfg, argv, now, 1)
+	svc.revisions = []revision{{cfg: svc.cfg, time: svc.deployTime}}
+	mgr.svcs[svcCfg.ID] = svc
+	return newDeployment(mgr, svc), nil
+}
+
+// UpdateSvc takes the new SvcCfg of a deployed service and starts its
+// processes next to the old ones, returning an Operation. The old processes
+// are killed once all of the new ones are ready, so if they never get ready,
+// the old ones keep running.
+func (mgr *manager) UpdateSvc(svcCfg anysched.SvcCfg) (anysched.Operation, error) {
+	argv, err := getExecutableArgv(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "process.manager.UpdateSvc")
+	}
+
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.svcs[svcCfg.ID]; !ok {
+		return nil, fmt.Errorf("process.manager.UpdateSvc: service %q does not exist", svcCfg.ID)
+	}
+	return mgr.updateSvc(svcCfg, argv), nil
+}
+
+// updateSvc replaces the deployed service of svcCfg with a new version of it,
+// whose processes run argv. The caller must hold mgr.mutex.
+func (mgr *manager) updateSvc(svcCfg anysched.SvcCfg, argv []string) *deployment {
+	oldSvc := mgr.svcs[svcCfg.ID]
+	if oldSvc.old != nil {
+		// The version before oldSvc never got replaced; it doesn't get a
+		// second chance. Cancelling it makes the retire goroutine of oldSvc
+		// stop it and exit.
+		oldSvc.old.cancel()
+		oldSvc.old = nil
+	}
+	previousRetired := oldSvc.retired
+	svc := startSvc(svcCfg, argv, oldSvc.creationTime, oldSvc.version+1)
+	svc.revisions = make([]revision, len(oldSvc.revisions), len(oldSvc.revisions)+1)
+	copy(svc.revisions, oldSvc.revisions)
+	svc.revisions = append(svc.revisions, revision{cfg: svc.cfg, time: svc.deployTime})
+	svc.old = oldSvc
+	svc.retired = make(chan struct{})
+	mgr.svcs[svcCfg.ID] = svc
+	go func() {
+		// Only one retire goroutine runs for a service at a time, so that
+		// the one of oldSvc can't stop a version after this one has.
+		if previousRetired != nil {
+			<-previousRetired
+		}
+		mgr.retire(svc, oldSvc)
+	}()
+	return newDeployment(mgr, svc)
+}
+
+// getExecutableArgv validates svcCfg and returns the executable, with its full
+// path, and arguments of its processes.
+func getExecutableArgv(svcCfg anysched.SvcCfg) ([]string, error) {
+	if err := svcCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "svcCfg.Validate failed")
+	}
+	if err := validateSvcCfg(svcCfg); err != nil {
+		return nil, errors.Wrap(err, "validateSvcCfg failed")
+	}
+	argv := getArgv(svcCfg)
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("service %q has no executable in Image or Command", svcCfg.ID)
+	}
+	executablePath, err := exec.LookPath(argv[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "exec.LookPath(%q) failed", argv[0])
+	}
+	argv[0] = executablePath
+	return argv, nil
+}
+
+// startSvc starts the processes of a version of a service.
+func startSvc(svcCfg anysched.SvcCfg, argv []string, creationTime time.Time, version int) *svc {
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &svc{
+		cfg:          svcCfg.Copy(),
+		creationTime: creationTime,
+		deployTime:   time.Now(),
+		version:      version,
+		argv:         argv,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	for i := 0; i < svcCfg.Count; i++ {
+		svc.tasks = append(svc.tasks, svc.startTask(i, svc.deployTime))
+	}
+	return svc
+}
+
+// startTask starts process i of svc. The caller must hold manager.mutex, unless
+// svc is new.
+func (svc *svc) startTask(i int, stageTime time.Time) *task {
+	ctx, cancel := context.WithCancel(svc.ctx)
+	task := newTask(fmt.Sprintf("%s.%d", svc.cfg.ID, i), stageTime, cancel)
+	go task.supervise(ctx, svc.argv, svc.cfg.EnvList())
+	return task
+}
+
+// retire kills the processes of the version that svc replaces once all of the
+// processes of svc are ready, or once either version is stopped, whichever
+// comes first.
+func (mgr *manager) retire(svc, old *svc) {
+	defer close(svc.retired)
+	for !isReady(mgr.getTasks(svc)) {
+		select {
+		case <-svc.ctx.Done():
+			old.stop()
+			return
+		case <-old.ctx.Done():
+			// A newer version replaced svc before it got ready.
+			old.stop()
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+	old.stop()
+	mgr.mutex.Lock()
+	svc.old = nil
+	mgr.mutex.Unlock()
+}
+
+// stop kills the processes of svc and waits for them to exit. The caller must
+// make sure that ScaleSvc can't get to svc anymore.
+func (svc *svc) stop() {
+	svc.cancel()
+	for _, task := range svc.tasks {
+		<-task.done
+	}
+}
+
+// getArgv returns the executable and arguments of the processes of a service.
+func getArgv(svcCfg anysched.SvcCfg) []string {
+	if len(svcCfg.Command) > 0 {
+		return append(append([]string{}, svcCfg.Command...), svcCfg.Args...)
+	}
+	argv := strings.Fields(svcCfg.Image)
+	if len(argv) > 0 && len(svcCfg.Args) > 0 {
+		argv = append(argv[:1], svcCfg.Args...)
+	}
+	return argv
+}
+
+// ScaleSvc changes the number of processes of a service to count, returning an
+// Operation. It starts the processes that it adds next to the running ones, and
+// kills the ones that it removes, newest first, before it returns.
+func (mgr *manager) ScaleSvc(svcID string, count int) (anysched.Operation, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("process.manager.ScaleSvc: service %q cannot be scaled to %d processes", svcID, count)
+	}
+	mgr.mutex.Lock()
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		mgr.mutex.Unlock()
+		return nil, fmt.Errorf("process.manager.ScaleSvc: service %q does not exist", svcID)
+	}
+	tasks := append([]*task{}, svc.tasks...)
+	var removed []*task
+	if count < len(tasks) {
+		tasks, removed = tasks[:count], tasks[count:]
+	}
+	now := time.Now()
+	for i := len(tasks); i < count; i++ {
+		tasks = append(tasks, svc.startTask(i, now))
+	}
+	svc.tasks = tasks
+	svc.cfg.Count = count
+	dep := newDeployment(mgr, svc)
+	dep.scaling = true
+	mgr.mutex.Unlock()
+
+	for _, task := range removed {
+		task.stop()
+	}
+	return dep, nil
+}
+
+// SvcHistory returns the revisions of a service, oldest first. Every version of
+// the service is a revision, whose number is the version.
+func (mgr *manager) SvcHistory(svcID string) ([]anysched.SvcRevision, error) {
+	svc, _, ok := mgr.getSvc(svcID)
+	if !ok {
+		return nil, fmt.Errorf("process.manager.SvcHistory: service %q does not exist", svcID)
+	}
+	revisions := make([]anysched.SvcRevision, len(svc.revisions))
+	for i, rev := range svc.revisions {
+		creationTime := rev.time
+		revisions[i] = anysched.SvcRevision{
+			Revision:     int64(i + 1),
+			Image:        rev.cfg.Image,
+			CreationTime: &creationTime,
+			Current:      i+1 == svc.version,
+		}
+	}
+	return revisions, nil
+}
+
+// RollbackSvc updates a service to the SvcCfg of one of its revisions, with
+// the number of processes that it has now, returning an Operation, like
+// UpdateSvc.
+func (mgr *manager) RollbackSvc(svcID string, revision int64) (anysched.Operation, error) {
+	svc, _, ok := mgr.getSvc(svcID)
+	if !ok {
+		return nil, fmt.Errorf("process.manager.RollbackSvc: service %q does not exist", svcID)
+	}
+	if revision < 1 || revision > int64(len(svc.revisions)) {
+		return nil, fmt.Errorf("process.manager.RollbackSvc: service %q has no revision %d", svcID, revision)
+	}
+	if revision == int64(svc.version) {
+		return nil, fmt.Errorf("process.manager.RollbackSvc: service %q is already at revision %d", svcID, revision)
+	}
+	svcCfg := svc.revisions[revision-1].cfg
+	svcCfg.Count = svc.cfg.Count
+	argv, err := getExecutableArgv(svcCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "process.manager.RollbackSvc")
+	}
+
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.svcs[svcID]; !ok {
+		return nil, fmt.Errorf("process.manager.RollbackSvc: service %q does not exist", svcID)
+	}
+	return mgr.updateSvc(svcCfg, argv), nil
+}
+
+// DiffSvc returns how the deployed service with the ID of svcCfg differs from
+// it, or nil if there is no such service.
+func (mgr *manager) DiffSvc(svcCfg anysched.SvcCfg) (*anysched.SvcDiff, error) {
+	svc, _, ok := mgr.getSvc(svcCfg.ID)
+	if !ok {
+		return nil, nil
+	}
+	return anysched.DiffSvcCfgs(svc.cfg, svcCfg), nil
+}
+
+// DestroySvc destroys a service. It kills the service's processes, returning
+// an Operation that is done once they have exited.
+func (mgr *manager) DestroySvc(svcID string) (anysched.Operation, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	svc, ok := mgr.svcs[svcID]
+	if !ok {
+		return nil, fmt.Errorf("process.manager.DestroySvc: service %q does not exist", svcID)
+	}
+	delete(mgr.svcs, svcID)
+	tasks := append([]*task{}, svc.tasks...)
+	if svc.old != nil {
+		svc.old.cancel()
+		tasks = append(tasks, svc.old.tasks...)
+	}
+	svc.cancel()
+	return &termination{svcID: svcID, tasks: tasks, timeoutDuration: 60 * time.Second}, nil
+}
@@ -0,0 +1,558 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+	"github.com/msabramo/go-anysched/conformance"
+)
+
+func init() {
+	pollInterval = 10 * time.Millisecond
+	restartDelay = 10 * time.Millisecond
+	minReadyDuration = 50 * time.Millisecond
+	conformance.SvcCfg.Image = "sleep 3600"
+}
+
+var _ = conformance.DescribeManager("process", func() anysched.Manager {
+	manager, err := NewManager("")
+	Expect(err).ToNot(HaveOccurred())
+	return manager
+})
+
+func deploySvc(manager anysched.Manager, image string, count int) *deployment {
+	timeout := 5 * time.Second
+	op, err := manager.DeploySvc(anysched.SvcCfg{
+		ID:                    "sleeper",
+		Image:                 image,
+		Count:                 count,
+		DeployTimeoutDuration: &timeout,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return op.(*deployment)
+}
+
+// processExists returns true if the process with the ID pid is running. A
+// zombie has exited already, and only waits for its parent to reap it.
+func processExists(pid int) bool {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	return err == nil && !strings.Contains(string(stat), ") Z ")
+}
+
+func svcTaskPIDs(manager anysched.Manager) []int {
+	tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "sleeper"})
+	Expect(err).ToNot(HaveOccurred())
+	pids := make([]int, len(tasks))
+	for i, task := range tasks {
+		pids[i] = task.PID
+	}
+	return pids
+}
+
+var _ = Describe("process/manager.go", func() {
+	var manager anysched.Manager
+
+	BeforeEach(func() {
+		var err error
+		manager, err = NewManager("")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if op, err := manager.DestroySvc("sleeper"); err == nil {
+			op.Wait(context.Background())
+		}
+	})
+
+	Describe("Svc", func() {
+		It("returns the service with its SvcCfg and version", func() {
+			dep := deploySvc(manager, "sleep 60", 2)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.Svc.ID).To(Equal("sleeper"))
+			Expect(*svcDetail.Svc.TasksHealthy).To(Equal(2))
+			Expect(svcDetail.SvcCfg.Image).To(Equal("sleep 60"))
+			Expect(svcDetail.SvcCfg.Count).To(Equal(2))
+			Expect(*svcDetail.Revision).To(Equal(int64(1)))
+		})
+
+		It("returns a copy of the SvcCfg that the service was deployed with", func() {
+			svcCfg := anysched.SvcCfg{ID: "sleeper", Image: "sleep 60", Count: 1, Env: map[string]string{"A": "1"}}
+			_, err := manager.DeploySvc(svcCfg)
+			Expect(err).ToNot(HaveOccurred())
+			svcCfg.Env["A"] = "2"
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.SvcCfg.Env).To(Equal(map[string]string{"A": "1"}))
+			svcDetail.SvcCfg.Env["A"] = "3"
+			svcDetail, err = manager.(anysched.SvcGetter).Svc("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcDetail.SvcCfg.Env).To(Equal(map[string]string{"A": "1"}))
+		})
+
+		It("returns an error if the service does not exist", func() {
+			svcDetail, err := manager.(anysched.SvcGetter).Svc("sleeper")
+			Expect(err).To(MatchError(`process.manager.Svc: service "sleeper" does not exist`))
+			Expect(svcDetail).To(BeNil())
+		})
+	})
+
+	Describe("DeploySvc", func() {
+		It("runs Count processes", func() {
+			dep := deploySvc(manager, "sleep 60", 2)
+			Expect(dep.String()).To(Equal(`<process.deployment name="sleeper" count=2 />`))
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			tasks, err := manager.Tasks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Name).To(Equal("sleeper.0"))
+			Expect(tasks[0].AppID).To(Equal("sleeper"))
+			Expect(tasks[0].State).To(Equal("running"))
+			Expect(tasks[0].StartTime).ToNot(BeNil())
+			Expect(tasks[1].Name).To(Equal("sleeper.1"))
+			Expect(tasks[0].PID).ToNot(Equal(tasks[1].PID))
+			for _, task := range tasks {
+				Expect(processExists(task.PID)).To(BeTrue())
+			}
+
+			svcs, err := manager.Svcs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ID).To(Equal("sleeper"))
+			Expect(*svcs[0].TasksRunning).To(Equal(2))
+			Expect(*svcs[0].TasksHealthy).To(Equal(2))
+		})
+
+		It("sets the environment variables of the processes", func() {
+			timeout := 5 * time.Second
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:                    "sleeper",
+				Image:                 "sleep 60",
+				Count:                 1,
+				Env:                   map[string]string{"GREETING": "hello world"},
+				DeployTimeoutDuration: &timeout,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			environ, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/environ", svcTaskPIDs(manager)[0]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Split(string(environ), "\x00")).To(ContainElement("GREETING=hello world"))
+		})
+
+		It("overrides the executable and arguments", func() {
+			timeout := 5 * time.Second
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:                    "sleeper",
+				Image:                 "/does/not/exist --flag",
+				Command:               []string{"sleep"},
+				Args:                  []string{"61"},
+				Count:                 1,
+				DeployTimeoutDuration: &timeout,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", svcTaskPIDs(manager)[0]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")).To(HaveLen(2))
+			Expect(strings.Split(string(cmdline), "\x00")[1]).To(Equal("61"))
+		})
+
+		It("returns an error if the executable does not exist", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "sleeper", Image: "/does/not/exist", Count: 1})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`exec.LookPath("/does/not/exist") failed`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for constraints", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:          "sleeper",
+				Image:       "sleep 60",
+				Count:       1,
+				Constraints: []anysched.Constraint{{Operator: anysched.ConstraintUniquePerHost}},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot be placed by constraints`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for volumes", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "sleeper",
+				Image:   "sleep 60",
+				Count:   1,
+				Volumes: []anysched.VolumeCfg{{Type: anysched.VolumeScratch, MountPath: "/tmp"}},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot mount volumes`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for secrets", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "sleeper",
+				Image:   "sleep 60",
+				Count:   1,
+				Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot expose secrets`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for a namespace", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "sleeper", Namespace: "payments", Image: "sleep 60", Count: 1})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot be deployed in a namespace`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for sidecars", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:       "sleeper",
+				Image:    "sleep 60",
+				Count:    1,
+				Sidecars: []anysched.ContainerCfg{{Name: "log-forwarder", Image: "fluent/fluent-bit:0.13"}},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot have sidecars or init containers`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for ports", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:    "sleeper",
+				Image: "sleep 60",
+				Count: 1,
+				Ports: []anysched.PortCfg{{ContainerPort: 8000}},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot publish ports`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for resources", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:        "sleeper",
+				Image:     "sleep 60",
+				Count:     1,
+				Resources: &anysched.Resources{CPU: 0.5},
+			})
+			Expect(err).To(MatchError(
+				`process.manager.DeploySvc: validateSvcCfg failed: service "sleeper" cannot have resources`))
+			Expect(op).To(BeNil())
+		})
+
+		It("returns an error for health and readiness checks", func() {
+			for _, svcCfg := range []anysched.SvcCfg{
+				{ID: "sleeper", Image: "sleep 60", Count: 1, HealthCheck: &anysched.HealthCheck{Command: []string{"true"}}},
+				{ID: "sleeper", Image: "sleep 60", Count: 1, ReadinessCheck: &anysched.HealthCheck{Port: 8000}},
+			} {
+				op, err := manager.DeploySvc(svcCfg)
+				Expect(err).To(MatchError(`process.manager.DeploySvc: validateSvcCfg failed: ` +
+					`service "sleeper" cannot have health or readiness checks`))
+				Expect(op).To(BeNil())
+			}
+		})
+
+		It("returns an error if Image is blank", func() {
+			op, err := manager.DeploySvc(anysched.SvcCfg{ID: "sleeper", Count: 1})
+			Expect(err).To(MatchError(`process.manager.DeploySvc: service "sleeper" has no executable in Image or Command`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("UpdateSvc", func() {
+		It("replaces the processes once the new ones are ready", func() {
+			dep := deploySvc(manager, "sleep 60", 2)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			oldPIDs := svcTaskPIDs(manager)
+
+			timeout := 5 * time.Second
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{
+				ID:                    "sleeper",
+				Image:                 "sleep 61",
+				Count:                 1,
+				DeployTimeoutDuration: &timeout,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(ContainSubstring("2 old ones are still running"))
+
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "sleeper"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(1))
+			Expect(tasks[0].Version).To(Equal("2"))
+			for _, pid := range oldPIDs {
+				Expect(processExists(pid)).To(BeFalse())
+			}
+		})
+
+		It("keeps the old processes if the new ones keep crashing", func() {
+			dep := deploySvc(manager, "sleep 60", 1)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			oldPID := svcTaskPIDs(manager)[0]
+
+			timeout := 200 * time.Millisecond
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{
+				ID:                    "sleeper",
+				Image:                 "false",
+				Count:                 1,
+				DeployTimeoutDuration: &timeout,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("1 old ones are still running"))
+			Expect(processExists(oldPID)).To(BeTrue())
+		})
+
+		It("kills the processes of every older version after updates in a row", func() {
+			dep := deploySvc(manager, "sleep 60", 1)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			pids := svcTaskPIDs(manager)
+
+			timeout := 5 * time.Second
+			var op anysched.Operation
+			for _, image := range []string{"sleep 61", "sleep 62", "sleep 63"} {
+				op, err = manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{
+					ID:                    "sleeper",
+					Image:                 image,
+					Count:                 1,
+					DeployTimeoutDuration: &timeout,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(func() []int { return svcTaskPIDs(manager) }).ShouldNot(ContainElement(0))
+				pids = append(pids, svcTaskPIDs(manager)...)
+			}
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			current := svcTaskPIDs(manager)
+			Expect(current).To(HaveLen(1))
+			for _, pid := range pids {
+				if pid != current[0] {
+					Eventually(func() bool { return processExists(pid) }).Should(BeFalse())
+				}
+			}
+		})
+
+		It("returns an error if the service does not exist", func() {
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{ID: "sleeper", Image: "sleep 60", Count: 1})
+			Expect(err).To(MatchError(`process.manager.UpdateSvc: service "sleeper" does not exist`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("ScaleSvc", func() {
+		It("starts processes next to the running ones", func() {
+			dep := deploySvc(manager, "sleep 60", 1)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			oldPID := svcTaskPIDs(manager)[0]
+
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("sleeper", 3)
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeFalse())
+			Expect(status.Msg).To(HavePrefix(`Waiting for service "sleeper" to scale: `))
+
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			status, err = op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Msg).To(Equal(`Service "sleeper" successfully scaled. 3 of 3 processes are running.`))
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "sleeper"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(3))
+			Expect(tasks[0].PID).To(Equal(oldPID))
+			Expect(tasks[2].Name).To(Equal("sleeper.2"))
+			Expect(tasks[2].Version).To(Equal("1"))
+		})
+
+		It("kills the processes that it removes", func() {
+			dep := deploySvc(manager, "sleep 60", 3)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			pids := svcTaskPIDs(manager)
+
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("sleeper", 1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcTaskPIDs(manager)).To(Equal(pids[:1]))
+			Expect(processExists(pids[1])).To(BeFalse())
+			Expect(processExists(pids[2])).To(BeFalse())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			op, err := manager.(anysched.SvcScaler).ScaleSvc("sleeper", 2)
+			Expect(err).To(MatchError(`process.manager.ScaleSvc: service "sleeper" does not exist`))
+			Expect(op).To(BeNil())
+		})
+	})
+
+	Describe("SvcHistory and RollbackSvc", func() {
+		It("roll the service back to a previous version", func() {
+			dep := deploySvc(manager, "sleep 60", 1)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			op, err := manager.(anysched.SvcUpdater).UpdateSvc(anysched.SvcCfg{ID: "sleeper", Image: "sleep 61", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = manager.(anysched.SvcScaler).ScaleSvc("sleeper", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			op, err = manager.(anysched.SvcRollbacker).RollbackSvc("sleeper", 1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			tasks, err := manager.SvcTasks(anysched.SvcCfg{ID: "sleeper"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+			Expect(tasks[0].Version).To(Equal("3"))
+			revisions, err := manager.(anysched.SvcHistoryGetter).SvcHistory("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			images := []string{}
+			for _, revision := range revisions {
+				images = append(images, revision.Image)
+			}
+			Expect(images).To(Equal([]string{"sleep 60", "sleep 61", "sleep 60"}))
+			Expect(revisions[2].Revision).To(Equal(int64(3)))
+			Expect(revisions[2].Current).To(BeTrue())
+		})
+
+		It("returns an error for a revision that does not exist", func() {
+			deploySvc(manager, "sleep 60", 1)
+			_, err := manager.(anysched.SvcRollbacker).RollbackSvc("sleeper", 2)
+			Expect(err).To(MatchError(`process.manager.RollbackSvc: service "sleeper" has no revision 2`))
+		})
+
+		It("return an error if the service does not exist", func() {
+			_, err := manager.(anysched.SvcHistoryGetter).SvcHistory("sleeper")
+			Expect(err).To(MatchError(`process.manager.SvcHistory: service "sleeper" does not exist`))
+			_, err = manager.(anysched.SvcRollbacker).RollbackSvc("sleeper", 1)
+			Expect(err).To(MatchError(`process.manager.RollbackSvc: service "sleeper" does not exist`))
+		})
+	})
+
+	Describe("supervision", func() {
+		It("restarts a process that exits", func() {
+			dep := deploySvc(manager, "sleep 60", 1)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			oldPID := svcTaskPIDs(manager)[0]
+			Expect(syscall.Kill(oldPID, syscall.SIGKILL)).To(Succeed())
+			Eventually(func() int { return svcTaskPIDs(manager)[0] }).ShouldNot(Or(Equal(0), Equal(oldPID)))
+		})
+
+		It("times out if the processes keep crashing", func() {
+			dep := deploySvc(manager, "false", 2)
+			dep.timeoutDuration = 200 * time.Millisecond
+			_, err := dep.Wait(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out after 200ms"))
+			Expect(err.Error()).To(ContainSubstring("last exit: exit status 1"))
+		})
+	})
+
+	Describe("DestroySvc", func() {
+		It("kills the processes", func() {
+			dep := deploySvc(manager, "sleep 60", 2)
+			_, err := dep.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			pids := svcTaskPIDs(manager)
+			op, err := manager.DestroySvc("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.(*termination).String()).To(Equal(`<process.termination name="sleeper" count=2 />`))
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			status, err := op.GetStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Done).To(BeTrue())
+			Expect(status.Msg).To(Equal(`Service "sleeper" successfully stopped.`))
+			for _, pid := range pids {
+				Expect(processExists(pid)).To(BeFalse())
+			}
+			_, err = dep.GetStatus()
+			Expect(err).To(MatchError(`process.deployment.GetStatus: service "sleeper" no longer exists`))
+		})
+
+		It("kills the processes that the processes started", func() {
+			pidFile, err := ioutil.TempFile("", "sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(pidFile.Name())
+			pidFile.Close()
+			op, err := manager.DeploySvc(anysched.SvcCfg{
+				ID:      "sleeper",
+				Command: []string{"sh", "-c", `sleep 60 & echo $! > "$0"; wait`, pidFile.Name()},
+				Count:   1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			var childPID int
+			Eventually(func() error {
+				pid, err := ioutil.ReadFile(pidFile.Name())
+				if err == nil {
+					_, err = fmt.Sscan(string(pid), &childPID)
+				}
+				return err
+			}).Should(Succeed())
+			Expect(processExists(childPID)).To(BeTrue())
+
+			op, err = manager.DestroySvc("sleeper")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = op.Wait(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() bool { return processExists(childPID) }).Should(BeFalse())
+		})
+
+		It("returns an error if the service does not exist", func() {
+			_, err := manager.DestroySvc("sleeper")
+			Expect(err).To(MatchError(`process.manager.DestroySvc: service "sleeper" does not exist`))
+		})
+	})
+
+	Describe("summarizeTasks", func() {
+		It("returns when the last process became ready", func() {
+			// The second process starts too late to be ready by the time of the test
+			deployTime := time.Now().Add(-time.Minute)
+			readyStartTime, startTime := deployTime.Add(time.Second), time.Now().Add(time.Minute)
+			svc := &svc{deployTime: deployTime, tasks: []*task{
+				{state: taskStateRunning, startTime: &readyStartTime},
+			}}
+			ready, _, _, lastUpdateTime := summarizeTasks(svc)
+			Expect(ready).To(Equal(1))
+			Expect(lastUpdateTime).To(Equal(readyStartTime.Add(minReadyDuration)))
+
+			svc.tasks = append(svc.tasks, &task{state: taskStateRunning, startTime: &startTime})
+			ready, _, _, lastUpdateTime = summarizeTasks(svc)
+			Expect(ready).To(Equal(1))
+			Expect(lastUpdateTime).To(Equal(startTime))
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package process
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// States of a task
+const (
+	taskStateStarting   = "starting"
+	taskStateRunning    = "running"
+	taskStateRestarting = "restarting"
+	taskStateStopped    = "stopped"
+)
+
+// task supervises one of the processes of a service, restarting it whenever
+// it exits.
+type task struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mutex     sync.Mutex
+	pid       int
+	state     string
+	stageTime time.Time
+	startTime *time.Time
+	restarts  int
+	lastErr   error
+}
+
+// taskSnapshot is a copy of the state of a task at one point in time.
+type taskSnapshot struct {
+	pid       int
+	state     string
+	stageTime time.Time
+	startTime *time.Time
+	restarts  int
+	lastErr   error
+}
+
+func newTask(name string, stageTime time.Time, cancel context.CancelFunc) *task {
+	return &task{
+		name:      name,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		state:     taskStateStarting,
+		stageTime: stageTime,
+	}
+}
+
+// supervise runs the task's process until ctx is done, restarting it after
+// restartDelay every time that it exits. The process gets the environment of
+// the current process, plus env, and a process group of its own, so that the
+// processes that it starts are killed with it.
+func (t *task) supervise(ctx context.Context, argv []string, env []string) {
+	defer close(t.done)
+	for {
+		cmd := exec.Command(argv[0], argv[1:]...)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		err := cmd.Start()
+		if err == nil {
+			t.started(cmd.Process.Pid)
+			err = waitOrKillGroup(ctx, cmd)
+		}
+		t.exited(err)
+		select {
+		case <-ctx.Done():
+			t.stopped()
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
+
+// waitOrKillGroup waits for the started process of cmd to exit, killing its
+// process group once ctx is done. exec.CommandContext only kills the process
+// itself, which would leave its children running.
+func waitOrKillGroup(ctx context.Context, cmd *exec.Cmd) error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		// The process group has the ID of its leader. It is gone already if
+		// the process and its children exited in the meantime.
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		return <-exited
+	}
+}
+
+// stop kills the task's process and waits for it to exit.
+func (t *task) stop() {
+	t.cancel()
+	<-t.done
+}
+
+func (t *task) started(pid int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	startTime := time.Now()
+	t.pid = pid
+	t.state = taskStateRunning
+	t.startTime = &startTime
+}
+
+func (t *task) exited(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pid = 0
+	t.state = taskStateRestarting
+	t.startTime = nil
+	t.restarts++
+	t.lastErr = err
+}
+
+func (t *task) stopped() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.state = taskStateStopped
+}
+
+func (t *task) snapshot() taskSnapshot {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return taskSnapshot{
+		pid:       t.pid,
+		state:     t.state,
+		stageTime: t.stageTime,
+		startTime: t.startTime,
+		restarts:  t.restarts,
+		lastErr:   t.lastErr,
+	}
+}
+
+// isReady returns true if the task's process has been running for at least
+// minReadyDuration.
+func (snapshot taskSnapshot) isReady() bool {
+	return snapshot.state == taskStateRunning && time.Since(*snapshot.startTime) >= minReadyDuration
+}
@@ -0,0 +1,64 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/msabramo/go-anysched"
+)
+
+// termination implements the anysched.Operation interface for the processes
+// of a destroyed service. It is done once all of them have exited.
+type termination struct {
+	svcID           string
+	tasks           []*task
+	timeoutDuration time.Duration
+}
+
+func (term *termination) String() string {
+	return fmt.Sprintf("<process.termination name=%q count=%d />", term.svcID, len(term.tasks))
+}
+
+// GetProperties returns a map with all labels, annotations, and basic
+// properties like name or uid
+func (term *termination) GetProperties() (propertiesMap map[string]interface{}) {
+	propertiesMap = map[string]interface{}{}
+	propertiesMap["name"] = term.svcID
+	propertiesMap["count"] = len(term.tasks)
+	return propertiesMap
+}
+
+// GetStatus is for polling the status of the termination
+func (term *termination) GetStatus() (*anysched.OperationStatus, error) {
+	running := 0
+	for _, task := range term.tasks {
+		select {
+		case <-task.done:
+		default:
+			running++
+		}
+	}
+	if running > 0 {
+		msg := fmt.Sprintf("Waiting for service %q to stop: %d processes are still running...", term.svcID, running)
+		return status(msg, false, time.Now()), nil
+	}
+	return status(fmt.Sprintf("Service %q successfully stopped.", term.svcID), true, time.Now()), nil
+}
+
+// Wait waits for an operation to finish and return error or nil
+func (term *termination) Wait(ctx context.Context) (result interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, term.timeoutDuration)
+	defer cancel()
+
+	for _, task := range term.tasks {
+		select {
+		case <-task.done:
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "process.termination.Wait: Timed out after %s", term.timeoutDuration)
+		}
+	}
+	return term, nil
+}
@@ -0,0 +1,70 @@
+package anysched
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePortCfg parses a port in the form "[HOST_PORT:]CONTAINER_PORT[/PROTOCOL]",
+// like "docker run --publish", e.g.: "8080", "8080/tcp" or "80:8080/tcp".
+func ParsePortCfg(s string) (PortCfg, error) {
+	var portCfg PortCfg
+	ports := s
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		ports, portCfg.Protocol = s[:i], strings.ToLower(s[i+1:])
+		if portCfg.Protocol != "tcp" && portCfg.Protocol != "udp" {
+			return PortCfg{}, fmt.Errorf("invalid port %q: protocol must be \"tcp\" or \"udp\"", s)
+		}
+	}
+	hostPort, containerPort := "", ports
+	if i := strings.Index(ports, ":"); i >= 0 {
+		hostPort, containerPort = ports[:i], ports[i+1:]
+	}
+	var err error
+	if portCfg.ContainerPort, err = parsePortNumber(containerPort); err != nil {
+		return PortCfg{}, fmt.Errorf("invalid container port in %q: %s", s, err)
+	}
+	if hostPort != "" {
+		if portCfg.HostPort, err = parsePortNumber(hostPort); err != nil {
+			return PortCfg{}, fmt.Errorf("invalid host port in %q: %s", s, err)
+		}
+	}
+	return portCfg, nil
+}
+
+// Validate returns an error if a port number is out of range or the protocol
+// is unknown.
+func (portCfg PortCfg) Validate() error {
+	if portCfg.ContainerPort < 1 || portCfg.ContainerPort > 65535 {
+		return fmt.Errorf("container port %d is not between 1 and 65535", portCfg.ContainerPort)
+	}
+	if portCfg.HostPort < 0 || portCfg.HostPort > 65535 {
+		return fmt.Errorf("host port %d is not between 1 and 65535", portCfg.HostPort)
+	}
+	if protocol := portCfg.ProtocolOrDefault(); protocol != "tcp" && protocol != "udp" {
+		return fmt.Errorf("protocol of port %d must be \"tcp\" or \"udp\", not %q", portCfg.ContainerPort, protocol)
+	}
+	return nil
+}
+
+// validatePorts returns an error if a port is invalid.
+func validatePorts(ports []PortCfg) error {
+	for _, portCfg := range ports {
+		if err := portCfg.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parsePortNumber(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%d is not between 1 and 65535", port)
+	}
+	return port, nil
+}
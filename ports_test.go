@@ -0,0 +1,46 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("ports.go", func() {
+	Describe("ParsePortCfg", func() {
+		It("works with a container port", func() {
+			portCfg, err := anysched.ParsePortCfg("8080")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(portCfg).To(Equal(anysched.PortCfg{ContainerPort: 8080}))
+			Expect(portCfg.ProtocolOrDefault()).To(Equal("tcp"))
+		})
+
+		It("works with a protocol", func() {
+			portCfg, err := anysched.ParsePortCfg("53/UDP")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(portCfg).To(Equal(anysched.PortCfg{ContainerPort: 53, Protocol: "udp"}))
+		})
+
+		It("works with a host port", func() {
+			portCfg, err := anysched.ParsePortCfg("80:8080/tcp")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(portCfg).To(Equal(anysched.PortCfg{ContainerPort: 8080, HostPort: 80, Protocol: "tcp"}))
+		})
+
+		It("returns an error for an unknown protocol", func() {
+			_, err := anysched.ParsePortCfg("8080/sctp")
+			Expect(err).To(MatchError(`invalid port "8080/sctp": protocol must be "tcp" or "udp"`))
+		})
+
+		It("returns an error for an invalid container port", func() {
+			_, err := anysched.ParsePortCfg("http/tcp")
+			Expect(err).To(MatchError(`invalid container port in "http/tcp": "http" is not a number`))
+		})
+
+		It("returns an error for an invalid host port", func() {
+			_, err := anysched.ParsePortCfg("70000:8080")
+			Expect(err).To(MatchError(`invalid host port in "70000:8080": 70000 is not between 1 and 65535`))
+		})
+	})
+})
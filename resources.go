@@ -0,0 +1,97 @@
+package anysched
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Resources is the compute resources of each task of a service. Zero values
+// mean that the scheduler's defaults apply.
+type Resources struct {
+	// CPU is the number of CPU cores requested, e.g.: 0.25
+	CPU float64
+
+	// Memory is the number of bytes of memory requested.
+	Memory int64
+
+	// CPULimit and MemoryLimit are the most that a task can use, if they are
+	// different from what it requests. They cannot be less than CPU and
+	// Memory.
+	CPULimit    float64
+	MemoryLimit int64
+
+	// Disk is the number of bytes of local disk requested.
+	Disk int64
+}
+
+// Validate returns an error if any of the resources are negative, or if a
+// limit is less than what is requested.
+func (resources *Resources) Validate() error {
+	if resources == nil {
+		return nil
+	}
+	if resources.CPU < 0 || resources.CPULimit < 0 {
+		return fmt.Errorf("CPU cannot be negative")
+	}
+	if resources.Memory < 0 || resources.MemoryLimit < 0 || resources.Disk < 0 {
+		return fmt.Errorf("memory and disk cannot be negative")
+	}
+	if resources.CPULimit != 0 && resources.CPULimit < resources.CPU {
+		return fmt.Errorf("CPU limit %g is less than CPU request %g", resources.CPULimit, resources.CPU)
+	}
+	if resources.MemoryLimit != 0 && resources.MemoryLimit < resources.Memory {
+		return fmt.Errorf("memory limit %d is less than memory request %d", resources.MemoryLimit, resources.Memory)
+	}
+	return nil
+}
+
+// EffectiveMemoryLimit returns MemoryLimit, or Memory if there is no separate
+// limit. It is for schedulers whose memory request is also a hard limit.
+func (resources *Resources) EffectiveMemoryLimit() int64 {
+	if resources.MemoryLimit != 0 {
+		return resources.MemoryLimit
+	}
+	return resources.Memory
+}
+
+// ParseCPU parses a number of CPU cores, like Kubernetes does, e.g.: "2", "0.5"
+// or "250m" (millicores).
+func ParseCPU(s string) (float64, error) {
+	number, divisor := s, 1.0
+	if strings.HasSuffix(s, "m") {
+		number, divisor = strings.TrimSuffix(s, "m"), 1000
+	}
+	cpu, err := strconv.ParseFloat(number, 64)
+	if err != nil || cpu < 0 || math.IsInf(cpu, 0) || math.IsNaN(cpu) {
+		return 0, fmt.Errorf("invalid CPU %q", s)
+	}
+	return cpu / divisor, nil
+}
+
+// byteUnits are the suffixes of quantities of bytes, like in Kubernetes.
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"K", 1e3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// ParseBytes parses a quantity of bytes, like Kubernetes does, e.g.: "512Mi",
+// "1.5Gi", "100M" or "1048576".
+func ParseBytes(s string) (int64, error) {
+	number, multiplier := s, 1.0
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			number, multiplier = strings.TrimSuffix(s, unit.suffix), unit.multiplier
+			break
+		}
+	}
+	bytes, err := strconv.ParseFloat(number, 64)
+	if err != nil || bytes < 0 || bytes*multiplier >= math.MaxInt64 || math.IsNaN(bytes) {
+		return 0, fmt.Errorf("invalid quantity of bytes %q", s)
+	}
+	return int64(math.Ceil(bytes * multiplier)), nil
+}
@@ -0,0 +1,76 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("resources.go", func() {
+	Describe("Resources", func() {
+		Describe("Validate", func() {
+			It("works", func() {
+				resources := &anysched.Resources{CPU: 0.5, CPULimit: 1, Memory: 256 << 20, MemoryLimit: 256 << 20}
+				Expect(resources.Validate()).To(Succeed())
+				Expect((&anysched.Resources{CPU: 0.5, Memory: 256 << 20}).Validate()).To(Succeed())
+			})
+
+			It("works if Resources is nil", func() {
+				var resources *anysched.Resources
+				Expect(resources.Validate()).To(Succeed())
+			})
+
+			It("returns an error if a limit is less than the request", func() {
+				Expect((&anysched.Resources{CPU: 1, CPULimit: 0.5}).Validate()).To(
+					MatchError("CPU limit 0.5 is less than CPU request 1"))
+				Expect((&anysched.Resources{Memory: 2048, MemoryLimit: 1024}).Validate()).To(
+					MatchError("memory limit 1024 is less than memory request 2048"))
+			})
+
+			It("returns an error if a resource is negative", func() {
+				Expect((&anysched.Resources{CPU: -1}).Validate()).To(MatchError("CPU cannot be negative"))
+				Expect((&anysched.Resources{Disk: -1}).Validate()).To(MatchError("memory and disk cannot be negative"))
+			})
+		})
+
+		Describe("EffectiveMemoryLimit", func() {
+			It("works", func() {
+				Expect((&anysched.Resources{Memory: 1024}).EffectiveMemoryLimit()).To(Equal(int64(1024)))
+				Expect((&anysched.Resources{Memory: 1024, MemoryLimit: 2048}).EffectiveMemoryLimit()).To(Equal(int64(2048)))
+			})
+		})
+	})
+
+	Describe("ParseCPU", func() {
+		It("works with cores and millicores", func() {
+			Expect(anysched.ParseCPU("2")).To(Equal(2.0))
+			Expect(anysched.ParseCPU("0.5")).To(Equal(0.5))
+			Expect(anysched.ParseCPU("250m")).To(Equal(0.25))
+		})
+
+		It("returns an error for an invalid CPU", func() {
+			_, err := anysched.ParseCPU("lots")
+			Expect(err).To(MatchError(`invalid CPU "lots"`))
+			_, err = anysched.ParseCPU("-1")
+			Expect(err).To(MatchError(`invalid CPU "-1"`))
+		})
+	})
+
+	Describe("ParseBytes", func() {
+		It("works with binary and decimal units", func() {
+			Expect(anysched.ParseBytes("1048576")).To(Equal(int64(1048576)))
+			Expect(anysched.ParseBytes("512Mi")).To(Equal(int64(512 << 20)))
+			Expect(anysched.ParseBytes("1.5Gi")).To(Equal(int64(3 << 29)))
+			Expect(anysched.ParseBytes("100M")).To(Equal(int64(100000000)))
+			Expect(anysched.ParseBytes("2k")).To(Equal(int64(2000)))
+		})
+
+		It("returns an error for an invalid quantity", func() {
+			_, err := anysched.ParseBytes("512MB")
+			Expect(err).To(MatchError(`invalid quantity of bytes "512MB"`))
+			_, err = anysched.ParseBytes("Gi")
+			Expect(err).To(MatchError(`invalid quantity of bytes "Gi"`))
+		})
+	})
+})
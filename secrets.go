@@ -0,0 +1,130 @@
+package anysched
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SecretRef exposes the value of a key of a secret to the tasks of a service,
+// as an environment variable or as a file, so that values like database
+// passwords don't have to be in Env.
+type SecretRef struct {
+	// Secret is the name of the secret, as the scheduler knows it: the name of
+	// a Kubernetes Secret, the path of a DC/OS secret for Marathon, which
+	// holds a secret for each key, a Vault path for Nomad, e.g.: "secret/db",
+	// or the name of a Secret that a SecretsManager created for Swarm.
+	Secret string
+
+	// Key is the key in the secret whose value is exposed, e.g.: "password".
+	Key string
+
+	// EnvVar is the name of the environment variable that the value is
+	// exposed as, and MountPath is the absolute path of the file that it is
+	// exposed as. Exactly one of them is set.
+	EnvVar    string
+	MountPath string
+}
+
+// ParseSecretRef parses a reference to a secret in the form
+// "SECRET:KEY=TARGET", where a TARGET that is an absolute path is a file and
+// any other TARGET is the name of an environment variable, e.g.:
+// "db:password=DB_PASSWORD" or "tls:key=/etc/tls/key.pem".
+func ParseSecretRef(s string) (SecretRef, error) {
+	parts := strings.SplitN(s, "=", 2)
+	i := strings.LastIndex(parts[0], ":")
+	if len(parts) != 2 || i < 0 {
+		return SecretRef{}, fmt.Errorf("invalid secret %q: expected SECRET:KEY=TARGET", s)
+	}
+	secretRef := SecretRef{Secret: parts[0][:i], Key: parts[0][i+1:], EnvVar: parts[1]}
+	if path.IsAbs(secretRef.EnvVar) {
+		secretRef.EnvVar, secretRef.MountPath = "", secretRef.EnvVar
+	}
+	if err := secretRef.Validate(); err != nil {
+		return SecretRef{}, fmt.Errorf("invalid secret %q: %s", s, err)
+	}
+	return secretRef, nil
+}
+
+// Validate returns an error if the secret or key is blank, or if the value is
+// exposed as neither or both of an environment variable and a file.
+func (secretRef SecretRef) Validate() error {
+	if secretRef.Secret == "" || secretRef.Key == "" {
+		return fmt.Errorf("secret %q has a blank name or key", secretRef.String())
+	}
+	if (secretRef.EnvVar == "") == (secretRef.MountPath == "") {
+		return fmt.Errorf("secret %q must be exposed as an environment variable or a file, not both or neither",
+			secretRef.String())
+	}
+	if strings.Contains(secretRef.EnvVar, "=") {
+		return fmt.Errorf("secret %q cannot be exposed as environment variable %q", secretRef.String(),
+			secretRef.EnvVar)
+	}
+	if secretRef.MountPath != "" && !path.IsAbs(secretRef.MountPath) {
+		return fmt.Errorf("mount path %q of secret %q is not an absolute path", secretRef.MountPath,
+			secretRef.String())
+	}
+	return nil
+}
+
+// String returns "SECRET:KEY".
+func (secretRef SecretRef) String() string {
+	return secretRef.Secret + ":" + secretRef.Key
+}
+
+// validateSecrets returns an error if a secret reference is invalid, if one of
+// them is exposed as an environment variable that is also in Env, or if one of
+// them is mounted at the same path as another one or as a volume.
+func validateSecrets(svcCfg SvcCfg) error {
+	envVars := map[string]bool{}
+	for name := range svcCfg.Env {
+		envVars[name] = true
+	}
+	mountPaths := map[string]bool{}
+	for _, volumeCfg := range svcCfg.Volumes {
+		mountPaths[volumeCfg.MountPath] = true
+	}
+	for _, secretRef := range svcCfg.Secrets {
+		if err := secretRef.Validate(); err != nil {
+			return err
+		}
+		if envVars[secretRef.EnvVar] {
+			return fmt.Errorf("environment variable %s is set more than once", secretRef.EnvVar)
+		}
+		if mountPaths[secretRef.MountPath] {
+			return fmt.Errorf("more than one volume or secret is mounted at %s", secretRef.MountPath)
+		}
+		envVars[secretRef.EnvVar] = secretRef.EnvVar != ""
+		mountPaths[secretRef.MountPath] = secretRef.MountPath != ""
+	}
+	return nil
+}
+
+// Secret is a secret that a SecretsManager stores: values by key, e.g.:
+// {"username": ..., "password": ...}.
+type Secret struct {
+	Name string
+	Data map[string][]byte
+}
+
+// secretKeyRegexp matches the keys of a Secret, e.g.: "password" or "tls.key"
+var secretKeyRegexp = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// Validate returns an error if the secret has no name or no values, or a key
+// that is blank or has a character other than an alphanumeric, "-", "_" or
+// ".", which is what every scheduler takes.
+func (secret Secret) Validate() error {
+	if secret.Name == "" {
+		return fmt.Errorf("secret has no name")
+	}
+	if len(secret.Data) == 0 {
+		return fmt.Errorf("secret %q has no values", secret.Name)
+	}
+	for key := range secret.Data {
+		if !secretKeyRegexp.MatchString(key) {
+			return fmt.Errorf("secret %q has invalid key %q", secret.Name, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("secrets.go", func() {
+	Describe("ParseSecretRef", func() {
+		It("works with an environment variable", func() {
+			secretRef, err := anysched.ParseSecretRef("secret/db:password=DB_PASSWORD")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretRef).To(Equal(anysched.SecretRef{Secret: "secret/db", Key: "password", EnvVar: "DB_PASSWORD"}))
+			Expect(secretRef.String()).To(Equal("secret/db:password"))
+		})
+
+		It("works with a file", func() {
+			secretRef, err := anysched.ParseSecretRef("tls:key=/etc/tls/key.pem")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretRef).To(Equal(anysched.SecretRef{Secret: "tls", Key: "key", MountPath: "/etc/tls/key.pem"}))
+		})
+
+		It("returns an error without a key", func() {
+			_, err := anysched.ParseSecretRef("db=DB_PASSWORD")
+			Expect(err).To(MatchError(`invalid secret "db=DB_PASSWORD": expected SECRET:KEY=TARGET`))
+		})
+
+		It("returns an error for a blank target", func() {
+			_, err := anysched.ParseSecretRef("db:password=")
+			Expect(err).To(MatchError(`invalid secret "db:password=": secret "db:password" must be exposed ` +
+				`as an environment variable or a file, not both or neither`))
+		})
+	})
+
+	Describe("SecretRef.Validate", func() {
+		It("returns an error for a blank key", func() {
+			err := anysched.SecretRef{Secret: "db", EnvVar: "DB_PASSWORD"}.Validate()
+			Expect(err).To(MatchError(`secret "db:" has a blank name or key`))
+		})
+
+		It("returns an error for a relative mount path", func() {
+			err := anysched.SecretRef{Secret: "db", Key: "password", MountPath: "password"}.Validate()
+			Expect(err).To(MatchError(`mount path "password" of secret "db:password" is not an absolute path`))
+		})
+	})
+
+	Describe("Secret.Validate", func() {
+		It("works", func() {
+			err := anysched.Secret{Name: "db", Data: map[string][]byte{"password": []byte("hunter2")}}.Validate()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error for a secret without values", func() {
+			err := anysched.Secret{Name: "db"}.Validate()
+			Expect(err).To(MatchError(`secret "db" has no values`))
+		})
+
+		It("returns an error for an invalid key", func() {
+			err := anysched.Secret{Name: "db", Data: map[string][]byte{"pass word": nil}}.Validate()
+			Expect(err).To(MatchError(`secret "db" has invalid key "pass word"`))
+		})
+	})
+})
@@ -0,0 +1,232 @@
+package anysched
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operators of the requirements of a Selector
+const (
+	SelectorOpEquals       = "="
+	SelectorOpNotEquals    = "!="
+	SelectorOpIn           = "in"
+	SelectorOpNotIn        = "notin"
+	SelectorOpExists       = "exists"
+	SelectorOpDoesNotExist = "!"
+)
+
+// Selector selects services by their labels, like a Kubernetes label selector.
+// A service is selected if its labels match all of the requirements.
+type Selector []SelectorRequirement
+
+// SelectorRequirement is one of the requirements of a Selector.
+//
+// SelectorOpEquals and SelectorOpNotEquals have one value, SelectorOpIn and
+// SelectorOpNotIn have one or more, and SelectorOpExists and
+// SelectorOpDoesNotExist have none. Like in Kubernetes, SelectorOpNotEquals
+// and SelectorOpNotIn also match labels that don't have the key at all.
+type SelectorRequirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// labelKeyRegexp matches label keys, e.g.: "team" or "example.com/team"
+var labelKeyRegexp = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// labelValueRegexp matches label values, which can be blank
+var labelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+
+// maxLabelLength is the maximum length of a label value, and of a label key
+// without its prefix
+const maxLabelLength = 63
+
+// selectorSetRegexp matches set-based requirements, e.g.: "tier in (web, api)"
+var selectorSetRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s+\((.*)\)$`)
+
+// ParseSelector parses a selector in Kubernetes' syntax: requirements separated
+// by commas, each of which is "key=value", "key==value", "key!=value",
+// "key in (value1,value2)", "key notin (value1,value2)", "key" or "!key", e.g.:
+// "team=payments,tier in (web,api),!deprecated". A blank selector selects
+// everything.
+func ParseSelector(s string) (Selector, error) {
+	selector := Selector{}
+	for _, part := range splitSelector(s) {
+		requirement, err := parseSelectorRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %s", s, err)
+		}
+		selector = append(selector, requirement)
+	}
+	return selector, nil
+}
+
+// splitSelector splits a selector on the commas that aren't in parentheses.
+func splitSelector(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseSelectorRequirement(s string) (SelectorRequirement, error) {
+	var requirement SelectorRequirement
+	if match := selectorSetRegexp.FindStringSubmatch(s); match != nil {
+		requirement = SelectorRequirement{Key: match[1], Operator: match[2]}
+		for _, value := range strings.Split(match[3], ",") {
+			requirement.Values = append(requirement.Values, strings.TrimSpace(value))
+		}
+	} else if i := strings.Index(s, "!="); i >= 0 {
+		requirement = SelectorRequirement{Key: s[:i], Operator: SelectorOpNotEquals, Values: []string{s[i+2:]}}
+	} else if i := strings.Index(s, "="); i >= 0 {
+		value := strings.TrimPrefix(s[i+1:], "=")
+		requirement = SelectorRequirement{Key: s[:i], Operator: SelectorOpEquals, Values: []string{value}}
+	} else if strings.HasPrefix(s, "!") {
+		requirement = SelectorRequirement{Key: strings.TrimSpace(s[1:]), Operator: SelectorOpDoesNotExist}
+	} else {
+		requirement = SelectorRequirement{Key: s, Operator: SelectorOpExists}
+	}
+	requirement.Key = strings.TrimSpace(requirement.Key)
+	if err := validateLabelKey(requirement.Key); err != nil {
+		return SelectorRequirement{}, err
+	}
+	for i, value := range requirement.Values {
+		requirement.Values[i] = strings.TrimSpace(value)
+		if err := validateLabelValue(requirement.Values[i]); err != nil {
+			return SelectorRequirement{}, err
+		}
+	}
+	return requirement, nil
+}
+
+// validateLabels returns an error if a key or value of labels doesn't follow
+// the rules of Kubernetes labels, so that labels mean the same for every
+// Manager.
+func validateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if err := validateLabelValue(value); err != nil {
+			return fmt.Errorf("label %q: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func validateLabelKey(key string) error {
+	name := key[strings.LastIndex(key, "/")+1:]
+	if !labelKeyRegexp.MatchString(key) || len(name) > maxLabelLength {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+func validateLabelValue(value string) error {
+	if !labelValueRegexp.MatchString(value) || len(value) > maxLabelLength {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
+
+// Matches returns true if labels match all of the requirements of the
+// selector.
+func (selector Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range selector {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if labels match the requirement.
+func (requirement SelectorRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[requirement.Key]
+	switch requirement.Operator {
+	case SelectorOpEquals, SelectorOpIn:
+		return ok && containsString(requirement.Values, value)
+	case SelectorOpNotEquals, SelectorOpNotIn:
+		return !ok || !containsString(requirement.Values, value)
+	case SelectorOpExists:
+		return ok
+	case SelectorOpDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// String returns the selector in Kubernetes' syntax.
+func (selector Selector) String() string {
+	parts := make([]string, len(selector))
+	for i, requirement := range selector {
+		parts[i] = requirement.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// String returns the requirement in Kubernetes' syntax.
+func (requirement SelectorRequirement) String() string {
+	switch requirement.Operator {
+	case SelectorOpIn, SelectorOpNotIn:
+		return fmt.Sprintf("%s %s (%s)", requirement.Key, requirement.Operator, strings.Join(requirement.Values, ","))
+	case SelectorOpExists:
+		return requirement.Key
+	case SelectorOpDoesNotExist:
+		return "!" + requirement.Key
+	}
+	return requirement.Key + requirement.Operator + strings.Join(requirement.Values, ",")
+}
+
+// SvcsWithSelector returns the running services whose labels match selector.
+// It uses the manager's SvcsWithSelector if it has one, so that the scheduler
+// can do the filtering, and otherwise filters what its Svcs returns.
+func SvcsWithSelector(manager SvcsGetter, selector Selector) ([]Svc, error) {
+	if svcsWithSelectorGetter, ok := manager.(SvcsWithSelectorGetter); ok {
+		return svcsWithSelectorGetter.SvcsWithSelector(selector)
+	}
+	svcs, err := manager.Svcs()
+	if err != nil {
+		return nil, err
+	}
+	return FilterSvcs(svcs, selector), nil
+}
+
+// FilterSvcs returns the services in svcs whose labels match selector.
+func FilterSvcs(svcs []Svc, selector Selector) []Svc {
+	selectedSvcs := []Svc{}
+	for _, svc := range svcs {
+		if selector.Matches(svc.Labels) {
+			selectedSvcs = append(selectedSvcs, svc)
+		}
+	}
+	return selectedSvcs
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
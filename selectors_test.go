@@ -0,0 +1,112 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+type fakeSvcsGetter struct {
+	svcs []anysched.Svc
+}
+
+func (getter fakeSvcsGetter) Svcs() ([]anysched.Svc, error) {
+	return getter.svcs, nil
+}
+
+type fakeSvcsWithSelectorGetter struct {
+	fakeSvcsGetter
+	selector anysched.Selector
+}
+
+func (getter *fakeSvcsWithSelectorGetter) SvcsWithSelector(selector anysched.Selector) ([]anysched.Svc, error) {
+	getter.selector = selector
+	return getter.svcs[:1], nil
+}
+
+var _ = Describe("selectors.go", func() {
+	Describe("ParseSelector", func() {
+		It("works with equality-based requirements", func() {
+			selector, err := anysched.ParseSelector("team=payments, tier==web,env!=prod")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selector).To(Equal(anysched.Selector{
+				{Key: "team", Operator: anysched.SelectorOpEquals, Values: []string{"payments"}},
+				{Key: "tier", Operator: anysched.SelectorOpEquals, Values: []string{"web"}},
+				{Key: "env", Operator: anysched.SelectorOpNotEquals, Values: []string{"prod"}},
+			}))
+			Expect(selector.String()).To(Equal("team=payments,tier=web,env!=prod"))
+		})
+
+		It("works with set-based requirements", func() {
+			selector, err := anysched.ParseSelector("tier in (web, api),env notin (prod),example.com/owner,!deprecated")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selector).To(Equal(anysched.Selector{
+				{Key: "tier", Operator: anysched.SelectorOpIn, Values: []string{"web", "api"}},
+				{Key: "env", Operator: anysched.SelectorOpNotIn, Values: []string{"prod"}},
+				{Key: "example.com/owner", Operator: anysched.SelectorOpExists},
+				{Key: "deprecated", Operator: anysched.SelectorOpDoesNotExist},
+			}))
+			Expect(selector.String()).To(Equal("tier in (web,api),env notin (prod),example.com/owner,!deprecated"))
+		})
+
+		It("returns an empty selector for a blank string", func() {
+			selector, err := anysched.ParseSelector(" ")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selector).To(BeEmpty())
+		})
+
+		It("returns an error for an invalid key", func() {
+			_, err := anysched.ParseSelector("team=payments,=web")
+			Expect(err).To(MatchError(`invalid selector "team=payments,=web": invalid label key ""`))
+		})
+
+		It("returns an error for an invalid value", func() {
+			_, err := anysched.ParseSelector("tier in (web,-api)")
+			Expect(err).To(MatchError(`invalid selector "tier in (web,-api)": invalid label value "-api"`))
+		})
+	})
+
+	Describe("Selector.Matches", func() {
+		labels := map[string]string{"team": "payments", "tier": "web"}
+
+		It("matches labels that match all of the requirements", func() {
+			for _, s := range []string{"", "team=payments", "team,tier in (web,api)", "env!=prod", "env notin (prod)", "!env"} {
+				selector, err := anysched.ParseSelector(s)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(selector.Matches(labels)).To(BeTrue(), s)
+			}
+		})
+
+		It("doesn't match labels that don't match a requirement", func() {
+			for _, s := range []string{"team=search", "team=payments,env", "tier notin (web)", "tier!=web", "!team"} {
+				selector, err := anysched.ParseSelector(s)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(selector.Matches(labels)).To(BeFalse(), s)
+			}
+		})
+	})
+
+	Describe("SvcsWithSelector", func() {
+		svcs := []anysched.Svc{
+			{ID: "payments-api", Labels: map[string]string{"team": "payments"}},
+			{ID: "search-api", Labels: map[string]string{"team": "search"}},
+			{ID: "unlabeled"},
+		}
+		selector := anysched.Selector{{Key: "team", Operator: anysched.SelectorOpEquals, Values: []string{"search"}}}
+
+		It("filters the services of a manager without SvcsWithSelector", func() {
+			selectedSvcs, err := anysched.SvcsWithSelector(fakeSvcsGetter{svcs: svcs}, selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selectedSvcs).To(Equal(svcs[1:2]))
+		})
+
+		It("uses the SvcsWithSelector of a manager that has it", func() {
+			getter := &fakeSvcsWithSelectorGetter{fakeSvcsGetter: fakeSvcsGetter{svcs: svcs}}
+			selectedSvcs, err := anysched.SvcsWithSelector(getter, selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selectedSvcs).To(Equal(svcs[:1]))
+			Expect(getter.selector).To(Equal(selector))
+		})
+	})
+})
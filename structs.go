@@ -1,6 +1,8 @@
 package anysched
 
 import (
+	"fmt"
+	"sort"
 	"time"
 )
 
@@ -9,8 +11,19 @@ import (
 type ManagerConfig struct {
 	Type    string // e.g.: "marathon", "kubernetes", etc.
 	Address string // e.g.: "http://127.0.0.1:8080"
+
+	// Namespace is the namespace that the manager works in, for schedulers
+	// that have them (Kubernetes), or AllNamespaces. Blank means the one that
+	// Address or the scheduler's own configuration picks, e.g. the namespace
+	// of the current kubeconfig context.
+	Namespace string
 }
 
+// AllNamespaces is the ManagerConfig.Namespace of a manager that lists the
+// services and tasks of every namespace. It can only deploy services that
+// have a Namespace.
+const AllNamespaces = "*"
+
 // Svc is short for "service" and it is our term for something that gets
 // scheduled or destroyed by a Manager
 // e.g.: a Marathon application, Kubernetes deployment, etc.
@@ -25,17 +38,330 @@ type SvcCfg struct {
 	Image string
 	Count int
 
+	// Namespace is the namespace to deploy the service in, for schedulers that
+	// have them. Blank means the manager's.
+	Namespace string
+
+	// Command overrides the entrypoint of the image, and Args overrides the
+	// arguments that are passed to it (the image's default command).
+	Command []string
+	Args    []string
+
+	// Env is the environment variables of the service's tasks, by name.
+	Env map[string]string
+
+	// Secrets is the secrets that are exposed to the service's tasks as
+	// environment variables or files.
+	Secrets []SecretRef
+
+	// Labels is metadata about the service, by key, that services can be
+	// selected by, e.g.: {"team": "payments"}. Keys and values follow the
+	// rules of Kubernetes labels.
+	Labels map[string]string
+
+	// Ports is the ports that the service's tasks listen on.
+	Ports []PortCfg
+
+	// Volumes is the volumes that are mounted in the service's tasks.
+	Volumes []VolumeCfg
+
+	// Resources is the resources of each task. nil means the scheduler's
+	// defaults.
+	Resources *Resources
+
+	// HealthCheck checks whether a running task is healthy. Schedulers restart
+	// (or replace) tasks that fail it. ReadinessCheck checks whether a task is
+	// ready for traffic, and deployments wait for it. Both are optional.
+	HealthCheck    *HealthCheck
+	ReadinessCheck *HealthCheck
+
+	// Sidecars is the containers that run next to the main container in each
+	// task, e.g. a log forwarder, and InitContainers is the containers that
+	// run to completion, one after the other, before any of them start, e.g.
+	// a database migration. Both are optional, and only some schedulers
+	// support them.
+	Sidecars       []ContainerCfg
+	InitContainers []ContainerCfg
+
+	// Constraints restrict which nodes the service's tasks are placed on and
+	// how they are spread across them. A task is only placed on a node that
+	// satisfies all of them.
+	Constraints []Constraint
+
 	DeployTimeoutDuration *time.Duration // pointer because optional
 }
 
+// EnvVar is an environment variable of a service.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// EnvVars returns the environment variables in Env, ordered by name, so that
+// everything rendered from them is deterministic.
+func (svcCfg SvcCfg) EnvVars() []EnvVar {
+	return envVars(svcCfg.Env)
+}
+
+// envVars returns the environment variables in env, ordered by name.
+func envVars(env map[string]string) []EnvVar {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	envVars := make([]EnvVar, len(names))
+	for i, name := range names {
+		envVars[i] = EnvVar{Name: name, Value: env[name]}
+	}
+	return envVars
+}
+
+// EnvList returns the environment variables in Env as "NAME=value" strings,
+// ordered by name, like the Env of exec.Cmd or a Docker container.
+func (svcCfg SvcCfg) EnvList() []string {
+	envVars := svcCfg.EnvVars()
+	envList := make([]string, len(envVars))
+	for i, envVar := range envVars {
+		envList[i] = envVar.Name + "=" + envVar.Value
+	}
+	return envList
+}
+
+// Copy returns a deep copy of the SvcCfg, which shares no maps, slices or
+// pointers with it, so that managers that keep SvcCfgs aren't affected when
+// their callers change the SvcCfgs afterwards.
+func (svcCfg SvcCfg) Copy() SvcCfg {
+	c := svcCfg
+	c.Command = copyStrings(svcCfg.Command)
+	c.Args = copyStrings(svcCfg.Args)
+	c.Env = copyStringMap(svcCfg.Env)
+	c.Labels = copyStringMap(svcCfg.Labels)
+	if svcCfg.Secrets != nil {
+		c.Secrets = append([]SecretRef{}, svcCfg.Secrets...)
+	}
+	if svcCfg.Ports != nil {
+		c.Ports = append([]PortCfg{}, svcCfg.Ports...)
+	}
+	if svcCfg.Volumes != nil {
+		c.Volumes = append([]VolumeCfg{}, svcCfg.Volumes...)
+	}
+	c.Resources = copyResources(svcCfg.Resources)
+	c.HealthCheck = copyHealthCheck(svcCfg.HealthCheck)
+	c.ReadinessCheck = copyHealthCheck(svcCfg.ReadinessCheck)
+	c.Sidecars = copyContainerCfgs(svcCfg.Sidecars)
+	c.InitContainers = copyContainerCfgs(svcCfg.InitContainers)
+	c.Constraints = copyConstraints(svcCfg.Constraints)
+	if svcCfg.DeployTimeoutDuration != nil {
+		deployTimeoutDuration := *svcCfg.DeployTimeoutDuration
+		c.DeployTimeoutDuration = &deployTimeoutDuration
+	}
+	return c
+}
+
+// copyStrings returns a copy of s, which is nil if s is nil.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+// copyStringMap returns a copy of m, which is nil if m is nil.
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// copyResources returns a copy of resources, which is nil if resources is
+// nil.
+func copyResources(resources *Resources) *Resources {
+	if resources == nil {
+		return nil
+	}
+	c := *resources
+	return &c
+}
+
+// copyHealthCheck returns a copy of healthCheck, which is nil if healthCheck
+// is nil.
+func copyHealthCheck(healthCheck *HealthCheck) *HealthCheck {
+	if healthCheck == nil {
+		return nil
+	}
+	c := *healthCheck
+	c.Command = copyStrings(healthCheck.Command)
+	return &c
+}
+
+// copyContainerCfgs returns deep copies of containerCfgs.
+func copyContainerCfgs(containerCfgs []ContainerCfg) []ContainerCfg {
+	if containerCfgs == nil {
+		return nil
+	}
+	c := make([]ContainerCfg, len(containerCfgs))
+	for i, containerCfg := range containerCfgs {
+		c[i] = containerCfg
+		c[i].Command = copyStrings(containerCfg.Command)
+		c[i].Args = copyStrings(containerCfg.Args)
+		c[i].Env = copyStringMap(containerCfg.Env)
+		if containerCfg.Ports != nil {
+			c[i].Ports = append([]PortCfg{}, containerCfg.Ports...)
+		}
+		c[i].Resources = copyResources(containerCfg.Resources)
+	}
+	return c
+}
+
+// copyConstraints returns deep copies of constraints.
+func copyConstraints(constraints []Constraint) []Constraint {
+	if constraints == nil {
+		return nil
+	}
+	c := make([]Constraint, len(constraints))
+	for i, constraint := range constraints {
+		c[i] = constraint
+		c[i].Values = copyStrings(constraint.Values)
+	}
+	return c
+}
+
+// Validate returns an error if the SvcCfg is invalid for any Manager, e.g.
+// because a port is out of range or a resource limit is less than its
+// request. Managers call it before they deploy a service.
+func (svcCfg SvcCfg) Validate() error {
+	if err := validateNamespace(svcCfg.Namespace); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validateLabels(svcCfg.Labels); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validatePorts(svcCfg.Ports); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validateVolumes(svcCfg.Volumes); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validateSecrets(svcCfg); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := svcCfg.validateTask(); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validateContainers(svcCfg); err != nil {
+		return fmt.Errorf("service %q: %s", svcCfg.ID, err)
+	}
+	if err := validateConstraints(svcCfg.Constraints); err != nil {
+		return fmt.Errorf("service %q: invalid constraint: %s", svcCfg.ID, err)
+	}
+	return nil
+}
+
+// validateTask returns an error if the resources, health check or readiness
+// check of the tasks is invalid.
+func (svcCfg SvcCfg) validateTask() error {
+	if err := svcCfg.Resources.Validate(); err != nil {
+		return fmt.Errorf("invalid resources: %s", err)
+	}
+	if err := svcCfg.HealthCheck.Validate(); err != nil {
+		return fmt.Errorf("invalid health check: %s", err)
+	}
+	if err := svcCfg.ReadinessCheck.Validate(); err != nil {
+		return fmt.Errorf("invalid readiness check: %s", err)
+	}
+	return nil
+}
+
+// validateNamespace returns an error if namespace isn't blank or a DNS label.
+func validateNamespace(namespace string) error {
+	if namespace != "" && (len(namespace) > maxLabelLength || !dnsLabelRegexp.MatchString(namespace)) {
+		return fmt.Errorf("invalid namespace %q: must be at most %d lowercase letters, digits or \"-\"",
+			namespace, maxLabelLength)
+	}
+	return nil
+}
+
+// PortCfg declares a port that the tasks of a service listen on, and how it is
+// exposed outside of them.
+type PortCfg struct {
+	// Name is optional, e.g.: "http"
+	Name string
+
+	// ContainerPort is the port that a task listens on.
+	ContainerPort int
+
+	// Protocol is "tcp" or "udp". Blank means "tcp".
+	Protocol string
+
+	// HostPort is the port that the port is exposed on: on the host for
+	// Marathon, Nomad and Docker, by the routing mesh for Swarm, and by the
+	// companion Service for Kubernetes. 0 means that the scheduler picks one
+	// (or, for Kubernetes, that it is the same as ContainerPort).
+	HostPort int
+}
+
+// ProtocolOrDefault returns Protocol, or "tcp" if it is blank.
+func (portCfg PortCfg) ProtocolOrDefault() string {
+	if portCfg.Protocol == "" {
+		return "tcp"
+	}
+	return portCfg.Protocol
+}
+
 // Svc contains information about a service, such as when it was started and
 // how many tasks are running.
 type Svc struct {
-	ID             string     `yaml:"ID" json:"ID"`
-	TasksRunning   *int       `yaml:"tasks-running,omitempty" json:"tasks-running,omitempty"`
-	TasksHealthy   *int       `yaml:"tasks-healthy,omitempty" json:"tasks-healthy,omitempty"`
-	TasksUnhealthy *int       `yaml:"tasks-unhealthy,omitempty" json:"tasks-unhealthy,omitempty"`
-	CreationTime   *time.Time `yaml:"creation-time,omitempty" json:"creation-time,omitempty"`
+	ID             string            `yaml:"ID" json:"ID"`
+	Namespace      string            `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	TasksRunning   *int              `yaml:"tasks-running,omitempty" json:"tasks-running,omitempty"`
+	TasksHealthy   *int              `yaml:"tasks-healthy,omitempty" json:"tasks-healthy,omitempty"`
+	TasksUnhealthy *int              `yaml:"tasks-unhealthy,omitempty" json:"tasks-unhealthy,omitempty"`
+	CreationTime   *time.Time        `yaml:"creation-time,omitempty" json:"creation-time,omitempty"`
+	Labels         map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// SvcDetail contains everything that a manager can tell about a deployed
+// service, for describing it or comparing it to a SvcCfg.
+type SvcDetail struct {
+	// Svc is the same as what Svcs returns for the service.
+	Svc Svc `yaml:"svc" json:"svc"`
+
+	// SvcCfg is the configuration of the service, reconstructed from what the
+	// scheduler reports: its ID, Namespace, Image, Count, Env, Ports, Labels
+	// and Resources, as far as the scheduler keeps them. Environment variables
+	// from secrets are left out.
+	SvcCfg SvcCfg `yaml:"svc-cfg" json:"svc-cfg"`
+
+	// Revision is the number of the current revision of the service, as
+	// SvcHistory numbers it, or nil if the manager doesn't keep the history
+	// of services.
+	Revision *int64 `yaml:"revision,omitempty" json:"revision,omitempty"`
+
+	// Native is the scheduler's own object for the service, e.g. the
+	// Deployment for Kubernetes, for debugging. Its type depends on the
+	// manager.
+	Native interface{} `yaml:"native,omitempty" json:"native,omitempty"`
+}
+
+// SvcRevision is one of the configurations that a service has been deployed or
+// updated with, which RollbackSvc can roll it back to.
+type SvcRevision struct {
+	// Revision is the number of the revision, which is higher for later
+	// revisions. Managers use the scheduler's own numbers if it has them.
+	Revision int64 `yaml:"revision" json:"revision"`
+	// Version is the scheduler's own ID of the revision if it isn't Revision,
+	// e.g. the version timestamp of a Marathon app.
+	Version      string     `yaml:"version,omitempty" json:"version,omitempty"`
+	Image        string     `yaml:"image,omitempty" json:"image,omitempty"`
+	CreationTime *time.Time `yaml:"creation-time,omitempty" json:"creation-time,omitempty"`
+	// Current is true for the revision that the service is running.
+	Current bool `yaml:"current" json:"current"`
 }
 
 // OperationStatus represents the status of a pending operation, such as a deployment.
@@ -51,6 +377,7 @@ type OperationStatus struct {
 // started and what IP addresses are assigned to it.
 type Task struct {
 	Name                string     `yaml:"name" json:"name"`
+	Namespace           string     `yaml:"namespace,omitempty" json:"namespace,omitempty"`
 	AppID               string     `yaml:"app-id,omitempty" json:"app-id,omitempty"`
 	HostName            string     `yaml:"host-name,omitempty" json:"host-name,omitempty"`
 	HostIP              string     `yaml:"host-ip,omitempty" json:"host,omitempty"`
@@ -59,6 +386,7 @@ type Task struct {
 	Ports               []int      `yaml:"ports,omitempty" json:"ports,omitempty"`
 	ServicePorts        []int      `yaml:"service-ports,omitempty" json:"service-ports,omitempty"`
 	MesosSlaveID        string     `yaml:"mesos-slave-id,omitempty" json:"mesos-slave-id,omitempty"`
+	PID                 int        `yaml:"pid,omitempty" json:"pid,omitempty"`
 	StageTime           *time.Time `yaml:"stage-time,omitempty" json:"stage-time,omitempty"`
 	StartTime           *time.Time `yaml:"start-time,omitempty" json:"start-time,omitempty"`
 	ReadyTime           *time.Time `yaml:"ready-time,omitempty" json:"ready-time,omitempty"`
@@ -0,0 +1,163 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("structs.go", func() {
+	Describe("SvcCfg", func() {
+		svcCfg := anysched.SvcCfg{Env: map[string]string{"PORT": "8000", "DEBUG": "", "LANG": "en_US.UTF-8"}}
+
+		Describe("EnvVars", func() {
+			It("works", func() {
+				Expect(svcCfg.EnvVars()).To(Equal([]anysched.EnvVar{
+					{Name: "DEBUG", Value: ""},
+					{Name: "LANG", Value: "en_US.UTF-8"},
+					{Name: "PORT", Value: "8000"},
+				}))
+			})
+
+			It("works if Env is nil", func() {
+				Expect(anysched.SvcCfg{}.EnvVars()).To(BeEmpty())
+			})
+		})
+
+		Describe("Validate", func() {
+			It("works", func() {
+				Expect(anysched.SvcCfg{
+					ID:        "httpbin",
+					Ports:     []anysched.PortCfg{{ContainerPort: 8000, HostPort: 80}},
+					Resources: &anysched.Resources{CPU: 0.5, Memory: 256 << 20},
+				}.Validate()).To(Succeed())
+			})
+
+			It("returns an error for an invalid namespace", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Namespace: "Payments"}.Validate()
+				Expect(err).To(MatchError(
+					`service "httpbin": invalid namespace "Payments": must be at most 63 lowercase letters, digits or "-"`))
+			})
+
+			It("returns an error for an invalid port", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Ports: []anysched.PortCfg{{ContainerPort: 8000, Protocol: "sctp"}}}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": protocol of port 8000 must be "tcp" or "udp", not "sctp"`))
+			})
+
+			It("returns an error for invalid resources", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Resources: &anysched.Resources{CPU: 2, CPULimit: 1}}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": invalid resources: CPU limit 1 is less than CPU request 2`))
+			})
+
+			It("returns an error for two volumes at the same mount path", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Volumes: []anysched.VolumeCfg{
+					{Type: anysched.VolumeScratch, MountPath: "/tmp"},
+					{Type: anysched.VolumeHostPath, Source: "/tmp", MountPath: "/tmp"},
+				}}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": more than one volume is mounted at /tmp`))
+			})
+
+			It("returns an error for a secret exposed as an environment variable that is in Env", func() {
+				err := anysched.SvcCfg{
+					ID:      "httpbin",
+					Env:     map[string]string{"DB_PASSWORD": "hunter2"},
+					Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", EnvVar: "DB_PASSWORD"}},
+				}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": environment variable DB_PASSWORD is set more than once`))
+			})
+
+			It("returns an error for a secret mounted at the same path as a volume", func() {
+				err := anysched.SvcCfg{
+					ID:      "httpbin",
+					Volumes: []anysched.VolumeCfg{{Type: anysched.VolumeScratch, MountPath: "/tmp"}},
+					Secrets: []anysched.SecretRef{{Secret: "db", Key: "password", MountPath: "/tmp"}},
+				}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": more than one volume or secret is mounted at /tmp`))
+			})
+
+			It("returns an error for a sidecar named after the service", func() {
+				err := anysched.SvcCfg{
+					ID:       "httpbin",
+					Sidecars: []anysched.ContainerCfg{{Name: "httpbin", Image: "fluent/fluent-bit"}},
+				}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": more than one container is named "httpbin"`))
+			})
+
+			It("returns an error for a sidecar that listens on a port of the main container", func() {
+				err := anysched.SvcCfg{
+					ID:    "httpbin",
+					Ports: []anysched.PortCfg{{ContainerPort: 8000}},
+					Sidecars: []anysched.ContainerCfg{
+						{Name: "proxy", Image: "envoyproxy/envoy", Ports: []anysched.PortCfg{{ContainerPort: 8000}}},
+					},
+				}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": containers "httpbin" and "proxy" both listen on port 8000/tcp`))
+			})
+
+			It("returns an error for an init container with ports", func() {
+				err := anysched.SvcCfg{
+					ID: "httpbin",
+					InitContainers: []anysched.ContainerCfg{
+						{Name: "migrate", Image: "example/payments", Ports: []anysched.PortCfg{{ContainerPort: 8000}}},
+					},
+				}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": init container "migrate" cannot have ports`))
+			})
+
+			It("returns an error for an invalid constraint", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Constraints: []anysched.Constraint{{Operator: "=="}}}.Validate()
+				Expect(err).To(MatchError(
+					`service "httpbin": invalid constraint: "==" constraint must have an attribute and one value`))
+			})
+
+			It("returns an error for an invalid label", func() {
+				err := anysched.SvcCfg{ID: "httpbin", Labels: map[string]string{"team": "pay ments"}}.Validate()
+				Expect(err).To(MatchError(`service "httpbin": label "team": invalid label value "pay ments"`))
+			})
+		})
+
+		Describe("EnvList", func() {
+			It("works", func() {
+				Expect(svcCfg.EnvList()).To(Equal([]string{"DEBUG=", "LANG=en_US.UTF-8", "PORT=8000"}))
+			})
+		})
+
+		Describe("Copy", func() {
+			newSvcCfg := func() anysched.SvcCfg {
+				return anysched.SvcCfg{
+					ID:             "httpbin",
+					Command:        []string{"httpbin"},
+					Env:            map[string]string{"PORT": "8000"},
+					Labels:         map[string]string{"team": "payments"},
+					Ports:          []anysched.PortCfg{{ContainerPort: 8000}},
+					Resources:      &anysched.Resources{CPU: 0.5},
+					HealthCheck:    &anysched.HealthCheck{Command: []string{"true"}},
+					Sidecars:       []anysched.ContainerCfg{{Name: "logs", Env: map[string]string{"LEVEL": "info"}}},
+					Constraints:    []anysched.Constraint{{Attribute: "zone", Operator: "in", Values: []string{"a"}}},
+					InitContainers: []anysched.ContainerCfg{{Name: "migrate", Args: []string{"migrate"}}},
+				}
+			}
+
+			It("works", func() {
+				Expect(newSvcCfg().Copy()).To(Equal(newSvcCfg()))
+				Expect(anysched.SvcCfg{}.Copy()).To(Equal(anysched.SvcCfg{}))
+			})
+
+			It("shares nothing with the original", func() {
+				original := newSvcCfg()
+				c := original.Copy()
+				original.Command[0] = "gunicorn"
+				original.Env["PORT"] = "9000"
+				original.Labels["team"] = "search"
+				original.Ports[0].ContainerPort = 9000
+				original.Resources.CPU = 1
+				original.HealthCheck.Command[0] = "false"
+				original.Sidecars[0].Env["LEVEL"] = "debug"
+				original.Constraints[0].Values[0] = "b"
+				original.InitContainers[0].Args[0] = "rollback"
+				Expect(c).To(Equal(newSvcCfg()))
+			})
+		})
+	})
+})
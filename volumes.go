@@ -0,0 +1,120 @@
+package anysched
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Types of volumes
+const (
+	// VolumeHostPath mounts a path on the host that a task runs on.
+	VolumeHostPath = "host-path"
+
+	// VolumeNamed mounts a volume that the scheduler manages, which outlives
+	// the tasks that mount it: a PersistentVolumeClaim for Kubernetes, an
+	// external volume for Marathon, or a Docker volume for Nomad, Swarm and
+	// Docker.
+	VolumeNamed = "named"
+
+	// VolumeScratch mounts scratch space that is deleted with the task: an
+	// emptyDir for Kubernetes, a directory in the sandbox or task directory for
+	// Marathon and Nomad, or a tmpfs for Swarm and Docker.
+	VolumeScratch = "scratch"
+)
+
+// VolumeCfg declares a volume that is mounted in the tasks of a service.
+type VolumeCfg struct {
+	// Type is VolumeHostPath, VolumeNamed or VolumeScratch.
+	Type string
+
+	// Source is the path on the host for VolumeHostPath, or the name of the
+	// volume (or claim) for VolumeNamed. Scratch volumes have no source.
+	Source string
+
+	// MountPath is the absolute path that the volume is mounted on in the
+	// task.
+	MountPath string
+
+	ReadOnly bool
+
+	// Driver is the volume driver of a named volume, e.g.: "rexray". Blank
+	// means the scheduler's default, except for Marathon, which needs one.
+	// Kubernetes ignores it, since the claim decides how the volume is
+	// provisioned.
+	Driver string
+}
+
+// ParseVolumeCfg parses a volume in the form "[SOURCE:]MOUNT_PATH[:ro|:rw]",
+// like "docker run --volume": a SOURCE that is an absolute path is a host
+// path, any other SOURCE is the name of a named volume, and no SOURCE is
+// scratch space, e.g.: "/var/log:/logs:ro", "pgdata:/var/lib/postgresql/data"
+// or "/tmp".
+func ParseVolumeCfg(s string) (VolumeCfg, error) {
+	var volumeCfg VolumeCfg
+	parts := strings.Split(s, ":")
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		volumeCfg.ReadOnly = parts[n-1] == "ro"
+		parts = parts[:n-1]
+	}
+	switch len(parts) {
+	case 1:
+		volumeCfg.Type, volumeCfg.MountPath = VolumeScratch, parts[0]
+	case 2:
+		volumeCfg.Type, volumeCfg.Source, volumeCfg.MountPath = VolumeNamed, parts[0], parts[1]
+		if path.IsAbs(volumeCfg.Source) {
+			volumeCfg.Type = VolumeHostPath
+		}
+	default:
+		return VolumeCfg{}, fmt.Errorf("invalid volume %q: expected [SOURCE:]MOUNT_PATH[:ro|:rw]", s)
+	}
+	if err := volumeCfg.Validate(); err != nil {
+		return VolumeCfg{}, fmt.Errorf("invalid volume %q: %s", s, err)
+	}
+	return volumeCfg, nil
+}
+
+// Validate returns an error if the volume has an unknown type, a mount path
+// that isn't absolute, or a source or driver that its type doesn't take.
+func (volumeCfg VolumeCfg) Validate() error {
+	if !path.IsAbs(volumeCfg.MountPath) {
+		return fmt.Errorf("mount path %q is not an absolute path", volumeCfg.MountPath)
+	}
+	switch volumeCfg.Type {
+	case VolumeHostPath:
+		if !path.IsAbs(volumeCfg.Source) {
+			return fmt.Errorf("host path %q of volume at %s is not an absolute path",
+				volumeCfg.Source, volumeCfg.MountPath)
+		}
+	case VolumeNamed:
+		if volumeCfg.Source == "" {
+			return fmt.Errorf("named volume at %s has no name", volumeCfg.MountPath)
+		}
+	case VolumeScratch:
+		if volumeCfg.Source != "" {
+			return fmt.Errorf("scratch volume at %s cannot have a source", volumeCfg.MountPath)
+		}
+	default:
+		return fmt.Errorf("volume at %s has unknown type %q", volumeCfg.MountPath, volumeCfg.Type)
+	}
+	if volumeCfg.Driver != "" && volumeCfg.Type != VolumeNamed {
+		return fmt.Errorf("%s volume at %s cannot have a driver", volumeCfg.Type, volumeCfg.MountPath)
+	}
+	return nil
+}
+
+// validateVolumes returns an error if a volume is invalid, or if more than one
+// volume is mounted at the same path.
+func validateVolumes(volumes []VolumeCfg) error {
+	mountPaths := map[string]bool{}
+	for _, volumeCfg := range volumes {
+		if err := volumeCfg.Validate(); err != nil {
+			return err
+		}
+		if mountPaths[volumeCfg.MountPath] {
+			return fmt.Errorf("more than one volume is mounted at %s", volumeCfg.MountPath)
+		}
+		mountPaths[volumeCfg.MountPath] = true
+	}
+	return nil
+}
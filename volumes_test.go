@@ -0,0 +1,66 @@
+package anysched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/msabramo/go-anysched"
+)
+
+var _ = Describe("volumes.go", func() {
+	Describe("ParseVolumeCfg", func() {
+		It("works with a host path", func() {
+			volumeCfg, err := anysched.ParseVolumeCfg("/var/log:/logs:ro")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(volumeCfg).To(Equal(anysched.VolumeCfg{
+				Type:      anysched.VolumeHostPath,
+				Source:    "/var/log",
+				MountPath: "/logs",
+				ReadOnly:  true,
+			}))
+		})
+
+		It("works with a named volume", func() {
+			volumeCfg, err := anysched.ParseVolumeCfg("pgdata:/var/lib/postgresql/data:rw")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(volumeCfg).To(Equal(anysched.VolumeCfg{
+				Type:      anysched.VolumeNamed,
+				Source:    "pgdata",
+				MountPath: "/var/lib/postgresql/data",
+			}))
+		})
+
+		It("works with scratch space", func() {
+			volumeCfg, err := anysched.ParseVolumeCfg("/tmp")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(volumeCfg).To(Equal(anysched.VolumeCfg{Type: anysched.VolumeScratch, MountPath: "/tmp"}))
+		})
+
+		It("returns an error for too many parts", func() {
+			_, err := anysched.ParseVolumeCfg("/a:/b:/c")
+			Expect(err).To(MatchError(`invalid volume "/a:/b:/c": expected [SOURCE:]MOUNT_PATH[:ro|:rw]`))
+		})
+
+		It("returns an error for a relative mount path", func() {
+			_, err := anysched.ParseVolumeCfg("pgdata:data")
+			Expect(err).To(MatchError(`invalid volume "pgdata:data": mount path "data" is not an absolute path`))
+		})
+	})
+
+	Describe("VolumeCfg.Validate", func() {
+		It("returns an error for an unknown type", func() {
+			err := anysched.VolumeCfg{Type: "nfs", MountPath: "/data"}.Validate()
+			Expect(err).To(MatchError(`volume at /data has unknown type "nfs"`))
+		})
+
+		It("returns an error for a named volume without a name", func() {
+			err := anysched.VolumeCfg{Type: anysched.VolumeNamed, MountPath: "/data"}.Validate()
+			Expect(err).To(MatchError(`named volume at /data has no name`))
+		})
+
+		It("returns an error for a driver of a volume that isn't named", func() {
+			err := anysched.VolumeCfg{Type: anysched.VolumeScratch, MountPath: "/tmp", Driver: "rexray"}.Validate()
+			Expect(err).To(MatchError(`scratch volume at /tmp cannot have a driver`))
+		})
+	})
+})